@@ -0,0 +1,265 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clocktest contains a fake clock for testing time-dependent code.
+// The clock implements the interfaces from k8s.io/utils/clock that are used across this module (for example, by the
+// events queue, the batcher, and the jobs manager), as well as the Clock interface of the backoff package, so the
+// same instance can drive multiple components in a single test.
+package clocktest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	kclock "k8s.io/utils/clock"
+)
+
+// Clock is a fake clock whose time only moves when Step or SetTime are invoked, or automatically when in
+// auto-advance mode.
+type Clock struct {
+	lock        sync.Mutex
+	cond        *sync.Cond
+	now         time.Time
+	waiters     []*waiter
+	autoAdvance bool
+}
+
+var _ kclock.WithTickerAndDelayedExecution = (*Clock)(nil)
+
+// New returns a new Clock set to the given time.
+func New(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.lock)
+	return c
+}
+
+type waiter struct {
+	target time.Time
+	period time.Duration
+	ch     chan time.Time
+	fn     func()
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed since t.
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After returns a channel that receives the time after d has elapsed.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer returns a new Timer that fires after d has elapsed.
+func (c *Clock) NewTimer(d time.Duration) kclock.Timer {
+	w := &waiter{ch: make(chan time.Time, 1)}
+	c.addWaiter(w, d)
+	return &timer{clock: c, w: w}
+}
+
+// AfterFunc invokes fn after d has elapsed. fn is invoked synchronously by Step and SetTime. When the clock is moved
+// forward in auto-advance mode, fn is invoked in a separate goroutine instead, as the caller that created the timer may
+// hold locks that fn needs.
+func (c *Clock) AfterFunc(d time.Duration, fn func()) kclock.Timer {
+	w := &waiter{fn: fn}
+	c.addWaiter(w, d)
+	return &timer{clock: c, w: w}
+}
+
+// Tick returns a channel that receives the time every d.
+func (c *Clock) Tick(d time.Duration) <-chan time.Time {
+	return c.NewTicker(d).C()
+}
+
+// NewTicker returns a new Ticker that fires every d.
+func (c *Clock) NewTicker(d time.Duration) kclock.Ticker {
+	w := &waiter{ch: make(chan time.Time, 1), period: d}
+	c.addWaiter(w, d)
+	return &ticker{clock: c, w: w}
+}
+
+// Sleep blocks until the clock has moved forward by d.
+func (c *Clock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Step moves the clock forward by d, firing the timers that are due.
+func (c *Clock) Step(d time.Duration) {
+	c.lock.Lock()
+	runCallbacks(c.setTimeLocked(c.now.Add(d)))
+}
+
+// SetTime sets the time of the clock, firing the timers that are due.
+func (c *Clock) SetTime(t time.Time) {
+	c.lock.Lock()
+	runCallbacks(c.setTimeLocked(t))
+}
+
+// SetAutoAdvance enables or disables auto-advance mode.
+// In auto-advance mode, each time a timer, ticker or sleep is created, the clock moves forward to its deadline,
+// firing it (and any timers that are due earlier) immediately; AfterFunc callbacks that are due are invoked in a
+// background goroutine. This is useful for code that waits on the clock in
+// a loop, such as retries with backoff, when the test only cares about the sequence of events.
+func (c *Clock) SetAutoAdvance(enabled bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.autoAdvance = enabled
+}
+
+// Waiters returns the number of timers, tickers and sleeps that are waiting on the clock.
+func (c *Clock) Waiters() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.waiters)
+}
+
+// HasWaiters returns true if there are timers, tickers or sleeps waiting on the clock.
+func (c *Clock) HasWaiters() bool {
+	return c.Waiters() > 0
+}
+
+// BlockUntilTimers blocks until at least n timers, tickers or sleeps are waiting on the clock.
+// This is used to make sure that code running in background goroutines has reached the point where it waits on the
+// clock, before moving it forward.
+func (c *Clock) BlockUntilTimers(n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *Clock) addWaiter(w *waiter, d time.Duration) {
+	c.lock.Lock()
+	w.target = c.now.Add(d)
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+
+	if c.autoAdvance {
+		target := w.target
+		if target.Before(c.now) {
+			target = c.now
+		}
+		// Releases the lock. Callbacks don't run synchronously, as the caller creating the timer could be holding a
+		// lock the callbacks need.
+		if fns := c.setTimeLocked(target); len(fns) > 0 {
+			go runCallbacks(fns)
+		}
+		return
+	}
+	c.lock.Unlock()
+}
+
+func (c *Clock) removeWaiter(w *waiter) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, cur := range c.waiters {
+		if cur == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// setTimeLocked sets the time and fires the timers that are due, returning the AfterFunc callbacks to invoke.
+// This must be invoked while the caller has a lock, which is released by this method.
+// Callbacks must be invoked after that, as they may use the clock.
+func (c *Clock) setTimeLocked(t time.Time) []func() {
+	c.now = t
+
+	var (
+		due  []*waiter
+		keep []*waiter
+	)
+	for _, w := range c.waiters {
+		if w.target.After(t) {
+			keep = append(keep, w)
+			continue
+		}
+		due = append(due, w)
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].target.Before(due[j].target)
+	})
+
+	var fns []func()
+	for _, w := range due {
+		if w.ch != nil {
+			select {
+			case w.ch <- t:
+			default:
+			}
+		}
+		if w.fn != nil {
+			fns = append(fns, w.fn)
+		}
+		if w.period > 0 {
+			// Reschedule tickers, skipping ticks that were missed
+			for !w.target.After(t) {
+				w.target = w.target.Add(w.period)
+			}
+			keep = append(keep, w)
+		}
+	}
+	c.waiters = keep
+	c.lock.Unlock()
+	return fns
+}
+
+// runCallbacks invokes the callbacks of the timers that fired, in order.
+func runCallbacks(fns []func()) {
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+type timer struct {
+	clock *Clock
+	w     *waiter
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.w.ch
+}
+
+func (t *timer) Stop() bool {
+	return t.clock.removeWaiter(t.w)
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	active := t.clock.removeWaiter(t.w)
+	t.clock.addWaiter(t.w, d)
+	return active
+}
+
+type ticker struct {
+	clock *Clock
+	w     *waiter
+}
+
+func (t *ticker) C() <-chan time.Time {
+	return t.w.ch
+}
+
+func (t *ticker) Stop() {
+	t.clock.removeWaiter(t.w)
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clocktest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/events/queue"
+)
+
+func assertFired(t *testing.T, ch <-chan time.Time) time.Time {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire")
+	}
+	return time.Time{}
+}
+
+func assertNotFired(t *testing.T, ch <-chan time.Time) {
+	t.Helper()
+	select {
+	case <-ch:
+		t.Fatal("timer fired unexpectedly")
+	default:
+	}
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("timers", func(t *testing.T) {
+		c := New(start)
+		tm := c.NewTimer(time.Second)
+		assert.Equal(t, 1, c.Waiters())
+
+		c.Step(500 * time.Millisecond)
+		assertNotFired(t, tm.C())
+		c.Step(500 * time.Millisecond)
+		assert.Equal(t, start.Add(time.Second), assertFired(t, tm.C()))
+		assert.False(t, c.HasWaiters())
+		assert.False(t, tm.Stop())
+
+		assert.False(t, tm.Reset(time.Second))
+		assert.True(t, tm.Stop())
+		c.Step(time.Second)
+		assertNotFired(t, tm.C())
+	})
+
+	t.Run("tickers", func(t *testing.T) {
+		c := New(start)
+		tk := c.NewTicker(time.Second)
+		for i := 1; i <= 3; i++ {
+			c.Step(time.Second)
+			assert.Equal(t, start.Add(time.Duration(i)*time.Second), assertFired(t, tk.C()))
+		}
+		tk.Stop()
+		assert.False(t, c.HasWaiters())
+	})
+
+	t.Run("after func", func(t *testing.T) {
+		c := New(start)
+		var order []int
+		c.AfterFunc(2*time.Second, func() {
+			order = append(order, 2)
+		})
+		c.AfterFunc(time.Second, func() {
+			order = append(order, 1)
+			// Callbacks can use the clock
+			_ = c.Now()
+		})
+		c.Step(5 * time.Second)
+		assert.Equal(t, []int{1, 2}, order)
+	})
+
+	t.Run("block until timers", func(t *testing.T) {
+		c := New(start)
+		doneCh := make(chan struct{})
+		go func() {
+			c.Sleep(time.Second)
+			c.Sleep(time.Second)
+			close(doneCh)
+		}()
+
+		for i := 0; i < 2; i++ {
+			c.BlockUntilTimers(1)
+			c.Step(time.Second)
+		}
+		select {
+		case <-doneCh:
+		case <-time.After(time.Second):
+			t.Fatal("sleeps did not return")
+		}
+		assert.Equal(t, start.Add(2*time.Second), c.Now())
+	})
+
+	t.Run("auto advance", func(t *testing.T) {
+		c := New(start)
+		c.SetAutoAdvance(true)
+
+		c.Sleep(time.Hour)
+		assert.Equal(t, start.Add(time.Hour), c.Now())
+		assert.Equal(t, start.Add(time.Hour+time.Minute), assertFired(t, c.After(time.Minute)))
+
+		// Works with backoff
+		b := backoff.NewExponentialBackOff()
+		b.Clock = c
+		b.Reset()
+		var attempts int
+		err := backoff.RetryNotifyWithTimer(func() error {
+			attempts++
+			if attempts < 5 {
+				return assert.AnError
+			}
+			return nil
+		}, b, nil, &backoffTimer{clock: c})
+		require.NoError(t, err)
+		assert.Equal(t, 5, attempts)
+
+		// Callbacks don't run while the caller creating the timer holds a lock they need
+		var lock sync.Mutex
+		firedCh := make(chan struct{})
+		lock.Lock()
+		c.AfterFunc(time.Second, func() {
+			lock.Lock()
+			defer lock.Unlock()
+			close(firedCh)
+		})
+		lock.Unlock()
+		select {
+		case <-firedCh:
+		case <-time.After(time.Second):
+			t.Fatal("callback was not invoked")
+		}
+	})
+}
+
+// backoffTimer implements backoff.Timer using a Clock.
+type backoffTimer struct {
+	clock *Clock
+	ch    <-chan time.Time
+}
+
+func (t *backoffTimer) Start(d time.Duration) {
+	t.ch = t.clock.After(d)
+}
+
+func (t *backoffTimer) Stop() {}
+
+func (t *backoffTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+type queueItem struct {
+	key string
+	at  time.Time
+}
+
+func (q *queueItem) Key() string {
+	return q.key
+}
+
+func (q *queueItem) ScheduledTime() time.Time {
+	return q.at
+}
+
+func TestClockWithQueue(t *testing.T) {
+	c := New(time.Now())
+	executed := make(chan string, 2)
+	p := queue.NewProcessor[*queueItem](func(r *queueItem) {
+		executed <- r.key
+	}).WithClock(c)
+	defer p.Close()
+
+	require.NoError(t, p.Enqueue(&queueItem{key: "b", at: c.Now().Add(2 * time.Second)}))
+	require.NoError(t, p.Enqueue(&queueItem{key: "a", at: c.Now().Add(time.Second)}))
+
+	for _, key := range []string{"a", "b"} {
+		c.BlockUntilTimers(1)
+		c.Step(time.Second)
+		select {
+		case k := <-executed:
+			assert.Equal(t, key, k)
+		case <-time.After(time.Second):
+			t.Fatalf("item %s was not executed", key)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types of the built-in codecs.
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/protobuf"
+)
+
+// Content types of formats without a built-in codec, for codecs registered by consumers.
+// CBOR and MessagePack codecs are not included on purpose, so the module doesn't depend on libraries for those
+// formats; they can be created with New, for example:
+//
+//	codec.New("cbor", []string{codec.ContentTypeCBOR}, cbor.Marshal, cbor.Unmarshal)
+const (
+	ContentTypeCBOR    = "application/cbor"
+	ContentTypeMsgPack = "application/msgpack"
+)
+
+// ErrNotProtoMessage is returned by the protobuf codec when the value is not a proto.Message.
+var ErrNotProtoMessage = errors.New("value is not a proto.Message")
+
+var (
+	// JSON is the codec for JSON, using encoding/json.
+	JSON Codec = jsonCodec{}
+	// Protobuf is the codec for the binary protobuf encoding. Values must implement proto.Message.
+	Protobuf Codec = protobufCodec{}
+)
+
+// New returns a Codec with the given name and content types, which uses the marshal and unmarshal functions, such as
+// the ones of a third-party library. The first content type is the canonical one.
+func New(
+	name string, contentTypes []string,
+	marshal func(v any) ([]byte, error), unmarshal func(data []byte, v any) error,
+) Codec {
+	return funcCodec{
+		name:         name,
+		contentTypes: contentTypes,
+		marshal:      marshal,
+		unmarshal:    unmarshal,
+	}
+}
+
+type funcCodec struct {
+	name         string
+	contentTypes []string
+	marshal      func(v any) ([]byte, error)
+	unmarshal    func(data []byte, v any) error
+}
+
+func (c funcCodec) Name() string {
+	return c.name
+}
+
+func (c funcCodec) ContentTypes() []string {
+	return c.contentTypes
+}
+
+func (c funcCodec) Marshal(v any) ([]byte, error) {
+	return c.marshal(v)
+}
+
+func (c funcCodec) Unmarshal(data []byte, v any) error {
+	return c.unmarshal(data, v)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) ContentTypes() []string {
+	return []string{ContentTypeJSON, "text/json"}
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string {
+	return "protobuf"
+}
+
+func (protobufCodec) ContentTypes() []string {
+	return []string{ContentTypeProtobuf, "application/x-protobuf", "application/vnd.google.protobuf"}
+}
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNotProtoMessage, v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrNotProtoMessage, v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// defaultRegistry is the registry used by the package-level functions.
+var defaultRegistry, _ = NewRegistry(JSON, Protobuf)
+
+// Default returns the default registry, which contains the JSON (default) and protobuf codecs.
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Register adds a codec to the default registry.
+func Register(c Codec) error {
+	return defaultRegistry.Register(c)
+}
+
+// ForContentType returns the codec for a content type from the default registry.
+func ForContentType(contentType string) (Codec, error) {
+	return defaultRegistry.ForContentType(contentType)
+}
+
+// Negotiate returns the codec for a response from the default registry. See Registry.Negotiate.
+func Negotiate(accept string) (Codec, string, error) {
+	return defaultRegistry.Negotiate(accept)
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package codec contains a registry of named codecs that encode and decode messages, such as JSON and protobuf, with
+// content-type negotiation and optional schema validation hooks.
+// Codecs for other formats, such as CBOR or MessagePack, are not built in, so the module doesn't depend on libraries
+// for them: consumers can register them, wrapping third-party libraries with New.
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnsupportedContentType is returned when there's no codec for a content type.
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	// ErrCodecExists is returned when registering a codec with a name or content type that is already registered.
+	ErrCodecExists = errors.New("codec already registered")
+	// ErrValidation is returned when a message fails schema validation.
+	ErrValidation = errors.New("message failed validation")
+)
+
+// Codec encodes and decodes messages in a format.
+type Codec interface {
+	// Name of the codec, such as "json".
+	Name() string
+	// ContentTypes returns the media types handled by the codec. The first one is the canonical one, which is used in
+	// responses.
+	ContentTypes() []string
+	// Marshal encodes v.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes data into v.
+	Unmarshal(data []byte, v any) error
+}
+
+// Validator validates a message against a schema. It's invoked with the encoded message and the decoded value: after
+// encoding in Marshal, and after decoding in Unmarshal.
+type Validator func(data []byte, v any) error
+
+// ValidationError is returned when a message fails validation. It matches ErrValidation with errors.Is.
+type ValidationError struct {
+	// Codec is the name of the codec.
+	Codec string
+	// Err is the error returned by the validator.
+	Err error
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", ErrValidation, e.Codec, e.Err)
+}
+
+// Unwrap returns the error returned by the validator.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Is allows matching the error with ErrValidation.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Registry contains codecs, addressed by name and content type. It's safe for concurrent use.
+type Registry struct {
+	lock       sync.RWMutex
+	codecs     []Codec
+	byName     map[string]Codec
+	byType     map[string]Codec
+	validators map[string][]Validator
+}
+
+// NewRegistry returns a new Registry with the given codecs. The first codec is the default one, used when any
+// content type is acceptable.
+func NewRegistry(codecs ...Codec) (*Registry, error) {
+	r := &Registry{
+		byName:     make(map[string]Codec, len(codecs)),
+		byType:     make(map[string]Codec),
+		validators: make(map[string][]Validator),
+	}
+	for _, c := range codecs {
+		err := r.Register(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds a codec to the registry.
+func (r *Registry) Register(c Codec) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.byName[c.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrCodecExists, c.Name())
+	}
+	for _, ct := range c.ContentTypes() {
+		if _, ok := r.byType[normalizeMediaType(ct)]; ok {
+			return fmt.Errorf("%w: content type %s", ErrCodecExists, ct)
+		}
+	}
+
+	r.codecs = append(r.codecs, c)
+	r.byName[c.Name()] = c
+	for _, ct := range c.ContentTypes() {
+		r.byType[normalizeMediaType(ct)] = c
+	}
+	return nil
+}
+
+// AddValidator adds a validator for the messages encoded and decoded with the codec with the given name.
+// Validators are invoked in the order they are added.
+func (r *Registry) AddValidator(codecName string, v Validator) {
+	r.lock.Lock()
+	r.validators[codecName] = append(r.validators[codecName], v)
+	r.lock.Unlock()
+}
+
+// Get returns the codec with the given name.
+func (r *Registry) Get(name string) (Codec, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	c, ok := r.byName[name]
+	return c, ok
+}
+
+// ForContentType returns the codec for a content type, such as the value of a Content-Type header.
+// Parameters (like "charset") are ignored, and structured syntax suffixes are supported, so for example
+// "application/cloudevents+json" is handled by the codec for "application/json".
+func (r *Registry) ForContentType(contentType string) (Codec, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	mt := normalizeMediaType(contentType)
+	if c, ok := r.byType[mt]; ok {
+		return c, nil
+	}
+	// Structured syntax suffix, e.g. "+json"
+	if i := strings.LastIndexByte(mt, '+'); i >= 0 {
+		if slash := strings.IndexByte(mt, '/'); slash >= 0 && slash < i {
+			if c, ok := r.byType[mt[:slash+1]+mt[i+1:]]; ok {
+				return c, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+}
+
+// Negotiate returns the codec to use for a response, given the value of an Accept header of the request, and the
+// content type to set in the response. Media ranges are considered in order of preference (quality values), and
+// wildcards are supported. If accept is empty, the default codec is returned.
+func (r *Registry) Negotiate(accept string) (Codec, string, error) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		ranges = []acceptRange{{mediaType: "*/*", q: 1}}
+	}
+
+	for _, ar := range ranges {
+		if ar.q <= 0 {
+			continue
+		}
+		switch {
+		case ar.mediaType == "*/*":
+			r.lock.RLock()
+			var c Codec
+			if len(r.codecs) > 0 {
+				c = r.codecs[0]
+			}
+			r.lock.RUnlock()
+			if c != nil {
+				return c, canonicalContentType(c), nil
+			}
+		case strings.HasSuffix(ar.mediaType, "/*"):
+			prefix := strings.TrimSuffix(ar.mediaType, "*")
+			r.lock.RLock()
+			for _, c := range r.codecs {
+				if ct := canonicalContentType(c); strings.HasPrefix(ct, prefix) {
+					r.lock.RUnlock()
+					return c, ct, nil
+				}
+			}
+			r.lock.RUnlock()
+		default:
+			if c, err := r.ForContentType(ar.mediaType); err == nil {
+				return c, ar.mediaType, nil
+			}
+		}
+	}
+	return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedContentType, accept)
+}
+
+// Marshal encodes v with the codec for the content type, then runs the validators.
+func (r *Registry) Marshal(contentType string, v any) ([]byte, error) {
+	c, err := r.ForContentType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message as %s: %w", c.Name(), err)
+	}
+	err = r.validate(c, data, v)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Unmarshal decodes data into v with the codec for the content type, then runs the validators.
+func (r *Registry) Unmarshal(contentType string, data []byte, v any) error {
+	c, err := r.ForContentType(contentType)
+	if err != nil {
+		return err
+	}
+	err = c.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("failed to decode message as %s: %w", c.Name(), err)
+	}
+	return r.validate(c, data, v)
+}
+
+func (r *Registry) validate(c Codec, data []byte, v any) error {
+	r.lock.RLock()
+	validators := r.validators[c.Name()]
+	r.lock.RUnlock()
+
+	for _, validate := range validators {
+		err := validate(data, v)
+		if err != nil {
+			return &ValidationError{Codec: c.Name(), Err: err}
+		}
+	}
+	return nil
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept parses the value of an Accept header, returning the media ranges sorted by preference.
+func parseAccept(accept string) []acceptRange {
+	var res []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mt, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			// Ignore invalid media ranges
+			continue
+		}
+		ar := acceptRange{mediaType: mt, q: 1}
+		if q, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(q, 64); err == nil {
+				ar.q = f
+			}
+		}
+		res = append(res, ar)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].q > res[j].q
+	})
+	return res
+}
+
+// normalizeMediaType returns the media type of a content type, lowercased and without parameters.
+func normalizeMediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	}
+	return mt
+}
+
+func canonicalContentType(c Codec) string {
+	cts := c.ContentTypes()
+	if len(cts) == 0 {
+		return ""
+	}
+	return normalizeMediaType(cts[0])
+}
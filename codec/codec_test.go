@@ -0,0 +1,194 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package codec
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// reverseCodec is a test codec that "encodes" strings by reversing them.
+type reverseCodec struct{}
+
+func (reverseCodec) Name() string           { return "reverse" }
+func (reverseCodec) ContentTypes() []string { return []string{"text/x-reverse"} }
+
+func (reverseCodec) Marshal(v any) ([]byte, error) {
+	s := []byte(v.(string))
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+	return s, nil
+}
+
+func (c reverseCodec) Unmarshal(data []byte, v any) error {
+	res, _ := c.Marshal(string(data))
+	*(v.(*string)) = string(res)
+	return nil
+}
+
+func TestForContentType(t *testing.T) {
+	tests := map[string]string{
+		"application/json":                         "json",
+		"application/json; charset=utf-8":          "json",
+		"Application/JSON":                         "json",
+		"text/json":                                "json",
+		"application/cloudevents+json":             "json",
+		"application/protobuf":                     "protobuf",
+		"application/x-protobuf":                   "protobuf",
+		"application/vnd.google.protobuf; proto=x": "protobuf",
+	}
+	for ct, name := range tests {
+		c, err := ForContentType(ct)
+		require.NoError(t, err, ct)
+		assert.Equal(t, name, c.Name(), ct)
+	}
+
+	_, err := ForContentType("application/cbor")
+	require.ErrorIs(t, err, ErrUnsupportedContentType)
+}
+
+func TestNegotiate(t *testing.T) {
+	r, err := NewRegistry(JSON, Protobuf, reverseCodec{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		accept      string
+		codec       string
+		contentType string
+	}{
+		{"", "json", "application/json"},
+		{"*/*", "json", "application/json"},
+		{"application/protobuf", "protobuf", "application/protobuf"},
+		{"application/json;q=0.5, application/x-protobuf", "protobuf", "application/x-protobuf"},
+		{"text/*", "reverse", "text/x-reverse"},
+		{"application/cbor, application/json;q=0.1", "json", "application/json"},
+		{"application/cbor, */*;q=0.1", "json", "application/json"},
+		{"application/cloudevents+json", "json", "application/cloudevents+json"},
+	}
+	for _, tt := range tests {
+		c, ct, err := r.Negotiate(tt.accept)
+		require.NoError(t, err, tt.accept)
+		assert.Equal(t, tt.codec, c.Name(), tt.accept)
+		assert.Equal(t, tt.contentType, ct, tt.accept)
+	}
+
+	_, _, err = r.Negotiate("application/cbor, application/json;q=0")
+	require.ErrorIs(t, err, ErrUnsupportedContentType)
+}
+
+func TestRegister(t *testing.T) {
+	r, err := NewRegistry(JSON)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, r.Register(JSON), ErrCodecExists)
+	require.NoError(t, r.Register(reverseCodec{}))
+
+	c, ok := r.Get("reverse")
+	require.True(t, ok)
+	assert.Equal(t, reverseCodec{}, c)
+
+	_, err = NewRegistry(JSON, JSON)
+	require.ErrorIs(t, err, ErrCodecExists)
+}
+
+func TestNew(t *testing.T) {
+	// A stand-in for a third-party MessagePack library
+	c := New("msgpack", []string{ContentTypeMsgPack, "application/x-msgpack"},
+		func(v any) ([]byte, error) {
+			return []byte("msgpack:" + v.(string)), nil
+		},
+		func(data []byte, v any) error {
+			*(v.(*string)) = string(data[len("msgpack:"):])
+			return nil
+		},
+	)
+	r, err := NewRegistry(JSON, c)
+	require.NoError(t, err)
+
+	got, err := r.ForContentType("application/x-msgpack")
+	require.NoError(t, err)
+	assert.Equal(t, "msgpack", got.Name())
+	_, ct, err := r.Negotiate("application/msgpack")
+	require.NoError(t, err)
+	assert.Equal(t, ContentTypeMsgPack, ct)
+
+	data, err := r.Marshal(ContentTypeMsgPack, "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "msgpack:hello", string(data))
+	var out string
+	require.NoError(t, r.Unmarshal(ContentTypeMsgPack, data, &out))
+	assert.Equal(t, "hello", out)
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	r, err := NewRegistry(JSON, Protobuf)
+	require.NoError(t, err)
+
+	t.Run("JSON", func(t *testing.T) {
+		data, err := r.Marshal("application/json", map[string]int{"a": 1})
+		require.NoError(t, err)
+		assert.Equal(t, `{"a":1}`, string(data))
+
+		var v map[string]int
+		require.NoError(t, r.Unmarshal("application/json", data, &v))
+		assert.Equal(t, map[string]int{"a": 1}, v)
+
+		err = r.Unmarshal("application/json", []byte("{"), &v)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode message as json")
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		data, err := r.Marshal(ContentTypeProtobuf, wrapperspb.String("hello"))
+		require.NoError(t, err)
+
+		var v wrapperspb.StringValue
+		require.NoError(t, r.Unmarshal(ContentTypeProtobuf, data, &v))
+		assert.True(t, proto.Equal(wrapperspb.String("hello"), &v))
+
+		_, err = r.Marshal(ContentTypeProtobuf, "not a proto")
+		require.ErrorIs(t, err, ErrNotProtoMessage)
+	})
+
+	t.Run("validators", func(t *testing.T) {
+		errInvalid := errors.New("missing name")
+		r.AddValidator("json", func(data []byte, v any) error {
+			if m, ok := v.(*map[string]string); ok && (*m)["name"] == "" {
+				return errInvalid
+			}
+			return nil
+		})
+
+		var v map[string]string
+		require.NoError(t, r.Unmarshal("application/json", []byte(`{"name":"dapr"}`), &v))
+
+		var invalid map[string]string
+		err := r.Unmarshal("application/json", []byte(`{"other":"x"}`), &invalid)
+		require.ErrorIs(t, err, ErrValidation)
+		require.ErrorIs(t, err, errInvalid)
+		var verr *ValidationError
+		require.True(t, errors.As(err, &verr))
+		assert.Equal(t, "json", verr.Codec)
+
+		// Validators don't apply to other codecs
+		_, err = r.Marshal(ContentTypeProtobuf, wrapperspb.String(""))
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBrokenBarrier is returned by Barrier.Wait when the barrier is broken, because a party stopped waiting (for
+// example, because its context was canceled) or the barrier was reset while parties were waiting.
+var ErrBrokenBarrier = errors.New("barrier is broken")
+
+// Barrier blocks a fixed number of parties until all of them have reached it.
+// It's cyclic: after all parties are released, it can be used again.
+type Barrier struct {
+	parties int
+
+	lock    sync.Mutex
+	waiting int
+	gen     *barrierGeneration
+}
+
+type barrierGeneration struct {
+	doneCh chan struct{}
+	broken bool
+}
+
+// NewBarrier returns a Barrier for the given number of parties, which must be positive.
+func NewBarrier(parties int) *Barrier {
+	if parties <= 0 {
+		panic("barrier parties must be positive")
+	}
+	return &Barrier{
+		parties: parties,
+		gen:     &barrierGeneration{doneCh: make(chan struct{})},
+	}
+}
+
+// Wait blocks until all parties have invoked Wait, or ctx is canceled.
+// If ctx is canceled, the barrier is broken: Wait returns the context's error, and all other parties waiting, and
+// those that invoke Wait afterwards, get ErrBrokenBarrier until Reset is invoked.
+func (b *Barrier) Wait(ctx context.Context) error {
+	b.lock.Lock()
+	g := b.gen
+	if g.broken {
+		b.lock.Unlock()
+		return ErrBrokenBarrier
+	}
+
+	b.waiting++
+	if b.waiting == b.parties {
+		// Last party: release everyone and start a new generation
+		b.waiting = 0
+		b.gen = &barrierGeneration{doneCh: make(chan struct{})}
+		close(g.doneCh)
+		b.lock.Unlock()
+		return nil
+	}
+	b.lock.Unlock()
+
+	select {
+	case <-g.doneCh:
+		if g.broken {
+			return ErrBrokenBarrier
+		}
+		return nil
+	case <-ctx.Done():
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		if b.gen != g {
+			// The barrier was tripped in the meanwhile
+			if g.broken {
+				return ErrBrokenBarrier
+			}
+			return nil
+		}
+		if !g.broken {
+			b.breakLocked()
+		}
+		return ctx.Err()
+	}
+}
+
+// Reset breaks the barrier for the parties that are currently waiting, which get ErrBrokenBarrier, and makes it
+// usable again.
+func (b *Barrier) Reset() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !b.gen.broken {
+		b.breakLocked()
+	}
+	b.waiting = 0
+	b.gen = &barrierGeneration{doneCh: make(chan struct{})}
+}
+
+// Broken returns true if the barrier is broken.
+func (b *Barrier) Broken() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.gen.broken
+}
+
+// Waiting returns the number of parties currently waiting.
+func (b *Barrier) Waiting() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.waiting
+}
+
+// breakLocked breaks the current generation.
+// This must be invoked while the caller has a lock.
+func (b *Barrier) breakLocked() {
+	b.gen.broken = true
+	close(b.gen.doneCh)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarrier(t *testing.T) {
+	t.Run("releases all parties together, and is reusable", func(t *testing.T) {
+		b := NewBarrier(3)
+		for round := 0; round < 2; round++ {
+			var wg sync.WaitGroup
+			errs := make(chan error, 3)
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- b.Wait(context.Background())
+				}()
+			}
+			assert.Eventually(t, func() bool { return b.Waiting() == 2 }, time.Second, time.Millisecond)
+
+			require.NoError(t, b.Wait(context.Background()))
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				require.NoError(t, err)
+			}
+			assert.Zero(t, b.Waiting())
+		}
+	})
+
+	t.Run("canceled waits break the barrier", func(t *testing.T) {
+		b := NewBarrier(3)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- b.Wait(context.Background())
+		}()
+		assert.Eventually(t, func() bool { return b.Waiting() == 1 }, time.Second, time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, b.Wait(ctx), context.Canceled)
+		require.ErrorIs(t, <-errCh, ErrBrokenBarrier)
+		assert.True(t, b.Broken())
+		require.ErrorIs(t, b.Wait(context.Background()), ErrBrokenBarrier)
+
+		// Reset makes the barrier usable again
+		b.Reset()
+		assert.False(t, b.Broken())
+		errCh = make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				errCh <- b.Wait(context.Background())
+			}()
+		}
+		require.NoError(t, b.Wait(context.Background()))
+		require.NoError(t, <-errCh)
+		require.NoError(t, <-errCh)
+	})
+
+	t.Run("reset breaks waiting parties", func(t *testing.T) {
+		b := NewBarrier(2)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- b.Wait(context.Background())
+		}()
+		assert.Eventually(t, func() bool { return b.Waiting() == 1 }, time.Second, time.Millisecond)
+
+		b.Reset()
+		require.ErrorIs(t, <-errCh, ErrBrokenBarrier)
+		assert.False(t, b.Broken())
+	})
+
+	t.Run("invalid parties", func(t *testing.T) {
+		assert.Panics(t, func() { NewBarrier(0) })
+	})
+}
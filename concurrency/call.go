@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/dapr/kit/utils"
+)
+
+// ErrAlreadyReplied is returned by Request.Reply when the request was already replied to.
+var ErrAlreadyReplied = errors.New("request was already replied to")
+
+// Request is a request sent over a channel with Call, which must be replied to with Reply.
+type Request[T any, R any] struct {
+	// Value of the request.
+	Value T
+
+	ctx     context.Context
+	replyCh chan utils.Result[R]
+	replied atomic.Bool
+}
+
+// Context returns the context of the caller. It's canceled when the caller stops waiting for the reply.
+func (r *Request[T, R]) Context() context.Context {
+	return r.ctx
+}
+
+// Reply sends the reply to the caller. It never blocks, even if the caller stopped waiting.
+// It returns ErrAlreadyReplied if invoked more than once.
+func (r *Request[T, R]) Reply(value R, err error) error {
+	if !r.replied.CompareAndSwap(false, true) {
+		return ErrAlreadyReplied
+	}
+	// The channel is buffered, so this never blocks
+	r.replyCh <- utils.ResultOf(value, err)
+	return nil
+}
+
+// Call sends a request with value req over reqCh and waits for the reply, implementing the request/response pattern
+// over channels, for example to query the state owned by a control loop.
+// If ctx is canceled (or its deadline is exceeded) before the request is received or replied to, Call returns the
+// context's error. Because the reply channel is buffered, the receiver never blocks (and never leaks a goroutine)
+// when replying to a caller that stopped waiting.
+func Call[T any, R any](ctx context.Context, reqCh chan<- *Request[T, R], req T) (R, error) {
+	var zero R
+
+	r := &Request[T, R]{
+		Value:   req,
+		ctx:     ctx,
+		replyCh: make(chan utils.Result[R], 1),
+	}
+
+	select {
+	case reqCh <- r:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+
+	select {
+	case res := <-r.replyCh:
+		return res.Get()
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// Serve receives requests from reqCh and replies to each of them with the result of handler, until ctx is canceled
+// or reqCh is closed.
+// Requests whose caller stopped waiting are skipped.
+func Serve[T any, R any](ctx context.Context, reqCh <-chan *Request[T, R], handler func(ctx context.Context, req T) (R, error)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case r, ok := <-reqCh:
+			if !ok {
+				return nil
+			}
+			if r.ctx.Err() != nil {
+				continue
+			}
+			res, err := handler(r.ctx, r.Value)
+			_ = r.Reply(res, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCall(t *testing.T) {
+	t.Run("request and reply", func(t *testing.T) {
+		reqCh := make(chan *Request[int, string])
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		serveErrCh := make(chan error, 1)
+		go func() {
+			serveErrCh <- Serve(ctx, reqCh, func(ctx context.Context, req int) (string, error) {
+				if req < 0 {
+					return "", errors.New("negative")
+				}
+				return strconv.Itoa(req * 2), nil
+			})
+		}()
+
+		res, err := Call(context.Background(), reqCh, 21)
+		require.NoError(t, err)
+		assert.Equal(t, "42", res)
+
+		_, err = Call(context.Background(), reqCh, -1)
+		require.EqualError(t, err, "negative")
+
+		cancel()
+		require.ErrorIs(t, <-serveErrCh, context.Canceled)
+	})
+
+	t.Run("timeout before the request is received", func(t *testing.T) {
+		reqCh := make(chan *Request[int, int])
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := Call(ctx, reqCh, 1)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("replying after the caller stopped waiting does not block", func(t *testing.T) {
+		reqCh := make(chan *Request[int, int], 1)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := Call(ctx, reqCh, 1)
+			errCh <- err
+		}()
+
+		r := <-reqCh
+		cancel()
+		require.ErrorIs(t, <-errCh, context.Canceled)
+		require.ErrorIs(t, r.Context().Err(), context.Canceled)
+
+		// Must not block
+		require.NoError(t, r.Reply(1, nil))
+		require.ErrorIs(t, r.Reply(2, nil), ErrAlreadyReplied)
+	})
+
+	t.Run("serve skips abandoned requests and stops when the channel is closed", func(t *testing.T) {
+		reqCh := make(chan *Request[int, int], 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		// Abandoned request is enqueued before the server starts
+		_, err := Call(ctx, reqCh, 1)
+		require.ErrorIs(t, err, context.Canceled)
+
+		var handled int
+		close(reqCh)
+		err = Serve(context.Background(), reqCh, func(ctx context.Context, req int) (int, error) {
+			handled++
+			return req, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 0, handled)
+	})
+}
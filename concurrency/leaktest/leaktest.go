@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package leaktest detects goroutines leaked by tests, such as workers of pools, processors, and broadcasters that
+// were created during a test and never stopped.
+//
+//	func TestMyComponent(t *testing.T) {
+//		leaktest.Check(t)
+//		// ...
+//	}
+//
+// Goroutines that were already running when Check was invoked are ignored, and so are the long-lived internals of
+// kit and of the Go runtime. Because all goroutines in the process are inspected, Check must not be used in tests
+// that run in parallel with others.
+package leaktest
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// DefaultTimeout is the default maximum time to wait for goroutines to exit at the end of the test.
+const DefaultTimeout = 2 * time.Second
+
+// defaultIgnored contains the functions of goroutines that are never reported as leaked.
+var defaultIgnored = []string{
+	// Go runtime and testing package
+	"testing.tRunner",
+	"testing.(*T).Run",
+	"testing.(*M).Run",
+	"testing.runFuzzing",
+	"os/signal.signal_recv",
+	"os/signal.loop",
+	"runtime.ensureSigM",
+
+	// Long-lived internals of kit, which are started once per process or per logger configuration
+	"github.com/dapr/kit/logger.(*sinkWriter).startAsync.func1",
+}
+
+// Option configures Check.
+type Option func(o *options)
+
+type options struct {
+	timeout time.Duration
+	ignored []string
+}
+
+// WithTimeout sets the maximum time to wait for goroutines to exit at the end of the test. Default is DefaultTimeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// IgnoreFunction ignores goroutines that have the function anywhere in their stack, or that were created by it.
+// Functions are fully-qualified, for example "github.com/dapr/kit/events/queue.(*Processor[...]).processLoop".
+func IgnoreFunction(fns ...string) Option {
+	return func(o *options) {
+		o.ignored = append(o.ignored, fns...)
+	}
+}
+
+// Check records the goroutines that are currently running, and, when the test completes, fails it if there are new
+// goroutines that haven't exited within the timeout.
+func Check(t testing.TB, opts ...Option) {
+	t.Helper()
+
+	o := options{
+		timeout: DefaultTimeout,
+		ignored: defaultIgnored,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	baseline := map[int]struct{}{}
+	for _, g := range goroutines() {
+		baseline[g.id] = struct{}{}
+	}
+
+	t.Cleanup(func() {
+		leaked := find(baseline, o)
+		if len(leaked) == 0 {
+			return
+		}
+
+		stacks := make([]string, len(leaked))
+		for i, g := range leaked {
+			stacks[i] = g.stack
+		}
+		t.Errorf("found %d leaked goroutines:\n\n%s", len(leaked), strings.Join(stacks, "\n\n"))
+	})
+}
+
+// find returns the goroutines that are not in the baseline nor ignored, waiting up to the timeout for them to exit.
+func find(baseline map[int]struct{}, o options) []goroutine {
+	deadline := time.Now().Add(o.timeout)
+	delay := time.Millisecond
+	for {
+		var leaked []goroutine
+		for _, g := range goroutines() {
+			if _, ok := baseline[g.id]; !ok && !g.matches(o.ignored) {
+				leaked = append(leaked, g)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+
+		time.Sleep(delay)
+		if delay < 100*time.Millisecond {
+			delay *= 2
+		}
+	}
+}
+
+// goroutine is a goroutine in a stack dump.
+type goroutine struct {
+	id    int
+	funcs []string
+	stack string
+}
+
+// matches returns true if any of the functions is in the stack of the goroutine.
+func (g goroutine) matches(fns []string) bool {
+	for _, f := range g.funcs {
+		for _, fn := range fns {
+			if f == fn {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// goroutines returns all goroutines, except the current one.
+func goroutines() []goroutine {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			// The first goroutine is the current one
+			res := parseGoroutines(buf[:n])
+			if len(res) > 0 {
+				res = res[1:]
+			}
+			return res
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parseGoroutines parses the output of runtime.Stack.
+func parseGoroutines(dump []byte) []goroutine {
+	var res []goroutine
+	for _, block := range bytes.Split(bytes.TrimSpace(dump), []byte("\n\n")) {
+		lines := strings.Split(string(block), "\n")
+		header, ok := strings.CutPrefix(lines[0], "goroutine ")
+		if !ok {
+			continue
+		}
+		idStr, _, _ := strings.Cut(header, " ")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			continue
+		}
+
+		g := goroutine{id: id, stack: string(block)}
+		for _, line := range lines[1:] {
+			if line == "" || line[0] == '\t' {
+				// File and line of the previous function
+				continue
+			}
+			if fn, ok := strings.CutPrefix(line, "created by "); ok {
+				fn, _, _ = strings.Cut(fn, " in goroutine ")
+				g.funcs = append(g.funcs, fn)
+				continue
+			}
+			if i := strings.LastIndexByte(line, '('); i > 0 && strings.HasSuffix(line, ")") {
+				line = line[:i]
+			}
+			g.funcs = append(g.funcs, line)
+		}
+		res = append(res, g)
+	}
+	return res
+}
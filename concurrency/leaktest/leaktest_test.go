@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaktest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/events/queue"
+	"github.com/dapr/kit/logger"
+)
+
+const testDump = `goroutine 7 [running]:
+github.com/dapr/kit/concurrency/leaktest.goroutines()
+	/src/leaktest.go:120 +0x45
+testing.tRunner(0xc000007a00, 0x5c8fb8)
+	/usr/local/go/src/testing/testing.go:1595 +0xff
+created by testing.(*T).Run in goroutine 1
+	/usr/local/go/src/testing/testing.go:1648 +0x3ad
+
+goroutine 12 [chan receive]:
+github.com/dapr/kit/events/queue.(*Processor[...]).processLoop(0xc0000b4000)
+	/src/processor.go:250 +0x1ab
+github.com/dapr/kit/events/queue.(*Processor[...]).process.func1()
+	/src/processor.go:200 +0x5e
+created by github.com/dapr/kit/events/queue.(*Processor[...]).process in goroutine 7
+	/src/processor.go:198 +0x10a
+`
+
+func TestParseGoroutines(t *testing.T) {
+	gs := parseGoroutines([]byte(testDump))
+	require.Len(t, gs, 2)
+
+	assert.Equal(t, 7, gs[0].id)
+	assert.Equal(t, []string{
+		"github.com/dapr/kit/concurrency/leaktest.goroutines",
+		"testing.tRunner",
+		"testing.(*T).Run",
+	}, gs[0].funcs)
+
+	assert.Equal(t, 12, gs[1].id)
+	assert.Equal(t, []string{
+		"github.com/dapr/kit/events/queue.(*Processor[...]).processLoop",
+		"github.com/dapr/kit/events/queue.(*Processor[...]).process.func1",
+		"github.com/dapr/kit/events/queue.(*Processor[...]).process",
+	}, gs[1].funcs)
+	assert.Contains(t, gs[1].stack, "[chan receive]")
+	assert.True(t, gs[1].matches([]string{"github.com/dapr/kit/events/queue.(*Processor[...]).process"}))
+}
+
+func snapshot() map[int]struct{} {
+	baseline := map[int]struct{}{}
+	for _, g := range goroutines() {
+		baseline[g.id] = struct{}{}
+	}
+	return baseline
+}
+
+func TestFind(t *testing.T) {
+	o := options{timeout: 100 * time.Millisecond, ignored: defaultIgnored}
+
+	t.Run("leaked goroutines", func(t *testing.T) {
+		baseline := snapshot()
+		stopCh := make(chan struct{})
+		go func() {
+			<-stopCh
+		}()
+
+		leaked := find(baseline, o)
+		require.Len(t, leaked, 1)
+		assert.Contains(t, leaked[0].stack, "TestFind")
+
+		close(stopCh)
+		assert.Empty(t, find(baseline, o))
+	})
+
+	t.Run("leaked processors", func(t *testing.T) {
+		baseline := snapshot()
+		p := queue.NewProcessor(func(*testItem) {})
+		require.NoError(t, p.Enqueue(&testItem{key: "a", at: time.Now().Add(time.Hour)}))
+
+		leaked := find(baseline, o)
+		require.Len(t, leaked, 1)
+		assert.Contains(t, leaked[0].stack, "processLoop")
+
+		require.NoError(t, p.Close())
+		assert.Empty(t, find(baseline, o))
+	})
+
+	t.Run("ignored functions", func(t *testing.T) {
+		baseline := snapshot()
+		p := queue.NewProcessor(func(*testItem) {})
+		defer p.Close()
+		require.NoError(t, p.Enqueue(&testItem{key: "a", at: time.Now().Add(time.Hour)}))
+
+		o := o
+		IgnoreFunction("github.com/dapr/kit/events/queue.(*Processor[...]).process")(&o)
+		assert.Empty(t, find(baseline, o))
+	})
+
+	t.Run("kit internals are ignored", func(t *testing.T) {
+		baseline := snapshot()
+		log := logger.NewLogger("test.leaktest")
+		require.NoError(t, logger.SetSinks(log, logger.SinksOptions{}, logger.Sink{Name: "async", Writer: &bytes.Buffer{}, Async: true}))
+		defer logger.SetSinks(log, logger.SinksOptions{})
+
+		assert.Empty(t, find(baseline, o))
+	})
+}
+
+func TestCheck(t *testing.T) {
+	Check(t)
+
+	doneCh := make(chan struct{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(doneCh)
+	}()
+	<-doneCh
+}
+
+type testItem struct {
+	key string
+	at  time.Time
+}
+
+func (i *testItem) Key() string {
+	return i.key
+}
+
+func (i *testItem) ScheduledTime() time.Time {
+	return i.at
+}
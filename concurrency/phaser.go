@@ -0,0 +1,174 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrPhaserTerminated is returned by Phaser methods after all parties have deregistered.
+	ErrPhaserTerminated = errors.New("phaser is terminated")
+	// ErrUnregisteredParty is returned when more parties arrive at a phase than are registered.
+	ErrUnregisteredParty = errors.New("arrival of an unregistered party")
+)
+
+// Phaser is a reusable synchronization barrier whose number of parties can change over time.
+// Parties register with Register, signal that they reached the current phase with Arrive, and wait for all other
+// parties with AwaitAdvance. When all registered parties have arrived, the phaser advances to the next phase. When all
+// parties have deregistered, the phaser is terminated.
+//
+// For example, in a multi-stage startup sequence every subsystem registers, then invokes ArriveAndAwaitAdvance after
+// completing each stage, so no subsystem begins a stage before all others have completed the previous one.
+type Phaser struct {
+	lock       sync.Mutex
+	parties    int
+	arrived    int
+	phase      int
+	advanceCh  chan struct{}
+	terminated bool
+}
+
+// NewPhaser returns a Phaser with the given number of initially registered parties, at phase 0.
+func NewPhaser(parties int) *Phaser {
+	return &Phaser{
+		parties:   parties,
+		advanceCh: make(chan struct{}),
+	}
+}
+
+// Register adds a party to the phaser, and returns the current phase.
+// If parties are arriving at the current phase, the new party must arrive too before the phase can advance.
+func (p *Phaser) Register() (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.terminated {
+		return 0, ErrPhaserTerminated
+	}
+	p.parties++
+	return p.phase, nil
+}
+
+// Arrive signals that a party reached the current phase, without waiting for the others, and returns the phase.
+func (p *Phaser) Arrive() (int, error) {
+	return p.arrive(false)
+}
+
+// ArriveAndDeregister signals that a party reached the current phase and deregisters it, and returns the phase.
+// If it was the last party, the phaser is terminated.
+func (p *Phaser) ArriveAndDeregister() (int, error) {
+	return p.arrive(true)
+}
+
+// ArriveAndAwaitAdvance signals that a party reached the current phase, then waits for all other parties to arrive.
+// It returns the new phase.
+func (p *Phaser) ArriveAndAwaitAdvance(ctx context.Context) (int, error) {
+	phase, err := p.Arrive()
+	if err != nil {
+		return 0, err
+	}
+	return p.AwaitAdvance(ctx, phase)
+}
+
+// AwaitAdvance waits for the phaser to advance from the given phase, and returns the new phase.
+// If the phaser is at a different phase already, it returns right away. If ctx is canceled, it returns the context's
+// error; unlike a Barrier, this doesn't affect the other parties.
+func (p *Phaser) AwaitAdvance(ctx context.Context, phase int) (int, error) {
+	p.lock.Lock()
+	if p.terminated {
+		p.lock.Unlock()
+		return 0, ErrPhaserTerminated
+	}
+	if p.phase != phase {
+		cur := p.phase
+		p.lock.Unlock()
+		return cur, nil
+	}
+	ch := p.advanceCh
+	p.lock.Unlock()
+
+	select {
+	case <-ch:
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if p.terminated {
+			return 0, ErrPhaserTerminated
+		}
+		return phase + 1, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// Phase returns the current phase.
+func (p *Phaser) Phase() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.phase
+}
+
+// Parties returns the number of registered parties.
+func (p *Phaser) Parties() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.parties
+}
+
+// Arrived returns the number of parties that arrived at the current phase.
+func (p *Phaser) Arrived() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.arrived
+}
+
+// Terminated returns true if all parties have deregistered.
+func (p *Phaser) Terminated() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.terminated
+}
+
+func (p *Phaser) arrive(deregister bool) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.terminated {
+		return 0, ErrPhaserTerminated
+	}
+	if p.arrived >= p.parties {
+		return 0, ErrUnregisteredParty
+	}
+
+	phase := p.phase
+	if deregister {
+		p.parties--
+		if p.parties == 0 {
+			p.terminated = true
+			close(p.advanceCh)
+			return phase, nil
+		}
+	} else {
+		p.arrived++
+	}
+
+	if p.arrived == p.parties {
+		p.arrived = 0
+		p.phase++
+		close(p.advanceCh)
+		p.advanceCh = make(chan struct{})
+	}
+	return phase, nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhaser(t *testing.T) {
+	t.Run("multi-stage sequence", func(t *testing.T) {
+		const parties = 4
+		p := NewPhaser(parties)
+
+		var (
+			lock     sync.Mutex
+			progress []int
+			wg       sync.WaitGroup
+		)
+		for i := 0; i < parties; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for stage := 0; stage < 3; stage++ {
+					lock.Lock()
+					progress = append(progress, stage)
+					lock.Unlock()
+
+					phase, err := p.ArriveAndAwaitAdvance(context.Background())
+					assert.NoError(t, err)
+					assert.Equal(t, stage+1, phase)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// No party begins a stage before all others have completed the previous one
+		require.Len(t, progress, 3*parties)
+		for i, stage := range progress {
+			assert.Equal(t, i/parties, stage)
+		}
+		assert.Equal(t, 3, p.Phase())
+	})
+
+	t.Run("dynamic registration", func(t *testing.T) {
+		p := NewPhaser(1)
+		phase, err := p.Register()
+		require.NoError(t, err)
+		assert.Equal(t, 0, phase)
+		assert.Equal(t, 2, p.Parties())
+
+		_, err = p.Arrive()
+		require.NoError(t, err)
+		assert.Equal(t, 0, p.Phase())
+		assert.Equal(t, 1, p.Arrived())
+
+		// Deregistering the other party advances the phase
+		_, err = p.ArriveAndDeregister()
+		require.NoError(t, err)
+		assert.Equal(t, 1, p.Phase())
+		assert.Equal(t, 1, p.Parties())
+
+		// Waiting for a past phase returns right away
+		phase, err = p.AwaitAdvance(context.Background(), 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1, phase)
+
+		// Arrivals without registered parties
+		p = NewPhaser(0)
+		_, err = p.Arrive()
+		require.ErrorIs(t, err, ErrUnregisteredParty)
+		_, err = p.Register()
+		require.NoError(t, err)
+		_, err = p.Arrive()
+		require.NoError(t, err)
+		assert.Equal(t, 1, p.Phase())
+	})
+
+	t.Run("context-aware waits", func(t *testing.T) {
+		p := NewPhaser(2)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := p.ArriveAndAwaitAdvance(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// Other parties are not affected
+		_, err = p.Arrive()
+		require.NoError(t, err)
+		assert.Equal(t, 1, p.Phase())
+	})
+
+	t.Run("termination", func(t *testing.T) {
+		p := NewPhaser(2)
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := p.ArriveAndAwaitAdvance(context.Background())
+			errCh <- err
+		}()
+		assert.Eventually(t, func() bool { return p.Arrived() == 1 }, time.Second, time.Millisecond)
+
+		_, err := p.ArriveAndDeregister()
+		require.NoError(t, err)
+		// The phase advanced for the waiting party
+		require.NoError(t, <-errCh)
+
+		_, err = p.ArriveAndDeregister()
+		require.NoError(t, err)
+		assert.True(t, p.Terminated())
+		_, err = p.Register()
+		require.ErrorIs(t, err, ErrPhaserTerminated)
+		_, err = p.Arrive()
+		require.ErrorIs(t, err, ErrPhaserTerminated)
+		_, err = p.AwaitAdvance(context.Background(), 1)
+		require.ErrorIs(t, err, ErrPhaserTerminated)
+	})
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pools
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Files that contain the CPU quota of the cgroup of the process, for cgroups v2 and v1.
+const (
+	cgroupV2CPUMax     = "/sys/fs/cgroup/cpu.max"
+	cgroupV1CFSQuotaUs = "/sys/fs/cgroup/cpu/cpu.cfs_quota_us"
+	cgroupV1CFSPeriod  = "/sys/fs/cgroup/cpu/cpu.cfs_period_us"
+)
+
+// DetectCPUs returns the number of CPUs the process can use: the value of GOMAXPROCS, capped by the CPU quota of
+// the cgroup of the process (rounded up), if any. This is the case, for example, of containers with a CPU limit.
+// The result is always at least 1.
+func DetectCPUs() int {
+	return detectCPUs(os.ReadFile, runtime.GOMAXPROCS(0))
+}
+
+func detectCPUs(readFile func(string) ([]byte, error), gomaxprocs int) int {
+	cpus := gomaxprocs
+	if quota, ok := cgroupCPUQuota(readFile); ok {
+		if q := int(math.Ceil(quota)); q < cpus {
+			cpus = q
+		}
+	}
+	if cpus < 1 {
+		cpus = 1
+	}
+	return cpus
+}
+
+// cgroupCPUQuota returns the CPU quota of the cgroup, as a number of CPUs.
+// The returned boolean is false if there's no quota, or it can't be determined.
+func cgroupCPUQuota(readFile func(string) ([]byte, error)) (float64, bool) {
+	// cgroups v2: "<quota> <period>", where quota can be "max"
+	if data, err := readFile(cgroupV2CPUMax); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return parseQuota(fields[0], fields[1])
+	}
+
+	// cgroups v1: quota and period in separate files, where quota is -1 if there's no limit
+	quota, err := readFile(cgroupV1CFSQuotaUs)
+	if err != nil {
+		return 0, false
+	}
+	period, err := readFile(cgroupV1CFSPeriod)
+	if err != nil {
+		return 0, false
+	}
+	return parseQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func parseQuota(quotaStr, periodStr string) (float64, bool) {
+	quota, err := strconv.ParseInt(quotaStr, 10, 64)
+	if err != nil || quota <= 0 {
+		return 0, false
+	}
+	period, err := strconv.ParseInt(periodStr, 10, 64)
+	if err != nil || period <= 0 {
+		return 0, false
+	}
+	return float64(quota) / float64(period), true
+}
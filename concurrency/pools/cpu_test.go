@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pools
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectCPUs(t *testing.T) {
+	files := func(m map[string]string) func(string) ([]byte, error) {
+		return func(name string) ([]byte, error) {
+			data, ok := m[name]
+			if !ok {
+				return nil, os.ErrNotExist
+			}
+			return []byte(data), nil
+		}
+	}
+
+	tests := []struct {
+		name       string
+		files      map[string]string
+		gomaxprocs int
+		expect     int
+	}{
+		{name: "no cgroup", files: nil, gomaxprocs: 8, expect: 8},
+		{name: "cgroup v2 without limit", files: map[string]string{cgroupV2CPUMax: "max 100000\n"}, gomaxprocs: 8, expect: 8},
+		{name: "cgroup v2 with limit", files: map[string]string{cgroupV2CPUMax: "200000 100000\n"}, gomaxprocs: 8, expect: 2},
+		{name: "cgroup v2 fractional limit", files: map[string]string{cgroupV2CPUMax: "150000 100000\n"}, gomaxprocs: 8, expect: 2},
+		{name: "cgroup v2 limit above gomaxprocs", files: map[string]string{cgroupV2CPUMax: "1600000 100000\n"}, gomaxprocs: 8, expect: 8},
+		{name: "cgroup v2 small limit", files: map[string]string{cgroupV2CPUMax: "10000 100000\n"}, gomaxprocs: 8, expect: 1},
+		{name: "cgroup v2 invalid", files: map[string]string{cgroupV2CPUMax: "foo"}, gomaxprocs: 4, expect: 4},
+		{name: "cgroup v1 with limit", files: map[string]string{cgroupV1CFSQuotaUs: "300000\n", cgroupV1CFSPeriod: "100000\n"}, gomaxprocs: 8, expect: 3},
+		{name: "cgroup v1 without limit", files: map[string]string{cgroupV1CFSQuotaUs: "-1\n", cgroupV1CFSPeriod: "100000\n"}, gomaxprocs: 8, expect: 8},
+		{name: "invalid gomaxprocs", files: nil, gomaxprocs: 0, expect: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, detectCPUs(files(tt.files), tt.gomaxprocs))
+		})
+	}
+
+	assert.GreaterOrEqual(t, DetectCPUs(), 1)
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pools
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPoolFull is returned when submitting work to a pool whose queue of waiting tasks is full.
+var ErrPoolFull = errors.New("worker pool is full")
+
+// Config contains the configuration for a pool.
+type Config struct {
+	// MaxWorkers is the maximum number of tasks that run concurrently.
+	MaxWorkers int `mapstructure:"maxWorkers" json:"maxWorkers"`
+	// MaxQueued is the maximum number of tasks that can wait for a worker. If 0, there's no limit.
+	MaxQueued int `mapstructure:"maxQueued" json:"maxQueued"`
+}
+
+// Stats contains metrics about a pool.
+type Stats struct {
+	Name       string `json:"name"`
+	MaxWorkers int    `json:"maxWorkers"`
+	MaxQueued  int    `json:"maxQueued"`
+	// Active is the number of tasks that are running.
+	Active int `json:"active"`
+	// Queued is the number of tasks that are waiting for a worker.
+	Queued int `json:"queued"`
+	// Completed is the number of tasks that completed.
+	Completed uint64 `json:"completed"`
+	// Rejected is the number of tasks that were rejected because the pool was full or the context was canceled.
+	Rejected uint64 `json:"rejected"`
+}
+
+// Pool runs tasks in background goroutines, limiting how many run concurrently.
+type Pool struct {
+	name string
+
+	lock      sync.Mutex
+	cfg       Config
+	active    int
+	waiters   []chan struct{}
+	completed uint64
+	rejected  uint64
+	wg        sync.WaitGroup
+}
+
+// NewPool returns a new Pool that is not part of the registry.
+func NewPool(name string, cfg Config) *Pool {
+	return &Pool{
+		name: name,
+		cfg:  normalizeConfig(cfg),
+	}
+}
+
+// Name returns the name of the pool.
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// Submit runs fn in a background goroutine, waiting for a worker to be available if needed.
+// It returns an error if the context is canceled while waiting, or if the queue of waiting tasks is full.
+func (p *Pool) Submit(ctx context.Context, fn func()) error {
+	err := p.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	p.run(fn)
+	return nil
+}
+
+// TrySubmit runs fn in a background goroutine if a worker is available right away, and returns true in that case.
+func (p *Pool) TrySubmit(fn func()) bool {
+	p.lock.Lock()
+	if p.active >= p.cfg.MaxWorkers {
+		p.rejected++
+		p.lock.Unlock()
+		return false
+	}
+	p.active++
+	p.lock.Unlock()
+
+	p.run(fn)
+	return true
+}
+
+// Wait blocks until all submitted tasks have completed.
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+// SetConfig updates the configuration of the pool.
+// If the number of workers is increased, waiting tasks are started right away; if it's decreased, running tasks are
+// not interrupted.
+func (p *Pool) SetConfig(cfg Config) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.cfg = normalizeConfig(cfg)
+	for len(p.waiters) > 0 && p.active < p.cfg.MaxWorkers {
+		p.active++
+		p.grantLocked()
+	}
+}
+
+// Config returns the current configuration of the pool.
+func (p *Pool) Config() Config {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.cfg
+}
+
+// Stats returns metrics about the pool.
+func (p *Pool) Stats() Stats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return Stats{
+		Name:       p.name,
+		MaxWorkers: p.cfg.MaxWorkers,
+		MaxQueued:  p.cfg.MaxQueued,
+		Active:     p.active,
+		Queued:     len(p.waiters),
+		Completed:  p.completed,
+		Rejected:   p.rejected,
+	}
+}
+
+func (p *Pool) run(fn func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer p.release()
+		fn()
+	}()
+}
+
+// acquire waits for a worker to be available.
+func (p *Pool) acquire(ctx context.Context) error {
+	p.lock.Lock()
+	if p.active < p.cfg.MaxWorkers && len(p.waiters) == 0 {
+		p.active++
+		p.lock.Unlock()
+		return nil
+	}
+	if p.cfg.MaxQueued > 0 && len(p.waiters) >= p.cfg.MaxQueued {
+		p.rejected++
+		p.lock.Unlock()
+		return ErrPoolFull
+	}
+	ch := make(chan struct{})
+	p.waiters = append(p.waiters, ch)
+	p.lock.Unlock()
+
+	select {
+	case <-ch:
+		// The worker was handed over to us
+		return nil
+	case <-ctx.Done():
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		for i, w := range p.waiters {
+			if w == ch {
+				p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+				p.rejected++
+				return ctx.Err()
+			}
+		}
+		// We were granted a worker after the context was canceled: we've returned an error, so release it
+		p.rejected++
+		p.releaseLocked()
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) release() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.completed++
+	p.releaseLocked()
+}
+
+// releaseLocked releases a worker, handing it over to the first waiting task if any.
+// This must be invoked while the caller has a lock.
+func (p *Pool) releaseLocked() {
+	if len(p.waiters) > 0 && p.active <= p.cfg.MaxWorkers {
+		// Hand over the worker, so active doesn't change
+		p.grantLocked()
+		return
+	}
+	p.active--
+}
+
+// grantLocked wakes up the first waiting task.
+// This must be invoked while the caller has a lock.
+func (p *Pool) grantLocked() {
+	ch := p.waiters[0]
+	p.waiters = p.waiters[1:]
+	close(ch)
+}
+
+func normalizeConfig(cfg Config) Config {
+	if cfg.MaxWorkers <= 0 {
+		cfg.MaxWorkers = DefaultMaxWorkers
+	}
+	if cfg.MaxQueued < 0 {
+		cfg.MaxQueued = 0
+	}
+	return cfg
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pools
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool(t *testing.T) {
+	t.Run("limits concurrency", func(t *testing.T) {
+		p := NewPool("test", Config{MaxWorkers: 2})
+
+		var running, maxRunning atomic.Int32
+		for i := 0; i < 10; i++ {
+			require.NoError(t, p.Submit(context.Background(), func() {
+				n := running.Add(1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Add(-1)
+			}))
+		}
+		p.Wait()
+
+		assert.Equal(t, int32(2), maxRunning.Load())
+		stats := p.Stats()
+		assert.Equal(t, uint64(10), stats.Completed)
+		assert.Equal(t, 0, stats.Active)
+		assert.Equal(t, 0, stats.Queued)
+	})
+
+	t.Run("queue limits and try submit", func(t *testing.T) {
+		p := NewPool("test", Config{MaxWorkers: 1, MaxQueued: 1})
+		blockCh := make(chan struct{})
+		require.True(t, p.TrySubmit(func() {
+			<-blockCh
+		}))
+		assert.False(t, p.TrySubmit(func() {}))
+
+		// One task can wait
+		submitted := make(chan error)
+		go func() {
+			submitted <- p.Submit(context.Background(), func() {})
+		}()
+		require.Eventually(t, func() bool {
+			return p.Stats().Queued == 1
+		}, time.Second, 5*time.Millisecond)
+
+		require.ErrorIs(t, p.Submit(context.Background(), func() {}), ErrPoolFull)
+
+		close(blockCh)
+		require.NoError(t, <-submitted)
+		p.Wait()
+		stats := p.Stats()
+		assert.Equal(t, uint64(2), stats.Completed)
+		assert.Equal(t, uint64(2), stats.Rejected)
+	})
+
+	t.Run("context canceled while waiting", func(t *testing.T) {
+		p := NewPool("test", Config{MaxWorkers: 1})
+		blockCh := make(chan struct{})
+		require.NoError(t, p.Submit(context.Background(), func() {
+			<-blockCh
+		}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, p.Submit(ctx, func() {}), context.DeadlineExceeded)
+		assert.Equal(t, 0, p.Stats().Queued)
+
+		close(blockCh)
+		p.Wait()
+		assert.Equal(t, 0, p.Stats().Active)
+	})
+
+	t.Run("increasing the limit starts waiting tasks", func(t *testing.T) {
+		p := NewPool("test", Config{MaxWorkers: 1})
+		blockCh := make(chan struct{})
+		require.NoError(t, p.Submit(context.Background(), func() {
+			<-blockCh
+		}))
+
+		startedCh := make(chan struct{})
+		go func() {
+			_ = p.Submit(context.Background(), func() {
+				close(startedCh)
+			})
+		}()
+		require.Eventually(t, func() bool {
+			return p.Stats().Queued == 1
+		}, time.Second, 5*time.Millisecond)
+
+		p.SetConfig(Config{MaxWorkers: 2})
+		select {
+		case <-startedCh:
+		case <-time.After(time.Second):
+			t.Fatal("waiting task was not started")
+		}
+		close(blockCh)
+		p.Wait()
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Cleanup(func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+		for _, name := range []string{"test-registry-a", "test-registry-b"} {
+			delete(registry, name)
+			delete(configs, name)
+		}
+	})
+
+	Configure(map[string]Config{
+		"test-registry-a": {MaxWorkers: 3},
+	})
+
+	a := Get("test-registry-a")
+	assert.Same(t, a, Get("test-registry-a"))
+	assert.Equal(t, 3, a.Config().MaxWorkers)
+
+	b := Get("test-registry-b")
+	assert.Equal(t, DefaultMaxWorkers, b.Config().MaxWorkers)
+
+	// Configuration applies to existing pools
+	Configure(map[string]Config{
+		"test-registry-b": {MaxWorkers: 5, MaxQueued: 10},
+	})
+	assert.Equal(t, Config{MaxWorkers: 5, MaxQueued: 10}, b.Config())
+
+	var names []string
+	for _, s := range AllStats() {
+		names = append(names, s.Name)
+	}
+	assert.Contains(t, names, "test-registry-a")
+	assert.Contains(t, names, "test-registry-b")
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pools
+
+import (
+	"sync/atomic"
+)
+
+// Preset is a configuration for pools derived from the number of CPUs available to the process.
+type Preset int
+
+const (
+	// PresetCPUBound is for pools that run CPU-bound tasks: there's one worker per CPU.
+	PresetCPUBound Preset = iota + 1
+	// PresetIOBound is for pools that run tasks that mostly wait on I/O: there are IOBoundWorkersPerCPU workers per
+	// CPU.
+	PresetIOBound
+)
+
+// IOBoundWorkersPerCPU is the number of workers per CPU of pools that use PresetIOBound.
+var IOBoundWorkersPerCPU = 8
+
+// cpuDetector returns the number of CPUs used by presets. It can be replaced in tests.
+var cpuDetector = DetectCPUs
+
+// presetCPUs is the number of CPUs presets are computed for, as of the last call to Retune. 0 if not detected yet.
+var presetCPUs atomic.Int64
+
+// String implements fmt.Stringer.
+func (p Preset) String() string {
+	switch p {
+	case PresetCPUBound:
+		return "cpu-bound"
+	case PresetIOBound:
+		return "io-bound"
+	default:
+		return "unknown"
+	}
+}
+
+// Config returns the configuration of the preset for the given number of CPUs.
+func (p Preset) Config(cpus int) Config {
+	if cpus < 1 {
+		cpus = 1
+	}
+	switch p {
+	case PresetIOBound:
+		return Config{MaxWorkers: cpus * IOBoundWorkersPerCPU}
+	default:
+		return Config{MaxWorkers: cpus}
+	}
+}
+
+// CurrentConfig returns the configuration of the preset for the number of CPUs available to the process.
+func (p Preset) CurrentConfig() Config {
+	return p.Config(presetCPUCount())
+}
+
+// GetWithPreset returns the pool with the given name, creating it if it doesn't exist.
+// The pool uses the configuration of the preset, which is updated by Retune, unless a configuration for the pool is
+// set explicitly with Configure, which takes precedence.
+func GetWithPreset(name string, preset Preset) *Pool {
+	cfg := preset.CurrentConfig()
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	presets[name] = preset
+	p, ok := registry[name]
+	if !ok {
+		if explicit, ok := configs[name]; ok {
+			cfg = explicit
+		}
+		p = NewPool(name, cfg)
+		registry[name] = p
+	} else if _, ok := configs[name]; !ok {
+		p.SetConfig(cfg)
+	}
+	return p
+}
+
+// Retune detects the number of CPUs available to the process again, and updates the configuration of all pools that
+// use a preset and are not configured explicitly. It should be invoked when the limits of the process change, for
+// example after GOMAXPROCS is changed or the CPU quota of the container is updated.
+// It returns the number of CPUs that was detected.
+func Retune() int {
+	cpus := cpuDetector()
+	presetCPUs.Store(int64(cpus))
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	for name, preset := range presets {
+		if _, ok := configs[name]; ok {
+			continue
+		}
+		if p, ok := registry[name]; ok {
+			p.SetConfig(preset.Config(cpus))
+		}
+	}
+	return cpus
+}
+
+// presetCPUCount returns the number of CPUs presets are computed for, detecting it the first time.
+func presetCPUCount() int {
+	cpus := presetCPUs.Load()
+	if cpus == 0 {
+		cpus = int64(cpuDetector())
+		presetCPUs.CompareAndSwap(0, cpus)
+	}
+	return int(cpus)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresetConfig(t *testing.T) {
+	assert.Equal(t, Config{MaxWorkers: 4}, PresetCPUBound.Config(4))
+	assert.Equal(t, Config{MaxWorkers: 4 * IOBoundWorkersPerCPU}, PresetIOBound.Config(4))
+	assert.Equal(t, Config{MaxWorkers: 1}, PresetCPUBound.Config(0))
+	assert.Equal(t, "io-bound", PresetIOBound.String())
+}
+
+func TestGetWithPresetAndRetune(t *testing.T) {
+	names := []string{"test-preset-cpu", "test-preset-io", "test-preset-explicit"}
+	origDetector := cpuDetector
+	origCPUs := presetCPUs.Load()
+	t.Cleanup(func() {
+		cpuDetector = origDetector
+		presetCPUs.Store(origCPUs)
+		registryLock.Lock()
+		defer registryLock.Unlock()
+		for _, name := range names {
+			delete(registry, name)
+			delete(configs, name)
+			delete(presets, name)
+		}
+	})
+
+	cpus := 2
+	cpuDetector = func() int {
+		return cpus
+	}
+	assert.Equal(t, 2, Retune())
+
+	Configure(map[string]Config{
+		"test-preset-explicit": {MaxWorkers: 3},
+	})
+
+	cpu := GetWithPreset("test-preset-cpu", PresetCPUBound)
+	io := GetWithPreset("test-preset-io", PresetIOBound)
+	explicit := GetWithPreset("test-preset-explicit", PresetIOBound)
+	assert.Same(t, cpu, Get("test-preset-cpu"))
+	assert.Equal(t, 2, cpu.Config().MaxWorkers)
+	assert.Equal(t, 2*IOBoundWorkersPerCPU, io.Config().MaxWorkers)
+	assert.Equal(t, 3, explicit.Config().MaxWorkers)
+
+	// Limits change
+	cpus = 6
+	assert.Equal(t, 6, Retune())
+	assert.Equal(t, 6, cpu.Config().MaxWorkers)
+	assert.Equal(t, 6*IOBoundWorkersPerCPU, io.Config().MaxWorkers)
+	assert.Equal(t, 3, explicit.Config().MaxWorkers)
+	assert.Equal(t, Config{MaxWorkers: 6}, PresetCPUBound.CurrentConfig())
+}
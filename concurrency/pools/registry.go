@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pools contains a process-wide registry of named worker pools.
+// Subsystems obtain pools by name (for example, pools.Get("pubsub-ingress")), so they can share capacity policies,
+// and operators can tune the limits of all pools centrally, for example from configuration, with Configure.
+// Pools obtained with GetWithPreset size themselves from the CPUs available to the process, including the CPU quota of
+// the container, and can be retuned at runtime with Retune.
+package pools
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// DefaultMaxWorkers is the number of workers of pools that are not configured explicitly.
+var DefaultMaxWorkers = runtime.NumCPU()
+
+var (
+	registry     = map[string]*Pool{}
+	configs      = map[string]Config{}
+	presets      = map[string]Preset{}
+	registryLock sync.Mutex
+)
+
+// Get returns the pool with the given name, creating it if it doesn't exist.
+// New pools use the configuration set with Configure, or the default one.
+func Get(name string) *Pool {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	p, ok := registry[name]
+	if !ok {
+		p = NewPool(name, configs[name])
+		registry[name] = p
+	}
+	return p
+}
+
+// Configure sets the configuration of the pools with the given names.
+// The configuration applies to existing pools right away, and to pools that are created later.
+func Configure(cfgs map[string]Config) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	for name, cfg := range cfgs {
+		configs[name] = cfg
+		if p, ok := registry[name]; ok {
+			p.SetConfig(cfg)
+		}
+	}
+}
+
+// AllStats returns the stats of all pools in the registry, sorted by name.
+func AllStats() []Stats {
+	registryLock.Lock()
+	pools := make([]*Pool, 0, len(registry))
+	for _, p := range registry {
+		pools = append(pools, p)
+	}
+	registryLock.Unlock()
+
+	res := make([]Stats, len(pools))
+	for i, p := range pools {
+		res[i] = p.Stats()
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
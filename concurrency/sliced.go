@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package concurrency contains utilities for running and coordinating concurrent work.
+package concurrency
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// DefaultSliceBudget is the slice budget used by RunSliced when the value passed is not positive.
+const DefaultSliceBudget = 10 * time.Millisecond
+
+// StepFn performs a small unit of CPU-bound work.
+// It returns true when all work is done.
+type StepFn func() (done bool, err error)
+
+// RunSliced runs a CPU-bound task cooperatively, by invoking fn repeatedly until it reports that it's done or it
+// returns an error.
+// Work is split in slices: after fn has been running for sliceBudget, RunSliced yields the processor to other
+// goroutines and checks if ctx is canceled, in which case it returns the context's error.
+// Each invocation of fn should be short compared to sliceBudget, so cancellation is observed promptly.
+func RunSliced(ctx context.Context, fn StepFn, sliceBudget time.Duration) error {
+	if sliceBudget <= 0 {
+		sliceBudget = DefaultSliceBudget
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	sliceStart := time.Now()
+	for {
+		done, err := fn()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		if time.Since(sliceStart) >= sliceBudget {
+			// End of the slice: yield and check for cancellation
+			runtime.Gosched()
+			err = ctx.Err()
+			if err != nil {
+				return err
+			}
+			sliceStart = time.Now()
+		}
+	}
+}
+
+// RunSlicedEach invokes fn for each item in items, using RunSliced.
+func RunSlicedEach[T any](ctx context.Context, items []T, fn func(item T) error, sliceBudget time.Duration) error {
+	var i int
+	return RunSliced(ctx, func() (bool, error) {
+		if i >= len(items) {
+			return true, nil
+		}
+		err := fn(items[i])
+		i++
+		return i >= len(items), err
+	}, sliceBudget)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSliced(t *testing.T) {
+	t.Run("runs until done", func(t *testing.T) {
+		var n int
+		err := RunSliced(context.Background(), func() (bool, error) {
+			n++
+			return n == 1000, nil
+		}, time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, 1000, n)
+	})
+
+	t.Run("returns errors", func(t *testing.T) {
+		var n int
+		err := RunSliced(context.Background(), func() (bool, error) {
+			n++
+			if n == 5 {
+				return false, errors.New("boom")
+			}
+			return false, nil
+		}, 0)
+		require.EqualError(t, err, "boom")
+		assert.Equal(t, 5, n)
+	})
+
+	t.Run("stops when context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+
+		start := time.Now()
+		err := RunSliced(ctx, func() (bool, error) {
+			// Busy work that never completes
+			for i := 0; i < 1000; i++ {
+				_ = i * i
+			}
+			return false, nil
+		}, time.Millisecond)
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("canceled context does not run", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := RunSliced(ctx, func() (bool, error) {
+			t.Fatal("should not be invoked")
+			return true, nil
+		}, time.Millisecond)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestRunSlicedEach(t *testing.T) {
+	var sum int
+	err := RunSlicedEach(context.Background(), []int{1, 2, 3, 4}, func(item int) error {
+		sum += item
+		return nil
+	}, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 10, sum)
+
+	require.NoError(t, RunSlicedEach(context.Background(), []int{}, func(item int) error {
+		t.Fatal("should not be invoked")
+		return nil
+	}, time.Millisecond))
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kclock "k8s.io/utils/clock"
+
+	"github.com/dapr/kit/logger"
+)
+
+// DefaultDeprecationWarningInterval is the minimum interval between two deprecation warnings for the same key.
+const DefaultDeprecationWarningInterval = 10 * time.Minute
+
+// Alias declares that the metadata key Old was renamed to New.
+type Alias struct {
+	// Old is the deprecated name of the key.
+	Old string
+	// New is the current name of the key.
+	New string
+}
+
+// DeprecatedKeyUsage reports how many times a deprecated key was used.
+type DeprecatedKeyUsage struct {
+	Old      string    `json:"old"`
+	New      string    `json:"new"`
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// Aliases renames deprecated metadata keys to their new names, logging deprecation warnings and keeping track of
+// which deprecated keys were used.
+// Keys are matched case-insensitively, like Decode does.
+type Aliases struct {
+	aliases         []Alias
+	log             logger.Logger
+	clock           kclock.PassiveClock
+	warningInterval time.Duration
+
+	lock     sync.Mutex
+	lastWarn map[string]time.Time
+	usage    map[string]*DeprecatedKeyUsage
+}
+
+// NewAliases returns a new Aliases object. If log is nil, no warning is logged.
+func NewAliases(log logger.Logger, aliases ...Alias) *Aliases {
+	return &Aliases{
+		aliases:         aliases,
+		log:             log,
+		clock:           kclock.RealClock{},
+		warningInterval: DefaultDeprecationWarningInterval,
+		lastWarn:        map[string]time.Time{},
+		usage:           map[string]*DeprecatedKeyUsage{},
+	}
+}
+
+// WithClock sets the clock used to rate-limit warnings. Used for testing.
+func (a *Aliases) WithClock(clock kclock.PassiveClock) *Aliases {
+	a.clock = clock
+	return a
+}
+
+// SetWarningInterval sets the minimum interval between two warnings for the same deprecated key.
+// If the interval is 0, a warning is logged every time a deprecated key is used.
+func (a *Aliases) SetWarningInterval(interval time.Duration) {
+	a.lock.Lock()
+	a.warningInterval = interval
+	a.lock.Unlock()
+}
+
+// Apply returns a copy of md in which deprecated keys are renamed to their new names.
+// If both the deprecated and the new key are set, the value of the new key is kept.
+func (a *Aliases) Apply(md map[string]string) map[string]string {
+	return applyAliases(a, md)
+}
+
+// ApplyAny is like Apply, for maps with values of any type.
+func (a *Aliases) ApplyAny(md map[string]any) map[string]any {
+	return applyAliases(a, md)
+}
+
+// Decode applies the aliases to input, if it is a map[string]string or map[string]any, and decodes it into output
+// using Decode.
+func (a *Aliases) Decode(input any, output any) error {
+	switch md := input.(type) {
+	case map[string]string:
+		input = a.Apply(md)
+	case map[string]any:
+		input = a.ApplyAny(md)
+	}
+	return Decode(input, output)
+}
+
+// Used returns the deprecated keys that were used, sorted by their name.
+func (a *Aliases) Used() []DeprecatedKeyUsage {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	res := make([]DeprecatedKeyUsage, 0, len(a.usage))
+	for _, u := range a.usage {
+		res = append(res, *u)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Old < res[j].Old
+	})
+	return res
+}
+
+func applyAliases[V any](a *Aliases, md map[string]V) map[string]V {
+	if md == nil {
+		return nil
+	}
+
+	res := make(map[string]V, len(md))
+	for k, v := range md {
+		res[k] = v
+	}
+
+	for _, alias := range a.aliases {
+		oldKey, ok := findKey(res, alias.Old)
+		if !ok {
+			continue
+		}
+		v := res[oldKey]
+		delete(res, oldKey)
+		if _, ok = findKey(res, alias.New); !ok {
+			res[alias.New] = v
+		}
+		a.deprecatedKeyUsed(alias)
+	}
+	return res
+}
+
+func findKey[V any](md map[string]V, key string) (string, bool) {
+	if _, ok := md[key]; ok {
+		return key, true
+	}
+	for k := range md {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+func (a *Aliases) deprecatedKeyUsed(alias Alias) {
+	now := a.clock.Now()
+
+	a.lock.Lock()
+	u, ok := a.usage[alias.Old]
+	if !ok {
+		u = &DeprecatedKeyUsage{Old: alias.Old, New: alias.New}
+		a.usage[alias.Old] = u
+	}
+	u.Count++
+	u.LastUsed = now
+
+	last, warned := a.lastWarn[alias.Old]
+	warn := !warned || now.Sub(last) >= a.warningInterval
+	if warn {
+		a.lastWarn[alias.Old] = now
+	}
+	a.lock.Unlock()
+
+	if warn && a.log != nil {
+		a.log.Warnf("Metadata key '%s' is deprecated and will be removed in a future release; use '%s' instead", alias.Old, alias.New)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/kit/logger"
+)
+
+func TestAliases(t *testing.T) {
+	newAliases := func() (*Aliases, *bytes.Buffer, *clocktesting.FakeClock) {
+		var buf bytes.Buffer
+		log := logger.NewLogger("test")
+		log.SetOutput(&buf)
+		clock := clocktesting.NewFakeClock(time.Now())
+		a := NewAliases(log,
+			Alias{Old: "connectionString", New: "url"},
+			Alias{Old: "maxConn", New: "maxConnections"},
+		).WithClock(clock)
+		return a, &buf, clock
+	}
+
+	t.Run("deprecated keys are renamed", func(t *testing.T) {
+		a, buf, _ := newAliases()
+		input := map[string]string{
+			"ConnectionString": "postgres://",
+			"other":            "value",
+		}
+		res := a.Apply(input)
+		assert.Equal(t, map[string]string{
+			"url":   "postgres://",
+			"other": "value",
+		}, res)
+		// The input is not modified
+		assert.Contains(t, input, "ConnectionString")
+		assert.Contains(t, buf.String(), "Metadata key 'connectionString' is deprecated")
+		assert.Contains(t, buf.String(), "use 'url' instead")
+	})
+
+	t.Run("new keys take precedence", func(t *testing.T) {
+		a, _, _ := newAliases()
+		res := a.ApplyAny(map[string]any{
+			"maxConn":        1,
+			"maxConnections": 2,
+		})
+		assert.Equal(t, map[string]any{"maxConnections": 2}, res)
+	})
+
+	t.Run("warnings are rate-limited", func(t *testing.T) {
+		a, buf, clock := newAliases()
+		md := map[string]string{"maxConn": "1"}
+
+		a.Apply(md)
+		a.Apply(md)
+		assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("deprecated")))
+
+		clock.Step(DefaultDeprecationWarningInterval)
+		a.Apply(md)
+		assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("deprecated")))
+
+		used := a.Used()
+		require.Len(t, used, 1)
+		assert.Equal(t, DeprecatedKeyUsage{
+			Old:      "maxConn",
+			New:      "maxConnections",
+			Count:    3,
+			LastUsed: clock.Now(),
+		}, used[0])
+	})
+
+	t.Run("decode", func(t *testing.T) {
+		a, _, _ := newAliases()
+		var md struct {
+			URL            string `mapstructure:"url"`
+			MaxConnections int    `mapstructure:"maxConnections"`
+		}
+		require.NoError(t, a.Decode(map[string]string{
+			"connectionString": "redis://",
+			"maxConn":          "5",
+		}, &md))
+		assert.Equal(t, "redis://", md.URL)
+		assert.Equal(t, 5, md.MaxConnections)
+
+		used := a.Used()
+		require.Len(t, used, 2)
+		assert.Equal(t, "connectionString", used[0].Old)
+		assert.Equal(t, "maxConn", used[1].Old)
+	})
+
+	t.Run("no deprecated keys", func(t *testing.T) {
+		a, buf, _ := newAliases()
+		assert.Equal(t, map[string]string{"url": "x"}, a.Apply(map[string]string{"url": "x"}))
+		assert.Nil(t, a.Apply(nil))
+		assert.Empty(t, a.Used())
+		assert.Empty(t, buf.String())
+	})
+}
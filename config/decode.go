@@ -44,6 +44,9 @@ type StringDecoder interface {
 //
 // Most of the heavy lifting is handled by the mapstructure library. A custom decoder is used to handle
 // decoding string values to the supported primitives.
+//
+// Values of fields tagged with `secret:"true"`, including those of nested structs, are removed from the messages of
+// the returned errors. The decoded value itself is not redacted when printed: use Redact for that.
 func Decode(input interface{}, output interface{}) error {
 	decoder, err := mapstructure.NewDecoder(
 		&mapstructure.DecoderConfig{ //nolint: exhaustruct
@@ -54,7 +57,12 @@ func Decode(input interface{}, output interface{}) error {
 		return err
 	}
 
-	return decoder.Decode(input)
+	err = decoder.Decode(input)
+	if err != nil {
+		return scrubError(err, input, output)
+	}
+
+	return nil
 }
 
 //nolint:cyclop
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Encode encodes the Go struct `input` (or a pointer to a struct) into a map of strings, using the names from the
+// `mapstructure` struct tags as keys. This is the inverse of Decode and it's useful to echo component metadata.
+// Fields tagged with `secret:"true"` are never included in the result. Nil pointers are omitted.
+//
+// Values are formatted so they can be parsed by Decode: durations and types implementing fmt.Stringer use their
+// String method, and times are formatted as RFC3339 with nanoseconds.
+func Encode(input any) (map[string]string, error) {
+	v := reflect.ValueOf(input)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, errors.New("input is nil")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct: got %T", input)
+	}
+
+	res := map[string]string{}
+	err := encodeStruct(v, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func encodeStruct(v reflect.Value, res map[string]string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || isSecretField(f) || isIgnoredField(f) {
+			continue
+		}
+
+		name, squash := fieldName(f)
+		fv := v.Field(i)
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Ptr {
+			// Nil pointer
+			continue
+		}
+
+		if squash && fv.Kind() == reflect.Struct {
+			err := encodeStruct(fv, res)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		val, err := encodeValue(fv)
+		if err != nil {
+			return fmt.Errorf("error encoding '%s': %w", name, err)
+		}
+		res[name] = val
+	}
+	return nil
+}
+
+//nolint:cyclop
+func encodeValue(v reflect.Value) (string, error) {
+	switch v.Type() {
+	case typeDuration:
+		return time.Duration(v.Int()).String(), nil
+	case typeTime:
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	}
+
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
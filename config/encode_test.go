@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/config"
+)
+
+func TestEncodeIgnoredFields(t *testing.T) {
+	type ignoredConfig struct {
+		Host     string `mapstructure:"host"`
+		Internal string `mapstructure:"-"`
+	}
+	res, err := config.Encode(ignoredConfig{Host: "localhost", Internal: "internal"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "localhost"}, res)
+}
+
+func TestEncode(t *testing.T) {
+	token := 42
+	cfg := secretConfig{
+		Host:     "localhost",
+		Password: "hunter2",
+		Token:    &token,
+		Timeout:  5 * time.Second,
+		Embedded: Embedded{
+			APIKey: "abc123",
+			Region: "eu",
+		},
+	}
+
+	t.Run("nested structs are not supported", func(t *testing.T) {
+		_, err := config.Encode(cfg)
+		require.ErrorContains(t, err, "error encoding 'auth'")
+	})
+
+	t.Run("secrets are omitted", func(t *testing.T) {
+		type flatConfig struct {
+			Host     string         `mapstructure:"host"`
+			Password string         `mapstructure:"password" secret:"true"`
+			Timeout  time.Duration  `mapstructure:"timeout"`
+			Retries  *int           `mapstructure:"retries"`
+			Missing  *time.Duration `mapstructure:"missing"`
+			Ratio    float64        `mapstructure:"ratio"`
+			Enabled  bool           `mapstructure:"enabled"`
+			Embedded `mapstructure:",squash"`
+		}
+		in := flatConfig{
+			Host:     "localhost",
+			Password: "hunter2",
+			Timeout:  5 * time.Second,
+			Retries:  &token,
+			Ratio:    0.5,
+			Enabled:  true,
+			Embedded: cfg.Embedded,
+		}
+
+		res, err := config.Encode(&in)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"host":    "localhost",
+			"timeout": "5s",
+			"retries": "42",
+			"ratio":   "0.5",
+			"enabled": "true",
+			"region":  "eu",
+		}, res)
+
+		// Encoded values can be decoded back
+		var out flatConfig
+		require.NoError(t, config.Decode(res, &out))
+		in.Password = ""
+		in.APIKey = ""
+		assert.Equal(t, in, out)
+	})
+
+	t.Run("input must be a struct", func(t *testing.T) {
+		_, err := config.Encode("foo")
+		require.Error(t, err)
+		_, err = config.Encode((*secretConfig)(nil))
+		require.Error(t, err)
+	})
+}
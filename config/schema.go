@@ -0,0 +1,229 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema document, as generated by Schema.
+// It contains only the subset of keywords that can be derived from Go types and struct tags.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	WriteOnly            bool                   `json:"writeOnly,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+}
+
+// Schema generates a JSON Schema for the config struct T, using the same field names as Decode.
+// The following struct tags are used to enrich the schema:
+//
+//   - `description:"..."`: description of the field.
+//   - `default:"..."`: default value of the field, which is converted to the field's type.
+//   - `enum:"a,b,c"`: comma-separated list of allowed values, which are converted to the field's type.
+//   - `secret:"true"`: the field is marked as "writeOnly".
+//
+// Durations are represented as strings with format "duration" and times as strings with format "date-time".
+// Types implementing StringDecoder are represented as strings.
+// Recursive types are represented with "$ref": references to T point to the root of the document, and references to
+// other recursive structs point to their definition in "$defs".
+func Schema[T any]() (*JSONSchema, error) {
+	var zero T
+	t := reflect.TypeOf(&zero).Elem()
+	st, ok := structType(t)
+	if !ok {
+		return nil, fmt.Errorf("expected struct: got %s", t)
+	}
+
+	g := &schemaGenerator{
+		root:      st,
+		visiting:  map[reflect.Type]bool{},
+		recursive: map[reflect.Type]bool{},
+	}
+	s, err := g.schemaForType(t)
+	if err != nil {
+		return nil, err
+	}
+	s.Schema = jsonSchemaDraft
+	if len(g.defs) > 0 {
+		s.Defs = g.defs
+	}
+	return s, nil
+}
+
+// schemaGenerator keeps track of the structs that are being generated, to detect recursive types.
+type schemaGenerator struct {
+	root reflect.Type
+	// Structs whose schema is being generated
+	visiting map[reflect.Type]bool
+	// Structs that reference themselves, directly or indirectly
+	recursive map[reflect.Type]bool
+	defs      map[string]*JSONSchema
+}
+
+// ref returns the reference to the schema of the struct t.
+func (g *schemaGenerator) ref(t reflect.Type) string {
+	if t == g.root {
+		return "#"
+	}
+	return "#/$defs/" + t.String()
+}
+
+//nolint:cyclop
+func (g *schemaGenerator) schemaForType(t reflect.Type) (*JSONSchema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == typeDuration:
+		return &JSONSchema{Type: "string", Format: "duration"}, nil
+	case t == typeTime:
+		return &JSONSchema{Type: "string", Format: "date-time"}, nil
+	case t.Implements(typeStringDecoder), reflect.PtrTo(t).Implements(typeStringDecoder):
+		return &JSONSchema{Type: "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}, nil
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := g.schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Interface:
+		// Any value is allowed
+		return &JSONSchema{}, nil
+	case reflect.Struct:
+		if g.visiting[t] {
+			g.recursive[t] = true
+			return &JSONSchema{Ref: g.ref(t)}, nil
+		}
+
+		g.visiting[t] = true
+		s := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+		err := g.addStructProperties(s, t)
+		delete(g.visiting, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if g.recursive[t] && t != g.root {
+			if g.defs == nil {
+				g.defs = map[string]*JSONSchema{}
+			}
+			g.defs[t.String()] = s
+			return &JSONSchema{Ref: g.ref(t)}, nil
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func (g *schemaGenerator) addStructProperties(s *JSONSchema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || isIgnoredField(f) {
+			continue
+		}
+
+		name, squash := fieldName(f)
+		if st, ok := structType(f.Type); squash && ok {
+			if g.visiting[st] {
+				return fmt.Errorf("cannot squash recursive struct %s in '%s'", st, f.Name)
+			}
+			g.visiting[st] = true
+			err := g.addStructProperties(s, st)
+			delete(g.visiting, st)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		prop, err := g.schemaForType(f.Type)
+		if err != nil {
+			return fmt.Errorf("error generating schema for '%s': %w", name, err)
+		}
+
+		prop.Description = f.Tag.Get("description")
+		prop.WriteOnly = isSecretField(f)
+		if v, ok := f.Tag.Lookup("default"); ok {
+			prop.Default, err = schemaValue(prop.Type, v)
+			if err != nil {
+				return fmt.Errorf("invalid default value for '%s': %w", name, err)
+			}
+		}
+		if v, ok := f.Tag.Lookup("enum"); ok && v != "" {
+			for _, e := range strings.Split(v, ",") {
+				ev, err := schemaValue(prop.Type, strings.TrimSpace(e))
+				if err != nil {
+					return fmt.Errorf("invalid enum value for '%s': %w", name, err)
+				}
+				prop.Enum = append(prop.Enum, ev)
+			}
+		}
+
+		s.Properties[name] = prop
+	}
+	return nil
+}
+
+// schemaValue converts a value from a struct tag to the JSON type of the schema.
+func schemaValue(typ string, val string) (any, error) {
+	switch typ {
+	case "string", "":
+		return val, nil
+	case "boolean":
+		return strconv.ParseBool(val)
+	case "integer":
+		return strconv.ParseInt(val, 10, 64)
+	case "number":
+		return strconv.ParseFloat(val, 64)
+	default:
+		return nil, fmt.Errorf("values are not supported for type %s", typ)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/config"
+)
+
+type schemaConfig struct {
+	Host     string            `mapstructure:"host" description:"Address of the server"`
+	Port     int               `mapstructure:"port" default:"6379"`
+	Mode     string            `mapstructure:"mode" enum:"fast, safe" default:"safe"`
+	Ratio    *float32          `mapstructure:"ratio"`
+	TLS      bool              `mapstructure:"tls" default:"true"`
+	Password string            `mapstructure:"password" secret:"true"`
+	Timeout  time.Duration     `mapstructure:"timeout" default:"5s"`
+	Start    time.Time         `mapstructure:"start"`
+	Limit    Decoded           `mapstructure:"limit"`
+	Tags     []string          `mapstructure:"tags"`
+	Labels   map[string]string `mapstructure:"labels"`
+	Nested   nested            `mapstructure:"nested"`
+	Ignored  string            `mapstructure:"-"`
+	Embedded `mapstructure:",squash"`
+
+	unexported string //nolint:unused
+}
+
+func TestSchema(t *testing.T) {
+	t.Run("generate schema", func(t *testing.T) {
+		s, err := config.Schema[schemaConfig]()
+		require.NoError(t, err)
+
+		enc, err := json.Marshal(s)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"type": "object",
+			"properties": {
+				"host": {"type": "string", "description": "Address of the server"},
+				"port": {"type": "integer", "default": 6379},
+				"mode": {"type": "string", "enum": ["fast", "safe"], "default": "safe"},
+				"ratio": {"type": "number"},
+				"tls": {"type": "boolean", "default": true},
+				"password": {"type": "string", "writeOnly": true},
+				"timeout": {"type": "string", "format": "duration", "default": "5s"},
+				"start": {"type": "string", "format": "date-time"},
+				"limit": {"type": "string"},
+				"tags": {"type": "array", "items": {"type": "string"}},
+				"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+				"nested": {
+					"type": "object",
+					"properties": {
+						"integer": {"type": "integer"},
+						"string": {"type": "string"}
+					}
+				},
+				"apiKey": {"type": "string", "writeOnly": true},
+				"region": {"type": "string"}
+			}
+		}`, string(enc))
+	})
+
+	t.Run("pointer to struct", func(t *testing.T) {
+		s, err := config.Schema[*nested]()
+		require.NoError(t, err)
+		assert.Equal(t, "object", s.Type)
+		assert.Len(t, s.Properties, 2)
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		_, err := config.Schema[string]()
+		require.Error(t, err)
+	})
+
+	t.Run("invalid default", func(t *testing.T) {
+		type invalid struct {
+			Port int `mapstructure:"port" default:"foo"`
+		}
+		_, err := config.Schema[invalid]()
+		require.ErrorContains(t, err, "invalid default value for 'port'")
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		type invalid struct {
+			Ch chan int `mapstructure:"ch"`
+		}
+		_, err := config.Schema[invalid]()
+		require.ErrorContains(t, err, "error generating schema for 'ch'")
+	})
+
+	t.Run("recursive types", func(t *testing.T) {
+		type Node struct {
+			Name     string  `mapstructure:"name"`
+			Children []*Node `mapstructure:"children"`
+		}
+		type Tree struct {
+			Root  *Node           `mapstructure:"root"`
+			Count int             `mapstructure:"count"`
+			Named map[string]Node `mapstructure:"named"`
+		}
+
+		s, err := config.Schema[Node]()
+		require.NoError(t, err)
+		enc, err := json.Marshal(s)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"children": {"type": "array", "items": {"$ref": "#"}}
+			}
+		}`, string(enc))
+
+		s, err = config.Schema[Tree]()
+		require.NoError(t, err)
+		enc, err = json.Marshal(s)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"type": "object",
+			"properties": {
+				"root": {"$ref": "#/$defs/config_test.Node"},
+				"count": {"type": "integer"},
+				"named": {"type": "object", "additionalProperties": {"$ref": "#/$defs/config_test.Node"}}
+			},
+			"$defs": {
+				"config_test.Node": {
+					"type": "object",
+					"properties": {
+						"name": {"type": "string"},
+						"children": {"type": "array", "items": {"$ref": "#/$defs/config_test.Node"}}
+					}
+				}
+			}
+		}`, string(enc))
+	})
+
+	t.Run("recursive squashed struct", func(t *testing.T) {
+		type Loop struct {
+			*Loop `mapstructure:",squash"`
+		}
+		_, err := config.Schema[Loop]()
+		require.ErrorContains(t, err, "cannot squash recursive struct")
+	})
+}
@@ -0,0 +1,398 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// secretTag is the struct tag used to mark fields that contain secrets.
+	// Fields tagged with `secret:"true"` are never included in the output of Encode or Redact.
+	secretTag = "secret"
+	// redactedValue replaces the value of secret fields.
+	redactedValue = "***"
+)
+
+// isSecretField returns true if the struct field is tagged with `secret:"true"`.
+func isSecretField(f reflect.StructField) bool {
+	v, ok := f.Tag.Lookup(secretTag)
+	if !ok {
+		return false
+	}
+	secret, _ := strconv.ParseBool(v)
+	return secret
+}
+
+// isIgnoredField returns true if the struct field is tagged with `mapstructure:"-"`, so it's ignored by Decode, Encode,
+// Redact and the JSON schema.
+func isIgnoredField(f reflect.StructField) bool {
+	tag, ok := f.Tag.Lookup("mapstructure")
+	return ok && strings.Split(tag, ",")[0] == "-"
+}
+
+// fieldName returns the name of the struct field as used by mapstructure, and whether the field is squashed.
+func fieldName(f reflect.StructField) (name string, squash bool) {
+	name = f.Name
+	tag, ok := f.Tag.Lookup("mapstructure")
+	if !ok {
+		return name, false
+	}
+	parts := strings.Split(tag, ",")
+	if parts[0] != "" {
+		name = parts[0]
+	}
+	for _, p := range parts[1:] {
+		if p == "squash" {
+			squash = true
+		}
+	}
+	return name, squash
+}
+
+// structType returns the struct type of t, de-referencing pointers. The returned bool is false if t is not a struct.
+func structType(t reflect.Type) (reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct
+}
+
+// secretFieldNames returns the names of the fields of t that are tagged as secret, including those of squashed structs.
+func secretFieldNames(t reflect.Type) []string {
+	t, ok := structType(t)
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isIgnoredField(f) {
+			continue
+		}
+		name, squash := fieldName(f)
+		switch {
+		case isSecretField(f):
+			names = append(names, name)
+		case squash:
+			names = append(names, secretFieldNames(f.Type)...)
+		}
+	}
+	return names
+}
+
+// minScrubLength is the length below which secret values are only removed from error messages when they appear as a
+// whole word, so short values such as "a" don't cause every occurrence of those characters to be replaced.
+const minScrubLength = 8
+
+// scrubError removes the values of secret fields found in input from the error message.
+// It's used to ensure that errors returned by Decode never contain secrets. The returned error wraps err, so it can
+// still be inspected with errors.Is and errors.As; note that the messages of the wrapped errors are not scrubbed.
+func scrubError(err error, input any, output any) error {
+	t, ok := structType(reflect.TypeOf(output))
+	if !ok {
+		return err
+	}
+	var values []string
+	collectSecretValues(t, reflect.ValueOf(input), &values)
+	if len(values) == 0 {
+		return err
+	}
+
+	// Replace longer values first, in case a value contains another one
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	msg := err.Error()
+	scrubbed := msg
+	for _, val := range values {
+		if len(val) >= minScrubLength {
+			scrubbed = strings.ReplaceAll(scrubbed, val, redactedValue)
+		} else {
+			scrubbed = replaceWord(scrubbed, val, redactedValue)
+		}
+	}
+
+	if scrubbed == msg {
+		return err
+	}
+	return &scrubbedError{msg: scrubbed, err: err}
+}
+
+// scrubbedError is an error whose message doesn't contain the values of secret fields.
+type scrubbedError struct {
+	msg string
+	err error
+}
+
+// Error implements error.
+func (e *scrubbedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the original error.
+func (e *scrubbedError) Unwrap() error {
+	return e.err
+}
+
+// collectSecretValues appends the values in the input map v of the fields of the struct type t that are tagged as
+// secret, walking nested structs, including those in slices and maps, alongside their input.
+func collectSecretValues(t reflect.Type, v reflect.Value, values *[]string) {
+	v = indirectValue(v)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isIgnoredField(f) {
+			continue
+		}
+		name, squash := fieldName(f)
+		if squash {
+			if ft, ok := structType(f.Type); ok {
+				collectSecretValues(ft, v, values)
+			}
+			continue
+		}
+
+		// Mapstructure matches keys case-insensitively
+		iter := v.MapRange()
+		for iter.Next() {
+			if !strings.EqualFold(iter.Key().String(), name) {
+				continue
+			}
+			if isSecretField(f) {
+				collectValues(iter.Value(), values)
+			} else {
+				collectNestedSecretValues(f.Type, iter.Value(), values)
+			}
+		}
+	}
+}
+
+// collectNestedSecretValues invokes collectSecretValues for the structs of type t in v, which can be a struct, or a
+// slice, array or map of structs.
+func collectNestedSecretValues(t reflect.Type, v reflect.Value, values *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		collectSecretValues(t, v, values)
+	case reflect.Slice, reflect.Array:
+		v = indirectValue(v)
+		if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			for i := 0; i < v.Len(); i++ {
+				collectNestedSecretValues(t.Elem(), v.Index(i), values)
+			}
+		}
+	case reflect.Map:
+		v = indirectValue(v)
+		if v.Kind() == reflect.Map {
+			iter := v.MapRange()
+			for iter.Next() {
+				collectNestedSecretValues(t.Elem(), iter.Value(), values)
+			}
+		}
+	}
+}
+
+// collectValues appends the string representation of v to values, or those of its elements if it's a slice or map.
+func collectValues(v reflect.Value, values *[]string) {
+	v = indirectValue(v)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectValues(v.Index(i), values)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectValues(iter.Value(), values)
+		}
+	default:
+		if val := fmt.Sprintf("%v", v.Interface()); val != "" {
+			*values = append(*values, val)
+		}
+	}
+}
+
+// indirectValue de-references pointers and interfaces.
+func indirectValue(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// replaceWord replaces the occurrences of old in s that are not part of a longer word.
+func replaceWord(s string, old string, replacement string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, old)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(old)
+		if (i > 0 && isWordByte(s[i-1])) || (end < len(s) && isWordByte(s[end])) {
+			b.WriteString(s[:end])
+		} else {
+			b.WriteString(s[:i])
+			b.WriteString(replacement)
+		}
+		s = s[end:]
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Redact returns a fmt.Stringer for the struct v (or a pointer to a struct) that includes all exported fields,
+// except those tagged with `secret:"true"` whose value is replaced with "***". Nested structs, slices and maps are
+// redacted recursively.
+// Decoded structs are not redacted automatically when they're printed: Decode can't wrap the value it decodes into,
+// which has the caller's type, and methods can't be attached to caller-defined types. Redact must be used explicitly
+// wherever configuration is printed.
+// This is useful to print configuration objects in logs and errors without exposing secrets, for example:
+//
+//	log.Infof("Loaded configuration: %v", config.Redact(cfg))
+func Redact(v any) fmt.Stringer {
+	return redacted{v: v}
+}
+
+type redacted struct {
+	v any
+}
+
+// String implements fmt.Stringer.
+func (r redacted) String() string {
+	var b strings.Builder
+	writeRedacted(&b, reflect.ValueOf(r.v))
+	return b.String()
+}
+
+// GoString implements fmt.GoStringer, so secrets aren't included when formatting with "%#v".
+func (r redacted) GoString() string {
+	return r.String()
+}
+
+func writeRedacted(b *strings.Builder, v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			b.WriteString("<nil>")
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		// Elements can be structs with secrets
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteRune('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteRune(' ')
+			}
+			writeRedacted(b, v.Index(i))
+		}
+		b.WriteRune(']')
+		return
+	case reflect.Map:
+		keys := v.MapKeys()
+		keyStrings := make([]string, len(keys))
+		for i, k := range keys {
+			keyStrings[i] = fmt.Sprintf("%v", k.Interface())
+		}
+		sort.Sort(mapKeys{keys: keys, strings: keyStrings})
+		b.WriteString("map[")
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteRune(' ')
+			}
+			b.WriteString(keyStrings[i])
+			b.WriteRune(':')
+			writeRedacted(b, v.MapIndex(k))
+		}
+		b.WriteRune(']')
+		return
+	}
+
+	if v.Kind() != reflect.Struct {
+		if v.IsValid() && v.CanInterface() {
+			fmt.Fprintf(b, "%v", v.Interface())
+		} else {
+			b.WriteString("<nil>")
+		}
+		return
+	}
+
+	// Structs that implement fmt.Stringer, such as time.Time, are printed as-is, unless they contain secrets
+	if v.CanInterface() && len(secretFieldNames(v.Type())) == 0 {
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			b.WriteString(s.String())
+			return
+		}
+	}
+
+	t := v.Type()
+	b.WriteRune('{')
+	first := true
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || isIgnoredField(f) {
+			continue
+		}
+		if !first {
+			b.WriteRune(' ')
+		}
+		first = false
+
+		name, _ := fieldName(f)
+		b.WriteString(name)
+		b.WriteRune('=')
+		if isSecretField(f) {
+			b.WriteString(redactedValue)
+			continue
+		}
+		writeRedacted(b, v.Field(i))
+	}
+	b.WriteRune('}')
+}
+
+// mapKeys sorts the keys of a map by their string representation, like fmt does for most key types.
+type mapKeys struct {
+	keys    []reflect.Value
+	strings []string
+}
+
+func (m mapKeys) Len() int           { return len(m.keys) }
+func (m mapKeys) Less(i, j int) bool { return m.strings[i] < m.strings[j] }
+func (m mapKeys) Swap(i, j int) {
+	m.keys[i], m.keys[j] = m.keys[j], m.keys[i]
+	m.strings[i], m.strings[j] = m.strings[j], m.strings[i]
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/config"
+)
+
+type secretConfig struct {
+	Host     string        `mapstructure:"host"`
+	Password string        `mapstructure:"password" secret:"true"`
+	Token    *int          `mapstructure:"token" secret:"true"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	Auth     secretAuth    `mapstructure:"auth"`
+	Embedded `mapstructure:",squash"`
+}
+
+type secretAuth struct {
+	User string `mapstructure:"user"`
+	Key  string `mapstructure:"key" secret:"true"`
+}
+
+type Embedded struct {
+	APIKey string `mapstructure:"apiKey" secret:"true"`
+	Region string `mapstructure:"region"`
+}
+
+func TestDecodeScrubsSecrets(t *testing.T) {
+	t.Run("secret value is removed from errors", func(t *testing.T) {
+		var cfg secretConfig
+		err := config.Decode(map[string]string{
+			"host":  "localhost",
+			"token": "hunter2",
+		}, &cfg)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "hunter2")
+		assert.Contains(t, err.Error(), "***")
+	})
+
+	t.Run("keys are matched case-insensitively", func(t *testing.T) {
+		var cfg secretConfig
+		err := config.Decode(map[string]interface{}{
+			"TOKEN": "hunter2",
+		}, &cfg)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "hunter2")
+	})
+
+	t.Run("non-secret values are preserved in errors", func(t *testing.T) {
+		var cfg secretConfig
+		err := config.Decode(map[string]string{
+			"password": "hunter2",
+			"timeout":  "notaduration",
+		}, &cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "notaduration")
+	})
+
+	t.Run("secret values in nested maps are removed from errors", func(t *testing.T) {
+		type pinAuth struct {
+			User string `mapstructure:"user"`
+			PIN  int    `mapstructure:"pin" secret:"true"`
+		}
+		var cfg struct {
+			Auth     pinAuth   `mapstructure:"auth"`
+			Replicas []pinAuth `mapstructure:"replicas"`
+		}
+		err := config.Decode(map[string]any{
+			"auth": map[string]any{
+				"user": "alice",
+				"PIN":  "nestedsecret",
+			},
+			"replicas": []any{
+				map[string]any{"pin": "replicasecret"},
+			},
+		}, &cfg)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "nestedsecret")
+		assert.NotContains(t, err.Error(), "replicasecret")
+		assert.Contains(t, err.Error(), "***")
+	})
+
+	t.Run("short secret values are only removed as whole words", func(t *testing.T) {
+		var cfg secretConfig
+		err := config.Decode(map[string]any{
+			"token":   "a",
+			"timeout": "notaduration",
+		}, &cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "notaduration")
+		assert.NotContains(t, err.Error(), "'a'")
+		assert.NotContains(t, err.Error(), `"a"`)
+	})
+
+	t.Run("scrubbed errors wrap the original error", func(t *testing.T) {
+		var cfg secretConfig
+		err := config.Decode(map[string]string{
+			"token": "hunter2",
+		}, &cfg)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "hunter2")
+		var mErr *mapstructure.Error
+		require.ErrorAs(t, err, &mErr)
+	})
+
+	t.Run("secrets are decoded", func(t *testing.T) {
+		var cfg secretConfig
+		err := config.Decode(map[string]string{
+			"password": "hunter2",
+			"apiKey":   "abc",
+		}, &cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "hunter2", cfg.Password)
+		assert.Equal(t, "abc", cfg.APIKey)
+	})
+}
+
+func TestRedact(t *testing.T) {
+	token := 42
+	cfg := secretConfig{
+		Host:     "localhost",
+		Password: "hunter2",
+		Token:    &token,
+		Timeout:  5 * time.Second,
+		Auth: secretAuth{
+			User: "admin",
+			Key:  "s3cr3t",
+		},
+		Embedded: Embedded{
+			APIKey: "abc123",
+			Region: "eu",
+		},
+	}
+
+	expect := "{host=localhost password=*** token=*** timeout=5s auth={user=admin key=***} Embedded={apiKey=*** region=eu}}"
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		out := fmt.Sprintf(format, config.Redact(cfg))
+		assert.Equal(t, expect, out, format)
+	}
+	assert.Equal(t, expect, config.Redact(&cfg).String())
+
+	var nilCfg *secretConfig
+	assert.Equal(t, "<nil>", config.Redact(nilCfg).String())
+}
+
+func TestRedactNested(t *testing.T) {
+	type nestedConfig struct {
+		Hosts    []string              `mapstructure:"hosts"`
+		Accounts []secretAuth          `mapstructure:"accounts"`
+		ByName   map[string]secretAuth `mapstructure:"byName"`
+		Ptrs     []*secretAuth         `mapstructure:"ptrs"`
+		Internal string                `mapstructure:"-"`
+		Empty    []secretAuth          `mapstructure:"empty"`
+	}
+	cfg := nestedConfig{
+		Hosts:    []string{"a", "b"},
+		Accounts: []secretAuth{{User: "u1", Key: "k1"}, {User: "u2", Key: "k2"}},
+		ByName: map[string]secretAuth{
+			"z": {User: "u3", Key: "k3"},
+			"a": {User: "u4", Key: "k4"},
+		},
+		Ptrs:     []*secretAuth{{User: "u5", Key: "k5"}, nil},
+		Internal: "internal",
+	}
+
+	out := config.Redact(cfg).String()
+	assert.Equal(t, "{hosts=[a b] accounts=[{user=u1 key=***} {user=u2 key=***}] "+
+		"byName=map[a:{user=u4 key=***} z:{user=u3 key=***}] ptrs=[{user=u5 key=***} <nil>] empty=[]}", out)
+}
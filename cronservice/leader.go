@@ -0,0 +1,52 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronservice
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// LeaderElector reports whether the current replica is the leader, which is the only one that executes jobs.
+// Leadership is only an optimization to avoid contention on the Store: even if more than one replica believes it's
+// the leader, for example during a failover, each tick is executed once because it must be claimed in the Store.
+type LeaderElector interface {
+	// IsLeader returns true if the current replica is the leader.
+	IsLeader(ctx context.Context) bool
+}
+
+// AlwaysLeader is a LeaderElector for deployments with a single replica.
+type AlwaysLeader struct{}
+
+// IsLeader implements LeaderElector.
+func (AlwaysLeader) IsLeader(context.Context) bool {
+	return true
+}
+
+// StaticLeader is a LeaderElector whose leadership is set manually, for example by a callback of an external leader
+// election mechanism.
+// The zero value is not the leader.
+type StaticLeader struct {
+	leader atomic.Bool
+}
+
+// SetLeader sets whether the current replica is the leader.
+func (l *StaticLeader) SetLeader(leader bool) {
+	l.leader.Store(leader)
+}
+
+// IsLeader implements LeaderElector.
+func (l *StaticLeader) IsLeader(context.Context) bool {
+	return l.leader.Load()
+}
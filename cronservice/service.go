@@ -0,0 +1,342 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cronservice contains an embeddable service that executes periodic jobs on a cron schedule.
+// When the service runs on multiple replicas sharing the same Store, each scheduled execution ("tick") of a job
+// happens on a single replica: only the leader (see LeaderElector) executes jobs, and each tick must be claimed in
+// the Store first, so ticks are never executed twice even if leadership changes.
+package cronservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kclock "k8s.io/utils/clock"
+
+	"github.com/dapr/kit/cron"
+	"github.com/dapr/kit/logger"
+)
+
+var (
+	// ErrJobExists is returned when adding a job with a name that is already registered.
+	ErrJobExists = errors.New("job already exists")
+	// ErrJobNotFound is returned when a job with the given name is not registered.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrServiceRunning is returned when starting a service that is already running.
+	ErrServiceRunning = errors.New("service is already running")
+)
+
+// Func is the function executed at every tick of a job.
+// The context is canceled when the service is stopped.
+type Func func(ctx context.Context) error
+
+// Options contains the options for a Service.
+type Options struct {
+	// ID of the replica, recorded as the owner of runs in the Store. Default is the hostname.
+	ID string
+	// Store where runs are claimed and recorded. Must be shared by all replicas. Default is a new MemoryStore.
+	Store Store
+	// Leader reports whether the current replica executes jobs. Default is AlwaysLeader.
+	Leader LeaderElector
+	// Location is the time zone of the schedules. Default is the local time zone.
+	Location *time.Location
+	// Log is used to log failed jobs. Optional.
+	Log logger.Logger
+}
+
+// Service executes jobs on a cron schedule.
+type Service struct {
+	opts  Options
+	clock kclock.Clock
+
+	lock    sync.Mutex
+	jobs    map[string]*job
+	resetCh chan struct{}
+	wg      sync.WaitGroup
+	running atomic.Bool
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule cron.Schedule
+	fn       Func
+	next     time.Time
+	active   atomic.Bool
+}
+
+// New returns a new Service.
+func New(opts Options) *Service {
+	if opts.ID == "" {
+		opts.ID, _ = os.Hostname()
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	if opts.Leader == nil {
+		opts.Leader = AlwaysLeader{}
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+
+	return &Service{
+		opts:    opts,
+		clock:   kclock.RealClock{},
+		jobs:    map[string]*job{},
+		resetCh: make(chan struct{}, 1),
+	}
+}
+
+// WithClock sets the clock used by the service. Used for testing.
+func (s *Service) WithClock(clock kclock.Clock) *Service {
+	s.clock = clock
+	return s
+}
+
+// Add registers a job that is executed on the schedule described by spec, in the standard cron format
+// (for example "*/5 * * * *") or with a descriptor such as "@hourly" or "@every 30s".
+// Jobs can be added while the service is running.
+func (s *Service) Add(name string, spec string, fn Func) error {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("invalid schedule for job '%s': %w", name, err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, name)
+	}
+	j := &job{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		fn:       fn,
+	}
+	j.next = s.nextTick(j, s.clock.Now())
+	s.jobs[name] = j
+
+	s.reset()
+	return nil
+}
+
+// Remove unregisters a job. Executions that are in progress are not interrupted.
+func (s *Service) Remove(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.jobs[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	delete(s.jobs, name)
+
+	s.reset()
+	return nil
+}
+
+// Run executes jobs on their schedules.
+// This method blocks until the context is canceled, then it waits for the executions in progress to return.
+func (s *Service) Run(ctx context.Context) error {
+	if !s.running.CompareAndSwap(false, true) {
+		return ErrServiceRunning
+	}
+	defer s.running.Store(false)
+	defer s.wg.Wait()
+
+	for {
+		var (
+			t      kclock.Timer
+			timerC <-chan time.Time
+		)
+		if next, ok := s.nextDeadline(); ok {
+			d := next.Sub(s.clock.Now())
+			if d <= 0 {
+				// Ticks are already due
+				s.runDue(ctx)
+				continue
+			}
+			t = s.clock.NewTimer(d)
+			timerC = t.C()
+		}
+
+		select {
+		case <-timerC:
+			s.runDue(ctx)
+		case <-s.resetCh:
+			stopTimer(t)
+		case <-ctx.Done():
+			stopTimer(t)
+			return nil
+		}
+	}
+}
+
+// JobStatus contains the status of a job.
+type JobStatus struct {
+	// Name of the job.
+	Name string `json:"name"`
+	// Schedule of the job, as passed to Add.
+	Schedule string `json:"schedule"`
+	// Next is the time of the next tick.
+	Next time.Time `json:"next"`
+	// Active is true if the job is being executed by the current replica.
+	Active bool `json:"active"`
+	// LastRun is the last execution of the job on any replica, as recorded in the Store.
+	LastRun *Run `json:"lastRun,omitempty"`
+}
+
+// Status returns the status of all jobs, sorted by name.
+func (s *Service) Status(ctx context.Context) ([]JobStatus, error) {
+	s.lock.Lock()
+	res := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		res = append(res, JobStatus{
+			Name:     j.name,
+			Schedule: j.spec,
+			Next:     j.next,
+			Active:   j.active.Load(),
+		})
+	}
+	s.lock.Unlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	for i := range res {
+		run, ok, err := s.opts.Store.LastRun(ctx, res[i].Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load last run of job '%s': %w", res[i].Name, err)
+		}
+		if ok {
+			res[i].LastRun = &run
+		}
+	}
+	return res, nil
+}
+
+// runDue triggers all jobs whose tick is due and computes their next tick.
+func (s *Service) runDue(ctx context.Context) {
+	now := s.clock.Now()
+
+	type tick struct {
+		job       *job
+		scheduled time.Time
+	}
+	var due []tick
+	s.lock.Lock()
+	for _, j := range s.jobs {
+		// A zero time means that the schedule has no more ticks
+		if j.next.IsZero() || j.next.After(now) {
+			continue
+		}
+		due = append(due, tick{job: j, scheduled: j.next})
+		j.next = s.nextTick(j, now)
+	}
+	s.lock.Unlock()
+
+	if len(due) == 0 || !s.opts.Leader.IsLeader(ctx) {
+		return
+	}
+	for _, d := range due {
+		s.trigger(ctx, d.job, d.scheduled)
+	}
+}
+
+// trigger executes a tick of the job in background, if it can be claimed in the store.
+func (s *Service) trigger(ctx context.Context, j *job, scheduled time.Time) {
+	// Do not overlap subsequent executions of the same job
+	if !j.active.CompareAndSwap(false, true) {
+		s.warnf("Skipping tick of job '%s' scheduled at %v: previous execution is still in progress", j.name, scheduled)
+		return
+	}
+
+	claimed, err := s.opts.Store.ClaimRun(ctx, j.name, scheduled, s.opts.ID)
+	if err != nil || !claimed {
+		j.active.Store(false)
+		if err != nil {
+			s.warnf("Failed to claim tick of job '%s' scheduled at %v: %v", j.name, scheduled, err)
+		}
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer j.active.Store(false)
+
+		runErr := j.fn(ctx)
+		if runErr != nil {
+			s.warnf("Job '%s' scheduled at %v failed: %v", j.name, scheduled, runErr)
+		}
+
+		// Record the outcome even if the service is stopping
+		err := s.opts.Store.CompleteRun(context.Background(), j.name, scheduled, s.clock.Now(), runErr)
+		if err != nil {
+			s.warnf("Failed to record run of job '%s' scheduled at %v: %v", j.name, scheduled, err)
+		}
+	}()
+}
+
+// nextDeadline returns the earliest tick among all jobs.
+func (s *Service) nextDeadline() (time.Time, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var next time.Time
+	for _, j := range s.jobs {
+		if j.next.IsZero() {
+			continue
+		}
+		if next.IsZero() || j.next.Before(next) {
+			next = j.next
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// nextTick returns the first tick of j after now.
+func (s *Service) nextTick(j *job, now time.Time) time.Time {
+	return j.schedule.Next(now.In(s.opts.Location))
+}
+
+// reset signals the loop to re-compute the next deadline.
+// This must be invoked while the caller has a lock.
+func (s *Service) reset() {
+	select {
+	case s.resetCh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Service) warnf(format string, args ...any) {
+	if s.opts.Log != nil {
+		s.opts.Log.Warnf(format, args...)
+	}
+}
+
+func stopTimer(t kclock.Timer) {
+	if t != nil && !t.Stop() {
+		select {
+		case <-t.C():
+		default:
+		}
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronservice
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func startService(t *testing.T, s *Service) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		assert.NoError(t, s.Run(ctx))
+	}()
+	require.Eventually(t, s.running.Load, time.Second, 10*time.Millisecond)
+
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-doneCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("service did not stop in time")
+		}
+	})
+}
+
+// step advances the clock once the service is waiting on its timer, and waits until the due ticks are processed and
+// the timer is re-armed.
+func step(t *testing.T, clock *clocktesting.FakeClock, d time.Duration) {
+	t.Helper()
+	require.Eventually(t, clock.HasWaiters, time.Second, time.Millisecond)
+	clock.Step(d)
+	require.Eventually(t, clock.HasWaiters, time.Second, time.Millisecond)
+}
+
+func TestServiceRunsJobs(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := New(Options{ID: "replica-1", Location: time.UTC}).WithClock(clock)
+
+	var calls atomic.Int32
+	require.NoError(t, s.Add("every-minute", "* * * * *", func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}))
+	require.ErrorIs(t, s.Add("every-minute", "* * * * *", nil), ErrJobExists)
+	require.Error(t, s.Add("invalid", "not a schedule", nil))
+
+	startService(t, s)
+
+	step(t, clock, time.Minute)
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	step(t, clock, time.Minute)
+	assert.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, time.Millisecond)
+
+	status, err := s.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, status, 1)
+	assert.Equal(t, "every-minute", status[0].Name)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 3, 0, 0, time.UTC), status[0].Next)
+	require.NotNil(t, status[0].LastRun)
+	assert.Equal(t, "replica-1", status[0].LastRun.Owner)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 2, 0, 0, time.UTC), status[0].LastRun.Scheduled)
+
+	require.NoError(t, s.Remove("every-minute"))
+	require.ErrorIs(t, s.Remove("every-minute"), ErrJobNotFound)
+}
+
+func TestServiceSingletonAcrossReplicas(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := NewMemoryStore()
+
+	var calls atomic.Int32
+	fn := func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}
+
+	// Both replicas believe they're the leader
+	replicas := make([]*Service, 2)
+	for i := range replicas {
+		replicas[i] = New(Options{ID: string(rune('a' + i)), Store: store}).WithClock(clock)
+		require.NoError(t, replicas[i].Add("job", "@every 10s", fn))
+	}
+	for _, r := range replicas {
+		startService(t, r)
+	}
+
+	for i := 1; i <= 3; i++ {
+		step(t, clock, 10*time.Second)
+
+		want := int32(i)
+		assert.Eventually(t, func() bool { return calls.Load() == want }, time.Second, time.Millisecond)
+	}
+
+	// Give the replicas time to execute any duplicate
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestServiceLeader(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	leader := &StaticLeader{}
+	s := New(Options{Leader: leader}).WithClock(clock)
+
+	var calls atomic.Int32
+	require.NoError(t, s.Add("job", "@every 10s", func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}))
+	startService(t, s)
+
+	// Not the leader: ticks are skipped
+	step(t, clock, 10*time.Second)
+	step(t, clock, 10*time.Second)
+	assert.Equal(t, int32(0), calls.Load())
+
+	leader.SetLeader(true)
+	step(t, clock, 10*time.Second)
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+}
+
+func TestServiceNoOverlap(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := New(Options{}).WithClock(clock)
+
+	var calls atomic.Int32
+	releaseCh := make(chan struct{})
+	errJob := errors.New("job failed")
+	require.NoError(t, s.Add("slow", "@every 10s", func(context.Context) error {
+		calls.Add(1)
+		<-releaseCh
+		return errJob
+	}))
+	startService(t, s)
+
+	step(t, clock, 10*time.Second)
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	// The first execution is still in progress, so this tick is skipped
+	step(t, clock, 10*time.Second)
+	status, err := s.Status(context.Background())
+	require.NoError(t, err)
+	assert.True(t, status[0].Active)
+
+	close(releaseCh)
+	require.Eventually(t, func() bool {
+		status, err := s.Status(context.Background())
+		return err == nil && !status[0].Active
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load())
+
+	status, err = s.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, errJob.Error(), status[0].LastRun.Error)
+	assert.False(t, status[0].LastRun.Completed.IsZero())
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	tick := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, ok, err := store.LastRun(ctx, "job")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	claimed, err := store.ClaimRun(ctx, "job", tick, "a")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	// Same or earlier ticks can't be claimed again
+	claimed, _ = store.ClaimRun(ctx, "job", tick, "b")
+	assert.False(t, claimed)
+	claimed, _ = store.ClaimRun(ctx, "job", tick.Add(-time.Minute), "b")
+	assert.False(t, claimed)
+
+	require.NoError(t, store.CompleteRun(ctx, "job", tick, tick.Add(time.Second), nil))
+	run, ok, err := store.LastRun(ctx, "job")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "a", run.Owner)
+	assert.Equal(t, tick.Add(time.Second), run.Completed)
+
+	claimed, _ = store.ClaimRun(ctx, "job", tick.Add(time.Minute), "b")
+	assert.True(t, claimed)
+}
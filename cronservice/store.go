@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronservice
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Run is the record of a job execution.
+type Run struct {
+	// Job is the name of the job.
+	Job string `json:"job"`
+	// Scheduled is the time the execution was scheduled for.
+	Scheduled time.Time `json:"scheduled"`
+	// Owner is the ID of the replica that executed the job.
+	Owner string `json:"owner"`
+	// Completed is the time the execution completed, or the zero value if it's still running.
+	Completed time.Time `json:"completed,omitempty"`
+	// Error is the error returned by the job, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Store persists the executions of jobs and is shared by all replicas.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// ClaimRun atomically records that owner is executing job for the tick scheduled at the given time.
+	// It returns false if the tick (or a later one) was already claimed, by any replica.
+	ClaimRun(ctx context.Context, job string, scheduled time.Time, owner string) (bool, error)
+	// CompleteRun records the outcome of the execution claimed with ClaimRun.
+	CompleteRun(ctx context.Context, job string, scheduled time.Time, completed time.Time, runErr error) error
+	// LastRun returns the last execution of job, if any.
+	LastRun(ctx context.Context, job string) (Run, bool, error)
+}
+
+// MemoryStore is a Store that keeps runs in memory.
+// It provides singleton execution across services in the same process only, and it's meant for single-replica
+// deployments and tests.
+type MemoryStore struct {
+	lock sync.Mutex
+	runs map[string]Run
+}
+
+// NewMemoryStore returns a new MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		runs: map[string]Run{},
+	}
+}
+
+// ClaimRun implements Store.
+func (s *MemoryStore) ClaimRun(_ context.Context, job string, scheduled time.Time, owner string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	last, ok := s.runs[job]
+	if ok && !scheduled.After(last.Scheduled) {
+		return false, nil
+	}
+	s.runs[job] = Run{
+		Job:       job,
+		Scheduled: scheduled,
+		Owner:     owner,
+	}
+	return true, nil
+}
+
+// CompleteRun implements Store.
+func (s *MemoryStore) CompleteRun(_ context.Context, job string, scheduled time.Time, completed time.Time, runErr error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	run, ok := s.runs[job]
+	if !ok || !run.Scheduled.Equal(scheduled) {
+		// A later run was claimed already
+		return nil
+	}
+	run.Completed = completed
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	s.runs[job] = run
+	return nil
+}
+
+// LastRun implements Store.
+func (s *MemoryStore) LastRun(_ context.Context, job string) (Run, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	run, ok := s.runs[job]
+	return run, ok, nil
+}
@@ -16,7 +16,6 @@ package crypto
 
 import (
 	"crypto"
-	"crypto/rand"
 	"crypto/rsa"
 
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -40,6 +39,12 @@ func EncryptPublicKey(plaintext []byte, algorithm string, key jwk.Key, associate
 	if err != nil {
 		return nil, ErrKeyTypeMismatch
 	}
+	if dc := debugChecks.Load(); dc != nil {
+		err = dc.checkAsymmetric(algorithm, key)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	switch algorithm {
 	case Algorithm_RSA1_5:
@@ -61,7 +66,7 @@ func encryptPublicKeyRSAPKCS1v15(plaintext []byte, key jwk.Key) ([]byte, error)
 	if key.Raw(rsaKey) != nil {
 		return nil, ErrKeyTypeMismatch
 	}
-	return rsa.EncryptPKCS1v15(rand.Reader, rsaKey, plaintext)
+	return rsa.EncryptPKCS1v15(Rand(), rsaKey, plaintext)
 }
 
 func encryptPublicKeyRSAOAEP(plaintext []byte, key jwk.Key, hash crypto.Hash, label []byte) ([]byte, error) {
@@ -69,7 +74,7 @@ func encryptPublicKeyRSAOAEP(plaintext []byte, key jwk.Key, hash crypto.Hash, la
 	if key.Raw(rsaKey) != nil {
 		return nil, ErrKeyTypeMismatch
 	}
-	return rsa.EncryptOAEP(hash.New(), rand.Reader, rsaKey, plaintext, label)
+	return rsa.EncryptOAEP(hash.New(), Rand(), rsaKey, plaintext, label)
 }
 
 // DecryptPrivateKey decrypts a message using a private key and the specified algorithm.
@@ -95,7 +100,7 @@ func decryptPrivateKeyRSAPKCS1v15(ciphertext []byte, key jwk.Key) ([]byte, error
 	if key.Raw(rsaKey) != nil {
 		return nil, ErrKeyTypeMismatch
 	}
-	return rsa.DecryptPKCS1v15(rand.Reader, rsaKey, ciphertext)
+	return rsa.DecryptPKCS1v15(Rand(), rsaKey, ciphertext)
 }
 
 func decryptPrivateKeyRSAOAEP(ciphertext []byte, key jwk.Key, hash crypto.Hash, label []byte) ([]byte, error) {
@@ -103,5 +108,5 @@ func decryptPrivateKeyRSAOAEP(ciphertext []byte, key jwk.Key, hash crypto.Hash,
 	if key.Raw(rsaKey) != nil {
 		return nil, ErrKeyTypeMismatch
 	}
-	return rsa.DecryptOAEP(hash.New(), rand.Reader, rsaKey, ciphertext, label)
+	return rsa.DecryptOAEP(hash.New(), Rand(), rsaKey, ciphertext, label)
 }
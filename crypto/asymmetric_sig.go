@@ -18,7 +18,6 @@ import (
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
-	"crypto/rand"
 	"crypto/rsa"
 	"errors"
 
@@ -40,6 +39,13 @@ func SupportedSignatureAlgorithms() []string {
 // SignPrivateKey creates a signature from a digest using a private key and the specified algorithm.
 // Note: when using EdDSA, the message gets hashed as part of the signing process, so users should normally pass the full message for the "digest" parameter.
 func SignPrivateKey(digest []byte, algorithm string, key jwk.Key) (signature []byte, err error) {
+	if dc := debugChecks.Load(); dc != nil {
+		err = dc.checkSignature(algorithm, key, digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	switch algorithm {
 	case Algorithm_RS256, Algorithm_RS384, Algorithm_RS512:
 		return signPrivateKeyRSAPKCS1v15(digest, getSHAHash(algorithm), key)
@@ -63,7 +69,7 @@ func signPrivateKeyRSAPKCS1v15(digest []byte, hash crypto.Hash, key jwk.Key) ([]
 	if key.Raw(rsaKey) != nil {
 		return nil, ErrKeyTypeMismatch
 	}
-	return rsa.SignPKCS1v15(rand.Reader, rsaKey, hash, digest)
+	return rsa.SignPKCS1v15(Rand(), rsaKey, hash, digest)
 }
 
 func signPrivateKeyRSAPSS(digest []byte, hash crypto.Hash, key jwk.Key) ([]byte, error) {
@@ -71,7 +77,7 @@ func signPrivateKeyRSAPSS(digest []byte, hash crypto.Hash, key jwk.Key) ([]byte,
 	if key.Raw(rsaKey) != nil {
 		return nil, ErrKeyTypeMismatch
 	}
-	return rsa.SignPSS(rand.Reader, rsaKey, hash, digest, nil)
+	return rsa.SignPSS(Rand(), rsaKey, hash, digest, nil)
 }
 
 func signPrivateKeyECDSA(digest []byte, key jwk.Key) ([]byte, error) {
@@ -80,7 +86,7 @@ func signPrivateKeyECDSA(digest []byte, key jwk.Key) ([]byte, error) {
 		return nil, ErrKeyTypeMismatch
 	}
 
-	return ecdsa.SignASN1(rand.Reader, ecdsaKey, digest)
+	return ecdsa.SignASN1(Rand(), ecdsaKey, digest)
 }
 
 func signPrivateKeyEdDSA(message []byte, key jwk.Key) ([]byte, error) {
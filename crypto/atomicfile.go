@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// ErrInsecurePermissions is returned by WriteFileAtomic when private permissions are enforced and the requested
+	// permissions grant access to users other than the owner.
+	ErrInsecurePermissions = errors.New("file permissions grant access to group or others")
+	// ErrFileOwnership is returned by WriteFileAtomic when ownership checks are enabled and the file or its directory
+	// are owned by a different user.
+	ErrFileOwnership = errors.New("file or directory is not owned by the current user")
+)
+
+// WriteFileOption is an option for WriteFileAtomic.
+type WriteFileOption func(o *writeFileOptions)
+
+type writeFileOptions struct {
+	privatePerm bool
+	checkOwner  bool
+}
+
+// WithPrivatePermissions makes WriteFileAtomic fail if the permissions grant any access to group or others, such as
+// for files containing keys and tokens. For example, 0600 is allowed, but 0640 is not.
+func WithPrivatePermissions() WriteFileOption {
+	return func(o *writeFileOptions) {
+		o.privatePerm = true
+	}
+}
+
+// WithOwnershipCheck makes WriteFileAtomic fail if the target directory, or the file being replaced, are owned by a
+// user other than the current one. This is a no-op on platforms that don't support file ownership, like Windows.
+func WithOwnershipCheck() WriteFileOption {
+	return func(o *writeFileOptions) {
+		o.checkOwner = true
+	}
+}
+
+// WriteFileAtomic writes data to the file at path, replacing it atomically if it exists.
+// Data is written to a temporary file in the same directory, which is synced to disk and then renamed to path, so
+// readers never observe a partially-written file.
+func WriteFileAtomic(path string, data []byte, perm fs.FileMode, opts ...WriteFileOption) (err error) {
+	var o writeFileOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.privatePerm && perm&0o077 != 0 {
+		return fmt.Errorf("%w: %v", ErrInsecurePermissions, perm)
+	}
+
+	dir := filepath.Dir(path)
+	if o.checkOwner {
+		err = checkOwner(dir)
+		if err != nil {
+			return err
+		}
+		err = checkOwner(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpName := f.Name()
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(tmpName)
+		}
+	}()
+
+	err = f.Chmod(perm)
+	if err != nil {
+		return fmt.Errorf("failed to set permissions on temporary file: %w", err)
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	err = f.Sync()
+	if err != nil {
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	err = os.Rename(tmpName, path)
+	if err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	// Sync the directory so the rename is persisted too
+	syncDir(dir)
+
+	return nil
+}
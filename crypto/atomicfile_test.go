@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "key.pem")
+
+	t.Run("create file", func(t *testing.T) {
+		require.NoError(t, WriteFileAtomic(path, []byte("first"), 0o600, WithOwnershipCheck()))
+		read, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "first", string(read))
+
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		}
+	})
+
+	t.Run("replace file", func(t *testing.T) {
+		require.NoError(t, WriteFileAtomic(path, []byte("second"), 0o600, WithPrivatePermissions(), WithOwnershipCheck()))
+		read, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "second", string(read))
+
+		// No temporary files are left behind
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("insecure permissions", func(t *testing.T) {
+		err := WriteFileAtomic(path, []byte("third"), 0o644, WithPrivatePermissions())
+		require.ErrorIs(t, err, ErrInsecurePermissions)
+		read, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "second", string(read))
+	})
+
+	t.Run("directory does not exist", func(t *testing.T) {
+		err := WriteFileAtomic(filepath.Join(dir, "missing", "file"), []byte("x"), 0o600)
+		require.Error(t, err)
+		err = WriteFileAtomic(filepath.Join(dir, "missing", "file"), []byte("x"), 0o600, WithOwnershipCheck())
+		require.Error(t, err)
+	})
+}
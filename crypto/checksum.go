@@ -0,0 +1,239 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:nosnakecase,stylecheck,revive
+package crypto
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Hash algorithms supported by the checksum utilities.
+const (
+	HashAlgorithm_SHA256     = "SHA256"     // SHA-256
+	HashAlgorithm_SHA512     = "SHA512"     // SHA-512
+	HashAlgorithm_BLAKE2b256 = "BLAKE2b256" // BLAKE2b with 256-bit digest
+	HashAlgorithm_BLAKE2b512 = "BLAKE2b512" // BLAKE2b with 512-bit digest
+)
+
+var (
+	// ErrChecksumMismatch is returned when a digest doesn't match the expected value.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrInvalidManifestPath is returned when a path in a manifest is absolute or points outside of the root directory.
+	ErrInvalidManifestPath = errors.New("invalid manifest path")
+)
+
+// HashProgressFn is invoked while data is hashed, with the total number of bytes processed so far.
+type HashProgressFn func(processed int64)
+
+// SupportedHashAlgorithms returns the list of supported hash algorithms for the checksum utilities.
+func SupportedHashAlgorithms() []string {
+	return []string{
+		HashAlgorithm_SHA256, HashAlgorithm_SHA512,
+		HashAlgorithm_BLAKE2b256, HashAlgorithm_BLAKE2b512,
+	}
+}
+
+// NewHash returns a new hash.Hash for one of the algorithms returned by SupportedHashAlgorithms.
+func NewHash(algorithm string) (hash.Hash, error) {
+	return newHash(algorithm)
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case HashAlgorithm_SHA256:
+		return sha256.New(), nil
+	case HashAlgorithm_SHA512:
+		return sha512.New(), nil
+	case HashAlgorithm_BLAKE2b256:
+		return blake2b.New256(nil)
+	case HashAlgorithm_BLAKE2b512:
+		return blake2b.New512(nil)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+// HashReader computes the digest of all data read from r using the given algorithm.
+// If progress is not nil, it's invoked after each chunk of data is hashed.
+func HashReader(r io.Reader, algorithm string, progress HashProgressFn) ([]byte, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var w io.Writer = h
+	if progress != nil {
+		w = &progressWriter{w: h, fn: progress}
+	}
+
+	_, err = io.Copy(w, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// HashFile computes the digest of the file at path using the given algorithm.
+// If progress is not nil, it's invoked after each chunk of data is hashed.
+func HashFile(path string, algorithm string, progress HashProgressFn) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	return HashReader(f, algorithm, progress)
+}
+
+// progressWriter is an io.Writer that reports the number of bytes written after each write.
+type progressWriter struct {
+	w     io.Writer
+	fn    HashProgressFn
+	total int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.total += int64(n)
+	p.fn(p.total)
+	return n, err
+}
+
+// ChecksumManifest contains the digests of a set of files, keyed by their path.
+// Paths are relative to a root directory and always use forward slashes as separator. Absolute paths, and paths that
+// point outside of the root directory (such as "../file"), are rejected.
+// The manifest can be serialized to JSON.
+type ChecksumManifest struct {
+	// Algorithm used to compute the digests.
+	Algorithm string `json:"algorithm"`
+	// Files is a map of path to hex-encoded digest.
+	Files map[string]string `json:"files"`
+}
+
+// NewChecksumManifest creates a new manifest by hashing the files at the given paths.
+// Paths are relative to root.
+func NewChecksumManifest(algorithm string, root string, paths ...string) (*ChecksumManifest, error) {
+	m := &ChecksumManifest{
+		Algorithm: algorithm,
+		Files:     make(map[string]string, len(paths)),
+	}
+	for _, p := range paths {
+		if err := validateManifestPath(p); err != nil {
+			return nil, err
+		}
+		digest, err := HashFile(filepath.Join(root, filepath.FromSlash(p)), algorithm, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute checksum for '%s': %w", p, err)
+		}
+		m.Add(p, digest)
+	}
+	return m, nil
+}
+
+// ParseChecksumManifest parses a JSON-encoded manifest.
+func ParseChecksumManifest(data []byte) (*ChecksumManifest, error) {
+	m := &ChecksumManifest{}
+	err := json.Unmarshal(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if _, err = newHash(m.Algorithm); err != nil {
+		return nil, fmt.Errorf("invalid manifest algorithm '%s': %w", m.Algorithm, err)
+	}
+	for p := range m.Files {
+		if err = validateManifestPath(p); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Add the digest for the file at path to the manifest.
+func (m *ChecksumManifest) Add(path string, digest []byte) {
+	if m.Files == nil {
+		m.Files = map[string]string{}
+	}
+	m.Files[filepath.ToSlash(path)] = hex.EncodeToString(digest)
+}
+
+// Paths returns the list of paths in the manifest, sorted alphabetically.
+func (m ChecksumManifest) Paths() []string {
+	paths := make([]string, 0, len(m.Files))
+	for p := range m.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// VerifyFile checks that the digest of the file at path, relative to root, matches the one in the manifest.
+// It returns ErrChecksumMismatch if the digest doesn't match or if the path isn't in the manifest.
+// It returns ErrInvalidManifestPath if path is absolute or points outside of root.
+func (m ChecksumManifest) VerifyFile(root string, path string) error {
+	if err := validateManifestPath(path); err != nil {
+		return err
+	}
+	expect, ok := m.Files[filepath.ToSlash(path)]
+	if !ok {
+		return fmt.Errorf("%w: '%s' is not in the manifest", ErrChecksumMismatch, path)
+	}
+	expectBytes, err := hex.DecodeString(expect)
+	if err != nil {
+		return fmt.Errorf("invalid digest in manifest for '%s': %w", path, err)
+	}
+
+	digest, err := HashFile(filepath.Join(root, filepath.FromSlash(path)), m.Algorithm, nil)
+	if err != nil {
+		return fmt.Errorf("failed to compute checksum for '%s': %w", path, err)
+	}
+
+	if subtle.ConstantTimeCompare(expectBytes, digest) != 1 {
+		return fmt.Errorf("%w: '%s'", ErrChecksumMismatch, path)
+	}
+	return nil
+}
+
+// validateManifestPath returns ErrInvalidManifestPath if path isn't a local path, relative to the root directory.
+func validateManifestPath(path string) error {
+	if !filepath.IsLocal(filepath.FromSlash(path)) {
+		return fmt.Errorf("%w: '%s'", ErrInvalidManifestPath, path)
+	}
+	return nil
+}
+
+// Verify checks that all files in the manifest, relative to root, match their digests.
+// Files are verified in alphabetical order and the first failure is returned.
+func (m ChecksumManifest) Verify(root string) error {
+	for _, p := range m.Paths() {
+		err := m.VerifyFile(root, p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:nosnakecase
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashReader(t *testing.T) {
+	// Digests of "hello world"
+	tests := map[string]string{
+		HashAlgorithm_SHA256:     "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+		HashAlgorithm_SHA512:     "309ecc489c12d6eb4cc40f50c902f2b4d0ed77ee511a7c7a9bcd3ca86d4cd86f989dd35bc5ff499670da34255b45b0cfd830e81f605dcf7dc5542e93ae9cd76f",
+		HashAlgorithm_BLAKE2b256: "256c83b297114d201b30179f3f0ef0cace9783622da5974326b436178aeef610",
+		HashAlgorithm_BLAKE2b512: "021ced8799296ceca557832ab941a50b4a11f83478cf141f51f933f653ab9fbcc05a037cddbed06e309bf334942c4e58cdf1a46e237911ccd7fcf9787cbc7fd0",
+	}
+
+	for alg, expect := range tests {
+		t.Run(alg, func(t *testing.T) {
+			digest, err := HashReader(strings.NewReader("hello world"), alg, nil)
+			require.NoError(t, err)
+			assert.Equal(t, expect, hex.EncodeToString(digest))
+		})
+	}
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := HashReader(strings.NewReader("hello world"), "MD5", nil)
+		require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		data := bytes.Repeat([]byte{'a'}, 100_000)
+		var calls int
+		var last int64
+		_, err := HashReader(bytes.NewReader(data), HashAlgorithm_SHA256, func(processed int64) {
+			calls++
+			assert.Greater(t, processed, last)
+			last = processed
+		})
+		require.NoError(t, err)
+		assert.Positive(t, calls)
+		assert.Equal(t, int64(len(data)), last)
+	})
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte("hello world"), 0o600))
+
+	digest, err := HashFile(path, HashAlgorithm_SHA256, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", hex.EncodeToString(digest))
+
+	_, err = HashFile(filepath.Join(t.TempDir(), "missing"), HashAlgorithm_SHA256, nil)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestChecksumManifest(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "b.txt"), []byte("world"), 0o600))
+
+	m, err := NewChecksumManifest(HashAlgorithm_BLAKE2b256, root, "a.txt", "sub/b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "sub/b.txt"}, m.Paths())
+	require.NoError(t, m.Verify(root))
+
+	t.Run("round trip", func(t *testing.T) {
+		enc, err := json.Marshal(m)
+		require.NoError(t, err)
+		parsed, err := ParseChecksumManifest(enc)
+		require.NoError(t, err)
+		assert.Equal(t, m, parsed)
+	})
+
+	t.Run("invalid algorithm", func(t *testing.T) {
+		_, err := ParseChecksumManifest([]byte(`{"algorithm":"MD5","files":{}}`))
+		require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	})
+
+	t.Run("paths outside of root", func(t *testing.T) {
+		outside := filepath.Join(filepath.Dir(root), "outside.txt")
+		require.NoError(t, os.WriteFile(outside, []byte("hello"), 0o600))
+		defer os.Remove(outside)
+
+		for _, p := range []string{"../outside.txt", "sub/../../outside.txt", filepath.ToSlash(outside), "/etc/passwd", ""} {
+			_, err := ParseChecksumManifest([]byte(`{"algorithm":"BLAKE2b256","files":{"` + p + `":"00"}}`))
+			require.ErrorIs(t, err, ErrInvalidManifestPath, p)
+
+			_, err = NewChecksumManifest(HashAlgorithm_BLAKE2b256, root, p)
+			require.ErrorIs(t, err, ErrInvalidManifestPath, p)
+
+			bad := ChecksumManifest{Algorithm: HashAlgorithm_BLAKE2b256}
+			bad.Add(p, []byte{0})
+			require.ErrorIs(t, bad.Verify(root), ErrInvalidManifestPath, p)
+		}
+
+		// Paths are cleaned, but must stay within root
+		_, err := ParseChecksumManifest([]byte(`{"algorithm":"BLAKE2b256","files":{"sub/../a.txt":"00"}}`))
+		require.NoError(t, err)
+	})
+
+	t.Run("path not in manifest", func(t *testing.T) {
+		err := m.VerifyFile(root, "c.txt")
+		require.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("modified file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "b.txt"), []byte("world!"), 0o600))
+		require.NoError(t, m.VerifyFile(root, "a.txt"))
+		err := m.Verify(root)
+		require.ErrorIs(t, err, ErrChecksumMismatch)
+		assert.Contains(t, err.Error(), "sub/b.txt")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(root, "a.txt")))
+		err := m.Verify(root)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
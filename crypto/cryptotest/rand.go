@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cryptotest contains helpers for testing code that uses the crypto package.
+package cryptotest
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/dapr/kit/crypto"
+)
+
+// ErrInjected is the default error returned by RandSource when a failure is injected.
+var ErrInjected = errors.New("injected random source failure")
+
+// RandSource is a crypto.RandSource that can inject short reads and errors, so code paths that handle entropy
+// failures can be tested.
+// Faults are consumed in the order they are added, one per call to Read; once all faults are consumed, reads are
+// served by the underlying source, unless FailAlways was invoked.
+type RandSource struct {
+	source io.Reader
+
+	lock       sync.Mutex
+	faults     []fault
+	failAlways error
+	reads      int
+}
+
+type fault struct {
+	err   error
+	limit int
+}
+
+// NewRandSource returns a new RandSource that reads from crypto/rand when no fault is injected.
+func NewRandSource() *RandSource {
+	return &RandSource{
+		source: rand.Reader,
+	}
+}
+
+// WithSource sets the source used when no fault is injected, for example to return deterministic bytes.
+func (r *RandSource) WithSource(source io.Reader) *RandSource {
+	r.source = source
+	return r
+}
+
+// FailNext makes the next call to Read return the error. If err is nil, ErrInjected is used.
+func (r *RandSource) FailNext(err error) *RandSource {
+	if err == nil {
+		err = ErrInjected
+	}
+	r.lock.Lock()
+	r.faults = append(r.faults, fault{err: err})
+	r.lock.Unlock()
+	return r
+}
+
+// ShortNext makes the next call to Read return at most n bytes, without an error.
+func (r *RandSource) ShortNext(n int) *RandSource {
+	r.lock.Lock()
+	r.faults = append(r.faults, fault{limit: n})
+	r.lock.Unlock()
+	return r
+}
+
+// FailAlways makes all calls to Read, after the queued faults, return the error. If err is nil, ErrInjected is used.
+func (r *RandSource) FailAlways(err error) *RandSource {
+	if err == nil {
+		err = ErrInjected
+	}
+	r.lock.Lock()
+	r.failAlways = err
+	r.lock.Unlock()
+	return r
+}
+
+// Reads returns the number of times Read was invoked.
+func (r *RandSource) Reads() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.reads
+}
+
+// Install sets r as the crypto.RandSource used by the crypto package and returns a function that restores the
+// previous one.
+func (r *RandSource) Install() (restore func()) {
+	return crypto.SetRandSource(r)
+}
+
+// Read implements io.Reader.
+func (r *RandSource) Read(p []byte) (int, error) {
+	r.lock.Lock()
+	r.reads++
+	var f *fault
+	if len(r.faults) > 0 {
+		f = &r.faults[0]
+		r.faults = r.faults[1:]
+	} else if r.failAlways != nil {
+		f = &fault{err: r.failAlways}
+	}
+	r.lock.Unlock()
+
+	if f != nil {
+		if f.err != nil {
+			return 0, f.err
+		}
+		if f.limit < len(p) {
+			p = p[:f.limit]
+		}
+	}
+	return r.source.Read(p)
+}
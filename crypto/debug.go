@@ -0,0 +1,216 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:nosnakecase
+package crypto
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// MinRSAKeyBits is the minimum size of RSA keys that are not reported as weak by the debug checks.
+	MinRSAKeyBits = 2048
+	// DefaultMaxTrackedNonces is the default number of nonces remembered by the debug checks to detect reuse.
+	DefaultMaxTrackedNonces = 100_000
+)
+
+var (
+	// ErrNonceReuse is returned in strict debug mode when a nonce is used twice with the same key.
+	ErrNonceReuse = errors.New("nonce reused with the same key")
+	// ErrWeakParameters is returned in strict debug mode when an operation uses weak keys or parameters.
+	ErrWeakParameters = errors.New("weak cryptographic parameters")
+)
+
+// DebugOptions contains the options for the debug checks.
+type DebugOptions struct {
+	// Log receives the warnings. Default is the "dapr.kit.crypto" logger.
+	Log logger.Logger
+	// Strict makes operations that fail a check return an error (ErrNonceReuse or ErrWeakParameters), in addition to
+	// logging a warning.
+	Strict bool
+	// MaxTrackedNonces is the number of nonces remembered to detect reuse; when the limit is reached, the oldest half
+	// is forgotten. Default is DefaultMaxTrackedNonces.
+	MaxTrackedNonces int
+}
+
+// debugChecks is the active debug checker, or nil if debug checks are disabled.
+var debugChecks atomic.Pointer[debugChecker]
+
+// EnableDebugChecks enables runtime checks that catch common misuses of the helpers in this package:
+//
+//   - Nonces reused with the same key in EncryptSymmetric, within the process.
+//   - Nonces that are all zeros.
+//   - RSA keys shorter than MinRSAKeyBits.
+//   - Digests whose length doesn't match the hash of the signature algorithm.
+//   - Weak or unauthenticated algorithms, such as RSA1_5, RSA-OAEP with SHA-1, and AES-CBC without HMAC.
+//
+// Each kind of issue is logged once per algorithm. The checks add overhead and keep nonces in memory, so they're
+// meant for development and tests, not for production builds; they're enabled automatically in binaries built with
+// the "dapr_crypto_debug" build tag.
+// The returned function disables the checks, restoring the previous configuration.
+func EnableDebugChecks(opts DebugOptions) (disable func()) {
+	if opts.Log == nil {
+		opts.Log = logger.NewLogger("dapr.kit.crypto")
+	}
+	if opts.MaxTrackedNonces <= 0 {
+		opts.MaxTrackedNonces = DefaultMaxTrackedNonces
+	}
+
+	dc := &debugChecker{
+		opts:   opts,
+		nonces: map[string]struct{}{},
+	}
+	prev := debugChecks.Swap(dc)
+	return func() {
+		debugChecks.CompareAndSwap(dc, prev)
+	}
+}
+
+type debugChecker struct {
+	opts DebugOptions
+
+	lock      sync.Mutex
+	nonces    map[string]struct{}
+	oldNonces map[string]struct{}
+	warned    sync.Map
+}
+
+// warn logs the issue once per kind and algorithm, and returns err in strict mode.
+func (dc *debugChecker) warn(err error, algorithm string, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	if _, loaded := dc.warned.LoadOrStore(err.Error()+"|"+algorithm+"|"+msg, struct{}{}); !loaded {
+		dc.opts.Log.Warnf("Crypto misuse detected (%s): %s", algorithm, msg)
+	}
+	if dc.opts.Strict {
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return nil
+}
+
+// checkSymmetric checks the parameters of EncryptSymmetric.
+func (dc *debugChecker) checkSymmetric(algorithm string, key []byte, nonce []byte) error {
+	switch algorithm {
+	case Algorithm_A128CBC, Algorithm_A192CBC, Algorithm_A256CBC,
+		Algorithm_A128CBC_NOPAD, Algorithm_A192CBC_NOPAD, Algorithm_A256CBC_NOPAD:
+		err := dc.warn(ErrWeakParameters, algorithm, "AES-CBC without HMAC does not authenticate the ciphertext")
+		if err != nil {
+			return err
+		}
+	case Algorithm_A128KW, Algorithm_A192KW, Algorithm_A256KW:
+		// Key wrap doesn't use nonces
+		return nil
+	}
+
+	if len(nonce) == 0 {
+		return nil
+	}
+	if isZero(nonce) {
+		err := dc.warn(ErrWeakParameters, algorithm, "nonce is all zeros")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Track the SHA-256 of the key rather than the key itself
+	h := sha256.New()
+	h.Write([]byte(algorithm))
+	h.Write([]byte{0})
+	h.Write(key)
+	h.Write(nonce)
+	id := string(h.Sum(nil))
+
+	dc.lock.Lock()
+	_, reused := dc.nonces[id]
+	if !reused {
+		_, reused = dc.oldNonces[id]
+	}
+	if !reused {
+		if len(dc.nonces) >= dc.opts.MaxTrackedNonces/2 {
+			dc.oldNonces = dc.nonces
+			dc.nonces = make(map[string]struct{}, len(dc.oldNonces))
+		}
+		dc.nonces[id] = struct{}{}
+	}
+	dc.lock.Unlock()
+
+	if reused {
+		return dc.warn(ErrNonceReuse, algorithm, "the same nonce was used more than once with the same key")
+	}
+	return nil
+}
+
+// checkAsymmetric checks the parameters of EncryptPublicKey.
+func (dc *debugChecker) checkAsymmetric(algorithm string, key jwk.Key) error {
+	switch algorithm {
+	case Algorithm_RSA1_5:
+		err := dc.warn(ErrWeakParameters, algorithm, "RSA-PKCS1v1.5 encryption is vulnerable to padding oracle attacks; use RSA-OAEP-256")
+		if err != nil {
+			return err
+		}
+	case Algorithm_RSA_OAEP:
+		err := dc.warn(ErrWeakParameters, algorithm, "RSA-OAEP with SHA-1 is deprecated; use RSA-OAEP-256")
+		if err != nil {
+			return err
+		}
+	}
+	return dc.checkRSAKey(algorithm, key)
+}
+
+// checkSignature checks the parameters of SignPrivateKey.
+func (dc *debugChecker) checkSignature(algorithm string, key jwk.Key, digest []byte) error {
+	switch algorithm {
+	case Algorithm_RS256, Algorithm_RS384, Algorithm_RS512, Algorithm_PS256, Algorithm_PS384, Algorithm_PS512:
+		err := dc.checkRSAKey(algorithm, key)
+		if err != nil {
+			return err
+		}
+		fallthrough
+	case Algorithm_ES256, Algorithm_ES384, Algorithm_ES512:
+		if size := getSHAHash(algorithm).Size(); len(digest) != size {
+			return dc.warn(ErrWeakParameters, algorithm, "digest is %d bytes but the algorithm expects %d; pass the hash of the message, not the message", len(digest), size)
+		}
+	}
+	return nil
+}
+
+func (dc *debugChecker) checkRSAKey(algorithm string, key jwk.Key) error {
+	var pub rsa.PublicKey
+	pk, err := key.PublicKey()
+	if err != nil || pk.Raw(&pub) != nil || pub.N == nil {
+		// Not an RSA key; the operation will fail anyways
+		return nil
+	}
+	if bits := pub.N.BitLen(); bits < MinRSAKeyBits {
+		return dc.warn(ErrWeakParameters, algorithm, "RSA key is %d bits; at least %d bits are required", bits, MinRSAKeyBits)
+	}
+	return nil
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:nosnakecase
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+func enableTestDebugChecks(t *testing.T, strict bool) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	log := logger.NewLogger("test.crypto.debug")
+	log.SetOutput(&buf)
+	disable := EnableDebugChecks(DebugOptions{Log: log, Strict: strict})
+	t.Cleanup(disable)
+	return &buf
+}
+
+func TestDebugChecksNonceReuse(t *testing.T) {
+	key, err := jwk.FromRaw(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	nonce := bytes.Repeat([]byte{2}, 12)
+
+	t.Run("disabled", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, _, err = EncryptSymmetric([]byte("message"), Algorithm_A256GCM, key, nonce, nil)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		buf := enableTestDebugChecks(t, false)
+		for i := 0; i < 3; i++ {
+			_, _, err = EncryptSymmetric([]byte("message"), Algorithm_A256GCM, key, nonce, nil)
+			require.NoError(t, err)
+		}
+		// Warnings are logged once
+		assert.Equal(t, 1, strings.Count(buf.String(), "the same nonce was used more than once"))
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		enableTestDebugChecks(t, true)
+		_, _, err = EncryptSymmetric([]byte("message"), Algorithm_A256GCM, key, nonce, nil)
+		require.NoError(t, err)
+		_, _, err = EncryptSymmetric([]byte("message"), Algorithm_A256GCM, key, nonce, nil)
+		require.ErrorIs(t, err, ErrNonceReuse)
+
+		// A different key or nonce is fine
+		otherKey, err := jwk.FromRaw(bytes.Repeat([]byte{3}, 32))
+		require.NoError(t, err)
+		_, _, err = EncryptSymmetric([]byte("message"), Algorithm_A256GCM, otherKey, nonce, nil)
+		require.NoError(t, err)
+		_, _, err = EncryptSymmetric([]byte("message"), Algorithm_A256GCM, key, bytes.Repeat([]byte{4}, 12), nil)
+		require.NoError(t, err)
+
+		_, _, err = EncryptSymmetric([]byte("message"), Algorithm_A256GCM, key, make([]byte, 12), nil)
+		require.ErrorIs(t, err, ErrWeakParameters)
+	})
+
+	t.Run("tracked nonces are bounded", func(t *testing.T) {
+		dc := &debugChecker{
+			opts:   DebugOptions{Log: logger.NewLogger("test.crypto.debug"), MaxTrackedNonces: 4},
+			nonces: map[string]struct{}{},
+		}
+		for i := byte(1); i <= 10; i++ {
+			require.NoError(t, dc.checkSymmetric(Algorithm_A256GCM, []byte("key"), []byte{i}))
+		}
+		assert.LessOrEqual(t, len(dc.nonces)+len(dc.oldNonces), 4)
+	})
+}
+
+func TestDebugChecksWeakParameters(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	weakKey, err := jwk.FromRaw(rsaKey)
+	require.NoError(t, err)
+	aesKey, err := jwk.FromRaw(bytes.Repeat([]byte{1}, 16))
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+
+	t.Run("warnings", func(t *testing.T) {
+		buf := enableTestDebugChecks(t, false)
+
+		_, err = EncryptPublicKey([]byte("message"), Algorithm_RSA1_5, weakKey, nil)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "RSA-PKCS1v1.5 encryption is vulnerable")
+		assert.Contains(t, buf.String(), "RSA key is 1024 bits")
+
+		_, _, err = EncryptSymmetric([]byte("message"), Algorithm_A128CBC, aesKey, bytes.Repeat([]byte{1}, 16), nil)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "does not authenticate the ciphertext")
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		enableTestDebugChecks(t, true)
+
+		_, err = EncryptPublicKey([]byte("message"), Algorithm_RSA_OAEP_256, weakKey, nil)
+		require.ErrorIs(t, err, ErrWeakParameters)
+		_, err = SignPrivateKey(digest[:], Algorithm_PS256, weakKey)
+		require.ErrorIs(t, err, ErrWeakParameters)
+	})
+
+	t.Run("digest length", func(t *testing.T) {
+		enableTestDebugChecks(t, true)
+
+		ecRaw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		ecKey, err := jwk.FromRaw(ecRaw)
+		require.NoError(t, err)
+		_, err = SignPrivateKey(digest[:], Algorithm_ES256, ecKey)
+		require.NoError(t, err)
+		_, err = SignPrivateKey([]byte("the message, not the digest"), Algorithm_ES256, ecKey)
+		require.ErrorIs(t, err, ErrWeakParameters)
+	})
+}
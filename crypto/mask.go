@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// maskMinRevealLen is the minimum length, in characters, of secrets whose first and last characters are revealed by
+	// Mask. Shorter secrets are masked entirely.
+	maskMinRevealLen = 8
+	// maskRevealChars is the number of characters revealed at the beginning and at the end of secrets.
+	maskRevealChars = 2
+	// maskHashBytes is the number of bytes of the hash included in masked values.
+	maskHashBytes = 4
+	// maskHashPrefix is prepended to secrets before hashing them, so the suffix of masked values can't be matched
+	// against plain SHA-256 digests.
+	maskHashPrefix = "dapr-kit-mask:"
+)
+
+// Mask returns a representation of a secret that can be included in logs and errors without exposing it, such as
+// "ab***yz#1a2b3c4d".
+// The first and last 2 characters are included only if the secret has at least 8 characters; the suffix is derived
+// from a hash of the secret, so the same secret always has the same representation and it can be correlated across
+// logs and errors, even from different processes.
+// Because the hash is not keyed, the masked value of low-entropy secrets, such as short passwords, could be guessed
+// with a brute-force attack.
+func Mask(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(maskHashPrefix + s))
+	suffix := "#" + hex.EncodeToString(sum[:maskHashBytes])
+
+	if utf8.RuneCountInString(s) < maskMinRevealLen {
+		return "***" + suffix
+	}
+	runes := []rune(s)
+	return string(runes[:maskRevealChars]) + "***" + string(runes[len(runes)-maskRevealChars:]) + suffix
+}
+
+// SecretEqual compares two secrets in constant time, with respect to their contents and lengths.
+func SecretEqual(a string, b string) bool {
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// SecretString is a string containing a secret, which is masked with Mask when the value is formatted, including with
+// the "%v", "%s" and "%#v" verbs, or encoded as text or JSON.
+type SecretString string
+
+// String implements fmt.Stringer.
+func (s SecretString) String() string {
+	return Mask(string(s))
+}
+
+// GoString implements fmt.GoStringer.
+func (s SecretString) GoString() string {
+	return Mask(string(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s SecretString) MarshalText() ([]byte, error) {
+	return []byte(Mask(string(s))), nil
+}
+
+// Equal compares the secret with other in constant time.
+func (s SecretString) Equal(other string) bool {
+	return SecretEqual(string(s), other)
+}
+
+// RedactInLogs registers a logger redactor that replaces the secrets in all log records with their masked
+// representation, and returns a function that removes it.
+// Empty secrets are ignored. Secrets should be long enough not to match unrelated text.
+func RedactInLogs(secrets ...string) (remove func()) {
+	list := make([]string, 0, len(secrets))
+	for _, s := range secrets {
+		if s != "" {
+			list = append(list, s)
+		}
+	}
+	if len(list) == 0 {
+		return func() {}
+	}
+
+	// Longer secrets are replaced first, in case a secret contains another one
+	sort.Slice(list, func(i, j int) bool {
+		return len(list[i]) > len(list[j])
+	})
+	pairs := make([]string, 0, 2*len(list))
+	for _, s := range list {
+		pairs = append(pairs, s, Mask(s))
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	return logger.AddRedactor(replacer.Replace)
+}
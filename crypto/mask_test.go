@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+func TestMask(t *testing.T) {
+	masked := Mask("my-api-key-123456")
+	assert.Regexp(t, `^my\*\*\*56#[0-9a-f]{8}$`, masked)
+	// Masked values are stable
+	assert.Equal(t, masked, Mask("my-api-key-123456"))
+	assert.NotEqual(t, masked[len(masked)-8:], Mask("my-api-key-123457")[len(masked)-8:])
+
+	// Short secrets are masked entirely
+	assert.Regexp(t, `^\*\*\*#[0-9a-f]{8}$`, Mask("short"))
+	assert.Regexp(t, `^ün\*\*\*çé#[0-9a-f]{8}$`, Mask("ünicode-çé"))
+	assert.Empty(t, Mask(""))
+}
+
+func TestSecretEqual(t *testing.T) {
+	assert.True(t, SecretEqual("secret", "secret"))
+	assert.False(t, SecretEqual("secret", "Secret"))
+	assert.False(t, SecretEqual("secret", "secret2"))
+	assert.True(t, SecretEqual("", ""))
+
+	s := SecretString("my-api-key-123456")
+	assert.True(t, s.Equal("my-api-key-123456"))
+	assert.False(t, s.Equal("other"))
+}
+
+func TestSecretString(t *testing.T) {
+	s := SecretString("my-api-key-123456")
+	masked := Mask("my-api-key-123456")
+
+	assert.Equal(t, masked, fmt.Sprintf("%v", s))
+	assert.Equal(t, masked, fmt.Sprintf("%s", s))
+	assert.Equal(t, masked, fmt.Sprintf("%#v", s))
+
+	enc, err := json.Marshal(map[string]any{"key": s})
+	require.NoError(t, err)
+	assert.Equal(t, `{"key":"`+masked+`"}`, string(enc))
+}
+
+func TestRedactInLogs(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLogger("test.crypto.mask")
+	log.SetOutput(&buf)
+
+	remove := RedactInLogs("my-api-key-123456", "my-api-key", "")
+	defer remove()
+
+	log.Infof("connecting with key %s and prefix %s", "my-api-key-123456", "my-api-key")
+	out := buf.String()
+	assert.NotContains(t, out, "my-api-key")
+	assert.Contains(t, out, Mask("my-api-key-123456"))
+	assert.Contains(t, out, Mask("my-api-key"))
+
+	remove()
+	buf.Reset()
+	log.Info("key my-api-key")
+	assert.Contains(t, buf.String(), "my-api-key")
+
+	// No secrets
+	RedactInLogs("")()
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// ErrRandSource is returned when the random source fails to return enough bytes.
+var ErrRandSource = errors.New("failed to read from the random source")
+
+// RandSource is a source of cryptographically-secure random bytes.
+// All crypto helpers in this package read entropy from the RandSource returned by Rand.
+type RandSource interface {
+	io.Reader
+}
+
+type randSourceHolder struct {
+	src RandSource
+}
+
+var randSource atomic.Pointer[randSourceHolder]
+
+// Rand returns the RandSource used by the crypto helpers.
+// By default, this is crypto/rand.Reader.
+func Rand() RandSource {
+	h := randSource.Load()
+	if h == nil {
+		return rand.Reader
+	}
+	return h.src
+}
+
+// SetRandSource replaces the RandSource used by the crypto helpers, and returns a function that restores the
+// previous one. Passing nil restores the default source.
+// This should only be used in tests, for example to inject failures with the cryptotest package.
+func SetRandSource(src RandSource) (restore func()) {
+	var h *randSourceHolder
+	if src != nil {
+		h = &randSourceHolder{src: src}
+	}
+	prev := randSource.Swap(h)
+	return func() {
+		randSource.Store(prev)
+	}
+}
+
+// RandomBytes returns n random bytes read from the RandSource.
+// Short reads are retried; if the source returns an error, it is wrapped in ErrRandSource.
+func RandomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(Rand(), b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRandSource, err)
+	}
+	return b, nil
+}
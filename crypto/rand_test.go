@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/crypto"
+	"github.com/dapr/kit/crypto/cryptotest"
+)
+
+func TestRandSource(t *testing.T) {
+	t.Run("default source", func(t *testing.T) {
+		assert.Equal(t, rand.Reader, crypto.Rand())
+		b, err := crypto.RandomBytes(16)
+		require.NoError(t, err)
+		assert.Len(t, b, 16)
+	})
+
+	t.Run("short reads are retried", func(t *testing.T) {
+		src := cryptotest.NewRandSource().
+			WithSource(bytes.NewReader(bytes.Repeat([]byte{0x42}, 32))).
+			ShortNext(3).
+			ShortNext(5)
+		defer src.Install()()
+
+		b, err := crypto.RandomBytes(16)
+		require.NoError(t, err)
+		assert.Equal(t, bytes.Repeat([]byte{0x42}, 16), b)
+		assert.Equal(t, 3, src.Reads())
+	})
+
+	t.Run("errors are returned", func(t *testing.T) {
+		src := cryptotest.NewRandSource().ShortNext(4).FailNext(nil)
+		restore := src.Install()
+
+		_, err := crypto.RandomBytes(16)
+		require.ErrorIs(t, err, crypto.ErrRandSource)
+		assert.ErrorContains(t, err, cryptotest.ErrInjected.Error())
+
+		// Faults are consumed
+		_, err = crypto.RandomBytes(16)
+		require.NoError(t, err)
+
+		restore()
+		assert.Equal(t, rand.Reader, crypto.Rand())
+	})
+
+	t.Run("helpers use the source", func(t *testing.T) {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		key, err := jwk.FromRaw(ecKey)
+		require.NoError(t, err)
+
+		src := cryptotest.NewRandSource().FailAlways(nil)
+		defer src.Install()()
+
+		_, err = crypto.SignPrivateKey(bytes.Repeat([]byte{1}, 32), "ES256", key)
+		require.Error(t, err)
+		assert.Positive(t, src.Reads())
+	})
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"errors"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/x25519"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// SealedBoxOverhead is the number of bytes that sealed boxes add to the message.
+const SealedBoxOverhead = box.AnonymousOverhead
+
+// ErrSealedBoxOpen is returned by OpenAnonymous when the sealed box cannot be decrypted or authenticated.
+var ErrSealedBoxOpen = errors.New("failed to open sealed box")
+
+// SealAnonymous encrypts a message for the owner of an X25519 public key (an "OKP" key with curve "X25519"), with
+// an ephemeral sender key.
+// The result is compatible with libsodium's crypto_box_seal (X25519 + XSalsa20-Poly1305), so it can be opened by any
+// system that supports NaCl sealed boxes.
+func SealAnonymous(pub jwk.Key, msg []byte) ([]byte, error) {
+	// Ensure we are using a public key
+	pub, err := pub.PublicKey()
+	if err != nil {
+		return nil, ErrKeyTypeMismatch
+	}
+	if !isX25519(pub) {
+		return nil, ErrKeyTypeMismatch
+	}
+
+	x25519Key := x25519.PublicKey{}
+	if pub.Raw(&x25519Key) != nil || len(x25519Key) != 32 {
+		return nil, ErrKeyTypeMismatch
+	}
+
+	return box.SealAnonymous(nil, msg, (*[32]byte)(x25519Key), Rand())
+}
+
+// OpenAnonymous decrypts a sealed box created by SealAnonymous or by libsodium's crypto_box_seal, using the
+// recipient's X25519 private key.
+func OpenAnonymous(priv jwk.Key, sealed []byte) ([]byte, error) {
+	if !isX25519(priv) {
+		return nil, ErrKeyTypeMismatch
+	}
+
+	x25519Key := x25519.PrivateKey{}
+	if priv.Raw(&x25519Key) != nil || len(x25519Key) != x25519.PrivateKeySize {
+		return nil, ErrKeyTypeMismatch
+	}
+	pubKey, ok := x25519Key.Public().(x25519.PublicKey)
+	if !ok {
+		return nil, ErrKeyTypeMismatch
+	}
+
+	if len(sealed) < SealedBoxOverhead {
+		return nil, ErrInvalidCiphertextLength
+	}
+
+	msg, ok := box.OpenAnonymous(nil, sealed, (*[32]byte)(pubKey), (*[32]byte)(x25519Key.Seed()))
+	if !ok {
+		return nil, ErrSealedBoxOpen
+	}
+	return msg, nil
+}
+
+func isX25519(key jwk.Key) bool {
+	if key.KeyType() != jwa.OKP {
+		return false
+	}
+	okpKey, ok := key.(interface{ Crv() jwa.EllipticCurveAlgorithm })
+	return ok && okpKey.Crv() == jwa.X25519
+}
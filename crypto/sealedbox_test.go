@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func TestSealedBox(t *testing.T) {
+	pubRaw, privRaw, err := x25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	priv, err := jwk.FromRaw(privRaw)
+	require.NoError(t, err)
+	pub, err := jwk.FromRaw(pubRaw)
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		sealed, err := SealAnonymous(pub, []byte(message))
+		require.NoError(t, err)
+		assert.Len(t, sealed, len(message)+SealedBoxOverhead)
+
+		opened, err := OpenAnonymous(priv, sealed)
+		require.NoError(t, err)
+		assert.Equal(t, message, string(opened))
+	})
+
+	t.Run("seal with private key", func(t *testing.T) {
+		sealed, err := SealAnonymous(priv, []byte("hello"))
+		require.NoError(t, err)
+
+		opened, err := OpenAnonymous(priv, sealed)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(opened))
+	})
+
+	t.Run("compatible with nacl", func(t *testing.T) {
+		pubArr := (*[32]byte)(pubRaw)
+		privArr := (*[32]byte)(privRaw.Seed())
+
+		sealed, err := box.SealAnonymous(nil, []byte("hello"), pubArr, rand.Reader)
+		require.NoError(t, err)
+		opened, err := OpenAnonymous(priv, sealed)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(opened))
+
+		sealed, err = SealAnonymous(pub, []byte("world"))
+		require.NoError(t, err)
+		opened, ok := box.OpenAnonymous(nil, sealed, pubArr, privArr)
+		require.True(t, ok)
+		assert.Equal(t, "world", string(opened))
+	})
+
+	t.Run("tampered box", func(t *testing.T) {
+		sealed, err := SealAnonymous(pub, []byte("hello"))
+		require.NoError(t, err)
+		sealed[len(sealed)-1] ^= 0xff
+
+		_, err = OpenAnonymous(priv, sealed)
+		assert.ErrorIs(t, err, ErrSealedBoxOpen)
+	})
+
+	t.Run("wrong recipient", func(t *testing.T) {
+		_, otherRaw, err := x25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		other, err := jwk.FromRaw(otherRaw)
+		require.NoError(t, err)
+
+		sealed, err := SealAnonymous(pub, []byte("hello"))
+		require.NoError(t, err)
+		_, err = OpenAnonymous(other, sealed)
+		assert.ErrorIs(t, err, ErrSealedBoxOpen)
+	})
+
+	t.Run("box too short", func(t *testing.T) {
+		_, err := OpenAnonymous(priv, make([]byte, SealedBoxOverhead-1))
+		assert.ErrorIs(t, err, ErrInvalidCiphertextLength)
+	})
+
+	t.Run("unsupported keys", func(t *testing.T) {
+		ed25519Key, err := ParseKey([]byte(privateKeyEd25519JSON), "application/json")
+		require.NoError(t, err)
+		_, err = SealAnonymous(ed25519Key, []byte("hello"))
+		assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+		_, err = OpenAnonymous(ed25519Key, make([]byte, 64))
+		assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+
+		// Public keys can't be used to open boxes
+		_, err = OpenAnonymous(pub, make([]byte, 64))
+		assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+	})
+}
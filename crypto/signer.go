@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+var (
+	// ErrKeyNotFound is returned when a key with the requested ID doesn't exist.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrNotPrivateKey is returned when a private key is required, but a public or symmetric key is provided.
+	ErrNotPrivateKey = errors.New("key is not a private key")
+	// ErrMissingKeyID is returned when a key doesn't have an ID.
+	ErrMissingKeyID = errors.New("key doesn't have an ID")
+)
+
+// Signer creates signatures with private keys that are addressed by ID.
+// Implementations may keep the keys in memory, or delegate the operations to a KMS or a HSM, in which case the private
+// keys never leave the provider. Callers should depend on this interface rather than on raw private keys.
+type Signer interface {
+	// Sign creates a signature of digest with the key with the given ID, using the specified algorithm.
+	// The supported algorithms are the ones returned by SupportedSignatureAlgorithms, although providers may only
+	// support a subset of them.
+	Sign(ctx context.Context, keyID string, digest []byte, algorithm string) ([]byte, error)
+	// PublicKey returns the public part of the key with the given ID.
+	PublicKey(ctx context.Context, keyID string) (jwk.Key, error)
+}
+
+// Decrypter decrypts messages with private keys that are addressed by ID.
+// See Signer for details.
+type Decrypter interface {
+	// Decrypt decrypts ciphertext with the key with the given ID, using the specified algorithm.
+	// The supported algorithms are the ones returned by SupportedAsymmetricAlgorithms, although providers may only
+	// support a subset of them.
+	Decrypt(ctx context.Context, keyID string, ciphertext []byte, algorithm string, associatedData []byte) ([]byte, error)
+	// PublicKey returns the public part of the key with the given ID.
+	PublicKey(ctx context.Context, keyID string) (jwk.Key, error)
+}
+
+// SoftwareKeyStore implements Signer and Decrypter with private keys that are kept in memory.
+// It's safe for concurrent use.
+type SoftwareKeyStore struct {
+	lock sync.RWMutex
+	keys map[string]jwk.Key
+}
+
+var (
+	_ Signer    = (*SoftwareKeyStore)(nil)
+	_ Decrypter = (*SoftwareKeyStore)(nil)
+)
+
+// NewSoftwareKeyStore returns a new SoftwareKeyStore containing the given private keys, which are addressed by their
+// "kid" property.
+func NewSoftwareKeyStore(keys ...jwk.Key) (*SoftwareKeyStore, error) {
+	s := &SoftwareKeyStore{
+		keys: make(map[string]jwk.Key, len(keys)),
+	}
+	for _, key := range keys {
+		err := s.Add(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add adds a private key to the store, which is addressed by its "kid" property.
+// If a key with the same ID already exists, it's replaced.
+func (s *SoftwareKeyStore) Add(key jwk.Key) error {
+	if key == nil || !isPrivateKey(key) {
+		return ErrNotPrivateKey
+	}
+	kid := key.KeyID()
+	if kid == "" {
+		return ErrMissingKeyID
+	}
+
+	s.lock.Lock()
+	s.keys[kid] = key
+	s.lock.Unlock()
+	return nil
+}
+
+// Remove removes the key with the given ID from the store, if present.
+func (s *SoftwareKeyStore) Remove(keyID string) {
+	s.lock.Lock()
+	delete(s.keys, keyID)
+	s.lock.Unlock()
+}
+
+// Sign implements Signer.
+func (s *SoftwareKeyStore) Sign(ctx context.Context, keyID string, digest []byte, algorithm string) ([]byte, error) {
+	key, err := s.get(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return SignPrivateKey(digest, algorithm, key)
+}
+
+// Decrypt implements Decrypter.
+func (s *SoftwareKeyStore) Decrypt(ctx context.Context, keyID string, ciphertext []byte, algorithm string, associatedData []byte) ([]byte, error) {
+	key, err := s.get(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptPrivateKey(ciphertext, algorithm, key, associatedData)
+}
+
+// PublicKey implements Signer and Decrypter.
+func (s *SoftwareKeyStore) PublicKey(ctx context.Context, keyID string) (jwk.Key, error) {
+	key, err := s.get(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return key.PublicKey()
+}
+
+func (s *SoftwareKeyStore) get(ctx context.Context, keyID string) (jwk.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.lock.RLock()
+	key, ok := s.keys[keyID]
+	s.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return key, nil
+}
+
+func isPrivateKey(key jwk.Key) bool {
+	switch key.(type) {
+	case jwk.RSAPrivateKey, jwk.ECDSAPrivateKey, jwk.OKPPrivateKey:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftwareKeyStore(t *testing.T) {
+	key, err := ParseKey([]byte(privateKeyRSAPKCS8), "application/x-pem-file")
+	require.NoError(t, err)
+
+	t.Run("keys must have an ID", func(t *testing.T) {
+		_, err = NewSoftwareKeyStore(key)
+		require.ErrorIs(t, err, ErrMissingKeyID)
+	})
+
+	require.NoError(t, key.Set(jwk.KeyIDKey, "mykey"))
+
+	t.Run("keys must be private", func(t *testing.T) {
+		pub, err := key.PublicKey()
+		require.NoError(t, err)
+		_, err = NewSoftwareKeyStore(pub)
+		require.ErrorIs(t, err, ErrNotPrivateKey)
+	})
+
+	store, err := NewSoftwareKeyStore(key)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("sign", func(t *testing.T) {
+		signature, err := store.Sign(ctx, "mykey", messageHash, Algorithm_PS256)
+		require.NoError(t, err)
+
+		pub, err := store.PublicKey(ctx, "mykey")
+		require.NoError(t, err)
+		_, isPrivate := pub.(jwk.RSAPrivateKey)
+		assert.False(t, isPrivate)
+
+		valid, err := VerifyPublicKey(messageHash, signature, Algorithm_PS256, pub)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("decrypt", func(t *testing.T) {
+		pub, err := store.PublicKey(ctx, "mykey")
+		require.NoError(t, err)
+		ciphertext, err := EncryptPublicKey([]byte("hello world"), Algorithm_RSA_OAEP_256, pub, []byte("aad"))
+		require.NoError(t, err)
+
+		plaintext, err := store.Decrypt(ctx, "mykey", ciphertext, Algorithm_RSA_OAEP_256, []byte("aad"))
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(plaintext))
+	})
+
+	t.Run("key not found", func(t *testing.T) {
+		_, err := store.Sign(ctx, "other", messageHash, Algorithm_PS256)
+		require.ErrorIs(t, err, ErrKeyNotFound)
+
+		store.Remove("mykey")
+		_, err = store.PublicKey(ctx, "mykey")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+		require.NoError(t, store.Add(key))
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := store.Sign(cctx, "mykey", messageHash, Algorithm_PS256)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -48,6 +48,12 @@ func EncryptSymmetric(plaintext []byte, algorithm string, key jwk.Key, nonce []b
 	if key.KeyType() != jwa.OctetSeq || key.Raw(&keyBytes) != nil {
 		return nil, nil, ErrKeyTypeMismatch
 	}
+	if dc := debugChecks.Load(); dc != nil {
+		err = dc.checkSymmetric(algorithm, keyBytes, nonce)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
 	switch algorithm {
 	case Algorithm_A128CBC, Algorithm_A192CBC, Algorithm_A256CBC,
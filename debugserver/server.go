@@ -0,0 +1,403 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package debugserver contains an embedded HTTP server that exposes a uniform operational surface for kit-based
+// binaries: pprof profiles, the log level handler, the stats of queue processors, the aggregated status of health
+// checks, and diagnostic bundles. The server only listens on loopback addresses, and requests can require a token.
+//
+// To protect the server from web pages opened by the operator, requests whose Host header is not a loopback address
+// (such as after a DNS rebinding) and requests with an Origin header (sent by browsers with cross-origin requests) are
+// rejected. Log levels can only be changed when a token is set.
+package debugserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dapr/kit/crypto"
+	"github.com/dapr/kit/diagnostics"
+	"github.com/dapr/kit/events/queue"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// DefaultAddress is the default address the server listens on.
+	DefaultAddress = "127.0.0.1:6060"
+	// DefaultHealthCheckTimeout is the default timeout for each health check.
+	DefaultHealthCheckTimeout = 5 * time.Second
+
+	// shutdownTimeout is the maximum time to wait for in-flight requests when the server is stopped.
+	shutdownTimeout = 5 * time.Second
+)
+
+// Paths of the endpoints.
+const (
+	PathPprof       = "/debug/pprof/"
+	PathLogLevel    = "/debug/loglevel"
+	PathQueues      = "/debug/queues"
+	PathDiagnostics = "/debug/diagnostics"
+	PathHealth      = "/healthz"
+)
+
+var (
+	// ErrNotLoopback is returned by New when the address is not a loopback address.
+	ErrNotLoopback = errors.New("the debug server can only listen on loopback addresses")
+	// ErrServerRunning is returned when running a server that is already running.
+	ErrServerRunning = errors.New("server is already running")
+)
+
+// StatsProvider is implemented by queue.Processor.
+type StatsProvider interface {
+	Stats() queue.Stats
+}
+
+// HealthCheckFn checks the health of a subsystem, returning an error if it's unhealthy.
+type HealthCheckFn func(ctx context.Context) error
+
+// Options contains the options for a Server.
+type Options struct {
+	// Address to listen on, such as "127.0.0.1:6060" or "localhost:0". The host must be a loopback IP, or "localhost"
+	// resolving only to loopback IPs. Default is DefaultAddress.
+	Address string
+	// Token, if set, must be included in all requests in the "Authorization: Bearer <token>" header.
+	// Without a token, the log level endpoint is read-only.
+	Token string
+	// Processors are the queue processors whose stats are returned by the queues endpoint, by name.
+	Processors map[string]StatsProvider
+	// HealthChecks are the checks aggregated by the health endpoint, by name.
+	HealthChecks map[string]HealthCheckFn
+	// HealthCheckTimeout is the timeout for each health check. Default is DefaultHealthCheckTimeout.
+	HealthCheckTimeout time.Duration
+	// Diagnostics is the collector of the bundles returned by the diagnostics endpoint. If nil, the endpoint is not
+	// mounted.
+	Diagnostics *diagnostics.Collector
+	// Handlers are additional handlers to mount, by pattern, such as a jobs.Manager on "/debug/jobs".
+	// Patterns can't use the paths of the built-in endpoints, nor conflict with them.
+	Handlers map[string]http.Handler
+	// Log is used to report the address the server listens on. Optional.
+	Log logger.Logger
+}
+
+// Server is the debug HTTP server.
+type Server struct {
+	opts    Options
+	handler http.Handler
+
+	running atomic.Bool
+	addr    atomic.Pointer[string]
+}
+
+// New returns a new Server.
+func New(opts Options) (*Server, error) {
+	if opts.Address == "" {
+		opts.Address = DefaultAddress
+	}
+	if opts.HealthCheckTimeout <= 0 {
+		opts.HealthCheckTimeout = DefaultHealthCheckTimeout
+	}
+
+	host, _, err := net.SplitHostPort(opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address '%s': %w", opts.Address, err)
+	}
+	if !isLoopback(context.Background(), host) {
+		return nil, fmt.Errorf("%w: %s", ErrNotLoopback, opts.Address)
+	}
+
+	s := &Server{opts: opts}
+	routes, err := s.routes()
+	if err != nil {
+		return nil, err
+	}
+	s.handler = s.protect(s.authenticate(routes))
+	return s, nil
+}
+
+// isLoopback returns true if host is a loopback IP, or if it's "localhost" and it resolves only to loopback IPs.
+// Other names are not resolved, as their resolution can be changed by whoever controls them.
+func isLoopback(ctx context.Context, host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback()
+	}
+	if !strings.EqualFold(host, "localhost") {
+		return false
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return false
+	}
+	for _, addr := range addrs {
+		if !addr.IP.IsLoopback() {
+			return false
+		}
+	}
+	return true
+}
+
+// Handler returns the handler of the server, including the authentication.
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Addr returns the address the server is listening on, which includes the port when the address has port 0.
+// It's empty if the server is not running.
+func (s *Server) Addr() string {
+	if a := s.addr.Load(); a != nil {
+		return *a
+	}
+	return ""
+}
+
+// Run starts the server. This method blocks until the context is canceled, then it stops the server, waiting for
+// in-flight requests to complete.
+func (s *Server) Run(ctx context.Context) error {
+	if !s.running.CompareAndSwap(false, true) {
+		return ErrServerRunning
+	}
+	defer s.running.Store(false)
+
+	ln, err := net.Listen("tcp", s.opts.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.opts.Address, err)
+	}
+	addr := ln.Addr().String()
+	s.addr.Store(&addr)
+	defer s.addr.Store(nil)
+
+	srv := &http.Server{
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+	if s.opts.Log != nil {
+		s.opts.Log.Infof("Debug server listening on %s", addr)
+	}
+
+	select {
+	case err = <-errCh:
+		return fmt.Errorf("debug server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	if serveErr := <-errCh; !errors.Is(serveErr, http.ErrServerClosed) {
+		err = errors.Join(err, serveErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stop debug server: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) routes() (http.Handler, error) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(PathPprof, pprof.Index)
+	mux.HandleFunc(PathPprof+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(PathPprof+"profile", pprof.Profile)
+	mux.HandleFunc(PathPprof+"symbol", pprof.Symbol)
+	mux.HandleFunc(PathPprof+"trace", pprof.Trace)
+
+	mux.Handle(PathLogLevel, logger.LevelHandler(logger.LevelHandlerOptions{
+		ReadOnly: s.opts.Token == "",
+	}))
+	mux.HandleFunc(PathQueues, s.serveQueues)
+	mux.HandleFunc(PathHealth, s.serveHealth)
+	if s.opts.Diagnostics != nil {
+		mux.Handle(PathDiagnostics, s.opts.Diagnostics)
+	}
+
+	// Mount the additional handlers in a deterministic order, so conflicts are always reported the same way
+	patterns := make([]string, 0, len(s.opts.Handlers))
+	for pattern := range s.opts.Handlers {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if isReservedPath(patternPath(pattern)) {
+			return nil, fmt.Errorf("invalid handler pattern '%s': the path is used by a built-in endpoint", pattern)
+		}
+		if err := handle(mux, pattern, s.opts.Handlers[pattern]); err != nil {
+			return nil, err
+		}
+	}
+
+	return mux, nil
+}
+
+// handle mounts h on the mux, returning an error instead of panicking if the pattern is invalid or conflicts with
+// another one.
+func handle(mux *http.ServeMux, pattern string, h http.Handler) (err error) {
+	if h == nil {
+		return fmt.Errorf("invalid handler pattern '%s': handler is nil", pattern)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid handler pattern '%s': %v", pattern, r)
+		}
+	}()
+	mux.Handle(pattern, h)
+	return nil
+}
+
+// patternPath returns the path of a ServeMux pattern, which has the format "[METHOD ][HOST]/[PATH]".
+func patternPath(pattern string) string {
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i >= 0 {
+		return pattern[i:]
+	}
+	return pattern
+}
+
+// isReservedPath returns true if path is used by a built-in endpoint, including the endpoints that are not mounted.
+// Because patterns with a method or a host take precedence over the built-in ones, they can't use these paths either.
+func isReservedPath(path string) bool {
+	switch path {
+	case PathLogLevel, PathQueues, PathDiagnostics, PathHealth, strings.TrimSuffix(PathPprof, "/"):
+		return true
+	}
+	return strings.HasPrefix(path, PathPprof)
+}
+
+// protect rejects requests that may come from a browser: requests with an Origin header, and requests whose Host
+// header is not a loopback address.
+func (s *Server) protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !isLoopback(r.Context(), strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")) {
+			http.Error(w, "invalid host", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate requires the token in all requests, if one is set.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.opts.Token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !crypto.SecretEqual(token, s.opts.Token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) serveQueues(w http.ResponseWriter, r *http.Request) {
+	res := make(map[string]queue.Stats, len(s.opts.Processors))
+	for name, p := range s.opts.Processors {
+		res[name] = p.Stats()
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// HealthStatus is the response of the health endpoint.
+type HealthStatus struct {
+	// Healthy is true if all checks passed.
+	Healthy bool `json:"healthy"`
+	// Checks contains the result of each check, by name.
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// CheckResult is the result of a health check.
+type CheckResult struct {
+	// Healthy is true if the check passed.
+	Healthy bool `json:"healthy"`
+	// Error returned by the check, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// CheckHealth runs all health checks concurrently and aggregates the results.
+func (s *Server) CheckHealth(ctx context.Context) HealthStatus {
+	res := HealthStatus{
+		Healthy: true,
+		Checks:  make(map[string]CheckResult, len(s.opts.HealthChecks)),
+	}
+
+	names := make([]string, 0, len(s.opts.HealthChecks))
+	for name := range s.opts.HealthChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	errs := make([]error, len(names))
+
+	var wg sync.WaitGroup
+	wg.Add(len(names))
+	for i, name := range names {
+		go func(i int, check HealthCheckFn) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, s.opts.HealthCheckTimeout)
+			defer cancel()
+			errs[i] = check(checkCtx)
+		}(i, s.opts.HealthChecks[name])
+	}
+	wg.Wait()
+
+	for i, name := range names {
+		if errs[i] != nil {
+			res.Healthy = false
+			res.Checks[name] = CheckResult{Error: errs[i].Error()}
+			continue
+		}
+		res.Checks[name] = CheckResult{Healthy: true}
+	}
+	return res
+}
+
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	status := s.CheckHealth(r.Context())
+	code := http.StatusOK
+	if !status.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, status)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/diagnostics"
+	"github.com/dapr/kit/events/queue"
+	"github.com/dapr/kit/logger"
+)
+
+type fakeStats queue.Stats
+
+func (s fakeStats) Stats() queue.Stats {
+	return queue.Stats(s)
+}
+
+func serve(t *testing.T, s *Server, method string, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	req.Host = "127.0.0.1:6060"
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNew(t *testing.T) {
+	for _, addr := range []string{"", "127.0.0.1:0", "localhost:6060", "[::1]:6060"} {
+		_, err := New(Options{Address: addr})
+		require.NoError(t, err, addr)
+	}
+	for _, addr := range []string{":6060", "0.0.0.0:6060", "192.168.1.1:6060", "example.com:6060"} {
+		_, err := New(Options{Address: addr})
+		require.ErrorIs(t, err, ErrNotLoopback, addr)
+	}
+	_, err := New(Options{Address: "localhost"})
+	require.Error(t, err)
+}
+
+func TestEndpoints(t *testing.T) {
+	s, err := New(Options{
+		Processors: map[string]StatsProvider{
+			"reminders": fakeStats{Pending: 3, Executed: 10},
+		},
+		HealthChecks: map[string]HealthCheckFn{
+			"ok": func(context.Context) error { return nil },
+		},
+		Diagnostics: diagnostics.NewCollector(diagnostics.CollectorOptions{Dir: t.TempDir()}),
+		Handlers: map[string]http.Handler{
+			"/debug/custom": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("custom"))
+			}),
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("pprof", func(t *testing.T) {
+		rec := serve(t, s, http.MethodGet, PathPprof, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "goroutine")
+	})
+
+	t.Run("log level", func(t *testing.T) {
+		rec := serve(t, s, http.MethodGet, PathLogLevel, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"loggers"`)
+	})
+
+	t.Run("queues", func(t *testing.T) {
+		rec := serve(t, s, http.MethodGet, PathQueues, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var res map[string]map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.EqualValues(t, 3, res["reminders"]["pending"])
+		assert.EqualValues(t, 10, res["reminders"]["executed"])
+	})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		rec := serve(t, s, http.MethodGet, PathDiagnostics, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var b diagnostics.Bundle
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &b))
+		assert.Equal(t, "api", b.Reason)
+	})
+
+	t.Run("custom handlers", func(t *testing.T) {
+		rec := serve(t, s, http.MethodGet, "/debug/custom", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "custom", rec.Body.String())
+	})
+
+	t.Run("diagnostics endpoint not mounted without a collector", func(t *testing.T) {
+		s, err := New(Options{})
+		require.NoError(t, err)
+		rec := serve(t, s, http.MethodGet, PathDiagnostics, nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestHandlerPatterns(t *testing.T) {
+	h := http.NotFoundHandler()
+	for _, pattern := range []string{
+		PathPprof, PathPprof + "heap", "/debug/pprof", PathLogLevel, PathQueues, PathHealth, PathDiagnostics,
+		"GET " + PathLogLevel, "localhost" + PathHealth, "GET localhost" + PathPprof + "goroutine",
+	} {
+		_, err := New(Options{Handlers: map[string]http.Handler{pattern: h}})
+		require.ErrorContains(t, err, "built-in endpoint", pattern)
+	}
+
+	// Invalid and conflicting patterns
+	for _, handlers := range []map[string]http.Handler{
+		{"": h},
+		{"/debug/{name": h},
+		{"/debug/custom": nil},
+		{"GET /debug/{name}/x": h, "/debug/custom/{name}": h},
+	} {
+		_, err := New(Options{Handlers: handlers})
+		require.ErrorContains(t, err, "invalid handler pattern")
+	}
+
+	_, err := New(Options{Handlers: map[string]http.Handler{"/debug/": h, "GET /debug/custom/{name}": h}})
+	require.NoError(t, err)
+}
+
+func TestHealth(t *testing.T) {
+	s, err := New(Options{
+		HealthChecks: map[string]HealthCheckFn{
+			"ok":     func(context.Context) error { return nil },
+			"failed": func(context.Context) error { return errors.New("connection refused") },
+			"slow": func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		HealthCheckTimeout: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	rec := serve(t, s, http.MethodGet, PathHealth, nil)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var status HealthStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, HealthStatus{
+		Healthy: false,
+		Checks: map[string]CheckResult{
+			"ok":     {Healthy: true},
+			"failed": {Error: "connection refused"},
+			"slow":   {Error: context.DeadlineExceeded.Error()},
+		},
+	}, status)
+
+	s, err = New(Options{})
+	require.NoError(t, err)
+	rec = serve(t, s, http.MethodGet, PathHealth, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestToken(t *testing.T) {
+	s, err := New(Options{Token: "s3cr3t-token"})
+	require.NoError(t, err)
+
+	rec := serve(t, s, http.MethodGet, PathHealth, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+
+	rec = serve(t, s, http.MethodGet, PathHealth, http.Header{"Authorization": {"Bearer wrong"}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = serve(t, s, http.MethodGet, PathHealth, http.Header{"Authorization": {"Bearer s3cr3t-token"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestBrowserProtection(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	for _, host := range []string{"localhost:6060", "127.0.0.1", "[::1]:6060", "[::1]"} {
+		req := httptest.NewRequest(http.MethodGet, PathHealth, nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, host)
+	}
+
+	// DNS rebinding
+	req := httptest.NewRequest(http.MethodGet, PathPprof, nil)
+	req.Host = "attacker.example.com:6060"
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Cross-origin requests
+	rec = serve(t, s, http.MethodPut, PathLogLevel+"?level=debug", http.Header{"Origin": {"https://attacker.example.com"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestLogLevelChanges(t *testing.T) {
+	// Without a token, levels are read-only
+	s, err := New(Options{})
+	require.NoError(t, err)
+	rec := serve(t, s, http.MethodPut, PathLogLevel+"?level=debug&logger=test.debugserver", nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	l := logger.NewLogger("test.debugserver")
+	defer l.SetOutputLevel(logger.InfoLevel)
+	s, err = New(Options{Token: "s3cr3t-token"})
+	require.NoError(t, err)
+	rec = serve(t, s, http.MethodPut, PathLogLevel+"?level=debug&logger=test.debugserver", http.Header{"Authorization": {"Bearer s3cr3t-token"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, l.IsOutputLevelEnabled(logger.DebugLevel))
+}
+
+func TestRun(t *testing.T) {
+	s, err := New(Options{Address: "127.0.0.1:0"})
+	require.NoError(t, err)
+	assert.Empty(t, s.Addr())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Run(ctx)
+	}()
+	assert.Eventually(t, func() bool { return s.Addr() != "" }, 5*time.Second, 10*time.Millisecond)
+	require.ErrorIs(t, s.Run(ctx), ErrServerRunning)
+
+	res, err := http.Get("http://" + s.Addr() + PathHealth)
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	cancel()
+	select {
+	case err = <-errCh:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+	assert.Empty(t, s.Addr())
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package di contains a lightweight dependency-injection container for wiring components.
+// Providers are registered for a type and are invoked lazily the first time the type is resolved; the result is
+// cached, so each type has a single instance ("singleton"). Components that implement Starter and Closer are started
+// in the order they were constructed (so dependencies are started first) and closed in the reverse order.
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrNotProvided is returned when resolving a type that doesn't have a provider.
+	ErrNotProvided = errors.New("no provider for type")
+	// ErrAlreadyProvided is returned when registering a provider for a type that already has one.
+	ErrAlreadyProvided = errors.New("a provider is already registered for type")
+	// ErrCycle is returned when a dependency cycle is detected.
+	ErrCycle = errors.New("dependency cycle detected")
+)
+
+// Starter is implemented by components that need to be started.
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// Closer is implemented by components that need to be closed.
+type Closer interface {
+	Close() error
+}
+
+// Container holds the providers and the instances they constructed.
+// Providers can be registered concurrently, but types should be resolved from a single goroutine (typically, while
+// wiring the application at startup), as concurrent resolutions of the same type may be reported as cycles.
+type Container struct {
+	lock      sync.Mutex
+	providers map[reflect.Type]*provider
+	// Instances in the order they were constructed
+	instances []*instance
+	// Stack of types being constructed, used to detect cycles
+	resolving []reflect.Type
+}
+
+type instance struct {
+	v any
+	// started is true if the instance was started, or if it doesn't need to be
+	started bool
+	// providers that returned the instance
+	providers []*provider
+}
+
+type provider struct {
+	fn       func(c *Container) (any, error)
+	instance any
+	built    bool
+	building bool
+}
+
+// New returns a new Container.
+func New() *Container {
+	return &Container{
+		providers: map[reflect.Type]*provider{},
+	}
+}
+
+// Provide registers a provider for the type T.
+// fn is invoked the first time T is resolved, and can resolve its own dependencies from the container.
+func Provide[T any](c *Container, fn func(c *Container) (T, error)) error {
+	typ := typeOf[T]()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.providers[typ]; ok {
+		return fmt.Errorf("%w %s", ErrAlreadyProvided, typ)
+	}
+	c.providers[typ] = &provider{
+		fn: func(c *Container) (any, error) {
+			return fn(c)
+		},
+	}
+	return nil
+}
+
+// ProvideValue registers an existing value for the type T.
+func ProvideValue[T any](c *Container, v T) error {
+	return Provide(c, func(*Container) (T, error) {
+		return v, nil
+	})
+}
+
+// Resolve returns the instance of type T, constructing it (and its dependencies) if needed.
+func Resolve[T any](c *Container) (T, error) {
+	var zero T
+	v, err := c.resolve(typeOf[T]())
+	if err != nil {
+		return zero, err
+	}
+	res, _ := v.(T)
+	return res, nil
+}
+
+// MustResolve is like Resolve, but panics in case of errors.
+func MustResolve[T any](c *Container) T {
+	v, err := Resolve[T](c)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (c *Container) resolve(typ reflect.Type) (any, error) {
+	c.lock.Lock()
+	p, ok := c.providers[typ]
+	if !ok {
+		c.lock.Unlock()
+		return nil, fmt.Errorf("%w %s", ErrNotProvided, typ)
+	}
+	if p.built {
+		c.lock.Unlock()
+		return p.instance, nil
+	}
+	if p.building {
+		path := make([]string, 0, len(c.resolving)+1)
+		start := 0
+		for i, r := range c.resolving {
+			if r == typ {
+				start = i
+				break
+			}
+		}
+		for _, r := range c.resolving[start:] {
+			path = append(path, r.String())
+		}
+		path = append(path, typ.String())
+		c.lock.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrCycle, strings.Join(path, " -> "))
+	}
+	p.building = true
+	c.resolving = append(c.resolving, typ)
+	c.lock.Unlock()
+
+	// Invoke the provider without holding the lock, as it can resolve other types
+	v, err := p.fn(c)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	p.building = false
+	c.resolving = c.resolving[:len(c.resolving)-1]
+	if err != nil {
+		if errors.Is(err, ErrCycle) || errors.Is(err, ErrNotProvided) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to construct %s: %w", typ, err)
+	}
+	p.instance = v
+	p.built = true
+	c.addInstance(p, v)
+	return v, nil
+}
+
+// addInstance records the instance constructed by p.
+// Values returned by multiple providers, such as a value registered for two types, are recorded once, so they're
+// started and closed once.
+// This must be invoked while the caller has a lock.
+func (c *Container) addInstance(p *provider, v any) {
+	for _, inst := range c.instances {
+		if sameValue(inst.v, v) {
+			inst.providers = append(inst.providers, p)
+			return
+		}
+	}
+	c.instances = append(c.instances, &instance{v: v, providers: []*provider{p}})
+}
+
+// removeInstances removes the instances from the container, so resolving their types constructs new instances.
+// This must be invoked while the caller has a lock.
+func (c *Container) removeInstances(removed []*instance) {
+	c.instances = slices.DeleteFunc(c.instances, func(inst *instance) bool {
+		return slices.Contains(removed, inst)
+	})
+	for _, inst := range removed {
+		for _, p := range inst.providers {
+			p.built = false
+			p.instance = nil
+		}
+	}
+}
+
+// sameValue returns true if a and b are the same value: for pointers, this means they point to the same object.
+// Values that are not comparable are never the same.
+func sameValue(a, b any) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() {
+		// Both are nil
+		return true
+	}
+	return va.Comparable() && vb.Comparable() && a == b
+}
+
+// Start all constructed instances that implement Starter and were not started yet, in the order they were
+// constructed.
+// If a component fails to start, the components that were started by this call are closed, in reverse order, and
+// they're removed from the container: they're not closed again by Close, and resolving their types again invokes
+// their providers. Components started by previous calls, and those that don't implement Starter, are left to Close.
+func (c *Container) Start(ctx context.Context) error {
+	c.lock.Lock()
+	instances := make([]*instance, 0, len(c.instances))
+	for _, inst := range c.instances {
+		if !inst.started {
+			instances = append(instances, inst)
+		}
+	}
+	c.lock.Unlock()
+
+	var started []*instance
+	for _, inst := range instances {
+		s, ok := inst.v.(Starter)
+		if !ok {
+			c.setStarted(inst)
+			continue
+		}
+		err := s.Start(ctx)
+		if err != nil {
+			c.lock.Lock()
+			c.removeInstances(started)
+			c.lock.Unlock()
+			closeErr := closeAll(started)
+			return errors.Join(fmt.Errorf("failed to start %T: %w", inst.v, err), closeErr)
+		}
+		c.setStarted(inst)
+		started = append(started, inst)
+	}
+	return nil
+}
+
+func (c *Container) setStarted(inst *instance) {
+	c.lock.Lock()
+	inst.started = true
+	c.lock.Unlock()
+}
+
+// Close all constructed instances that implement Closer, in the reverse order they were constructed.
+// All instances are closed even if some return an error, and errors are joined.
+func (c *Container) Close() error {
+	c.lock.Lock()
+	instances := c.instances
+	c.instances = nil
+	for _, p := range c.providers {
+		p.built = false
+		p.instance = nil
+	}
+	c.lock.Unlock()
+
+	return closeAll(instances)
+}
+
+func closeAll(instances []*instance) error {
+	var errs []error
+	for i := len(instances) - 1; i >= 0; i-- {
+		cl, ok := instances[i].v.(Closer)
+		if !ok {
+			continue
+		}
+		err := cl.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %T: %w", instances[i].v, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package di
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lifecycle struct {
+	name     string
+	events   *[]string
+	startErr error
+	closeErr error
+}
+
+func (l *lifecycle) Start(ctx context.Context) error {
+	*l.events = append(*l.events, "start "+l.name)
+	return l.startErr
+}
+
+func (l *lifecycle) Close() error {
+	*l.events = append(*l.events, "close "+l.name)
+	return l.closeErr
+}
+
+type (
+	db      struct{ *lifecycle }
+	cache   struct{ *lifecycle }
+	service struct {
+		*lifecycle
+		db    *db
+		cache *cache
+	}
+)
+
+// closeOnly implements Closer but not Starter.
+type closeOnly struct {
+	name   string
+	events *[]string
+}
+
+func (c *closeOnly) Close() error {
+	*c.events = append(*c.events, "close "+c.name)
+	return nil
+}
+
+func newTestContainer(t *testing.T, events *[]string) (*Container, *int) {
+	t.Helper()
+
+	var dbCalls int
+	c := New()
+	require.NoError(t, Provide(c, func(c *Container) (*db, error) {
+		dbCalls++
+		return &db{&lifecycle{name: "db", events: events}}, nil
+	}))
+	require.NoError(t, Provide(c, func(c *Container) (*cache, error) {
+		d, err := Resolve[*db](c)
+		if err != nil {
+			return nil, err
+		}
+		_ = d
+		return &cache{&lifecycle{name: "cache", events: events}}, nil
+	}))
+	require.NoError(t, Provide(c, func(c *Container) (*service, error) {
+		return &service{
+			lifecycle: &lifecycle{name: "service", events: events},
+			db:        MustResolve[*db](c),
+			cache:     MustResolve[*cache](c),
+		}, nil
+	}))
+	return c, &dbCalls
+}
+
+func TestContainer(t *testing.T) {
+	t.Run("lazy singletons", func(t *testing.T) {
+		var events []string
+		c, dbCalls := newTestContainer(t, &events)
+		assert.Equal(t, 0, *dbCalls)
+
+		svc, err := Resolve[*service](c)
+		require.NoError(t, err)
+		assert.Equal(t, 1, *dbCalls)
+
+		d := MustResolve[*db](c)
+		assert.Same(t, svc.db, d)
+		assert.Equal(t, 1, *dbCalls)
+	})
+
+	t.Run("lifecycle ordering", func(t *testing.T) {
+		var events []string
+		c, _ := newTestContainer(t, &events)
+		MustResolve[*service](c)
+
+		require.NoError(t, c.Start(context.Background()))
+		require.NoError(t, c.Close())
+		assert.Equal(t, []string{
+			"start db", "start cache", "start service",
+			"close service", "close cache", "close db",
+		}, events)
+	})
+
+	t.Run("failed start closes started components", func(t *testing.T) {
+		var events []string
+		c := New()
+		require.NoError(t, ProvideValue(c, &db{&lifecycle{name: "db", events: &events}}))
+		require.NoError(t, ProvideValue(c, &cache{&lifecycle{name: "cache", events: &events, startErr: errors.New("boom")}}))
+		MustResolve[*db](c)
+		MustResolve[*cache](c)
+
+		err := c.Start(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to start *di.cache: boom")
+		assert.Equal(t, []string{"start db", "start cache", "close db"}, events)
+
+		// Components that were rolled back are not closed again
+		require.NoError(t, c.Close())
+		assert.Equal(t, []string{"start db", "start cache", "close db", "close cache"}, events)
+	})
+
+	t.Run("failed start only rolls back components it started", func(t *testing.T) {
+		var events []string
+		c := New()
+		require.NoError(t, ProvideValue(c, &db{&lifecycle{name: "db", events: &events}}))
+		require.NoError(t, ProvideValue(c, &closeOnly{name: "plain", events: &events}))
+		require.NoError(t, ProvideValue(c, &cache{&lifecycle{name: "cache", events: &events, startErr: errors.New("boom")}}))
+		MustResolve[*db](c)
+		require.NoError(t, c.Start(context.Background()))
+
+		MustResolve[*closeOnly](c)
+		MustResolve[*cache](c)
+		require.Error(t, c.Start(context.Background()))
+		// db was started by the previous call, and plain doesn't implement Starter
+		assert.Equal(t, []string{"start db", "start cache"}, events)
+
+		require.NoError(t, c.Close())
+		assert.Equal(t, []string{"start db", "start cache", "close cache", "close plain", "close db"}, events)
+	})
+
+	t.Run("rolled back components are constructed again", func(t *testing.T) {
+		var (
+			events  []string
+			dbCalls int
+		)
+		c := New()
+		require.NoError(t, Provide(c, func(*Container) (*db, error) {
+			dbCalls++
+			return &db{&lifecycle{name: "db", events: &events}}, nil
+		}))
+		require.NoError(t, Provide(c, func(*Container) (*cache, error) {
+			return &cache{&lifecycle{name: "cache", events: &events, startErr: errors.New("boom")}}, nil
+		}))
+		first := MustResolve[*db](c)
+		MustResolve[*cache](c)
+		require.Error(t, c.Start(context.Background()))
+
+		second := MustResolve[*db](c)
+		assert.NotSame(t, first, second)
+		assert.Equal(t, 2, dbCalls)
+		// The component that failed to start is still cached; the new db is started after it
+		MustResolve[*cache](c).startErr = nil
+		require.NoError(t, c.Start(context.Background()))
+		assert.Equal(t, []string{"start db", "start cache", "close db", "start cache", "start db"}, events)
+	})
+
+	t.Run("values registered for multiple types are started and closed once", func(t *testing.T) {
+		var events []string
+		c := New()
+		l := &lifecycle{name: "shared", events: &events}
+		require.NoError(t, ProvideValue(c, l))
+		require.NoError(t, ProvideValue[Closer](c, l))
+		require.NoError(t, ProvideValue[Starter](c, l))
+		MustResolve[*lifecycle](c)
+		MustResolve[Closer](c)
+		MustResolve[Starter](c)
+
+		require.NoError(t, c.Start(context.Background()))
+		require.NoError(t, c.Close())
+		assert.Equal(t, []string{"start shared", "close shared"}, events)
+	})
+
+	t.Run("close errors are joined", func(t *testing.T) {
+		var events []string
+		c := New()
+		require.NoError(t, ProvideValue(c, &db{&lifecycle{name: "db", events: &events, closeErr: errors.New("db")}}))
+		require.NoError(t, ProvideValue(c, &cache{&lifecycle{name: "cache", events: &events, closeErr: errors.New("cache")}}))
+		MustResolve[*db](c)
+		MustResolve[*cache](c)
+
+		err := c.Close()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to close *di.db: db")
+		assert.Contains(t, err.Error(), "failed to close *di.cache: cache")
+		assert.Equal(t, []string{"close cache", "close db"}, events)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		c := New()
+		_, err := Resolve[*db](c)
+		require.ErrorIs(t, err, ErrNotProvided)
+		assert.Panics(t, func() {
+			MustResolve[*db](c)
+		})
+
+		require.NoError(t, ProvideValue(c, 1))
+		require.ErrorIs(t, ProvideValue(c, 2), ErrAlreadyProvided)
+
+		require.NoError(t, Provide(c, func(c *Container) (string, error) {
+			return "", errors.New("boom")
+		}))
+		_, err = Resolve[string](c)
+		require.EqualError(t, err, "failed to construct string: boom")
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		c := New()
+		require.NoError(t, Provide(c, func(c *Container) (*db, error) {
+			_, err := Resolve[*cache](c)
+			return &db{}, err
+		}))
+		require.NoError(t, Provide(c, func(c *Container) (*cache, error) {
+			_, err := Resolve[*db](c)
+			return &cache{}, err
+		}))
+
+		_, err := Resolve[*db](c)
+		require.ErrorIs(t, err, ErrCycle)
+		assert.Contains(t, err.Error(), "*di.db -> *di.cache -> *di.db")
+	})
+}
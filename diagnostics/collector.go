@@ -0,0 +1,294 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diagnostics collects diagnostic bundles, containing a full goroutine dump, a summary of the heap, the heap
+// profile, and the most recent log lines, that can be attached to bug reports.
+// Bundles can be collected on demand with an API call (see Collector.ServeHTTP), when the process receives a signal,
+// or when a goroutine panics.
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	kclock "k8s.io/utils/clock"
+
+	"github.com/dapr/kit/logger"
+)
+
+// Names of the files in tar bundles.
+const (
+	BundleFileSummary    = "summary.json"
+	BundleFileGoroutines = "goroutines.txt"
+	BundleFileHeap       = "heap.pprof"
+	BundleFileLogs       = "logs.txt"
+)
+
+// CollectorOptions contains the options for a Collector.
+type CollectorOptions struct {
+	// LogRing contains the recent log lines to include in bundles. Optional.
+	LogRing *LogRing
+	// Dir is the directory where bundles collected on signals and panics are written. Default is the temporary
+	// directory.
+	Dir string
+	// Log is used to report where bundles are written. Optional.
+	Log logger.Logger
+}
+
+// Collector collects diagnostic bundles.
+type Collector struct {
+	opts  CollectorOptions
+	clock kclock.PassiveClock
+}
+
+// Bundle contains the diagnostic information about the process at a point in time.
+type Bundle struct {
+	// Time when the bundle was collected.
+	Time time.Time `json:"time"`
+	// Reason the bundle was collected for, such as "api", "signal", or "panic".
+	Reason string `json:"reason"`
+	// Build contains information about the running binary.
+	Build logger.BuildInfo `json:"build"`
+	// NumGoroutine is the number of goroutines.
+	NumGoroutine int `json:"numGoroutine"`
+	// Heap is a summary of the heap.
+	Heap HeapSummary `json:"heap"`
+	// Panic is the value of the panic, if the bundle was collected because of a panic.
+	Panic string `json:"panic,omitempty"`
+	// PanicStack is the stack of the goroutine that panicked.
+	PanicStack string `json:"panicStack,omitempty"`
+	// Goroutines is the stack trace of all goroutines.
+	Goroutines string `json:"goroutines"`
+	// Logs are the most recent log lines.
+	Logs []string `json:"logs,omitempty"`
+
+	// Heap profile in the pprof format, included in tar bundles only.
+	heapProfile []byte
+}
+
+// HeapSummary contains the main memory statistics of the Go runtime.
+type HeapSummary struct {
+	HeapAlloc    uint64 `json:"heapAlloc"`
+	HeapInuse    uint64 `json:"heapInuse"`
+	HeapSys      uint64 `json:"heapSys"`
+	HeapObjects  uint64 `json:"heapObjects"`
+	TotalAlloc   uint64 `json:"totalAlloc"`
+	Sys          uint64 `json:"sys"`
+	NumGC        uint32 `json:"numGC"`
+	PauseTotalNs uint64 `json:"pauseTotalNs"`
+}
+
+// NewCollector returns a new Collector.
+func NewCollector(opts CollectorOptions) *Collector {
+	if opts.Dir == "" {
+		opts.Dir = os.TempDir()
+	}
+	return &Collector{
+		opts:  opts,
+		clock: kclock.RealClock{},
+	}
+}
+
+// WithClock sets the clock used by the collector. Used for testing.
+func (c *Collector) WithClock(clock kclock.PassiveClock) *Collector {
+	c.clock = clock
+	return c
+}
+
+// Collect collects a bundle with the current state of the process.
+func (c *Collector) Collect(reason string) *Bundle {
+	b := &Bundle{
+		Time:         c.clock.Now(),
+		Reason:       reason,
+		Build:        logger.GetBuildInfo(),
+		NumGoroutine: runtime.NumGoroutine(),
+		Goroutines:   goroutineDump(),
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	b.Heap = HeapSummary{
+		HeapAlloc:    ms.HeapAlloc,
+		HeapInuse:    ms.HeapInuse,
+		HeapSys:      ms.HeapSys,
+		HeapObjects:  ms.HeapObjects,
+		TotalAlloc:   ms.TotalAlloc,
+		Sys:          ms.Sys,
+		NumGC:        ms.NumGC,
+		PauseTotalNs: ms.PauseTotalNs,
+	}
+
+	if p := pprof.Lookup("heap"); p != nil {
+		var buf bytes.Buffer
+		if p.WriteTo(&buf, 0) == nil {
+			b.heapProfile = buf.Bytes()
+		}
+	}
+
+	if c.opts.LogRing != nil {
+		b.Logs = c.opts.LogRing.Lines()
+	}
+
+	return b
+}
+
+// WriteJSON writes the bundle as JSON. The heap profile is not included.
+func (b *Bundle) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(b)
+}
+
+// WriteTar writes the bundle as a tar archive, with the summary as JSON and the goroutine dump, heap profile and
+// logs in separate files.
+func (b *Bundle) WriteTar(w io.Writer) error {
+	summary := *b
+	summary.Goroutines = ""
+	summary.Logs = nil
+	summaryJSON, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{BundleFileSummary, summaryJSON},
+		{BundleFileGoroutines, []byte(b.Goroutines)},
+		{BundleFileHeap, b.heapProfile},
+		{BundleFileLogs, []byte(strings.Join(b.Logs, "\n"))},
+	}
+	for _, f := range files {
+		err = tw.WriteHeader(&tar.Header{
+			Name:    f.name,
+			Mode:    0o600,
+			Size:    int64(len(f.data)),
+			ModTime: b.Time,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write header for %s: %w", f.name, err)
+		}
+		_, err = tw.Write(f.data)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	return tw.Close()
+}
+
+// WriteFile writes the bundle as a tar archive in the collector's directory, and returns the path of the file.
+func (c *Collector) WriteFile(b *Bundle) (string, error) {
+	name := filepath.Join(c.opts.Dir, fmt.Sprintf("diagnostics-%s-%s.tar", b.Reason, b.Time.UTC().Format("20060102T150405.000000000Z")))
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create bundle file: %w", err)
+	}
+	err = b.WriteTar(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write bundle file: %w", err)
+	}
+
+	if c.opts.Log != nil {
+		c.opts.Log.Infof("Diagnostic bundle (%s) written to %s", b.Reason, name)
+	}
+	return name, nil
+}
+
+// ServeHTTP implements http.Handler and responds with a new bundle.
+// The bundle is returned as JSON, unless the "format" query string parameter is "tar".
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b := c.Collect("api")
+
+	if r.URL.Query().Get("format") == "tar" {
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Header().Set("Content-Disposition", `attachment; filename="diagnostics.tar"`)
+		_ = b.WriteTar(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = b.WriteJSON(w)
+}
+
+// WatchSignals writes a bundle to the collector's directory every time the process receives one of the signals,
+// until ctx is canceled. For example, on Unix systems SIGUSR1 is commonly used for this purpose.
+func (c *Collector) WatchSignals(ctx context.Context, sigs ...os.Signal) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, sigs...)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-sigCh:
+			_, err := c.WriteFile(c.Collect("signal"))
+			if err != nil && c.opts.Log != nil {
+				c.opts.Log.Errorf("Failed to write diagnostic bundle: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RecoverAndDump must be deferred at the beginning of a goroutine. If the goroutine panics, it writes a bundle,
+// including the panic and the stack of the goroutine, to the collector's directory, then it re-raises the panic.
+//
+//	go func() {
+//		defer collector.RecoverAndDump()
+//		// ...
+//	}()
+func (c *Collector) RecoverAndDump() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	c.dumpPanic(r, debug.Stack())
+	panic(r)
+}
+
+func (c *Collector) dumpPanic(r any, stack []byte) {
+	b := c.Collect("panic")
+	b.Panic = fmt.Sprint(r)
+	b.PanicStack = string(stack)
+
+	_, err := c.WriteFile(b)
+	if err != nil && c.opts.Log != nil {
+		c.opts.Log.Errorf("Failed to write diagnostic bundle: %v", err)
+	}
+}
+
+// goroutineDump returns the stack traces of all goroutines.
+func goroutineDump() string {
+	var buf bytes.Buffer
+	if p := pprof.Lookup("goroutine"); p != nil && p.WriteTo(&buf, 2) == nil {
+		return buf.String()
+	}
+	return ""
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/kit/logger"
+)
+
+func TestLogRing(t *testing.T) {
+	r := NewLogRing(3)
+	assert.Empty(t, r.Lines())
+
+	_, err := r.Write([]byte("one\ntwo\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, r.Lines())
+
+	// Partial lines are kept until completed
+	_, _ = r.Write([]byte("thr"))
+	assert.Equal(t, []string{"one", "two"}, r.Lines())
+	_, _ = r.Write([]byte("ee\nfour\n"))
+	assert.Equal(t, []string{"two", "three", "four"}, r.Lines())
+}
+
+func TestLogRingSink(t *testing.T) {
+	ring := NewLogRing(10)
+	log := logger.NewLogger("test.diagnostics")
+	require.NoError(t, logger.SetSinks(log, logger.SinksOptions{}, logger.Sink{Name: "ring", Writer: ring}))
+	defer logger.SetSinks(log, logger.SinksOptions{})
+
+	log.Info("hello diagnostics")
+	lines := ring.Lines()
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "hello diagnostics")
+}
+
+func newTestCollector(t *testing.T) *Collector {
+	t.Helper()
+
+	ring := NewLogRing(10)
+	_, _ = ring.Write([]byte("log line\n"))
+	clock := clocktesting.NewFakePassiveClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	return NewCollector(CollectorOptions{
+		LogRing: ring,
+		Dir:     t.TempDir(),
+	}).WithClock(clock)
+}
+
+func TestCollect(t *testing.T) {
+	c := newTestCollector(t)
+	b := c.Collect("test")
+
+	assert.Equal(t, "test", b.Reason)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), b.Time)
+	assert.Positive(t, b.NumGoroutine)
+	assert.Contains(t, b.Goroutines, "TestCollect")
+	assert.Positive(t, b.Heap.HeapAlloc)
+	assert.NotEmpty(t, b.heapProfile)
+	assert.Equal(t, []string{"log line"}, b.Logs)
+}
+
+func readTar(t *testing.T, r io.Reader) map[string][]byte {
+	t.Helper()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[h.Name] = data
+	}
+	return files
+}
+
+func TestServeHTTP(t *testing.T) {
+	c := newTestCollector(t)
+
+	t.Run("JSON", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/diagnostics", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var b Bundle
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &b))
+		assert.Equal(t, "api", b.Reason)
+		assert.NotEmpty(t, b.Goroutines)
+		assert.Equal(t, []string{"log line"}, b.Logs)
+	})
+
+	t.Run("tar", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/diagnostics?format=tar", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-tar", rec.Header().Get("Content-Type"))
+
+		files := readTar(t, rec.Body)
+		require.Contains(t, files, BundleFileSummary)
+		assert.Contains(t, string(files[BundleFileGoroutines]), "goroutine")
+		assert.NotEmpty(t, files[BundleFileHeap])
+		assert.Equal(t, "log line", string(files[BundleFileLogs]))
+
+		var summary Bundle
+		require.NoError(t, json.Unmarshal(files[BundleFileSummary], &summary))
+		assert.Equal(t, "api", summary.Reason)
+		assert.Empty(t, summary.Goroutines)
+	})
+}
+
+func TestDumpPanic(t *testing.T) {
+	c := newTestCollector(t)
+	c.dumpPanic("boom", []byte("stack of the panic"))
+
+	entries, err := os.ReadDir(c.opts.Dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "diagnostics-panic-20230101T000000.000000000Z.tar", entries[0].Name())
+
+	f, err := os.Open(filepath.Join(c.opts.Dir, entries[0].Name()))
+	require.NoError(t, err)
+	defer f.Close()
+
+	files := readTar(t, f)
+	var summary Bundle
+	require.NoError(t, json.Unmarshal(files[BundleFileSummary], &summary))
+	assert.Equal(t, "panic", summary.Reason)
+	assert.Equal(t, "boom", summary.Panic)
+	assert.Equal(t, "stack of the panic", summary.PanicStack)
+}
+
+func TestRecoverAndDump(t *testing.T) {
+	c := newTestCollector(t)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		defer c.RecoverAndDump()
+		panic("boom")
+	})
+
+	entries, err := os.ReadDir(c.opts.Dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// No bundle without panics
+	func() {
+		defer c.RecoverAndDump()
+	}()
+	entries, err = os.ReadDir(c.opts.Dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
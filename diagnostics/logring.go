@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"bytes"
+	"sync"
+)
+
+// DefaultLogRingSize is the default number of lines kept by a LogRing.
+const DefaultLogRingSize = 1000
+
+// LogRing is an io.Writer that keeps the most recent log lines in memory, so they can be included in diagnostic
+// bundles. It can be added to a logger as a sink with logger.SetSinks.
+type LogRing struct {
+	lock    sync.Mutex
+	lines   []string
+	next    int
+	full    bool
+	partial []byte
+}
+
+// NewLogRing returns a LogRing that keeps the last size lines.
+// If size is not positive, DefaultLogRingSize is used.
+func NewLogRing(size int) *LogRing {
+	if size <= 0 {
+		size = DefaultLogRingSize
+	}
+	return &LogRing{
+		lines: make([]string, size),
+	}
+}
+
+// Write implements io.Writer. Data is split in lines; an incomplete last line is kept until it's completed.
+func (r *LogRing) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			r.partial = append(r.partial, data...)
+			break
+		}
+		line := data[:i]
+		if len(r.partial) > 0 {
+			line = append(r.partial, line...)
+			r.partial = nil
+		}
+		r.add(string(line))
+		data = data[i+1:]
+	}
+	return len(p), nil
+}
+
+// Lines returns the lines in the ring, from the oldest to the most recent.
+func (r *LogRing) Lines() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	res := make([]string, 0, len(r.lines))
+	res = append(res, r.lines[r.next:]...)
+	res = append(res, r.lines[:r.next]...)
+	return res
+}
+
+// add appends a line to the ring.
+// This must be invoked while the caller has a lock.
+func (r *LogRing) add(line string) {
+	r.lines[r.next] = line
+	r.next++
+	if r.next == len(r.lines) {
+		r.next = 0
+		r.full = true
+	}
+}
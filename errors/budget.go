@@ -0,0 +1,225 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"sync"
+	"time"
+
+	kclock "k8s.io/utils/clock"
+)
+
+const budgetBuckets = 10
+
+// BudgetMonitorOptions contains the options for a BudgetMonitor.
+type BudgetMonitorOptions struct {
+	// Window over which error rates are computed. Default is 1 minute.
+	Window time.Duration
+	// Budgets contains the maximum number of errors allowed in the window, for each tag.
+	Budgets map[string]int
+	// DefaultBudget is the budget for tags that are not in Budgets. If 0, those tags are not monitored.
+	DefaultBudget int
+	// TagFn returns the tag of an error. Default is the error's reason.
+	TagFn func(e *Error) string
+	// OnExceeded is invoked when the number of errors for a tag in the window exceeds its budget.
+	// It's invoked once each time the budget is exceeded, and again only after the error count has dropped back
+	// within budget.
+	OnExceeded func(tag string, count int, budget int)
+	// OnRecovered is invoked when the number of errors for a tag whose budget was exceeded drops back within budget.
+	// Recoveries are detected when errors are observed or when Check is invoked. Optional.
+	OnRecovered func(tag string, count int, budget int)
+}
+
+// BudgetMonitor computes rolling error counts by tag and fires callbacks when the error budgets are exceeded,
+// providing SLO-style signals without an external metrics stack.
+// It implements Observer, so it can be registered with AddObserver.
+type BudgetMonitor struct {
+	opts     BudgetMonitorOptions
+	clock    kclock.PassiveClock
+	lock     sync.Mutex
+	counters map[string]*rollingCounter
+	exceeded map[string]bool
+}
+
+// NewBudgetMonitor returns a new BudgetMonitor.
+func NewBudgetMonitor(opts BudgetMonitorOptions) *BudgetMonitor {
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.TagFn == nil {
+		opts.TagFn = (*Error).Reason
+	}
+	return &BudgetMonitor{
+		opts:     opts,
+		clock:    kclock.RealClock{},
+		counters: map[string]*rollingCounter{},
+		exceeded: map[string]bool{},
+	}
+}
+
+// WithClock sets the clock used by the monitor. Used for testing.
+func (m *BudgetMonitor) WithClock(clock kclock.PassiveClock) *BudgetMonitor {
+	m.clock = clock
+	return m
+}
+
+// ObserveError implements Observer.
+func (m *BudgetMonitor) ObserveError(e *Error) {
+	tag := m.opts.TagFn(e)
+	budget, ok := m.budget(tag)
+	if !ok {
+		return
+	}
+
+	now := m.clock.Now()
+	m.lock.Lock()
+	c, ok := m.counters[tag]
+	if !ok {
+		c = newRollingCounter(m.opts.Window, now)
+		m.counters[tag] = c
+	}
+	c.add(now)
+	count := c.count(now)
+	fire := count > budget && !m.exceeded[tag]
+	recovered := count <= budget && m.exceeded[tag]
+	if fire {
+		m.exceeded[tag] = true
+	} else if recovered {
+		delete(m.exceeded, tag)
+	}
+	m.lock.Unlock()
+
+	if fire && m.opts.OnExceeded != nil {
+		m.opts.OnExceeded(tag, count, budget)
+	}
+	if recovered && m.opts.OnRecovered != nil {
+		m.opts.OnRecovered(tag, count, budget)
+	}
+}
+
+// Check re-evaluates all tags whose budget is exceeded, invoking OnRecovered for those that are back within budget.
+// Because counts only change when errors are observed, this should be invoked periodically to detect recoveries.
+func (m *BudgetMonitor) Check() {
+	now := m.clock.Now()
+
+	type recovery struct {
+		tag    string
+		count  int
+		budget int
+	}
+	var recovered []recovery
+
+	m.lock.Lock()
+	for tag := range m.exceeded {
+		budget, _ := m.budget(tag)
+		count := m.counters[tag].count(now)
+		if count <= budget {
+			delete(m.exceeded, tag)
+			recovered = append(recovered, recovery{tag: tag, count: count, budget: budget})
+		}
+	}
+	m.lock.Unlock()
+
+	if m.opts.OnRecovered != nil {
+		for _, r := range recovered {
+			m.opts.OnRecovered(r.tag, r.count, r.budget)
+		}
+	}
+}
+
+// Count returns the number of errors with the given tag in the current window.
+func (m *BudgetMonitor) Count(tag string) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	c, ok := m.counters[tag]
+	if !ok {
+		return 0
+	}
+	return c.count(m.clock.Now())
+}
+
+// Rate returns the rate of errors with the given tag in the current window, in errors per second.
+func (m *BudgetMonitor) Rate(tag string) float64 {
+	return float64(m.Count(tag)) / m.opts.Window.Seconds()
+}
+
+// Exceeded returns true if the budget for the tag is currently exceeded.
+func (m *BudgetMonitor) Exceeded(tag string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.exceeded[tag]
+}
+
+func (m *BudgetMonitor) budget(tag string) (int, bool) {
+	if b, ok := m.opts.Budgets[tag]; ok {
+		return b, true
+	}
+	if m.opts.DefaultBudget > 0 {
+		return m.opts.DefaultBudget, true
+	}
+	return 0, false
+}
+
+// rollingCounter counts events in a sliding window, split in buckets.
+type rollingCounter struct {
+	bucketSize time.Duration
+	buckets    [budgetBuckets]int
+	// Index of the bucket for the latest update, as number of buckets since the epoch
+	last int64
+}
+
+func newRollingCounter(window time.Duration, now time.Time) *rollingCounter {
+	c := &rollingCounter{
+		bucketSize: window / budgetBuckets,
+	}
+	if c.bucketSize <= 0 {
+		c.bucketSize = 1
+	}
+	c.last = c.bucketIndex(now)
+	return c
+}
+
+func (c *rollingCounter) bucketIndex(now time.Time) int64 {
+	return now.UnixNano() / int64(c.bucketSize)
+}
+
+// advance clears the buckets that are out of the window.
+func (c *rollingCounter) advance(now time.Time) {
+	idx := c.bucketIndex(now)
+	if idx <= c.last {
+		return
+	}
+	if idx-c.last >= budgetBuckets {
+		c.buckets = [budgetBuckets]int{}
+	} else {
+		for i := c.last + 1; i <= idx; i++ {
+			c.buckets[i%budgetBuckets] = 0
+		}
+	}
+	c.last = idx
+}
+
+func (c *rollingCounter) add(now time.Time) {
+	c.advance(now)
+	c.buckets[c.last%budgetBuckets]++
+}
+
+func (c *rollingCounter) count(now time.Time) int {
+	c.advance(now)
+	var n int
+	for _, b := range c.buckets {
+		n += b
+	}
+	return n
+}
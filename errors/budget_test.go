@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestBudgetMonitor(t *testing.T) {
+	clock := clocktesting.NewFakePassiveClock(time.Now())
+
+	type event struct {
+		tag      string
+		count    int
+		exceeded bool
+	}
+	var events []event
+	m := NewBudgetMonitor(BudgetMonitorOptions{
+		Window:  10 * time.Second,
+		Budgets: map[string]int{"STATE_FAILURE": 2},
+		OnExceeded: func(tag string, count int, budget int) {
+			events = append(events, event{tag, count, true})
+		},
+		OnRecovered: func(tag string, count int, budget int) {
+			events = append(events, event{tag, count, false})
+		},
+	}).WithClock(clock)
+	remove := AddObserver(m)
+	defer remove()
+
+	newStateErr := func() {
+		New(fmt.Errorf("state error"), nil, WithErrorReason("STATE_FAILURE", codes.Internal)).Emit()
+	}
+
+	newStateErr()
+	newStateErr()
+	// Tags without a budget are not monitored
+	New(fmt.Errorf("other"), nil, WithErrorReason("OTHER", codes.Internal)).Emit()
+	assert.Equal(t, 2, m.Count("STATE_FAILURE"))
+	assert.Equal(t, 0, m.Count("OTHER"))
+	assert.Empty(t, events)
+
+	// Exceeding the budget fires the callback once
+	newStateErr()
+	newStateErr()
+	assert.True(t, m.Exceeded("STATE_FAILURE"))
+	assert.Equal(t, []event{{"STATE_FAILURE", 3, true}}, events)
+	assert.InDelta(t, 0.4, m.Rate("STATE_FAILURE"), 0.001)
+
+	// Errors age out of the window
+	clock.SetTime(clock.Now().Add(5 * time.Second))
+	assert.Equal(t, 4, m.Count("STATE_FAILURE"))
+	clock.SetTime(clock.Now().Add(6 * time.Second))
+	assert.Equal(t, 0, m.Count("STATE_FAILURE"))
+
+	m.Check()
+	assert.False(t, m.Exceeded("STATE_FAILURE"))
+	assert.Equal(t, []event{{"STATE_FAILURE", 3, true}, {"STATE_FAILURE", 0, false}}, events)
+
+	// Budget can be exceeded again
+	newStateErr()
+	newStateErr()
+	newStateErr()
+	assert.Len(t, events, 3)
+}
+
+func TestBudgetMonitorDefaultBudget(t *testing.T) {
+	var exceeded []string
+	m := NewBudgetMonitor(BudgetMonitorOptions{
+		DefaultBudget: 1,
+		TagFn: func(e *Error) string {
+			return e.Description()
+		},
+		OnExceeded: func(tag string, count int, budget int) {
+			exceeded = append(exceeded, tag)
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		m.ObserveError(New(fmt.Errorf("a"), nil))
+	}
+	m.ObserveError(New(fmt.Errorf("b"), nil))
+	assert.Equal(t, []string{"a"}, exceeded)
+}
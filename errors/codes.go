@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dapr/kit/grpccodes"
+)
+
+// grpcStatusError is implemented by errors that carry a gRPC status, such as those returned by gRPC clients.
+type grpcStatusError interface {
+	GRPCStatus() *status.Status
+}
+
+// httpCodeError is implemented by errors that carry a HTTP status code.
+type httpCodeError interface {
+	HTTPCode() int
+}
+
+// CodeOf returns the gRPC status code and the HTTP status code for err.
+// It supports kit errors, errors returned by gRPC (or any error that has a GRPCStatus method), and errors with a
+// HTTPCode method, including when they are wrapped. When only one of the two codes is known, the other one is derived
+// from it.
+//
+// If err is nil, it returns codes.OK and http.StatusOK. Context cancellation and deadline errors are mapped to
+// codes.Canceled and codes.DeadlineExceeded. For all other errors, it returns codes.Unknown and
+// http.StatusInternalServerError.
+func CodeOf(err error) (codes.Code, int) {
+	if err == nil {
+		return codes.OK, http.StatusOK
+	}
+
+	var kitErr *Error
+	if errors.As(err, &kitErr) && kitErr != nil {
+		return kitErr.grpcStatusCode, kitErr.httpCode
+	}
+
+	var grpcErr grpcStatusError
+	if errors.As(err, &grpcErr) {
+		if st := grpcErr.GRPCStatus(); st != nil {
+			return st.Code(), grpccodes.HTTPStatusFromCode(st.Code())
+		}
+	}
+
+	var httpErr httpCodeError
+	if errors.As(err, &httpErr) {
+		httpCode := httpErr.HTTPCode()
+		return grpccodes.CodeFromHTTPStatus(httpCode), httpCode
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled, grpccodes.HTTPStatusFromCode(codes.Canceled)
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded, grpccodes.HTTPStatusFromCode(codes.DeadlineExceeded)
+	}
+
+	return codes.Unknown, http.StatusInternalServerError
+}
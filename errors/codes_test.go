@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testHTTPError struct {
+	code int
+}
+
+func (e testHTTPError) Error() string {
+	return http.StatusText(e.code)
+}
+
+func (e testHTTPError) HTTPCode() int {
+	return e.code
+}
+
+func TestCodeOf(t *testing.T) {
+	kitErr := New(errors.New("not found"), nil, WithErrorReason("NotFound", codes.NotFound))
+
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode codes.Code
+		expectedHTTP int
+	}{
+		{
+			name:         "nil",
+			err:          nil,
+			expectedCode: codes.OK,
+			expectedHTTP: http.StatusOK,
+		},
+		{
+			name:         "kit error",
+			err:          kitErr,
+			expectedCode: codes.NotFound,
+			expectedHTTP: http.StatusNotFound,
+		},
+		{
+			name:         "wrapped kit error",
+			err:          fmt.Errorf("failed to get state: %w", kitErr),
+			expectedCode: codes.NotFound,
+			expectedHTTP: http.StatusNotFound,
+		},
+		{
+			name:         "grpc error",
+			err:          status.Error(codes.PermissionDenied, "denied"),
+			expectedCode: codes.PermissionDenied,
+			expectedHTTP: http.StatusForbidden,
+		},
+		{
+			name:         "wrapped grpc error",
+			err:          fmt.Errorf("call failed: %w", status.Error(codes.ResourceExhausted, "slow down")),
+			expectedCode: codes.ResourceExhausted,
+			expectedHTTP: http.StatusTooManyRequests,
+		},
+		{
+			name:         "http error",
+			err:          fmt.Errorf("call failed: %w", testHTTPError{code: http.StatusServiceUnavailable}),
+			expectedCode: codes.Unavailable,
+			expectedHTTP: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "context canceled",
+			err:          fmt.Errorf("aborted: %w", context.Canceled),
+			expectedCode: codes.Canceled,
+			expectedHTTP: http.StatusRequestTimeout,
+		},
+		{
+			name:         "context deadline exceeded",
+			err:          context.DeadlineExceeded,
+			expectedCode: codes.DeadlineExceeded,
+			expectedHTTP: http.StatusGatewayTimeout,
+		},
+		{
+			name:         "other error",
+			err:          errors.New("boom"),
+			expectedCode: codes.Unknown,
+			expectedHTTP: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, httpCode := CodeOf(test.err)
+			assert.Equal(t, test.expectedCode, code)
+			assert.Equal(t, test.expectedHTTP, httpCode)
+		})
+	}
+}
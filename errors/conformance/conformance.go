@@ -0,0 +1,250 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conformance contains a test suite that verifies that error parsers, such as the ones in the Dapr SDKs,
+// interpret the errors created with the kit errors package the same way kit emits them, over both gRPC and HTTP.
+//
+// SDKs implement a Parser that converts the wire representation of an error to a Parsed value, then run the suite in a
+// test:
+//
+//	func TestErrorsConformance(t *testing.T) {
+//		conformance.Run(t, func(t testing.TB) conformance.Parser {
+//			return myParser{}
+//		})
+//	}
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	kiterrors "github.com/dapr/kit/errors"
+	"github.com/dapr/kit/grpccodes"
+)
+
+// Parser converts the wire representation of errors to Parsed values.
+type Parser interface {
+	// ParseGRPC parses the status of a failed gRPC call and the trailer metadata of the call.
+	ParseGRPC(st *status.Status, trailer metadata.MD) (Parsed, error)
+	// ParseHTTP parses the status code, headers, and body of a failed HTTP response.
+	ParseHTTP(statusCode int, header http.Header, body []byte) (Parsed, error)
+}
+
+// Factory returns the Parser to test.
+type Factory func(t testing.TB) Parser
+
+// Parsed is the normalized representation of an error.
+// Fields that are not present in the error have their zero value; empty maps and slices must be nil.
+type Parsed struct {
+	// GRPCCode is the gRPC status code.
+	GRPCCode codes.Code
+	// HTTPCode is the HTTP status code. It's 0 for errors parsed from gRPC.
+	HTTPCode int
+	// Message is the message of the status.
+	Message string
+	// Reason, Domain and Metadata are the contents of the ErrorInfo detail.
+	Reason   string
+	Domain   string
+	Metadata map[string]string
+	// Resource is the content of the ResourceInfo detail.
+	Resource *Resource
+	// RetryDelay is the delay in the RetryInfo detail, or nil if there's no RetryInfo detail.
+	RetryDelay *time.Duration
+	// FieldViolations are the violations in the BadRequest detail.
+	FieldViolations []FieldViolation
+	// RequestID is the ID in the RequestInfo detail, which is only included in HTTP responses.
+	RequestID string
+	// Tag is the tag of the error, propagated through the headers (HTTP) or the trailer (gRPC).
+	Tag string
+}
+
+// Resource is the content of a ResourceInfo detail.
+type Resource struct {
+	Type        string
+	Name        string
+	Owner       string
+	Description string
+}
+
+// FieldViolation is a field violation of a BadRequest detail.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// Case is a test case of the suite.
+type Case struct {
+	// Name of the test case.
+	Name string
+	// Err is the error emitted by kit.
+	Err *kiterrors.Error
+	// TraceID is the trace ID of the context of the HTTP response, included in a RequestInfo detail.
+	TraceID string
+	// Expected is the result a parser must return for the HTTP response. For gRPC, HTTPCode and RequestID are 0.
+	Expected Parsed
+}
+
+// allCodes are the gRPC codes of failed calls.
+var allCodes = []codes.Code{
+	codes.Canceled, codes.Unknown, codes.InvalidArgument, codes.DeadlineExceeded, codes.NotFound, codes.AlreadyExists,
+	codes.PermissionDenied, codes.ResourceExhausted, codes.FailedPrecondition, codes.Aborted, codes.OutOfRange,
+	codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unauthenticated,
+}
+
+const domain = "dapr.io"
+
+// Cases generates the test cases of the suite: the matrix of all gRPC codes, and of every detail type emitted by kit,
+// individually and combined.
+func Cases() []Case {
+	var res []Case
+
+	// Code mappings
+	for _, code := range allCodes {
+		reason := "CONFORMANCE_" + code.String()
+		res = append(res, Case{
+			Name: "code " + code.String(),
+			Err: kiterrors.New(fmt.Errorf("error with code %s", code), nil,
+				kiterrors.WithErrorReason(reason, code),
+				kiterrors.WithDescription("description of "+code.String()),
+			),
+			Expected: Parsed{
+				GRPCCode: code,
+				HTTPCode: grpccodes.HTTPStatusFromCode(code),
+				Message:  "description of " + code.String(),
+				Reason:   reason,
+				Domain:   domain,
+			},
+		})
+	}
+
+	// Details
+	base := func(name string, opts ...kiterrors.Option) (*kiterrors.Error, Parsed) {
+		opts = append([]kiterrors.Option{
+			kiterrors.WithErrorReason("CONFORMANCE_DETAILS", codes.FailedPrecondition),
+			kiterrors.WithDescription(name),
+		}, opts...)
+		return kiterrors.New(fmt.Errorf("error with %s", name), nil, opts...), Parsed{
+			GRPCCode: codes.FailedPrecondition,
+			HTTPCode: http.StatusBadRequest,
+			Message:  name,
+			Reason:   "CONFORMANCE_DETAILS",
+			Domain:   domain,
+		}
+	}
+
+	md := map[string]string{"key": "value", "other": "with spaces and ünicode"}
+	resource := &kiterrors.ResourceInfo{Type: "state", Name: "statestore", Owner: "conformance"}
+	retryDelay := 1500 * time.Millisecond
+	violations := []*errdetails.BadRequest_FieldViolation{
+		{Field: "key", Description: "must not be empty"},
+		{Field: "items[1].value", Description: "too long"},
+	}
+
+	err, exp := base("metadata", kiterrors.WithMetadata(md))
+	exp.Metadata = md
+	res = append(res, Case{Name: "error info metadata", Err: err, Expected: exp})
+
+	err, exp = base("resource info", kiterrors.WithResourceInfo(resource))
+	exp.Resource = &Resource{Type: "state", Name: "statestore", Owner: "conformance", Description: "error with resource info"}
+	res = append(res, Case{Name: "resource info", Err: err, Expected: exp})
+
+	err, exp = base("default resource owner", kiterrors.WithResourceInfo(&kiterrors.ResourceInfo{Type: "pubsub", Name: "broker"}))
+	exp.Resource = &Resource{Type: "pubsub", Name: "broker", Owner: "dapr-components", Description: "error with default resource owner"}
+	res = append(res, Case{Name: "resource info default owner", Err: err, Expected: exp})
+
+	err, exp = base("retry info", kiterrors.WithRetryInfo(retryDelay))
+	exp.RetryDelay = &retryDelay
+	res = append(res, Case{Name: "retry info", Err: err, Expected: exp})
+
+	err, exp = base("field violations", kiterrors.WithFieldViolations(violations...))
+	exp.FieldViolations = []FieldViolation{
+		{Field: "key", Description: "must not be empty"},
+		{Field: "items[1].value", Description: "too long"},
+	}
+	res = append(res, Case{Name: "bad request", Err: err, Expected: exp})
+
+	err, exp = base("request info")
+	exp.RequestID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	res = append(res, Case{Name: "request info", Err: err, TraceID: exp.RequestID, Expected: exp})
+
+	err, exp = base("tag", kiterrors.WithTag("component/statestore"))
+	exp.Tag = "component/statestore"
+	res = append(res, Case{Name: "tag", Err: err, Expected: exp})
+
+	err, exp = base("all details",
+		kiterrors.WithMetadata(md),
+		kiterrors.WithResourceInfo(resource),
+		kiterrors.WithRetryInfo(retryDelay),
+		kiterrors.WithFieldViolations(violations...),
+		kiterrors.WithTag("all"),
+	)
+	exp.Metadata = md
+	exp.Resource = &Resource{Type: "state", Name: "statestore", Owner: "conformance", Description: "error with all details"}
+	exp.RetryDelay = &retryDelay
+	exp.FieldViolations = []FieldViolation{
+		{Field: "key", Description: "must not be empty"},
+		{Field: "items[1].value", Description: "too long"},
+	}
+	exp.RequestID = "00f067aa0ba902b7"
+	exp.Tag = "all"
+	res = append(res, Case{Name: "all details", Err: err, TraceID: exp.RequestID, Expected: exp})
+
+	return res
+}
+
+// Run runs the conformance suite against the Parser returned by factory.
+// Every case is encoded as kit does, over gRPC (status and trailer metadata) and HTTP (status code, headers, and
+// JSON body), then parsed and compared with the expected result.
+func Run(t *testing.T, factory Factory) {
+	for _, c := range Cases() {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			t.Run("gRPC", func(t *testing.T) {
+				parser := factory(t)
+				parsed, err := parser.ParseGRPC(c.Err.GRPCStatus(), c.Err.GRPCMetadata())
+				require.NoError(t, err)
+
+				expected := c.Expected
+				expected.HTTPCode = 0
+				expected.RequestID = ""
+				assert.Equal(t, expected, parsed)
+			})
+
+			t.Run("HTTP", func(t *testing.T) {
+				parser := factory(t)
+
+				ctx := context.Background()
+				if c.TraceID != "" {
+					ctx = kiterrors.ContextWithTraceID(ctx, c.TraceID)
+				}
+				header := http.Header{}
+				c.Err.SetHTTPHeaders(header)
+				header.Set("Content-Type", "application/json")
+
+				parsed, err := parser.ParseHTTP(c.Err.HTTPCode(), header, c.Err.JSONErrorValueCtx(ctx))
+				require.NoError(t, err)
+				assert.Equal(t, c.Expected, parsed)
+			})
+		})
+	}
+}
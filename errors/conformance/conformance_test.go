@@ -0,0 +1,37 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferenceParser(t *testing.T) {
+	Run(t, func(testing.TB) Parser {
+		return ReferenceParser{}
+	})
+}
+
+func TestCases(t *testing.T) {
+	names := map[string]struct{}{}
+	for _, c := range Cases() {
+		_, dup := names[c.Name]
+		assert.False(t, dup, "duplicate case name %s", c.Name)
+		names[c.Name] = struct{}{}
+		assert.NotNil(t, c.Err)
+	}
+	assert.GreaterOrEqual(t, len(names), len(allCodes))
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"fmt"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+// ReferenceParser is the reference implementation of Parser, for Go clients.
+type ReferenceParser struct{}
+
+// ParseGRPC implements Parser.
+func (ReferenceParser) ParseGRPC(st *status.Status, trailer metadata.MD) (Parsed, error) {
+	p := parseStatus(st)
+	if s, ok := kiterrors.SummaryFromGRPCMetadata(trailer); ok {
+		p.Tag = s.Tag
+	}
+	return p, nil
+}
+
+// ParseHTTP implements Parser.
+func (ReferenceParser) ParseHTTP(statusCode int, header http.Header, body []byte) (Parsed, error) {
+	var pb spb.Status
+	// Ignore fields that are not part of the status, such as the ones added by SetJSONIncludeCodes
+	err := protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, &pb)
+	if err != nil {
+		return Parsed{}, fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	p := parseStatus(status.FromProto(&pb))
+	p.HTTPCode = statusCode
+	if s, ok := kiterrors.SummaryFromHTTPHeaders(header); ok {
+		p.Tag = s.Tag
+	}
+	return p, nil
+}
+
+func parseStatus(st *status.Status) Parsed {
+	p := Parsed{
+		GRPCCode: st.Code(),
+		Message:  st.Message(),
+	}
+
+	for _, d := range st.Details() {
+		switch v := d.(type) {
+		case *errdetails.ErrorInfo:
+			p.Reason = v.GetReason()
+			p.Domain = v.GetDomain()
+			if len(v.GetMetadata()) > 0 {
+				p.Metadata = v.GetMetadata()
+			}
+		case *errdetails.ResourceInfo:
+			p.Resource = &Resource{
+				Type:        v.GetResourceType(),
+				Name:        v.GetResourceName(),
+				Owner:       v.GetOwner(),
+				Description: v.GetDescription(),
+			}
+		case *errdetails.RetryInfo:
+			d := v.GetRetryDelay().AsDuration()
+			p.RetryDelay = &d
+		case *errdetails.BadRequest:
+			for _, fv := range v.GetFieldViolations() {
+				p.FieldViolations = append(p.FieldViolations, FieldViolation{
+					Field:       fv.GetField(),
+					Description: fv.GetDescription(),
+				})
+			}
+		case *errdetails.RequestInfo:
+			p.RequestID = v.GetRequestId()
+		}
+	}
+
+	return p
+}
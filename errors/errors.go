@@ -14,14 +14,18 @@ limitations under the License.
 package errors
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/dapr/kit/grpccodes"
 )
@@ -54,6 +58,8 @@ type Option func(*Error)
 //   - error reason
 //   - metadata information
 //   - optional resourceInfo (componenttype/name)
+//   - optional additional details (such as RetryInfo)
+//   - optional tag, propagated through headers
 type Error struct {
 	err            error
 	description    string
@@ -62,6 +68,8 @@ type Error struct {
 	grpcStatusCode codes.Code
 	metadata       map[string]string
 	resourceInfo   *ResourceInfo
+	details        []protoiface.MessageV1
+	tag            string
 }
 
 // New create a new Error using the supplied metadata and Options
@@ -114,6 +122,14 @@ func (e *Error) Description() string {
 	return e.err.Error()
 }
 
+// Reason returns the reason of the error.
+func (e *Error) Reason() string {
+	if e == nil {
+		return ""
+	}
+	return e.reason
+}
+
 // WithErrorReason used to pass reason and
 // grpcStatus code to the Error struct.
 func WithErrorReason(reason string, grpcStatusCode codes.Code) Option {
@@ -147,6 +163,16 @@ func WithMetadata(md map[string]string) Option {
 	}
 }
 
+// WithRetryInfo used to add a RetryInfo detail to the Error struct,
+// indicating how long clients should wait before retrying.
+func WithRetryInfo(retryDelay time.Duration) Option {
+	return func(e *Error) {
+		e.details = append(e.details, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(retryDelay),
+		})
+	}
+}
+
 func newErrorInfo(reason string, md map[string]string) *errdetails.ErrorInfo {
 	return &errdetails.ErrorInfo{
 		Domain:   errorInfoDefaultDomain,
@@ -172,13 +198,20 @@ func newResourceInfo(rid *ResourceInfo, err error) *errdetails.ResourceInfo {
 
 // GRPCStatus returns the gRPC status.Status object.
 func (e *Error) GRPCStatus() *status.Status {
-	var stErr error
-	ste := status.New(e.grpcStatusCode, e.description)
+	return e.grpcStatus()
+}
+
+// grpcStatus returns the gRPC status.Status object, including the extra details.
+func (e *Error) grpcStatus(extra ...protoiface.MessageV1) *status.Status {
+	details := make([]protoiface.MessageV1, 0, 2+len(e.details)+len(extra))
+	details = append(details, newErrorInfo(e.reason, e.metadata))
 	if e.resourceInfo != nil {
-		ste, stErr = ste.WithDetails(newErrorInfo(e.reason, e.metadata), newResourceInfo(e.resourceInfo, e.err))
-	} else {
-		ste, stErr = ste.WithDetails(newErrorInfo(e.reason, e.metadata))
+		details = append(details, newResourceInfo(e.resourceInfo, e.err))
 	}
+	details = append(details, e.details...)
+	details = append(details, extra...)
+
+	ste, stErr := status.New(e.grpcStatusCode, e.description).WithDetails(details...)
 	if stErr != nil {
 		return status.New(codes.Internal, fmt.Sprintf("failed to create gRPC status message: %v", stErr))
 	}
@@ -199,7 +232,7 @@ func (e *Error) ToHTTP() (int, []byte) {
 		return http.StatusInternalServerError, errJSON
 	}
 
-	return e.httpCode, resp
+	return e.httpCode, e.withJSONCodes(resp)
 }
 
 // HTTPCode returns the value of the HTTPCode property.
@@ -213,7 +246,24 @@ func (e *Error) HTTPCode() int {
 
 // JSONErrorValue implements the errorResponseValue interface (used by `github.com/dapr/dapr/pkg/http`).
 func (e *Error) JSONErrorValue() []byte {
-	b, err := protojson.Marshal(e.GRPCStatus().Proto())
+	return e.withJSONCodes(marshalJSONStatus(e.GRPCStatus()))
+}
+
+// JSONErrorValueCtx is a variant of JSONErrorValue that includes the trace ID of the current request, obtained
+// from ctx, in a RequestInfo detail. This allows users to look up the trace from the error body directly.
+// See SetTraceIDFunc for details on how trace IDs are obtained.
+func (e *Error) JSONErrorValueCtx(ctx context.Context) []byte {
+	traceID := traceIDFromContext(ctx)
+	if traceID == "" {
+		return e.JSONErrorValue()
+	}
+	return e.withJSONCodes(marshalJSONStatus(e.grpcStatus(&errdetails.RequestInfo{
+		RequestId: traceID,
+	})))
+}
+
+func marshalJSONStatus(st *status.Status) []byte {
+	b, err := protojson.Marshal(st.Proto())
 	if err != nil {
 		errJSON, _ := json.Marshal(fmt.Sprintf("failed to encode proto to JSON: %v", err))
 		return errJSON
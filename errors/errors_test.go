@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
@@ -139,6 +140,21 @@ func TestNewError(t *testing.T) {
 	}
 }
 
+func TestWithRetryInfo(t *testing.T) {
+	de := New(fmt.Errorf("some error"), nil,
+		WithErrorReason("Overloaded", codes.Unavailable),
+		WithResourceInfo(&ResourceInfo{Type: "testResourceType", Name: "testResourceName"}),
+		WithRetryInfo(2*time.Second))
+
+	details := de.GRPCStatus().Details()
+	assert.Len(t, details, 3)
+	retryInfo, ok := details[2].(*errdetails.RetryInfo)
+	if assert.True(t, ok, "expected RetryInfo, got %T", details[2]) {
+		assert.Equal(t, 2*time.Second, retryInfo.GetRetryDelay().AsDuration())
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, de.HTTPCode())
+}
+
 func TestToHTTP(t *testing.T) {
 	md := map[string]string{}
 	tests := []struct {
@@ -0,0 +1,228 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package errtest contains helpers to test errors created with the kit errors package.
+// Golden compares the gRPC status and the HTTP response of an error with golden files, so that accidental changes
+// to the public error contract are caught in tests.
+package errtest
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+// UpdateEnvVar is the name of the environment variable that, when set to "true", makes Golden write the golden
+// files instead of comparing them.
+const UpdateEnvVar = "ERRTEST_UPDATE"
+
+// Redacted is the value that replaces redacted data in golden files.
+const Redacted = "[REDACTED]"
+
+// Option for Golden.
+type Option func(o *options)
+
+type options struct {
+	dir      string
+	name     string
+	fields   map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+// WithDir sets the directory where golden files are stored. Default is "testdata".
+func WithDir(dir string) Option {
+	return func(o *options) {
+		o.dir = dir
+	}
+}
+
+// WithName sets the name of the golden files. Default is the name of the test.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// RedactFields replaces the values of the fields with the given names, at any depth, with Redacted.
+// This is useful for values that change at every run, such as request IDs.
+func RedactFields(names ...string) Option {
+	return func(o *options) {
+		for _, n := range names {
+			o.fields[n] = struct{}{}
+		}
+	}
+}
+
+// RedactPattern replaces all matches of the pattern in string values with Redacted.
+func RedactPattern(pattern *regexp.Regexp) Option {
+	return func(o *options) {
+		o.patterns = append(o.patterns, pattern)
+	}
+}
+
+// Golden compares the gRPC status and the HTTP response of err, which must be a kit error, with the golden files
+// "<name>.grpc.golden.json" and "<name>.http.golden.json".
+// The golden files contain indented JSON with stable ordering of keys. When the environment variable named by
+// UpdateEnvVar is "true", the golden files are created or updated instead.
+func Golden(t testing.TB, err error, opts ...Option) {
+	t.Helper()
+
+	o := options{
+		dir:    "testdata",
+		name:   goldenName(t.Name()),
+		fields: map[string]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var kitErr *kiterrors.Error
+	if !errors.As(err, &kitErr) {
+		t.Fatalf("error is not a kit error: %v", err)
+		return
+	}
+
+	grpcGolden, httpGolden, mErr := Serialize(kitErr, opts...)
+	if mErr != nil {
+		t.Fatalf("failed to serialize error: %v", mErr)
+		return
+	}
+
+	compareGolden(t, filepath.Join(o.dir, o.name+".grpc.golden.json"), grpcGolden)
+	compareGolden(t, filepath.Join(o.dir, o.name+".http.golden.json"), httpGolden)
+}
+
+// Serialize returns the serialized gRPC status and HTTP response of the error, as written to golden files.
+// Options that set the location of golden files are ignored.
+func Serialize(err *kiterrors.Error, opts ...Option) (grpcGolden []byte, httpGolden []byte, mErr error) {
+	o := options{
+		fields: map[string]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	st := err.GRPCStatus()
+	details, mErr := normalizeJSON(protojson.Marshal(st.Proto()))
+	if mErr != nil {
+		return nil, nil, mErr
+	}
+	// Replace the numeric code with its name, which is easier to review
+	if m, ok := details.(map[string]any); ok {
+		m["code"] = st.Code().String()
+	}
+	grpcGolden, mErr = o.marshal(details)
+	if mErr != nil {
+		return nil, nil, mErr
+	}
+
+	code, body := err.ToHTTP()
+	bodyObj, mErr := normalizeJSON(body, nil)
+	if mErr != nil {
+		return nil, nil, mErr
+	}
+	httpGolden, mErr = o.marshal(map[string]any{
+		"status": code,
+		"body":   bodyObj,
+	})
+	if mErr != nil {
+		return nil, nil, mErr
+	}
+
+	return grpcGolden, httpGolden, nil
+}
+
+// normalizeJSON parses the JSON so it can be re-encoded with stable formatting.
+// protojson intentionally produces unstable output, so it can't be compared directly.
+func normalizeJSON(data []byte, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+func (o options) marshal(v any) ([]byte, error) {
+	v = o.redact("", v)
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func (o options) redact(key string, v any) any {
+	if _, ok := o.fields[key]; ok && key != "" {
+		return Redacted
+	}
+	switch x := v.(type) {
+	case map[string]any:
+		for k, val := range x {
+			x[k] = o.redact(k, val)
+		}
+		return x
+	case []any:
+		for i, val := range x {
+			x[i] = o.redact("", val)
+		}
+		return x
+	case string:
+		for _, p := range o.patterns {
+			x = p.ReplaceAllString(x, Redacted)
+		}
+		return x
+	default:
+		return v
+	}
+}
+
+func compareGolden(t testing.TB, path string, actual []byte) {
+	t.Helper()
+
+	if update, _ := strconv.ParseBool(os.Getenv(UpdateEnvVar)); update {
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, actual, 0o644) //nolint:gosec
+		}
+		if err != nil {
+			t.Fatalf("failed to write golden file '%s': %v", path, err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file '%s' (set %s=true to create it): %v", path, UpdateEnvVar, err)
+		return
+	}
+	assert.Equal(t, string(expected), string(actual),
+		"error does not match golden file '%s' (set %s=true to update it)", path, UpdateEnvVar)
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// goldenName returns a file name for the test name, which can contain slashes for sub-tests.
+func goldenName(testName string) string {
+	return unsafeNameChars.ReplaceAllString(strings.ReplaceAll(testName, "/", "__"), "_")
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errtest
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+func newTestError() *kiterrors.Error {
+	return kiterrors.New(errors.New("state store not found"), nil,
+		kiterrors.WithDescription("state store mystore is not configured"),
+		kiterrors.WithMetadata(map[string]string{
+			"store":     "mystore",
+			"appID":     "myapp",
+			"requestID": "c0ffee-1234",
+		}),
+		kiterrors.WithErrorReason("DAPR_STATE_NOT_FOUND", codes.NotFound),
+		kiterrors.WithResourceInfo(&kiterrors.ResourceInfo{Type: "state", Name: "mystore"}),
+		kiterrors.WithRetryInfo(5*time.Second),
+	)
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, newTestError(), RedactFields("requestID"))
+
+	t.Run("wrapped error", func(t *testing.T) {
+		Golden(t, fmt.Errorf("wrapped: %w", newTestError()),
+			WithName("TestGolden"),
+			RedactFields("requestID"),
+		)
+	})
+}
+
+// fakeTB captures failures instead of failing the test.
+type fakeTB struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Name() string {
+	return "fake/test"
+}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.failed = true
+	f.msg = fmt.Sprintf(format, args...)
+}
+
+func (f *fakeTB) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+}
+
+func TestGoldenUpdateAndMismatch(t *testing.T) {
+	dir := t.TempDir()
+
+	// Create the golden files
+	t.Setenv(UpdateEnvVar, "true")
+	tb := &fakeTB{TB: t}
+	Golden(tb, newTestError(), WithDir(dir))
+	require.False(t, tb.failed, tb.msg)
+	assert.FileExists(t, filepath.Join(dir, "fake__test.grpc.golden.json"))
+	assert.FileExists(t, filepath.Join(dir, "fake__test.http.golden.json"))
+
+	// Same error matches, since the output is stable
+	t.Setenv(UpdateEnvVar, "")
+	for i := 0; i < 5; i++ {
+		tb = &fakeTB{TB: t}
+		Golden(tb, newTestError(), WithDir(dir))
+		require.False(t, tb.failed, tb.msg)
+	}
+
+	// A changed error doesn't
+	tb = &fakeTB{TB: t}
+	changed := kiterrors.New(errors.New("state store not found"), nil,
+		kiterrors.WithErrorReason("DAPR_STATE_NOT_FOUND", codes.InvalidArgument),
+	)
+	Golden(tb, changed, WithDir(dir))
+	assert.True(t, tb.failed)
+	assert.Contains(t, tb.msg, "does not match golden file")
+
+	// Missing golden files and non-kit errors fail
+	tb = &fakeTB{TB: t}
+	Golden(tb, newTestError(), WithDir(dir), WithName("missing"))
+	assert.True(t, tb.failed)
+	assert.Contains(t, tb.msg, UpdateEnvVar+"=true")
+
+	tb = &fakeTB{TB: t}
+	Golden(tb, errors.New("plain"), WithDir(dir))
+	assert.True(t, tb.failed)
+	assert.Contains(t, tb.msg, "not a kit error")
+}
+
+func TestSerializeRedaction(t *testing.T) {
+	grpcGolden, httpGolden, err := Serialize(newTestError(),
+		RedactFields("requestID"),
+		RedactPattern(regexp.MustCompile(`my[a-z]+`)),
+	)
+	require.NoError(t, err)
+
+	for _, b := range [][]byte{grpcGolden, httpGolden} {
+		s := string(b)
+		assert.NotContains(t, s, "c0ffee")
+		assert.NotContains(t, s, "mystore")
+		assert.NotContains(t, s, "myapp")
+		assert.Contains(t, s, Redacted)
+	}
+	assert.Contains(t, string(grpcGolden), `"code": "NotFound"`)
+	assert.Contains(t, string(httpGolden), `"status": 404`)
+}
+
+func TestGoldenName(t *testing.T) {
+	assert.Equal(t, "TestA__sub_test_1", goldenName("TestA/sub test#1"))
+}
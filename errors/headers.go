@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// HeaderErrorReason is the name of the header (or gRPC metadata key) that contains the reason of an error.
+	HeaderErrorReason = "x-dapr-error-reason"
+	// HeaderErrorTag is the name of the header (or gRPC metadata key) that contains the tag of an error.
+	HeaderErrorTag = "x-dapr-error-tag"
+
+	// maxHeaderValueLength is the maximum length of the values of the headers, before escaping.
+	maxHeaderValueLength = 256
+)
+
+// Summary is a compact representation of an error, which is propagated through headers so that errors can be
+// classified even when intermediate proxies don't forward the response body.
+type Summary struct {
+	Reason string
+	Tag    string
+}
+
+// WithTag sets a tag on the error, which is used to classify it further than the reason, for example with the name
+// of the component that returned it. The tag is included in the headers set by SetHTTPHeaders and GRPCMetadata.
+func WithTag(tag string) Option {
+	return func(e *Error) {
+		e.tag = tag
+	}
+}
+
+// Tag returns the tag of the error.
+func (e *Error) Tag() string {
+	if e == nil {
+		return ""
+	}
+	return e.tag
+}
+
+// Summary returns the compact summary of the error.
+func (e *Error) Summary() Summary {
+	return Summary{
+		Reason: e.Reason(),
+		Tag:    e.Tag(),
+	}
+}
+
+// SetHTTPHeaders sets the headers with the summary of the error on h.
+func (e *Error) SetHTTPHeaders(h http.Header) {
+	for k, v := range e.Summary().pairs() {
+		h.Set(k, v)
+	}
+}
+
+// GRPCMetadata returns gRPC metadata with the summary of the error.
+func (e *Error) GRPCMetadata() metadata.MD {
+	return metadata.New(e.Summary().pairs())
+}
+
+// SetGRPCTrailer sets the summary of the error in the trailer of the gRPC call whose server context is ctx.
+func (e *Error) SetGRPCTrailer(ctx context.Context) error {
+	return grpc.SetTrailer(ctx, e.GRPCMetadata())
+}
+
+// SummaryFromHTTPHeaders returns the summary of an error from the headers of a HTTP response.
+// It returns false if the headers don't contain an error summary.
+func SummaryFromHTTPHeaders(h http.Header) (Summary, bool) {
+	return summaryFromValues(h.Get(HeaderErrorReason), h.Get(HeaderErrorTag))
+}
+
+// SummaryFromGRPCMetadata returns the summary of an error from the metadata (headers or trailers) of a gRPC call.
+// It returns false if the metadata doesn't contain an error summary.
+func SummaryFromGRPCMetadata(md metadata.MD) (Summary, bool) {
+	return summaryFromValues(firstValue(md, HeaderErrorReason), firstValue(md, HeaderErrorTag))
+}
+
+func firstValue(md metadata.MD, key string) string {
+	v := md.Get(key)
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+func summaryFromValues(reason string, tag string) (Summary, bool) {
+	if reason == "" {
+		return Summary{}, false
+	}
+	s := Summary{
+		Reason: unescapeHeaderValue(reason),
+		Tag:    unescapeHeaderValue(tag),
+	}
+	return s, true
+}
+
+// pairs returns the header names and values for the summary.
+func (s Summary) pairs() map[string]string {
+	res := make(map[string]string, 2)
+	if s.Reason != "" {
+		res[HeaderErrorReason] = escapeHeaderValue(s.Reason)
+	}
+	if s.Tag != "" {
+		res[HeaderErrorTag] = escapeHeaderValue(s.Tag)
+	}
+	return res
+}
+
+// escapeHeaderValue truncates and escapes the value so it's safe to use in HTTP headers and gRPC metadata.
+func escapeHeaderValue(v string) string {
+	if len(v) > maxHeaderValueLength {
+		v = v[:maxHeaderValueLength]
+	}
+	return url.QueryEscape(v)
+}
+
+func unescapeHeaderValue(v string) string {
+	u, err := url.QueryUnescape(v)
+	if err != nil {
+		return v
+	}
+	return u
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHeaders(t *testing.T) {
+	kitErr := New(fmt.Errorf("not found"), nil,
+		WithErrorReason("DAPR_STATE_NOT_FOUND", codes.NotFound),
+		WithTag("state.redis/my store"),
+	)
+	assert.Equal(t, "state.redis/my store", kitErr.Tag())
+
+	t.Run("HTTP", func(t *testing.T) {
+		h := http.Header{}
+		kitErr.SetHTTPHeaders(h)
+		assert.Equal(t, "DAPR_STATE_NOT_FOUND", h.Get(HeaderErrorReason))
+		assert.Equal(t, "state.redis%2Fmy+store", h.Get(HeaderErrorTag))
+
+		s, ok := SummaryFromHTTPHeaders(h)
+		require.True(t, ok)
+		assert.Equal(t, Summary{Reason: "DAPR_STATE_NOT_FOUND", Tag: "state.redis/my store"}, s)
+	})
+
+	t.Run("gRPC", func(t *testing.T) {
+		md := kitErr.GRPCMetadata()
+		assert.Equal(t, []string{"DAPR_STATE_NOT_FOUND"}, md.Get(HeaderErrorReason))
+
+		s, ok := SummaryFromGRPCMetadata(md)
+		require.True(t, ok)
+		assert.Equal(t, kitErr.Summary(), s)
+	})
+
+	t.Run("no tag", func(t *testing.T) {
+		h := http.Header{}
+		New(fmt.Errorf("boom"), nil).SetHTTPHeaders(h)
+		assert.Equal(t, errorInfoResonUnknown, h.Get(HeaderErrorReason))
+		assert.Empty(t, h.Values(HeaderErrorTag))
+	})
+
+	t.Run("no summary", func(t *testing.T) {
+		_, ok := SummaryFromHTTPHeaders(http.Header{})
+		assert.False(t, ok)
+		_, ok = SummaryFromGRPCMetadata(metadata.MD{})
+		assert.False(t, ok)
+	})
+
+	t.Run("long values are truncated", func(t *testing.T) {
+		h := http.Header{}
+		New(fmt.Errorf("boom"), nil, WithTag(strings.Repeat("a", 1000))).SetHTTPHeaders(h)
+		assert.Len(t, h.Get(HeaderErrorTag), maxHeaderValueLength)
+	})
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync/atomic"
+
+	"google.golang.org/genproto/googleapis/rpc/code"
+)
+
+// Names of the fields added to the JSON body of errors when SetJSONIncludeCodes is enabled.
+const (
+	JSONFieldGRPCCode     = "grpcCode"
+	JSONFieldGRPCCodeName = "grpcCodeName"
+	JSONFieldHTTPStatus   = "httpStatus"
+)
+
+// jsonIncludeCodes enables the code fields in the JSON body of errors.
+var jsonIncludeCodes atomic.Bool
+
+// SetJSONIncludeCodes sets whether the body returned by ToHTTP, JSONErrorValue and JSONErrorValueCtx includes the numeric
+// gRPC code (grpcCode), its name (grpcCodeName, such as "NOT_FOUND"), and the HTTP status code (httpStatus), in
+// addition to the fields of the gRPC status. This allows clients to read the semantic codes of errors from the body,
+// without re-deriving them from the transport. It's disabled by default.
+func SetJSONIncludeCodes(enabled bool) {
+	jsonIncludeCodes.Store(enabled)
+}
+
+// jsonCodeFields contains the code fields added to the JSON body of errors.
+type jsonCodeFields struct {
+	GRPCCode     uint32 `json:"grpcCode"`
+	GRPCCodeName string `json:"grpcCodeName"`
+	HTTPStatus   int    `json:"httpStatus"`
+}
+
+// withJSONCodes appends the code fields of the error to the JSON object b, if enabled.
+func (e *Error) withJSONCodes(b []byte) []byte {
+	if !jsonIncludeCodes.Load() {
+		return b
+	}
+
+	b = bytes.TrimSpace(b)
+	if len(b) < 2 || b[0] != '{' || b[len(b)-1] != '}' {
+		return b
+	}
+	fields, err := json.Marshal(jsonCodeFields{
+		GRPCCode:     uint32(e.grpcStatusCode),
+		GRPCCodeName: code.Code(e.grpcStatusCode).String(),
+		HTTPStatus:   e.httpCode,
+	})
+	if err != nil {
+		return b
+	}
+
+	res := make([]byte, 0, len(b)+len(fields))
+	res = append(res, b[:len(b)-1]...)
+	if len(bytes.TrimSpace(b[1:len(b)-1])) > 0 {
+		res = append(res, ',')
+	}
+	return append(res, fields[1:]...)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestJSONIncludeCodes(t *testing.T) {
+	kitErr := New(errors.New("not found"), nil, WithErrorReason("NOT_FOUND_REASON", codes.NotFound))
+
+	decode := func(t *testing.T, b []byte) map[string]any {
+		t.Helper()
+		var res map[string]any
+		require.NoError(t, json.Unmarshal(b, &res))
+		return res
+	}
+
+	toHTTPBody := func() []byte {
+		_, b := kitErr.ToHTTP()
+		return b
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		for _, b := range [][]byte{kitErr.JSONErrorValue(), toHTTPBody()} {
+			body := decode(t, b)
+			assert.NotContains(t, body, JSONFieldGRPCCode)
+			assert.NotContains(t, body, JSONFieldHTTPStatus)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		SetJSONIncludeCodes(true)
+		defer SetJSONIncludeCodes(false)
+
+		for _, b := range [][]byte{
+			kitErr.JSONErrorValue(),
+			kitErr.JSONErrorValueCtx(ContextWithTraceID(context.Background(), "abc")),
+			toHTTPBody(),
+		} {
+			body := decode(t, b)
+			assert.Equal(t, float64(codes.NotFound), body[JSONFieldGRPCCode])
+			assert.Equal(t, "NOT_FOUND", body[JSONFieldGRPCCodeName])
+			assert.Equal(t, float64(http.StatusNotFound), body[JSONFieldHTTPStatus])
+			assert.Contains(t, body, "details")
+
+			// The body can still be parsed as a status
+			parsed, err := FromJSONErrorValue(b)
+			require.NoError(t, err)
+			assert.Equal(t, "NOT_FOUND_REASON", parsed.Reason())
+		}
+	})
+
+	t.Run("appending to empty objects", func(t *testing.T) {
+		SetJSONIncludeCodes(true)
+		defer SetJSONIncludeCodes(false)
+
+		body := decode(t, kitErr.withJSONCodes([]byte(" {} ")))
+		assert.Len(t, body, 3)
+		assert.Equal(t, `"not an object"`, string(kitErr.withJSONCodes([]byte(`"not an object"`))))
+	})
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/runtime/protoiface"
+
+	"github.com/dapr/kit/grpccodes"
+)
+
+// FromGRPCStatus returns an Error from a gRPC status received from another hop, for example from another sidecar,
+// so it can be returned to the caller over any transport without accumulating duplicate details.
+// The reason, metadata and code of the status are authoritative: options can add details, metadata keys that
+// aren't already present, a tag, or a new description, but the reason and code are kept stable across hops. If
+// options set a ResourceInfo, it replaces the received one.
+// RequestInfo details are dropped, since they refer to the request of the previous hop.
+func FromGRPCStatus(st *status.Status, options ...Option) *Error {
+	if st == nil || st.Code() == 0 {
+		return nil
+	}
+
+	var (
+		reason   = errorInfoResonUnknown
+		md       map[string]string
+		received []protoiface.MessageV1
+	)
+	for _, d := range st.Details() {
+		msg, ok := d.(protoiface.MessageV1)
+		if !ok {
+			continue
+		}
+		received = append(received, msg)
+	}
+	received = canonicalDetails(received)
+	details := make([]protoiface.MessageV1, 0, len(received))
+	for _, d := range received {
+		switch v := d.(type) {
+		case *errdetails.ErrorInfo:
+			reason = v.GetReason()
+			md = v.GetMetadata()
+		case *errdetails.RequestInfo:
+			// Dropped
+		default:
+			details = append(details, d)
+		}
+	}
+
+	code := st.Code()
+	opts := make([]Option, 0, len(options)+2)
+	opts = append(opts, func(e *Error) {
+		e.description = st.Message()
+		e.details = details
+	})
+	opts = append(opts, options...)
+	opts = append(opts, func(e *Error) {
+		// Restore the authoritative values
+		e.reason = reason
+		e.grpcStatusCode = code
+		e.httpCode = grpccodes.HTTPStatusFromCode(code)
+		e.metadata = mergeMetadata(md, e.metadata)
+
+		// Canonicalize the details once here, so they don't need to be canonicalized every time the error is emitted
+		// A ResourceInfo set by the options replaces the received one
+		merged := make([]protoiface.MessageV1, 0, len(e.details))
+		for _, d := range e.details {
+			if _, ok := d.(*errdetails.ResourceInfo); ok && e.resourceInfo != nil {
+				continue
+			}
+			merged = append(merged, d)
+		}
+		e.details = canonicalDetails(merged)
+	})
+
+	return New(errors.New(st.Message()), nil, opts...)
+}
+
+// FromJSONErrorValue returns an Error from the body of an HTTP response created with JSONErrorValue (or
+// JSONErrorValueCtx), received from another hop. See FromGRPCStatus for details.
+func FromJSONErrorValue(body []byte, options ...Option) (*Error, error) {
+	var pb spb.Status
+	// Ignore fields that are not part of the status, such as the ones added by SetJSONIncludeCodes
+	err := protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, &pb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode error: %w", err)
+	}
+	e := FromGRPCStatus(status.FromProto(&pb), options...)
+	if e == nil {
+		return nil, errors.New("the response body doesn't contain an error")
+	}
+	return e, nil
+}
+
+// CanonicalizeStatus returns a copy of st whose details are canonical:
+//   - Only the first ErrorInfo is kept, which is authoritative; metadata keys of the others that are not present in
+//     the first one are merged into it.
+//   - Only the first ResourceInfo, RetryInfo and RequestInfo are kept.
+//   - The field violations of all BadRequest details are merged into the first one, without duplicates.
+//   - Other details that are equal to a previous one are removed.
+//
+// This is useful for proxies that forward gRPC statuses verbatim.
+func CanonicalizeStatus(st *status.Status) *status.Status {
+	if st == nil {
+		return nil
+	}
+
+	pb := st.Proto()
+	details := make([]protoiface.MessageV1, 0, len(pb.GetDetails()))
+	for _, d := range st.Details() {
+		msg, ok := d.(protoiface.MessageV1)
+		if !ok {
+			// Unknown types can't be compared, so they're removed
+			continue
+		}
+		details = append(details, msg)
+	}
+
+	res, err := status.New(st.Code(), st.Message()).WithDetails(canonicalDetails(details)...)
+	if err != nil {
+		return st
+	}
+	return res
+}
+
+// canonicalDetails returns the canonical list of details. See CanonicalizeStatus.
+func canonicalDetails(details []protoiface.MessageV1) []protoiface.MessageV1 {
+	var (
+		res                                   = make([]protoiface.MessageV1, 0, len(details))
+		errorInfo                             *errdetails.ErrorInfo
+		badRequest                            *errdetails.BadRequest
+		hasResource, hasRetry, hasRequestInfo bool
+	)
+
+	for _, d := range details {
+		switch v := d.(type) {
+		case *errdetails.ErrorInfo:
+			if errorInfo == nil {
+				errorInfo = proto.Clone(v).(*errdetails.ErrorInfo)
+				res = append(res, errorInfo)
+				continue
+			}
+			if len(v.GetMetadata()) > 0 {
+				errorInfo.Metadata = mergeMetadata(errorInfo.GetMetadata(), v.GetMetadata())
+			}
+		case *errdetails.ResourceInfo:
+			if !hasResource {
+				hasResource = true
+				res = append(res, v)
+			}
+		case *errdetails.RetryInfo:
+			if !hasRetry {
+				hasRetry = true
+				res = append(res, v)
+			}
+		case *errdetails.RequestInfo:
+			if !hasRequestInfo {
+				hasRequestInfo = true
+				res = append(res, v)
+			}
+		case *errdetails.BadRequest:
+			if badRequest == nil {
+				badRequest = &errdetails.BadRequest{}
+				res = append(res, badRequest)
+			}
+			for _, fv := range v.GetFieldViolations() {
+				if !hasFieldViolation(badRequest, fv) {
+					badRequest.FieldViolations = append(badRequest.FieldViolations, fv)
+				}
+			}
+		default:
+			if !containsDetail(res, d) {
+				res = append(res, d)
+			}
+		}
+	}
+
+	return res
+}
+
+// mergeMetadata returns the union of the two maps, where values in authoritative take precedence.
+func mergeMetadata(authoritative, other map[string]string) map[string]string {
+	if len(other) == 0 {
+		return authoritative
+	}
+	res := make(map[string]string, len(authoritative)+len(other))
+	for k, v := range other {
+		res[k] = v
+	}
+	for k, v := range authoritative {
+		res[k] = v
+	}
+	return res
+}
+
+func hasFieldViolation(br *errdetails.BadRequest, fv *errdetails.BadRequest_FieldViolation) bool {
+	for _, existing := range br.GetFieldViolations() {
+		if existing.GetField() == fv.GetField() && existing.GetDescription() == fv.GetDescription() {
+			return true
+		}
+	}
+	return false
+}
+
+func containsDetail(details []protoiface.MessageV1, d protoiface.MessageV1) bool {
+	dm, ok := d.(proto.Message)
+	if !ok {
+		return false
+	}
+	for _, existing := range details {
+		if em, ok := existing.(proto.Message); ok && proto.Equal(em, dm) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func newMergeTestError() *Error {
+	return New(errors.New("state store failure"), nil,
+		WithErrorReason("DAPR_STATE_FAILED", codes.FailedPrecondition),
+		WithDescription("failed to save state"),
+		WithMetadata(map[string]string{"key": "value"}),
+		WithResourceInfo(&ResourceInfo{Type: "state", Name: "statestore"}),
+		WithRetryInfo(time.Second),
+		WithFieldViolations(&errdetails.BadRequest_FieldViolation{Field: "key", Description: "empty"}),
+	)
+}
+
+func TestFromGRPCStatus(t *testing.T) {
+	t.Run("round trip across hops", func(t *testing.T) {
+		orig := newMergeTestError()
+		st := orig.GRPCStatus()
+
+		// gRPC -> HTTP (with a RequestInfo detail) -> gRPC, twice
+		current := orig
+		for i := 0; i < 2; i++ {
+			hop := FromGRPCStatus(current.GRPCStatus())
+			require.NotNil(t, hop)
+			body := hop.JSONErrorValueCtx(ContextWithTraceID(context.Background(), "trace"))
+
+			var err error
+			current, err = FromJSONErrorValue(body)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, orig.Reason(), current.Reason())
+		assert.Equal(t, orig.HTTPCode(), current.HTTPCode())
+		assert.Equal(t, "failed to save state", current.Description())
+		assert.True(t, proto.Equal(st.Proto(), current.GRPCStatus().Proto()), "expected %v, got %v", st.Proto(), current.GRPCStatus().Proto())
+	})
+
+	t.Run("reason and code are authoritative", func(t *testing.T) {
+		st := newMergeTestError().GRPCStatus()
+		e := FromGRPCStatus(st,
+			WithErrorReason("OTHER", codes.Internal),
+			WithMetadata(map[string]string{"key": "override", "hop": "sidecar-b"}),
+			WithTag("hop"),
+		)
+
+		assert.Equal(t, "DAPR_STATE_FAILED", e.Reason())
+		assert.Equal(t, codes.FailedPrecondition, e.GRPCStatus().Code())
+		assert.Equal(t, "hop", e.Tag())
+
+		var infos []*errdetails.ErrorInfo
+		for _, d := range e.GRPCStatus().Details() {
+			if ei, ok := d.(*errdetails.ErrorInfo); ok {
+				infos = append(infos, ei)
+			}
+		}
+		require.Len(t, infos, 1)
+		assert.Equal(t, map[string]string{"key": "value", "hop": "sidecar-b"}, infos[0].GetMetadata())
+	})
+
+	t.Run("options replace the received resource info", func(t *testing.T) {
+		st := newMergeTestError().GRPCStatus()
+		e := FromGRPCStatus(st,
+			WithResourceInfo(&ResourceInfo{Type: "pubsub", Name: "pubsub"}),
+			WithRetryInfo(time.Minute),
+		)
+
+		var (
+			resources []*errdetails.ResourceInfo
+			retries   []*errdetails.RetryInfo
+		)
+		for _, d := range e.GRPCStatus().Details() {
+			switch v := d.(type) {
+			case *errdetails.ResourceInfo:
+				resources = append(resources, v)
+			case *errdetails.RetryInfo:
+				retries = append(retries, v)
+			}
+		}
+		require.Len(t, resources, 1)
+		assert.Equal(t, "pubsub", resources[0].GetResourceName())
+		// The received RetryInfo is authoritative
+		require.Len(t, retries, 1)
+		assert.Equal(t, time.Second, retries[0].GetRetryDelay().AsDuration())
+	})
+
+	t.Run("errors created locally are not canonicalized", func(t *testing.T) {
+		fv := &errdetails.BadRequest_FieldViolation{Field: "key", Description: "empty"}
+		e := New(errors.New("invalid"), nil, WithFieldViolations(fv), WithFieldViolations(fv))
+
+		var badRequests int
+		for _, d := range e.GRPCStatus().Details() {
+			if _, ok := d.(*errdetails.BadRequest); ok {
+				badRequests++
+			}
+		}
+		assert.Equal(t, 2, badRequests)
+	})
+
+	t.Run("no error", func(t *testing.T) {
+		assert.Nil(t, FromGRPCStatus(nil))
+		assert.Nil(t, FromGRPCStatus(status.New(codes.OK, "")))
+
+		_, err := FromJSONErrorValue([]byte(`{"code":0}`))
+		require.Error(t, err)
+		_, err = FromJSONErrorValue([]byte(`not json`))
+		require.Error(t, err)
+	})
+}
+
+func TestCanonicalizeStatus(t *testing.T) {
+	st, err := status.New(codes.NotFound, "not found").WithDetails(
+		&errdetails.ErrorInfo{Reason: "FIRST", Domain: "dapr.io", Metadata: map[string]string{"a": "1"}},
+		&errdetails.ResourceInfo{ResourceType: "state", ResourceName: "first"},
+		&errdetails.ErrorInfo{Reason: "SECOND", Domain: "dapr.io", Metadata: map[string]string{"a": "2", "b": "2"}},
+		&errdetails.ResourceInfo{ResourceType: "state", ResourceName: "second"},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "x", Description: "bad"}}},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "x", Description: "bad"},
+			{Field: "y", Description: "bad"},
+		}},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Second)},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Minute)},
+		&errdetails.Help{Links: []*errdetails.Help_Link{{Url: "https://dapr.io"}}},
+		&errdetails.Help{Links: []*errdetails.Help_Link{{Url: "https://dapr.io"}}},
+	)
+	require.NoError(t, err)
+
+	res := CanonicalizeStatus(st)
+	assert.Equal(t, codes.NotFound, res.Code())
+	assert.Equal(t, "not found", res.Message())
+
+	details := res.Details()
+	require.Len(t, details, 5)
+	assert.True(t, proto.Equal(&errdetails.ErrorInfo{Reason: "FIRST", Domain: "dapr.io", Metadata: map[string]string{"a": "1", "b": "2"}}, details[0].(proto.Message)))
+	assert.Equal(t, "first", details[1].(*errdetails.ResourceInfo).GetResourceName())
+	assert.Len(t, details[2].(*errdetails.BadRequest).GetFieldViolations(), 2)
+	assert.Equal(t, time.Second, details[3].(*errdetails.RetryInfo).GetRetryDelay().AsDuration())
+	assert.IsType(t, &errdetails.Help{}, details[4])
+
+	assert.Nil(t, CanonicalizeStatus(nil))
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"errors"
+	"sync"
+)
+
+// Observer is notified each time an Error is emitted, that is, when Emit is invoked as the error is returned to a
+// client. Converting an error, for example with GRPCStatus or ToHTTP, doesn't notify observers, since that also happens
+// in other places, such as in interceptors and tests. The same Error, such as a package-level sentinel error, is
+// observed each time it's emitted.
+// The HTTP, fasthttp and gRPC adapters of the middleware package and the loadshed middlewares emit the errors they
+// return; servers that return errors to clients in other ways must invoke Emit themselves.
+// Observers are invoked synchronously, so they should return quickly.
+type Observer interface {
+	ObserveError(e *Error)
+}
+
+// ObserverFunc is a function that implements Observer.
+type ObserverFunc func(e *Error)
+
+// ObserveError implements Observer.
+func (f ObserverFunc) ObserveError(e *Error) {
+	f(e)
+}
+
+var (
+	observers     []*observerEntry
+	observersLock sync.RWMutex
+)
+
+type observerEntry struct {
+	Observer
+}
+
+// AddObserver registers an Observer that is notified of all Errors that are emitted.
+// It returns a function that removes the observer.
+func AddObserver(o Observer) (remove func()) {
+	entry := &observerEntry{Observer: o}
+
+	observersLock.Lock()
+	observers = append(observers, entry)
+	observersLock.Unlock()
+
+	return func() {
+		observersLock.Lock()
+		defer observersLock.Unlock()
+		for i, e := range observers {
+			if e == entry {
+				observers = append(observers[:i:i], observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Emit invokes Emit on the Error in the chain of err, if any. It's useful for adapters that return errors of any type.
+func Emit(err error) {
+	var e *Error
+	if errors.As(err, &e) {
+		e.Emit()
+	}
+}
+
+// Emit notifies the observers that e is being returned to a client. It should be invoked once each time the error is
+// returned, right before converting it to a gRPC status or to a HTTP response.
+func (e *Error) Emit() {
+	if e == nil {
+		return
+	}
+
+	// Observers are invoked without holding the lock, so they can add or remove observers
+	observersLock.RLock()
+	list := make([]*observerEntry, len(observers))
+	copy(list, observers)
+	observersLock.RUnlock()
+	for _, o := range list {
+		o.ObserveError(e)
+	}
+}
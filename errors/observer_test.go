@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestObserver(t *testing.T) {
+	var observed []string
+	remove := AddObserver(ObserverFunc(func(e *Error) {
+		observed = append(observed, e.Reason())
+	}))
+
+	first := New(fmt.Errorf("first"), nil, WithErrorReason("FIRST", codes.Internal))
+	second := New(fmt.Errorf("second"), nil)
+
+	// Errors are observed when they're emitted, not when they're created or converted
+	_ = first.GRPCStatus()
+	_, _ = second.ToHTTP()
+	_ = first.JSONErrorValue()
+	_ = first.JSONErrorValueCtx(context.Background())
+	assert.Empty(t, observed)
+
+	first.Emit()
+	Emit(fmt.Errorf("wrapped: %w", second))
+	// The same error is observed each time it's emitted
+	first.Emit()
+	// Other errors are ignored
+	Emit(fmt.Errorf("not a kit error"))
+	Emit(nil)
+	var nilErr *Error
+	nilErr.Emit()
+	remove()
+	New(fmt.Errorf("third"), nil).Emit()
+
+	assert.Equal(t, []string{"FIRST", errorInfoResonUnknown, "FIRST"}, observed)
+}
+
+func TestObserverModifiesObservers(t *testing.T) {
+	var (
+		calls  int
+		remove func()
+	)
+	remove = AddObserver(ObserverFunc(func(e *Error) {
+		calls++
+		// Observers can remove themselves without deadlocking
+		remove()
+	}))
+
+	New(fmt.Errorf("first"), nil).Emit()
+	New(fmt.Errorf("second"), nil).Emit()
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// TraceIDFunc returns the trace ID of the request whose context is ctx, or an empty string if there's none.
+type TraceIDFunc func(ctx context.Context) string
+
+type traceIDContextKeyType struct{}
+
+// traceIDContextKey is how we find trace IDs in a context.Context.
+var traceIDContextKey = traceIDContextKeyType{}
+
+// traceIDFunc is the TraceIDFunc used by JSONErrorValueCtx.
+var traceIDFunc atomic.Pointer[TraceIDFunc]
+
+// ContextWithTraceID returns a new Context, derived from ctx, which carries the provided trace ID.
+// The trace ID is returned by the default TraceIDFunc.
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDContextKey, traceID)
+}
+
+// SetTraceIDFunc sets the function used to obtain the trace ID from a context, for example from the active
+// OpenTelemetry span. Passing nil restores the default, which returns the trace ID set with ContextWithTraceID.
+func SetTraceIDFunc(fn TraceIDFunc) {
+	if fn == nil {
+		traceIDFunc.Store(nil)
+		return
+	}
+	traceIDFunc.Store(&fn)
+}
+
+func traceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if fn := traceIDFunc.Load(); fn != nil {
+		return (*fn)(ctx)
+	}
+	traceID, _ := ctx.Value(traceIDContextKey).(string)
+	return traceID
+}
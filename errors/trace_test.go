@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestJSONErrorValueCtx(t *testing.T) {
+	de := New(fmt.Errorf("some error"), nil, WithErrorReason("RedisFailure", codes.Internal))
+
+	t.Run("no trace ID", func(t *testing.T) {
+		assert.Equal(t, de.JSONErrorValue(), de.JSONErrorValueCtx(context.Background()))
+		assert.NotContains(t, string(de.JSONErrorValueCtx(context.Background())), "RequestInfo")
+	})
+
+	t.Run("trace ID from context", func(t *testing.T) {
+		ctx := ContextWithTraceID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736")
+		body := string(de.JSONErrorValueCtx(ctx))
+		assert.Contains(t, body, `"@type":"type.googleapis.com/google.rpc.RequestInfo"`)
+		assert.Contains(t, body, `"requestId":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+		assert.Contains(t, body, "RedisFailure")
+
+		// The trace ID is not included in the gRPC status
+		assert.Len(t, de.GRPCStatus().Details(), 1)
+	})
+
+	t.Run("custom trace ID function", func(t *testing.T) {
+		SetTraceIDFunc(func(ctx context.Context) string {
+			return "custom"
+		})
+		defer SetTraceIDFunc(nil)
+
+		body := string(de.JSONErrorValueCtx(context.Background()))
+		assert.Contains(t, body, `"requestId":"custom"`)
+	})
+}
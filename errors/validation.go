@@ -0,0 +1,273 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// pgvValidationError is implemented by the errors generated by protoc-gen-validate (PGV).
+type pgvValidationError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// pgvMultiError is implemented by the multi-errors generated by protoc-gen-validate (PGV) for ValidateAll.
+type pgvMultiError interface {
+	AllErrors() []error
+}
+
+// WithFieldViolations used to add a BadRequest detail
+// with the given field violations to the Error struct.
+func WithFieldViolations(violations ...*errdetails.BadRequest_FieldViolation) Option {
+	return func(e *Error) {
+		if len(violations) == 0 {
+			return
+		}
+		e.details = append(e.details, &errdetails.BadRequest{
+			FieldViolations: violations,
+		})
+	}
+}
+
+// WithPreconditionFailure used to add a PreconditionFailure detail
+// with the given violations to the Error struct.
+func WithPreconditionFailure(violations ...*errdetails.PreconditionFailure_Violation) Option {
+	return func(e *Error) {
+		if len(violations) == 0 {
+			return
+		}
+		e.details = append(e.details, &errdetails.PreconditionFailure{
+			Violations: violations,
+		})
+	}
+}
+
+// WithValidationError used to add a BadRequest detail to the Error struct,
+// with the field violations contained in a validation error returned by
+// protovalidate or protoc-gen-validate (PGV).
+// This is typically combined with WithErrorReason and codes.InvalidArgument.
+func WithValidationError(err error) Option {
+	return WithFieldViolations(FieldViolations(err)...)
+}
+
+// FieldViolations converts the result of validating a protobuf message with protovalidate or protoc-gen-validate (PGV)
+// into BadRequest field violations.
+// It supports:
+//   - protovalidate's ValidationError, which contains a list of Violations.
+//   - PGV's errors for a single field, as returned by Validate.
+//   - PGV's multi-errors, as returned by ValidateAll.
+//
+// Errors that are not validation errors are ignored, and nil is returned.
+func FieldViolations(err error) []*errdetails.BadRequest_FieldViolation {
+	if err == nil {
+		return nil
+	}
+
+	// protovalidate
+	if violations := protovalidateViolations(err); len(violations) > 0 {
+		return violations
+	}
+
+	// PGV
+	var multi pgvMultiError
+	if errors.As(err, &multi) {
+		var res []*errdetails.BadRequest_FieldViolation
+		for _, e := range multi.AllErrors() {
+			res = append(res, FieldViolations(e)...)
+		}
+		return res
+	}
+	var pgvErr pgvValidationError
+	if errors.As(err, &pgvErr) {
+		return pgvFieldViolations(pgvErr, "")
+	}
+
+	return nil
+}
+
+// pgvFieldViolations returns the violations for a PGV error, following causes for embedded messages.
+func pgvFieldViolations(err pgvValidationError, prefix string) []*errdetails.BadRequest_FieldViolation {
+	field := err.Field()
+	if prefix != "" {
+		field = prefix + "." + field
+	}
+
+	// Errors on embedded messages have the embedded message's error as cause
+	if cause := err.Cause(); cause != nil {
+		var multi pgvMultiError
+		if errors.As(cause, &multi) {
+			var res []*errdetails.BadRequest_FieldViolation
+			for _, e := range multi.AllErrors() {
+				var nested pgvValidationError
+				if errors.As(e, &nested) {
+					res = append(res, pgvFieldViolations(nested, field)...)
+				}
+			}
+			if len(res) > 0 {
+				return res
+			}
+		}
+		var nested pgvValidationError
+		if errors.As(cause, &nested) {
+			return pgvFieldViolations(nested, field)
+		}
+	}
+
+	return []*errdetails.BadRequest_FieldViolation{{
+		Field:       field,
+		Description: err.Reason(),
+	}}
+}
+
+// protovalidateViolations returns the field violations from a protovalidate ValidationError.
+// This uses reflection to avoid a dependency on protovalidate: it looks for an exported "Violations" field containing
+// a list of buf.validate.Violation messages, either directly (as in older versions of protovalidate) or wrapped in the
+// "Proto" field of a struct (as in current versions).
+func protovalidateViolations(err error) []*errdetails.BadRequest_FieldViolation {
+	for err != nil {
+		v := reflect.ValueOf(err)
+		for v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Struct {
+			f := v.FieldByName("Violations")
+			if f.IsValid() && f.Kind() == reflect.Slice {
+				res := make([]*errdetails.BadRequest_FieldViolation, 0, f.Len())
+				for i := 0; i < f.Len(); i++ {
+					if m := protovalidateViolationMessage(f.Index(i)); m != nil {
+						res = append(res, &errdetails.BadRequest_FieldViolation{
+							Field:       protovalidateFieldPath(m),
+							Description: protoStringField(m, "message"),
+						})
+					}
+				}
+				if len(res) > 0 {
+					return res
+				}
+			}
+		}
+		err = errors.Unwrap(err)
+	}
+	return nil
+}
+
+// protovalidateViolationMessage returns the buf.validate.Violation message of an element of the Violations of a
+// protovalidate ValidationError, or nil.
+func protovalidateViolationMessage(v reflect.Value) protoreflect.Message {
+	if !v.CanInterface() {
+		return nil
+	}
+	if m, ok := v.Interface().(proto.Message); ok {
+		return validViolationMessage(m)
+	}
+
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.FieldByName("Proto")
+	if !f.IsValid() || !f.CanInterface() {
+		return nil
+	}
+	m, ok := f.Interface().(proto.Message)
+	if !ok {
+		return nil
+	}
+	return validViolationMessage(m)
+}
+
+func validViolationMessage(m proto.Message) protoreflect.Message {
+	if m == nil || reflect.ValueOf(m).IsNil() {
+		return nil
+	}
+	r := m.ProtoReflect()
+	if r.Descriptor().FullName() != "buf.validate.Violation" {
+		return nil
+	}
+	return r
+}
+
+// protovalidateFieldPath returns the path of the field of a buf.validate.Violation message, such as "items[2].name".
+// The path is built from the "field" message in current versions of protovalidate, like protovalidate's
+// FieldPathString does, or read from the "field_path" string in older versions.
+func protovalidateFieldPath(m protoreflect.Message) string {
+	fields := m.Descriptor().Fields()
+	if fd := fields.ByName("field"); fd != nil && fd.Message() != nil && m.Has(fd) {
+		path := m.Get(fd).Message()
+		elementsFd := path.Descriptor().Fields().ByName("elements")
+		if elementsFd != nil && elementsFd.IsList() {
+			var b strings.Builder
+			elements := path.Get(elementsFd).List()
+			for i := 0; i < elements.Len(); i++ {
+				if i > 0 {
+					b.WriteByte('.')
+				}
+				writeFieldPathElement(&b, elements.Get(i).Message())
+			}
+			return b.String()
+		}
+	}
+	return protoStringField(m, "field_path")
+}
+
+// writeFieldPathElement writes a buf.validate.FieldPathElement message, which is the name of a field, optionally
+// followed by the index or key of a repeated or map field within brackets.
+func writeFieldPathElement(b *strings.Builder, element protoreflect.Message) {
+	b.WriteString(protoStringField(element, "field_name"))
+
+	oneof := element.Descriptor().Oneofs().ByName("subscript")
+	if oneof == nil {
+		return
+	}
+	fd := element.WhichOneof(oneof)
+	if fd == nil {
+		return
+	}
+	val := element.Get(fd)
+	b.WriteByte('[')
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b.WriteString(strconv.FormatBool(val.Bool()))
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		b.WriteString(strconv.FormatInt(val.Int(), 10))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		b.WriteString(strconv.FormatUint(val.Uint(), 10))
+	case protoreflect.StringKind:
+		b.WriteString(strconv.Quote(val.String()))
+	default:
+		b.WriteString(val.String())
+	}
+	b.WriteByte(']')
+}
+
+// protoStringField returns the value of the string field of m with the given name, or an empty string.
+func protoStringField(m protoreflect.Message, name protoreflect.Name) string {
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() {
+		return ""
+	}
+	return m.Get(fd).String()
+}
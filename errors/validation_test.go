@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"buf.build/go/protovalidate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// Mimics the errors generated by protoc-gen-validate
+type fakePGVError struct {
+	field  string
+	reason string
+	cause  error
+}
+
+func (e fakePGVError) Field() string  { return e.field }
+func (e fakePGVError) Reason() string { return e.reason }
+func (e fakePGVError) Cause() error   { return e.cause }
+func (e fakePGVError) Error() string  { return "invalid " + e.field + ": " + e.reason }
+
+type fakePGVMultiError []error
+
+func (m fakePGVMultiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, e := range m {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (m fakePGVMultiError) AllErrors() []error { return m }
+
+func protovalidateViolation(message string, elements ...*validate.FieldPathElement) *protovalidate.Violation {
+	return &protovalidate.Violation{
+		Proto: &validate.Violation{
+			Field:   &validate.FieldPath{Elements: elements},
+			Message: proto.String(message),
+		},
+	}
+}
+
+func TestFieldViolations(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected []*errdetails.BadRequest_FieldViolation
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "not a validation error",
+			err:  fmt.Errorf("boom"),
+		},
+		{
+			name: "protovalidate",
+			err: fmt.Errorf("wrapped: %w", &protovalidate.ValidationError{Violations: []*protovalidate.Violation{
+				protovalidateViolation("value is required",
+					&validate.FieldPathElement{FieldName: proto.String("name")},
+				),
+				protovalidateViolation("value must be a valid UUID",
+					&validate.FieldPathElement{FieldName: proto.String("items"), Subscript: &validate.FieldPathElement_Index{Index: 0}},
+					&validate.FieldPathElement{FieldName: proto.String("id")},
+				),
+				protovalidateViolation("value must not be empty",
+					&validate.FieldPathElement{FieldName: proto.String("labels"), Subscript: &validate.FieldPathElement_StringKey{StringKey: "env"}},
+				),
+				protovalidateViolation("value must be greater than 0",
+					&validate.FieldPathElement{FieldName: proto.String("limits"), Subscript: &validate.FieldPathElement_IntKey{IntKey: -1}},
+				),
+			}}),
+			expected: []*errdetails.BadRequest_FieldViolation{
+				{Field: "name", Description: "value is required"},
+				{Field: "items[0].id", Description: "value must be a valid UUID"},
+				{Field: `labels["env"]`, Description: "value must not be empty"},
+				{Field: "limits[-1]", Description: "value must be greater than 0"},
+			},
+		},
+		{
+			name: "pgv single error",
+			err:  fakePGVError{field: "Name", reason: "value length must be at least 1 runes"},
+			expected: []*errdetails.BadRequest_FieldViolation{
+				{Field: "Name", Description: "value length must be at least 1 runes"},
+			},
+		},
+		{
+			name: "pgv multi error with embedded message",
+			err: fakePGVMultiError{
+				fakePGVError{field: "Name", reason: "value is required"},
+				fakePGVError{field: "Options", reason: "embedded message failed validation", cause: fakePGVMultiError{
+					fakePGVError{field: "Ttl", reason: "value must be greater than 0"},
+				}},
+			},
+			expected: []*errdetails.BadRequest_FieldViolation{
+				{Field: "Name", Description: "value is required"},
+				{Field: "Options.Ttl", Description: "value must be greater than 0"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FieldViolations(tt.err))
+		})
+	}
+}
+
+func TestWithValidationError(t *testing.T) {
+	verr := fakePGVError{field: "Name", reason: "value is required"}
+	kitErr := New(verr, nil,
+		WithErrorReason("INVALID_REQUEST", codes.InvalidArgument),
+		WithValidationError(verr),
+	)
+
+	details := kitErr.GRPCStatus().Details()
+	require.Len(t, details, 2)
+	badRequest, ok := details[1].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, badRequest.GetFieldViolations(), 1)
+	assert.Equal(t, "Name", badRequest.GetFieldViolations()[0].GetField())
+
+	// No detail is added if there are no violations
+	kitErr = New(fmt.Errorf("boom"), nil, WithValidationError(fmt.Errorf("boom")))
+	assert.Len(t, kitErr.GRPCStatus().Details(), 1)
+}
+
+func TestWithPreconditionFailure(t *testing.T) {
+	kitErr := New(fmt.Errorf("not supported"), nil,
+		WithErrorReason("UNSUPPORTED", codes.Unimplemented),
+		WithPreconditionFailure(&errdetails.PreconditionFailure_Violation{
+			Type:    "CAPABILITY",
+			Subject: "TTL",
+		}),
+	)
+
+	details := kitErr.GRPCStatus().Details()
+	require.Len(t, details, 2)
+	pf, ok := details[1].(*errdetails.PreconditionFailure)
+	require.True(t, ok)
+	require.Len(t, pf.GetViolations(), 1)
+	assert.Equal(t, "TTL", pf.GetViolations()[0].GetSubject())
+
+	// No detail is added if there are no violations
+	kitErr = New(fmt.Errorf("boom"), nil, WithPreconditionFailure())
+	assert.Len(t, kitErr.GRPCStatus().Details(), 1)
+}
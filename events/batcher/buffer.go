@@ -0,0 +1,323 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// ErrBufferClosed is returned when adding items to a Buffer that is closed.
+var ErrBufferClosed = errors.New("buffer is closed")
+
+// Sink receives the batches of items flushed by a Buffer.
+// If Flush returns an error, the items remain in the buffer and are included in the next flush.
+type Sink[I any] interface {
+	Flush(ctx context.Context, batch []I) error
+}
+
+// SinkFunc is a function that implements Sink.
+type SinkFunc[I any] func(ctx context.Context, batch []I) error
+
+// Flush implements Sink.
+func (f SinkFunc[I]) Flush(ctx context.Context, batch []I) error {
+	return f(ctx, batch)
+}
+
+// TransactionalSink is a Sink that receives batches in two phases, for example to write them to a transactional
+// outbox. The sink first receives the batch with Prepare; only if that succeeds, Commit is invoked.
+// Items are dropped from the buffer only after Commit returns successfully; if either phase fails, the items remain
+// in the buffer and are included in the next flush.
+type TransactionalSink[I any] interface {
+	// Prepare is invoked with the batch before it's committed.
+	Prepare(ctx context.Context, batch []I) error
+	// Commit is invoked after Prepare succeeds.
+	Commit(ctx context.Context) error
+}
+
+// BufferOptions contains the options for a Buffer.
+type BufferOptions struct {
+	// Interval after which buffered items are flushed, counted from when the first item is added to an empty buffer.
+	// If 0, items are flushed only when MaxSize is reached or when Flush is invoked.
+	Interval time.Duration
+	// MaxSize is the number of buffered items that triggers a flush. If 0, there's no limit.
+	MaxSize int
+	// OnError is invoked with errors returned by flushes that are triggered in background. Optional.
+	OnError func(err error)
+}
+
+// Buffer accumulates items and flushes them to a Sink or TransactionalSink in batches.
+type Buffer[I any] struct {
+	flushFn  func(ctx context.Context, batch []I) error
+	opts     BufferOptions
+	clock    clock.WithDelayedExecution
+	keyFn    func(item I) string
+	items    []I
+	inFlight int
+	// Keys of the items in batches that are being flushed, if keyFn is set
+	inFlightKeys map[string]struct{}
+	timer        clock.Timer
+	lock         sync.Mutex
+	flushSem     chan struct{}
+	// Number of background flush goroutines, which is at most the size of flushSem
+	bgFlushes int
+	// flushPending is set when a background flush is requested while all background goroutines are busy
+	flushPending bool
+	wg           sync.WaitGroup
+	closed       atomic.Bool
+}
+
+// NewBuffer returns a new Buffer that flushes items to sink.
+func NewBuffer[I any](sink Sink[I], opts BufferOptions) *Buffer[I] {
+	return &Buffer[I]{
+		flushFn:  sink.Flush,
+		opts:     opts,
+		clock:    clock.RealClock{},
+		flushSem: make(chan struct{}, 1),
+	}
+}
+
+// NewTransactionalBuffer returns a new Buffer that flushes items to sink in two phases.
+func NewTransactionalBuffer[I any](sink TransactionalSink[I], opts BufferOptions) *Buffer[I] {
+	return &Buffer[I]{
+		flushFn: func(ctx context.Context, batch []I) error {
+			err := sink.Prepare(ctx, batch)
+			if err != nil {
+				return err
+			}
+			return sink.Commit(ctx)
+		},
+		opts:     opts,
+		clock:    clock.RealClock{},
+		flushSem: make(chan struct{}, 1),
+	}
+}
+
+// WithClock sets the clock used by the buffer. Used for testing.
+func (b *Buffer[I]) WithClock(clock clock.WithDelayedExecution) {
+	b.clock = clock
+}
+
+// WithMaxConcurrentFlushes sets the maximum number of batches that can be flushed in parallel. Default is 1.
+// This can improve throughput when the sink benefits from pipelining, for example with a message broker.
+// This must be invoked before the buffer is used.
+func (b *Buffer[I]) WithMaxConcurrentFlushes(n int) {
+	if n < 1 {
+		n = 1
+	}
+	b.flushSem = make(chan struct{}, n)
+}
+
+// WithOrderingKey preserves the order of items with the same key when batches are flushed in parallel: items are not
+// included in a batch while another batch with an item with the same key is being flushed.
+// This must be invoked before the buffer is used.
+func (b *Buffer[I]) WithOrderingKey(keyFn func(item I) string) {
+	b.keyFn = keyFn
+	b.inFlightKeys = map[string]struct{}{}
+}
+
+// Add items to the buffer.
+func (b *Buffer[I]) Add(items ...I) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed.Load() {
+		return ErrBufferClosed
+	}
+
+	b.items = append(b.items, items...)
+
+	if b.opts.MaxSize > 0 && len(b.items) >= b.opts.MaxSize {
+		b.flushInBackground()
+	} else {
+		b.startTimer()
+	}
+
+	return nil
+}
+
+// Len returns the number of items in the buffer, including those that are being flushed.
+func (b *Buffer[I]) Len() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.items) + b.inFlight
+}
+
+// Flush sends the buffered items to the sink.
+// Up to the number of flushes set with WithMaxConcurrentFlushes run at a time (by default, only one); items added
+// while a flush is in progress are included in the next one. If an ordering key is set, items whose key is in a
+// batch that is being flushed are also left for the next flush.
+func (b *Buffer[I]) Flush(ctx context.Context) error {
+	select {
+	case b.flushSem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() {
+		<-b.flushSem
+	}()
+
+	b.lock.Lock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch, keys := b.takeBatch()
+	b.lock.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	err := b.flushFn(ctx, batch)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.inFlight -= len(batch)
+	for _, k := range keys {
+		delete(b.inFlightKeys, k)
+	}
+	if err != nil {
+		// Items remain in the buffer, before the ones added in the meanwhile; make sure they are flushed again
+		b.items = append(batch, b.items...)
+		if !b.closed.Load() {
+			b.startTimer()
+		}
+		return err
+	}
+
+	// Flush items that were held back, or that were added in the meanwhile, if needed
+	if !b.closed.Load() {
+		if b.opts.MaxSize > 0 && len(b.items) >= b.opts.MaxSize {
+			b.flushInBackground()
+		} else {
+			b.startTimer()
+		}
+	}
+	return nil
+}
+
+// takeBatch removes the items to flush from the buffer and returns them, together with their keys if an ordering key
+// is set.
+// This must be invoked while the caller has a lock.
+func (b *Buffer[I]) takeBatch() (batch []I, keys []string) {
+	if b.keyFn == nil {
+		batch = b.items
+		b.items = nil
+		b.inFlight += len(batch)
+		return batch, nil
+	}
+
+	var remaining []I
+	batchKeys := map[string]struct{}{}
+	for _, item := range b.items {
+		k := b.keyFn(item)
+		if _, ok := b.inFlightKeys[k]; ok {
+			remaining = append(remaining, item)
+			continue
+		}
+		batch = append(batch, item)
+		if _, ok := batchKeys[k]; !ok {
+			batchKeys[k] = struct{}{}
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range keys {
+		b.inFlightKeys[k] = struct{}{}
+	}
+	b.items = remaining
+	b.inFlight += len(batch)
+	return batch, keys
+}
+
+// startTimer starts the timer for the next flush, if there are items and the timer isn't running already.
+// This must be invoked while the caller has a lock.
+func (b *Buffer[I]) startTimer() {
+	if b.opts.Interval <= 0 || b.timer != nil || len(b.items) == 0 {
+		return
+	}
+	b.timer = b.clock.AfterFunc(b.opts.Interval, func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		b.timer = nil
+		b.flushInBackground()
+	})
+}
+
+// flushInBackground starts a flush in a background goroutine.
+// There are at most as many background goroutines as concurrent flushes: if they're all busy, the flush is recorded
+// as pending, and it's performed by the first goroutine that completes its flush.
+// This must be invoked while the caller has a lock.
+func (b *Buffer[I]) flushInBackground() {
+	if b.closed.Load() {
+		return
+	}
+	if b.bgFlushes >= cap(b.flushSem) {
+		b.flushPending = true
+		return
+	}
+
+	b.bgFlushes++
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			err := b.Flush(context.Background())
+			if err != nil && b.opts.OnError != nil {
+				b.opts.OnError(err)
+			}
+
+			b.lock.Lock()
+			if !b.flushPending || b.closed.Load() {
+				b.bgFlushes--
+				b.lock.Unlock()
+				return
+			}
+			b.flushPending = false
+			b.lock.Unlock()
+		}
+	}()
+}
+
+// Close the buffer, waiting for flushes in progress to complete, and then flushes the remaining items.
+// After this call, adding items returns ErrBufferClosed.
+func (b *Buffer[I]) Close(ctx context.Context) error {
+	b.lock.Lock()
+	if !b.closed.CompareAndSwap(false, true) {
+		b.lock.Unlock()
+		return nil
+	}
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.lock.Unlock()
+
+	b.wg.Wait()
+
+	// Wait for flushes invoked by callers of Flush to complete too, so all items can be included in the last one
+	for i := 0; i < cap(b.flushSem); i++ {
+		b.flushSem <- struct{}{}
+	}
+	for i := 0; i < cap(b.flushSem); i++ {
+		<-b.flushSem
+	}
+
+	return b.Flush(ctx)
+}
@@ -0,0 +1,362 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+type testSink struct {
+	lock       sync.Mutex
+	batches    [][]int
+	prepared   []int
+	prepareErr error
+	commitErr  error
+}
+
+func (s *testSink) Flush(ctx context.Context, batch []int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.commitErr != nil {
+		return s.commitErr
+	}
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *testSink) Prepare(ctx context.Context, batch []int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.prepareErr != nil {
+		return s.prepareErr
+	}
+	s.prepared = batch
+	return nil
+}
+
+func (s *testSink) Commit(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.commitErr != nil {
+		return s.commitErr
+	}
+	s.batches = append(s.batches, s.prepared)
+	s.prepared = nil
+	return nil
+}
+
+func (s *testSink) getBatches() [][]int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.batches
+}
+
+func TestBuffer(t *testing.T) {
+	t.Run("flush on interval", func(t *testing.T) {
+		sink := &testSink{}
+		clock := testingclock.NewFakeClock(time.Now())
+		b := NewBuffer[int](sink, BufferOptions{Interval: time.Second})
+		b.WithClock(clock)
+
+		require.NoError(t, b.Add(1, 2))
+		require.NoError(t, b.Add(3))
+		assert.Equal(t, 3, b.Len())
+		assert.True(t, clock.HasWaiters())
+
+		clock.Step(time.Second)
+		assert.Eventually(t, func() bool {
+			return b.Len() == 0
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, [][]int{{1, 2, 3}}, sink.getBatches())
+		require.NoError(t, b.Close(context.Background()))
+	})
+
+	t.Run("flush on max size", func(t *testing.T) {
+		sink := &testSink{}
+		b := NewBuffer[int](sink, BufferOptions{MaxSize: 2})
+
+		require.NoError(t, b.Add(1))
+		require.NoError(t, b.Add(2))
+		assert.Eventually(t, func() bool {
+			return b.Len() == 0
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, [][]int{{1, 2}}, sink.getBatches())
+		require.NoError(t, b.Close(context.Background()))
+	})
+
+	t.Run("failed flushes keep items", func(t *testing.T) {
+		sink := &testSink{commitErr: errors.New("boom")}
+		b := NewBuffer[int](sink, BufferOptions{})
+
+		require.NoError(t, b.Add(1, 2))
+		require.Error(t, b.Flush(context.Background()))
+		assert.Equal(t, 2, b.Len())
+
+		sink.commitErr = nil
+		require.NoError(t, b.Add(3))
+		require.NoError(t, b.Flush(context.Background()))
+		assert.Equal(t, 0, b.Len())
+		assert.Equal(t, [][]int{{1, 2, 3}}, sink.getBatches())
+	})
+
+	t.Run("close flushes remaining items", func(t *testing.T) {
+		sink := &testSink{}
+		b := NewBuffer[int](sink, BufferOptions{Interval: time.Hour})
+
+		require.NoError(t, b.Add(1))
+		require.NoError(t, b.Close(context.Background()))
+		assert.Equal(t, [][]int{{1}}, sink.getBatches())
+		require.ErrorIs(t, b.Add(2), ErrBufferClosed)
+		require.NoError(t, b.Close(context.Background()))
+	})
+}
+
+func TestTransactionalBuffer(t *testing.T) {
+	t.Run("commit drops items", func(t *testing.T) {
+		sink := &testSink{}
+		b := NewTransactionalBuffer[int](sink, BufferOptions{})
+
+		require.NoError(t, b.Add(1, 2))
+		require.NoError(t, b.Flush(context.Background()))
+		assert.Equal(t, 0, b.Len())
+		assert.Equal(t, [][]int{{1, 2}}, sink.getBatches())
+	})
+
+	t.Run("failed prepare keeps items", func(t *testing.T) {
+		sink := &testSink{prepareErr: errors.New("boom")}
+		b := NewTransactionalBuffer[int](sink, BufferOptions{})
+
+		require.NoError(t, b.Add(1, 2))
+		require.Error(t, b.Flush(context.Background()))
+		assert.Equal(t, 2, b.Len())
+		assert.Empty(t, sink.getBatches())
+	})
+
+	t.Run("failed commit keeps items", func(t *testing.T) {
+		sink := &testSink{commitErr: errors.New("boom")}
+		b := NewTransactionalBuffer[int](sink, BufferOptions{})
+
+		require.NoError(t, b.Add(1, 2))
+		require.Error(t, b.Flush(context.Background()))
+		assert.Equal(t, 2, b.Len())
+
+		sink.commitErr = nil
+		require.NoError(t, b.Flush(context.Background()))
+		assert.Equal(t, 0, b.Len())
+		assert.Equal(t, [][]int{{1, 2}}, sink.getBatches())
+	})
+
+	t.Run("background errors are reported", func(t *testing.T) {
+		sink := &testSink{prepareErr: errors.New("boom")}
+		errCh := make(chan error, 1)
+		clock := testingclock.NewFakeClock(time.Now())
+		b := NewTransactionalBuffer[int](sink, BufferOptions{
+			Interval: time.Second,
+			OnError: func(err error) {
+				errCh <- err
+			},
+		})
+		b.WithClock(clock)
+
+		require.NoError(t, b.Add(1))
+		clock.Step(time.Second)
+		select {
+		case err := <-errCh:
+			require.EqualError(t, err, "boom")
+		case <-time.After(time.Second):
+			t.Fatal("did not receive error")
+		}
+
+		// The flush is retried after another interval
+		sink.lock.Lock()
+		sink.prepareErr = nil
+		sink.lock.Unlock()
+		assert.Eventually(t, clock.HasWaiters, time.Second, 10*time.Millisecond)
+		clock.Step(time.Second)
+		assert.Eventually(t, func() bool {
+			return b.Len() == 0
+		}, time.Second, 10*time.Millisecond)
+		require.NoError(t, b.Close(context.Background()))
+	})
+}
+
+// blockingSink is a sink whose flushes block until they are released.
+type blockingSink struct {
+	lock      sync.Mutex
+	batches   [][]int
+	active    int
+	peak      int
+	releaseCh chan struct{}
+}
+
+func (s *blockingSink) Flush(ctx context.Context, batch []int) error {
+	s.lock.Lock()
+	s.active++
+	if s.active > s.peak {
+		s.peak = s.active
+	}
+	s.batches = append(s.batches, batch)
+	s.lock.Unlock()
+
+	<-s.releaseCh
+
+	s.lock.Lock()
+	s.active--
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *blockingSink) stats() (batches [][]int, active int, peak int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([][]int(nil), s.batches...), s.active, s.peak
+}
+
+func TestBufferConcurrentFlushes(t *testing.T) {
+	t.Run("flushes run in parallel up to the limit", func(t *testing.T) {
+		sink := &blockingSink{releaseCh: make(chan struct{})}
+		b := NewBuffer[int](sink, BufferOptions{MaxSize: 1})
+		b.WithMaxConcurrentFlushes(2)
+
+		for i := 0; i < 2; i++ {
+			require.NoError(t, b.Add(i))
+			require.Eventually(t, func() bool {
+				_, active, _ := sink.stats()
+				return active == i+1
+			}, time.Second, 5*time.Millisecond)
+		}
+		// These are held back until a flush completes
+		require.NoError(t, b.Add(2, 3))
+		_, active, _ := sink.stats()
+		assert.Equal(t, 2, active)
+		// Items being flushed are still counted
+		assert.Equal(t, 4, b.Len())
+
+		close(sink.releaseCh)
+		require.NoError(t, b.Close(context.Background()))
+
+		batches, _, peak := sink.stats()
+		assert.Equal(t, 2, peak)
+		var all []int
+		for _, batch := range batches {
+			all = append(all, batch...)
+		}
+		assert.ElementsMatch(t, []int{0, 1, 2, 3}, all)
+		assert.Equal(t, 0, b.Len())
+	})
+
+	t.Run("background flushes are bounded while the sink is busy", func(t *testing.T) {
+		sink := &blockingSink{releaseCh: make(chan struct{})}
+		b := NewBuffer[int](sink, BufferOptions{MaxSize: 1})
+		b.WithMaxConcurrentFlushes(2)
+
+		for i := 0; i < 100; i++ {
+			require.NoError(t, b.Add(i))
+		}
+		b.lock.Lock()
+		assert.LessOrEqual(t, b.bgFlushes, 2)
+		b.lock.Unlock()
+
+		close(sink.releaseCh)
+		// All items are flushed by the existing goroutines, which then exit
+		require.Eventually(t, func() bool {
+			b.lock.Lock()
+			defer b.lock.Unlock()
+			return len(b.items) == 0 && b.inFlight == 0 && b.bgFlushes == 0 && !b.flushPending
+		}, time.Second, 5*time.Millisecond)
+
+		require.NoError(t, b.Close(context.Background()))
+		batches, _, peak := sink.stats()
+		assert.LessOrEqual(t, peak, 2)
+		var all []int
+		for _, batch := range batches {
+			all = append(all, batch...)
+		}
+		assert.Len(t, all, 100)
+	})
+
+	t.Run("items with the same key are not flushed in parallel", func(t *testing.T) {
+		sink := &blockingSink{releaseCh: make(chan struct{})}
+		b := NewBuffer[int](sink, BufferOptions{})
+		b.WithMaxConcurrentFlushes(4)
+		// Key is the parity of the number
+		b.WithOrderingKey(func(item int) string {
+			if item%2 == 0 {
+				return "even"
+			}
+			return "odd"
+		})
+
+		require.NoError(t, b.Add(1))
+		go b.Flush(context.Background())
+		require.Eventually(t, func() bool {
+			_, active, _ := sink.stats()
+			return active == 1
+		}, time.Second, 5*time.Millisecond)
+
+		// 3 has the same key as the batch being flushed, so only 2 and 4 are flushed
+		require.NoError(t, b.Add(2, 3, 4))
+		go b.Flush(context.Background())
+		require.Eventually(t, func() bool {
+			_, active, _ := sink.stats()
+			return active == 2
+		}, time.Second, 5*time.Millisecond)
+
+		batches, _, _ := sink.stats()
+		assert.Equal(t, [][]int{{1}, {2, 4}}, batches)
+		assert.Equal(t, 4, b.Len())
+
+		close(sink.releaseCh)
+		require.NoError(t, b.Close(context.Background()))
+		batches, _, _ = sink.stats()
+		assert.Equal(t, [][]int{{1}, {2, 4}, {3}}, batches)
+	})
+
+	t.Run("failed batches are retried before newer items", func(t *testing.T) {
+		var (
+			lock    sync.Mutex
+			fail    = true
+			batches [][]int
+		)
+		b := NewBuffer[int](SinkFunc[int](func(ctx context.Context, batch []int) error {
+			lock.Lock()
+			defer lock.Unlock()
+			if fail {
+				return errors.New("simulated")
+			}
+			batches = append(batches, batch)
+			return nil
+		}), BufferOptions{})
+		b.WithMaxConcurrentFlushes(2)
+
+		require.NoError(t, b.Add(1, 2))
+		require.Error(t, b.Flush(context.Background()))
+		require.NoError(t, b.Add(3))
+		assert.Equal(t, 3, b.Len())
+
+		lock.Lock()
+		fail = false
+		lock.Unlock()
+		require.NoError(t, b.Flush(context.Background()))
+		assert.Equal(t, [][]int{{1, 2, 3}}, batches)
+	})
+}
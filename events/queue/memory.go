@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"errors"
+)
+
+// ErrMemoryLimitExceeded is returned by Enqueue when adding the item would exceed the memory limit of the processor
+// and the overflow policy is OverflowReject.
+var ErrMemoryLimitExceeded = errors.New("queue memory limit exceeded")
+
+// Sizer is implemented by items that can estimate how much memory they retain, in bytes.
+// The estimate is used to report the approximate memory used by a Processor, and to enforce its memory limit.
+// Items that don't implement Sizer have a size of 0, unless a size function is set with WithSizeFn.
+type Sizer interface {
+	SizeBytes() int64
+}
+
+// OverflowPolicy determines what happens when an item that would exceed the memory limit is enqueued.
+type OverflowPolicy int
+
+const (
+	// OverflowReject rejects the item, and Enqueue returns ErrMemoryLimitExceeded.
+	OverflowReject OverflowPolicy = iota
+	// OverflowAccept accepts the item anyways; the OnOverflow callback is still invoked, as an early warning.
+	OverflowAccept
+)
+
+// MemoryLimit is the memory limit of a Processor.
+type MemoryLimit struct {
+	// MaxBytes is the maximum estimated size of all items in the queue. If 0, there's no limit.
+	MaxBytes int64
+	// Policy is what happens when enqueuing an item would exceed the limit.
+	Policy OverflowPolicy
+	// OnOverflow is invoked, while the processor is locked, every time enqueuing an item would exceed the limit, with
+	// the size of the queue including the new item. Optional.
+	OnOverflow func(bytes int64, maxBytes int64)
+}
+
+// WithSizeFn sets the function used to estimate the size of items, in bytes, which replaces Sizer.
+func (p *Processor[T]) WithSizeFn(sizeFn func(r T) int64) *Processor[T] {
+	p.lock.Lock()
+	p.queue.sizeFn = sizeFn
+	p.lock.Unlock()
+	return p
+}
+
+// SetMemoryLimit sets the memory limit of the processor.
+// Items that are already in the queue are not removed if they exceed the new limit.
+func (p *Processor[T]) SetMemoryLimit(limit MemoryLimit) {
+	p.lock.Lock()
+	p.memoryLimit = limit
+	p.lock.Unlock()
+}
+
+// Bytes returns the estimated size of all items in the queue, in bytes.
+func (p *Processor[T]) Bytes() int64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.queue.Bytes()
+}
+
+// checkMemoryLimit returns an error if enqueuing r would exceed the memory limit and the policy is OverflowReject.
+// This must be invoked while the caller has a lock.
+func (p *Processor[T]) checkMemoryLimit(r T) error {
+	limit := p.memoryLimit
+	if limit.MaxBytes <= 0 {
+		return nil
+	}
+
+	projected := p.queue.Bytes() + p.queue.itemSize(r)
+	if existing, ok := p.queue.SizeOf(r.Key()); ok {
+		projected -= existing
+	}
+	if projected <= limit.MaxBytes {
+		return nil
+	}
+
+	if limit.OnOverflow != nil {
+		limit.OnOverflow(projected, limit.MaxBytes)
+	}
+	if limit.Policy == OverflowReject {
+		return ErrMemoryLimitExceeded
+	}
+	return nil
+}
+
+// sizerSize returns the size of items that implement Sizer, and 0 for the others.
+func sizerSize[T queueable](r T) int64 {
+	s, ok := any(r).(Sizer)
+	if !ok {
+		return 0
+	}
+	return s.SizeBytes()
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+type sizedItem struct {
+	queueableItem
+	payload []byte
+}
+
+func (r *sizedItem) SizeBytes() int64 {
+	return int64(len(r.payload))
+}
+
+func newSizedItem(name string, dueTime time.Time, size int) *sizedItem {
+	return &sizedItem{
+		queueableItem: queueableItem{Name: name, ExecutionTime: dueTime},
+		payload:       make([]byte, size),
+	}
+}
+
+func TestQueueBytes(t *testing.T) {
+	now := time.Now()
+	q := newQueue[*sizedItem]()
+	q.sizeFn = sizerSize[*sizedItem]
+
+	q.Insert(newSizedItem("a", now, 10), false)
+	q.Insert(newSizedItem("b", now.Add(time.Second), 20), false)
+	q.Insert(newSizedItem("ab", now.Add(2*time.Second), 5), false)
+	assert.Equal(t, int64(35), q.Bytes())
+
+	// Replacing updates the size, but only if replace is true
+	q.Insert(newSizedItem("b", now.Add(time.Second), 100), false)
+	assert.Equal(t, int64(35), q.Bytes())
+	q.Insert(newSizedItem("b", now.Add(time.Second), 1), true)
+	assert.Equal(t, int64(16), q.Bytes())
+	q.Update(newSizedItem("b", now.Add(time.Second), 2))
+	assert.Equal(t, int64(17), q.Bytes())
+
+	size, ok := q.SizeOf("b")
+	require.True(t, ok)
+	assert.Equal(t, int64(2), size)
+
+	q.Pop()
+	assert.Equal(t, int64(7), q.Bytes())
+	q.Remove("b")
+	assert.Equal(t, int64(5), q.Bytes())
+	q.ExtractPrefix("a")
+	assert.Equal(t, int64(0), q.Bytes())
+}
+
+func TestProcessorMemoryLimit(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	newProcessor := func() *Processor[*sizedItem] {
+		p := NewProcessor(func(r *sizedItem) {}).WithClock(clock)
+		t.Cleanup(func() {
+			p.Close()
+		})
+		return p
+	}
+	future := clock.Now().Add(time.Hour)
+
+	t.Run("reject", func(t *testing.T) {
+		p := newProcessor()
+		var overflows []int64
+		p.SetMemoryLimit(MemoryLimit{
+			MaxBytes: 100,
+			Policy:   OverflowReject,
+			OnOverflow: func(bytes int64, maxBytes int64) {
+				assert.Equal(t, int64(100), maxBytes)
+				overflows = append(overflows, bytes)
+			},
+		})
+
+		require.NoError(t, p.Enqueue(newSizedItem("a", future, 60)))
+		require.NoError(t, p.Enqueue(newSizedItem("b", future, 40)))
+		require.ErrorIs(t, p.Enqueue(newSizedItem("c", future, 1)), ErrMemoryLimitExceeded)
+		assert.Equal(t, []int64{101}, overflows)
+		assert.Equal(t, int64(100), p.Bytes())
+
+		// Replacing an item only counts the difference
+		require.NoError(t, p.Enqueue(newSizedItem("a", future, 50)))
+		require.NoError(t, p.Enqueue(newSizedItem("c", future, 10)))
+		assert.Equal(t, int64(100), p.Bytes())
+
+		stats := p.Stats()
+		assert.Equal(t, int64(100), stats.Bytes)
+		assert.Equal(t, int64(100), stats.MaxBytes)
+	})
+
+	t.Run("accept", func(t *testing.T) {
+		p := newProcessor()
+		var overflows int
+		p.SetMemoryLimit(MemoryLimit{
+			MaxBytes: 10,
+			Policy:   OverflowAccept,
+			OnOverflow: func(int64, int64) {
+				overflows++
+			},
+		})
+
+		require.NoError(t, p.Enqueue(newSizedItem("a", future, 20)))
+		require.NoError(t, p.Enqueue(newSizedItem("b", future, 20)))
+		assert.Equal(t, 2, overflows)
+		assert.Equal(t, int64(40), p.Bytes())
+	})
+
+	t.Run("custom size function", func(t *testing.T) {
+		p := newProcessor().WithSizeFn(func(r *sizedItem) int64 {
+			return 1
+		})
+		require.NoError(t, p.Enqueue(newSizedItem("a", future, 20)))
+		require.NoError(t, p.Enqueue(newSizedItem("b", future, 20)))
+		assert.Equal(t, int64(2), p.Bytes())
+
+		require.NoError(t, p.Dequeue("a"))
+		assert.Equal(t, int64(1), p.Bytes())
+	})
+
+	t.Run("executed items are released", func(t *testing.T) {
+		executed := make(chan string, 1)
+		p := NewProcessor(func(r *sizedItem) {
+			executed <- r.Name
+		}).WithClock(clock)
+		defer p.Close()
+
+		require.NoError(t, p.Enqueue(newSizedItem("a", clock.Now(), 20)))
+		select {
+		case <-executed:
+		case <-time.After(time.Second):
+			t.Fatal("item was not executed")
+		}
+		assert.Equal(t, int64(0), p.Bytes())
+	})
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultStoreBatchSize is the default number of pending operations that triggers a flush to the Store.
+	DefaultStoreBatchSize = 100
+	// DefaultStoreFlushInterval is the default maximum time operations are kept pending before they're flushed.
+	DefaultStoreFlushInterval = 100 * time.Millisecond
+)
+
+// StoreOpKind is the kind of a StoreOp.
+type StoreOpKind int
+
+const (
+	// StoreOpSave saves the item, replacing the one with the same key if present.
+	StoreOpSave StoreOpKind = iota
+	// StoreOpDelete deletes the item with the key.
+	StoreOpDelete
+)
+
+// StoreOp is an operation on a Store.
+type StoreOp[T queueable] struct {
+	Kind StoreOpKind
+	Key  string
+	// Item is set for StoreOpSave operations only.
+	Item T
+}
+
+// Store persists the items of a Processor, so they can be re-enqueued after a restart.
+type Store[T queueable] interface {
+	// Write applies a batch of operations.
+	// Batches contain at most one operation per key, and they're never written concurrently.
+	// If Write returns an error, the operations in the batch are retried with the next batch, unless they're
+	// superseded by newer operations on the same keys.
+	Write(ops []StoreOp[T]) error
+}
+
+// StoreOptions contains the options for the persistence of a Processor.
+type StoreOptions struct {
+	// BatchSize is the number of pending operations that triggers a flush. Default is DefaultStoreBatchSize.
+	BatchSize int
+	// FlushInterval is the maximum time operations are kept pending before they're flushed. Default is
+	// DefaultStoreFlushInterval.
+	FlushInterval time.Duration
+	// OnError is invoked when flushing in the background fails. Optional.
+	OnError func(err error)
+}
+
+// WithStore persists the items of the processor in store, using write-behind batching: enqueued items are saved, and
+// items that are dequeued, extracted, or whose execution completed are deleted, in batches that are flushed when they
+// reach BatchSize operations, every FlushInterval, and when the processor is closed.
+// Operations on the same key are coalesced, so only the last state of each item is written.
+//
+// Flushes happen in a background goroutine that runs until the processor is closed, so a processor with a Store must
+// always be stopped with Close, or the goroutine is leaked. Close waits for the executions that are in progress, so it
+// must not be invoked by executeFn synchronously.
+//
+// Because writes are deferred, if the process crashes, operations that weren't flushed yet are lost: the Store can
+// be missing recently-enqueued items, and it can still contain items that were already executed, which would be
+// executed again after being reloaded. Callers that rely on the Store must tolerate at-least-once execution, and
+// use Flush where stronger guarantees are required.
+//
+// This must be invoked right after creating the processor and after WithClock, before any item is enqueued.
+func (p *Processor[T]) WithStore(store Store[T], opts StoreOptions) *Processor[T] {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultStoreBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultStoreFlushInterval
+	}
+
+	w := &writeBehind[T]{
+		store:   store,
+		opts:    opts,
+		pending: map[string]int{},
+		flushCh: make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	p.lock.Lock()
+	p.persist = w
+	p.lock.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.flushLoop(w)
+	}()
+	return p
+}
+
+// Flush writes all pending operations to the Store. It's a nop if no Store is configured.
+func (p *Processor[T]) Flush() error {
+	p.lock.Lock()
+	w := p.persist
+	p.lock.Unlock()
+	if w == nil {
+		return nil
+	}
+	return w.flush()
+}
+
+// writeBehind batches the operations for a Store.
+type writeBehind[T queueable] struct {
+	store Store[T]
+	opts  StoreOptions
+
+	lock    sync.Mutex
+	ops     []StoreOp[T]
+	pending map[string]int // Index in ops of the pending operation for each key
+	// Serializes writes to the Store
+	flushLock sync.Mutex
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+// persistSave records that the item must be saved.
+// This must be invoked while the caller has a lock.
+func (p *Processor[T]) persistSave(r T) {
+	if p.persist != nil {
+		p.persist.add(StoreOp[T]{Kind: StoreOpSave, Key: r.Key(), Item: r})
+	}
+}
+
+// persistDelete records that the item with the key must be deleted.
+// This must be invoked while the caller has a lock.
+func (p *Processor[T]) persistDelete(key string) {
+	if p.persist != nil {
+		p.persist.add(StoreOp[T]{Kind: StoreOpDelete, Key: key})
+	}
+}
+
+func (w *writeBehind[T]) add(op StoreOp[T]) {
+	w.lock.Lock()
+	if i, ok := w.pending[op.Key]; ok {
+		w.ops[i] = op
+	} else {
+		w.pending[op.Key] = len(w.ops)
+		w.ops = append(w.ops, op)
+	}
+	full := len(w.ops) >= w.opts.BatchSize
+	w.lock.Unlock()
+
+	if full {
+		select {
+		case w.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *writeBehind[T]) flush() error {
+	w.flushLock.Lock()
+	defer w.flushLock.Unlock()
+
+	w.lock.Lock()
+	ops := w.ops
+	w.ops = nil
+	w.pending = map[string]int{}
+	w.lock.Unlock()
+	if len(ops) == 0 {
+		return nil
+	}
+
+	err := w.store.Write(ops)
+	if err != nil {
+		// Put back the operations that were not superseded in the meanwhile, ahead of the new ones
+		w.lock.Lock()
+		newOps := w.ops
+		w.ops = make([]StoreOp[T], 0, len(ops)+len(newOps))
+		for _, op := range ops {
+			if _, ok := w.pending[op.Key]; !ok {
+				w.ops = append(w.ops, op)
+			}
+		}
+		w.ops = append(w.ops, newOps...)
+		w.pending = make(map[string]int, len(w.ops))
+		for i, op := range w.ops {
+			w.pending[op.Key] = i
+		}
+		w.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// flushLoop flushes the pending operations in the background, until the write-behind is stopped.
+func (p *Processor[T]) flushLoop(w *writeBehind[T]) {
+	defer close(w.doneCh)
+
+	t := p.clock.NewTimer(w.opts.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.flushCh:
+		case <-t.C():
+		case <-w.stopCh:
+			return
+		}
+
+		err := w.flush()
+		if err != nil && w.opts.OnError != nil {
+			w.opts.OnError(err)
+		}
+
+		if !t.Stop() {
+			select {
+			case <-t.C():
+			default:
+			}
+		}
+		t.Reset(w.opts.FlushInterval)
+	}
+}
+
+// close stops the background flushes and writes the pending operations.
+func (w *writeBehind[T]) close() error {
+	close(w.stopCh)
+	<-w.doneCh
+	err := w.flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush pending operations to the store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+type memStore struct {
+	lock    sync.Mutex
+	items   map[string]*queueableItem
+	batches [][]StoreOp[*queueableItem]
+	fail    bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{items: map[string]*queueableItem{}}
+}
+
+func (s *memStore) Write(ops []StoreOp[*queueableItem]) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.fail {
+		return errors.New("store unavailable")
+	}
+	s.batches = append(s.batches, ops)
+	for _, op := range ops {
+		switch op.Kind {
+		case StoreOpSave:
+			s.items[op.Key] = op.Item
+		case StoreOpDelete:
+			delete(s.items, op.Key)
+		}
+	}
+	return nil
+}
+
+func (s *memStore) state() (keys []string, batches int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for k := range s.items {
+		keys = append(keys, k)
+	}
+	return keys, len(s.batches)
+}
+
+func (s *memStore) setFail(fail bool) {
+	s.lock.Lock()
+	s.fail = fail
+	s.lock.Unlock()
+}
+
+func TestProcessorStore(t *testing.T) {
+	newProcessor := func(store *memStore, opts StoreOptions) (*Processor[*queueableItem], *clocktesting.FakeClock, chan *queueableItem) {
+		clock := clocktesting.NewFakeClock(time.Now())
+		executeCh := make(chan *queueableItem, 10)
+		p := NewProcessor(func(r *queueableItem) {
+			executeCh <- r
+		}).WithClock(clock).WithStore(store, opts)
+		return p, clock, executeCh
+	}
+
+	t.Run("flush when the batch is full", func(t *testing.T) {
+		store := newMemStore()
+		p, clock, _ := newProcessor(store, StoreOptions{BatchSize: 3, FlushInterval: time.Hour})
+		defer p.Close()
+
+		for i := 1; i <= 3; i++ {
+			require.NoError(t, p.Enqueue(newTestItem(i, clock.Now().Add(time.Hour))))
+		}
+		assert.Eventually(t, func() bool {
+			keys, batches := store.state()
+			return len(keys) == 3 && batches == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("flush on interval", func(t *testing.T) {
+		store := newMemStore()
+		p, clock, _ := newProcessor(store, StoreOptions{FlushInterval: time.Second})
+		defer p.Close()
+
+		require.NoError(t, p.Enqueue(newTestItem(1, clock.Now().Add(time.Hour))))
+		keys, _ := store.state()
+		assert.Empty(t, keys)
+
+		assert.Eventually(t, clock.HasWaiters, time.Second, 10*time.Millisecond)
+		clock.Step(time.Second)
+		assert.Eventually(t, func() bool {
+			keys, _ := store.state()
+			return len(keys) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("operations on the same key are coalesced", func(t *testing.T) {
+		store := newMemStore()
+		p, clock, _ := newProcessor(store, StoreOptions{FlushInterval: time.Hour})
+		defer p.Close()
+
+		require.NoError(t, p.Enqueue(newTestItem(1, clock.Now().Add(time.Hour))))
+		require.NoError(t, p.Enqueue(newTestItem(1, clock.Now().Add(2*time.Hour))))
+		require.NoError(t, p.Enqueue(newTestItem(2, clock.Now().Add(time.Hour))))
+		require.NoError(t, p.Dequeue("2"))
+		// Dequeueing items that are not in the queue is not persisted
+		require.NoError(t, p.Dequeue("3"))
+		require.NoError(t, p.Flush())
+
+		require.Len(t, store.batches, 1)
+		batch := store.batches[0]
+		require.Len(t, batch, 2)
+		assert.Equal(t, StoreOpSave, batch[0].Kind)
+		assert.Equal(t, clock.Now().Add(2*time.Hour), batch[0].Item.ExecutionTime)
+		assert.Equal(t, StoreOp[*queueableItem]{Kind: StoreOpDelete, Key: "2"}, batch[1])
+	})
+
+	t.Run("failed batches are retried", func(t *testing.T) {
+		store := newMemStore()
+		p, clock, _ := newProcessor(store, StoreOptions{FlushInterval: time.Hour})
+		defer p.Close()
+
+		store.setFail(true)
+		require.NoError(t, p.Enqueue(newTestItem(1, clock.Now().Add(time.Hour))))
+		require.NoError(t, p.Enqueue(newTestItem(2, clock.Now().Add(time.Hour))))
+		require.Error(t, p.Flush())
+
+		// The newer operation on "2" supersedes the failed one
+		require.NoError(t, p.Dequeue("2"))
+		store.setFail(false)
+		require.NoError(t, p.Flush())
+		keys, batches := store.state()
+		assert.Equal(t, []string{"1"}, keys)
+		assert.Equal(t, 1, batches)
+		assert.Len(t, store.batches[0], 2)
+	})
+
+	t.Run("executed and extracted items are deleted, and Close flushes", func(t *testing.T) {
+		store := newMemStore()
+		p, clock, executeCh := newProcessor(store, StoreOptions{FlushInterval: time.Hour})
+
+		require.NoError(t, p.Enqueue(newTestItem(1, clock.Now())))
+		require.NoError(t, p.Enqueue(newTestItem(2, clock.Now().Add(time.Hour))))
+		require.NoError(t, p.Enqueue(newTestItem(3, clock.Now().Add(time.Hour))))
+		require.NoError(t, p.Flush())
+		keys, _ := store.state()
+		assert.Len(t, keys, 3)
+
+		select {
+		case r := <-executeCh:
+			assert.Equal(t, "1", r.Name)
+		case <-time.After(time.Second):
+			t.Fatal("item was not executed")
+		}
+		assert.Len(t, p.ExtractPrefix("2"), 1)
+
+		require.NoError(t, p.Close())
+		keys, _ = store.state()
+		assert.Equal(t, []string{"3"}, keys)
+	})
+
+	t.Run("items are deleted after their execution completes", func(t *testing.T) {
+		store := newMemStore()
+		clock := clocktesting.NewFakeClock(time.Now())
+		startedCh := make(chan string, 2)
+		releaseCh := make(chan struct{})
+		doneCh := make(chan struct{}, 2)
+		var p *Processor[*queueableItem]
+		p = NewProcessor(func(r *queueableItem) {
+			startedCh <- r.Name
+			<-releaseCh
+			if r.Name == "2" {
+				// Re-enqueueing the item from the callback must keep it in the Store
+				_ = p.Enqueue(newTestItem(2, clock.Now().Add(time.Hour)))
+			}
+			doneCh <- struct{}{}
+		}).WithClock(clock).WithStore(store, StoreOptions{FlushInterval: time.Hour})
+
+		require.NoError(t, p.Enqueue(newTestItem(1, clock.Now())))
+		require.NoError(t, p.Enqueue(newTestItem(2, clock.Now())))
+		for i := 0; i < 2; i++ {
+			select {
+			case <-startedCh:
+			case <-time.After(time.Second):
+				t.Fatal("item was not executed")
+			}
+		}
+
+		// While the callbacks are running, the items are still in the Store
+		require.NoError(t, p.Flush())
+		keys, _ := store.state()
+		assert.ElementsMatch(t, []string{"1", "2"}, keys)
+
+		close(releaseCh)
+		<-doneCh
+		<-doneCh
+		require.NoError(t, p.Close())
+		keys, _ = store.state()
+		assert.Equal(t, []string{"2"}, keys)
+	})
+}
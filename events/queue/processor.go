@@ -15,6 +15,7 @@ package queue
 
 import (
 	"errors"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,23 +28,40 @@ var ErrProcessorStopped = errors.New("processor is stopped")
 
 // Processor manages the queue of items and processes them at the correct time.
 type Processor[T queueable] struct {
-	executeFn          func(r T)
+	executeFn          func(r T) error
 	queue              queue[T]
 	clock              kclock.Clock
 	lock               sync.Mutex
 	wg                 sync.WaitGroup
+	executeWg          sync.WaitGroup
 	processorRunningCh chan struct{}
 	stopCh             chan struct{}
 	resetCh            chan struct{}
 	stopped            atomic.Bool
+	stats              processorStats
+	memoryLimit        MemoryLimit
+	persist            *writeBehind[T]
+	coalesce           time.Duration
+	statsKeys          bool
 }
 
 // NewProcessor returns a new Processor object.
 // executeFn is the callback invoked when the item is to be executed; this will be invoked in a background goroutine.
 func NewProcessor[T queueable](executeFn func(r T)) *Processor[T] {
+	return NewProcessorWithErrors(func(r T) error {
+		executeFn(r)
+		return nil
+	})
+}
+
+// NewProcessorWithErrors returns a new Processor object whose callback can return an error.
+// Errors returned by executeFn are counted as failures in the processor's Stats.
+func NewProcessorWithErrors[T queueable](executeFn func(r T) error) *Processor[T] {
+	q := newQueue[T]()
+	q.sizeFn = sizerSize[T]
 	return &Processor[T]{
 		executeFn:          executeFn,
-		queue:              newQueue[T](),
+		queue:              q,
 		processorRunningCh: make(chan struct{}, 1),
 		stopCh:             make(chan struct{}),
 		resetCh:            make(chan struct{}, 1),
@@ -57,6 +75,39 @@ func (p *Processor[T]) WithClock(clock kclock.Clock) *Processor[T] {
 	return p
 }
 
+// WithStrictFIFO makes items that are scheduled at the same time be dequeued in the order they were enqueued.
+// Without it, the order of items with identical scheduled times is not deterministic.
+// Note that executeFn is invoked in a separate goroutine for each item, so executions can still overlap.
+// Replacing an item that is already in the queue keeps its original position among items scheduled at the same time.
+// This should be invoked right after creating the processor, before any item is enqueued.
+func (p *Processor[T]) WithStrictFIFO() *Processor[T] {
+	p.lock.Lock()
+	p.queue.fifo = true
+	p.lock.Unlock()
+	return p
+}
+
+// WithCoalescing makes the processor wake up at most once per window of the given granularity, executing all the
+// items that are due in the window together. Wake-up times are rounded up to the end of the window, so items are
+// never executed early, but they can be executed up to granularity late.
+// This reduces timer churn for dense schedules, where many items are due within a few milliseconds of each other.
+// This should be invoked right after creating the processor, before any item is enqueued.
+func (p *Processor[T]) WithCoalescing(granularity time.Duration) *Processor[T] {
+	p.lock.Lock()
+	p.coalesce = granularity
+	p.lock.Unlock()
+	return p
+}
+
+// WithStatsKeys makes Stats include the full keys of the next items, instead of redacted ones.
+// Use it only if keys don't contain sensitive data, as stats are meant to be exposed by debug endpoints.
+func (p *Processor[T]) WithStatsKeys() *Processor[T] {
+	p.lock.Lock()
+	p.statsKeys = true
+	p.lock.Unlock()
+	return p
+}
+
 // Enqueue adds a new item to the queue.
 // If a item with the same ID already exists, it'll be replaced.
 func (p *Processor[T]) Enqueue(r T) error {
@@ -67,9 +118,14 @@ func (p *Processor[T]) Enqueue(r T) error {
 	// Insert or replace the item in the queue
 	// If the item added or replaced is the first one in the queue, we need to know that
 	p.lock.Lock()
+	if err := p.checkMemoryLimit(r); err != nil {
+		p.lock.Unlock()
+		return err
+	}
 	peek, ok := p.queue.Peek()
 	isFirst := (ok && peek.Key() == r.Key()) // This is going to be true if the item being replaced is the first one in the queue
 	p.queue.Insert(r, true)
+	p.persistSave(r)
 	peek, _ = p.queue.Peek()         // No need to check for "ok" here because we know this will return an item
 	isFirst = isFirst || (peek == r) // This is also going to be true if the item just added landed at the front of the queue
 	p.process(isFirst)
@@ -87,7 +143,10 @@ func (p *Processor[T]) Dequeue(key string) error {
 	// We need to check if this is the next item in the queue, as that requires stopping the processor
 	p.lock.Lock()
 	peek, ok := p.queue.Peek()
-	p.queue.Remove(key)
+	if _, exists := p.queue.items[key]; exists {
+		p.queue.Remove(key)
+		p.persistDelete(key)
+	}
 	if ok && peek.Key() == key {
 		// If the item was the first one in the queue, restart the processor
 		p.process(true)
@@ -97,8 +156,31 @@ func (p *Processor[T]) Dequeue(key string) error {
 	return nil
 }
 
+// ExtractPrefix atomically removes all items whose key starts with prefix from the queue and returns them, in the
+// order they were scheduled. This can be used to hand off items to another owner (for example, when an actor type
+// is moved to another host): items that are returned will not be executed by this processor.
+func (p *Processor[T]) ExtractPrefix(prefix string) []T {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	peek, ok := p.queue.Peek()
+	res := p.queue.ExtractPrefix(prefix)
+	for _, r := range res {
+		p.persistDelete(r.Key())
+	}
+	if ok && len(res) > 0 && !p.stopped.Load() && strings.HasPrefix(peek.Key(), prefix) {
+		// If the first item was extracted, restart the processor
+		p.process(true)
+	}
+
+	return res
+}
+
 // Close stops the processor.
-// This method blocks until the processor loop returns.
+// This method blocks until the processor loop returns. If a Store is configured, it also waits for the executions
+// that are in progress to complete, then flushes the pending operations and stops the background flushes.
+// For this reason, when a Store is configured, executeFn must not invoke Close, or it deadlocks waiting for itself;
+// executeFn can invoke Close in a separate goroutine instead.
 func (p *Processor[T]) Close() error {
 	defer p.wg.Wait()
 	if p.stopped.CompareAndSwap(false, true) {
@@ -106,6 +188,15 @@ func (p *Processor[T]) Close() error {
 		close(p.stopCh)
 		// Blocks until processor loop ends
 		p.processorRunningCh <- struct{}{}
+
+		p.lock.Lock()
+		w := p.persist
+		p.lock.Unlock()
+		if w != nil {
+			// Wait for in-progress executions so their deletes are included in the final flush
+			p.executeWg.Wait()
+			return w.close()
+		}
 		return nil
 	}
 
@@ -152,12 +243,14 @@ func (p *Processor[T]) processLoop() {
 		t             kclock.Timer
 		scheduledTime time.Time
 		deadline      time.Duration
+		coalesce      time.Duration
 	)
 
 	for {
 		// Continue processing items until the queue is empty
 		p.lock.Lock()
 		r, ok = p.queue.Peek()
+		coalesce = p.coalesce
 		p.lock.Unlock()
 		if !ok {
 			return
@@ -177,12 +270,18 @@ func (p *Processor[T]) processLoop() {
 		}
 
 		scheduledTime = r.ScheduledTime()
+		if coalesce > 0 {
+			// Round up to the end of the window
+			if rem := scheduledTime.Sub(scheduledTime.Truncate(coalesce)); rem > 0 {
+				scheduledTime = scheduledTime.Add(coalesce - rem)
+			}
+		}
 		deadline = scheduledTime.Sub(p.clock.Now())
 
 		// If the deadline is less than 0.5ms away, execute it right away
 		// This is more efficient than creating a timer
 		if deadline < 500*time.Microsecond {
-			p.execute(r)
+			p.executeNext(r, coalesce, scheduledTime)
 			continue
 		}
 
@@ -190,7 +289,7 @@ func (p *Processor[T]) processLoop() {
 		select {
 		// Wait for when it's time to execute the item
 		case <-t.C():
-			p.execute(r)
+			p.executeNext(r, coalesce, scheduledTime)
 
 		// If we get a reset signal, restart the loop
 		case <-p.resetCh:
@@ -208,11 +307,49 @@ func (p *Processor[T]) processLoop() {
 	}
 }
 
+// Executes the next item, or, when coalescing, all items that are due until the end of the window.
+func (p *Processor[T]) executeNext(r T, coalesce time.Duration, windowEnd time.Time) {
+	if coalesce > 0 {
+		p.executeWindow(windowEnd)
+		return
+	}
+	p.execute(r)
+}
+
+// Executes all items scheduled until the end of the window.
+func (p *Processor[T]) executeWindow(windowEnd time.Time) {
+	var batch []T
+	p.lock.Lock()
+	// Do not execute items if the processor was stopped in the meanwhile
+	if p.stopped.Load() {
+		p.lock.Unlock()
+		return
+	}
+	for {
+		r, ok := p.queue.Peek()
+		if !ok || r.ScheduledTime().After(windowEnd) {
+			break
+		}
+		r, _ = p.queue.Pop()
+		batch = append(batch, r)
+	}
+	p.lock.Unlock()
+
+	for _, r := range batch {
+		p.executeInBackground(r)
+	}
+}
+
 // Executes a item when it's time.
 func (p *Processor[T]) execute(r T) {
 	// Pop the item now that we're ready to process it
 	// There's a small chance this is a different item than the one we peeked before
 	p.lock.Lock()
+	// Do not execute the item if the processor was stopped in the meanwhile
+	if p.stopped.Load() {
+		p.lock.Unlock()
+		return
+	}
 	// For safety, let's peek at the first item before popping it and make sure it's the same object
 	// It's unlikely, but if it's a different object then restart the loop
 	peek, ok := p.queue.Peek()
@@ -226,5 +363,23 @@ func (p *Processor[T]) execute(r T) {
 		return
 	}
 
-	go p.executeFn(r)
+	p.executeInBackground(r)
+}
+
+// Invokes executeFn for an item that was popped from the queue, in a background goroutine.
+// Once executeFn returns, the item is deleted from the Store, unless an item with the same key was enqueued again in
+// the meanwhile (for example by executeFn itself).
+func (p *Processor[T]) executeInBackground(r T) {
+	p.executeWg.Add(1)
+	go func() {
+		defer p.executeWg.Done()
+		err := p.executeFn(r)
+		p.stats.record(p.clock.Now(), err)
+
+		p.lock.Lock()
+		if _, ok := p.queue.items[r.Key()]; !ok {
+			p.persistDelete(r.Key())
+		}
+		p.lock.Unlock()
+	}()
 }
@@ -426,3 +426,135 @@ func TestClose(t *testing.T) {
 
 	assert.NoError(t, processor.Close())
 }
+
+func TestProcessorStrictFIFO(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	processor := NewProcessor(func(r *queueableItem) {}).
+		WithClock(clock).
+		WithStrictFIFO()
+	defer processor.Close()
+
+	due := clock.Now().Add(time.Hour)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, processor.Enqueue(newTestItem(i, due)))
+	}
+
+	// Items are dequeued in the order they were enqueued
+	extracted := processor.ExtractPrefix("")
+	require.Len(t, extracted, 20)
+	for i, r := range extracted {
+		assert.Equal(t, strconv.Itoa(i), r.Name)
+	}
+}
+
+func TestProcessorCoalescing(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clocktesting.NewFakeClock(start)
+	executeCh := make(chan *queueableItem, 10)
+	processor := NewProcessor(func(r *queueableItem) {
+		executeCh <- r
+	}).
+		WithClock(clock).
+		WithCoalescing(10 * time.Millisecond)
+	defer processor.Close()
+
+	for i, offset := range []int{101, 103, 109, 115, 120} {
+		require.NoError(t, processor.Enqueue(newTestItem(i, start.Add(time.Duration(offset)*time.Millisecond))))
+	}
+
+	assertExecuted := func(t *testing.T, names ...string) {
+		t.Helper()
+		got := make([]string, 0, len(names))
+		for range names {
+			select {
+			case r := <-executeCh:
+				got = append(got, r.Name)
+			case <-time.After(time.Second):
+				t.Fatalf("expected %d items to be executed, got %v", len(names), got)
+			}
+		}
+		assert.ElementsMatch(t, names, got)
+		select {
+		case r := <-executeCh:
+			t.Fatalf("unexpected item executed: %s", r.Name)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// Items are never executed before the end of their window
+	assert.Eventually(t, clock.HasWaiters, time.Second, 10*time.Millisecond)
+	clock.Step(105 * time.Millisecond)
+	assertExecuted(t)
+
+	// Items due in the same window are executed together, with a single wake-up
+	clock.Step(5 * time.Millisecond)
+	assertExecuted(t, "0", "1", "2")
+
+	// Items at the end of a window are not rounded up
+	assert.Eventually(t, clock.HasWaiters, time.Second, 10*time.Millisecond)
+	clock.Step(10 * time.Millisecond)
+	assertExecuted(t, "3", "4")
+}
+
+func TestProcessorExtractPrefix(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	executeCh := make(chan *queueableItem)
+	processor := NewProcessor(func(r *queueableItem) {
+		executeCh <- r
+	})
+	processor.clock = clock
+	defer processor.Close()
+
+	for i := 1; i <= 4; i++ {
+		r := newTestItem(i, clock.Now().Add(time.Second*time.Duration(i)))
+		if i%2 == 1 {
+			r.Name = "moved||" + r.Name
+		}
+		require.NoError(t, processor.Enqueue(r))
+	}
+
+	// Extract the items, including the one at the front of the queue
+	extracted := processor.ExtractPrefix("moved||")
+	require.Len(t, extracted, 2)
+	assert.Equal(t, "moved||1", extracted[0].Name)
+	assert.Equal(t, "moved||3", extracted[1].Name)
+	assert.Equal(t, 2, processor.queue.Len())
+
+	// Only the remaining items are executed
+	for _, name := range []string{"2", "4"} {
+		require.Eventually(t, clock.HasWaiters, time.Second, 10*time.Millisecond)
+		clock.Step(2 * time.Second)
+		select {
+		case r := <-executeCh:
+			assert.Equal(t, name, r.Name)
+		case <-time.After(time.Second):
+			t.Fatalf("did not receive item %s", name)
+		}
+	}
+}
+
+func TestProcessorNoExecutionAfterClose(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	executeCh := make(chan *queueableItem, 10)
+	processor := NewProcessor(func(r *queueableItem) {
+		executeCh <- r
+	}).
+		WithClock(clock).
+		WithCoalescing(10 * time.Millisecond)
+
+	r := newTestItem(1, clock.Now().Add(time.Hour))
+	require.NoError(t, processor.Enqueue(r))
+	require.NoError(t, processor.Enqueue(newTestItem(2, clock.Now().Add(time.Hour))))
+	require.NoError(t, processor.Close())
+
+	// Simulate timers that fire while the processor is being closed
+	processor.executeWindow(clock.Now().Add(2 * time.Hour))
+	processor.execute(r)
+
+	select {
+	case r := <-executeCh:
+		t.Fatalf("unexpected item executed: %s", r.Name)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, 2, processor.queue.Len())
+}
@@ -15,6 +15,8 @@ package queue
 
 import (
 	"container/heap"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -32,6 +34,15 @@ type queueable interface {
 type queue[T queueable] struct {
 	heap  *queueHeap[T]
 	items map[string]*queueItem[T]
+
+	// sizeFn returns the estimated size of an item, in bytes. Optional.
+	sizeFn func(r T) int64
+	// bytes is the estimated size of all items in the queue.
+	bytes int64
+	// fifo makes items with the same scheduled time pop in the order they were inserted.
+	fifo bool
+	// seq is the sequence number of the last inserted item, used when fifo is enabled.
+	seq uint64
 }
 
 // newQueue creates a new queue.
@@ -56,7 +67,7 @@ func (p *queue[T]) Insert(r T, replace bool) {
 	item, ok := p.items[key]
 	if ok {
 		if replace {
-			item.value = r
+			p.setValue(item, r)
 			heap.Fix(p.heap, item.index)
 		}
 		return
@@ -64,7 +75,13 @@ func (p *queue[T]) Insert(r T, replace bool) {
 
 	item = &queueItem[T]{
 		value: r,
+		size:  p.itemSize(r),
+	}
+	if p.fifo {
+		p.seq++
+		item.seq = p.seq
 	}
+	p.bytes += item.size
 	heap.Push(p.heap, item)
 	p.items[key] = item
 }
@@ -84,6 +101,7 @@ func (p *queue[T]) Pop() (T, bool) {
 	}
 
 	delete(p.items, item.value.Key())
+	p.bytes -= item.size
 	return item.value, true
 }
 
@@ -108,6 +126,31 @@ func (p *queue[T]) Remove(key string) {
 
 	heap.Remove(p.heap, item.index)
 	delete(p.items, key)
+	p.bytes -= item.size
+}
+
+// ExtractPrefix removes all items whose key starts with prefix from the queue, and returns them in the order they
+// were scheduled.
+func (p *queue[T]) ExtractPrefix(prefix string) []T {
+	var extracted []*queueItem[T]
+	for key, item := range p.items {
+		if strings.HasPrefix(key, prefix) {
+			extracted = append(extracted, item)
+		}
+	}
+	if len(extracted) == 0 {
+		return nil
+	}
+
+	sort.Slice(extracted, func(i, j int) bool {
+		return extracted[i].before(extracted[j])
+	})
+	res := make([]T, len(extracted))
+	for i, item := range extracted {
+		res[i] = item.value
+		p.Remove(item.value.Key())
+	}
+	return res
 }
 
 // Update an item in the queue.
@@ -118,17 +161,98 @@ func (p *queue[T]) Update(r T) {
 		return
 	}
 
-	item.value = r
+	p.setValue(item, r)
 	heap.Fix(p.heap, item.index)
 }
 
+// PeekN returns the first n items in the queue, in the order they would be popped, without removing them.
+// Instead of sorting the whole heap, it walks down from the root, only considering the children of the items that
+// were already selected: this takes O(n^2) time, regardless of the length of the queue.
+func (p *queue[T]) PeekN(n int) []T {
+	h := *p.heap
+	if n > len(h) {
+		n = len(h)
+	}
+	res := make([]T, 0, n)
+	if n <= 0 {
+		return res
+	}
+
+	// Indexes of the items whose parent was already selected; the next item is always one of them
+	candidates := []int{0}
+	for len(res) < n {
+		best := 0
+		for i := 1; i < len(candidates); i++ {
+			if h[candidates[i]].before(h[candidates[best]]) {
+				best = i
+			}
+		}
+		idx := candidates[best]
+		candidates[best] = candidates[len(candidates)-1]
+		candidates = candidates[:len(candidates)-1]
+
+		res = append(res, h[idx].value)
+		for _, child := range [2]int{2*idx + 1, 2*idx + 2} {
+			if child < len(h) {
+				candidates = append(candidates, child)
+			}
+		}
+	}
+	return res
+}
+
+// Bytes returns the estimated size of all items in the queue, in bytes.
+func (p *queue[T]) Bytes() int64 {
+	return p.bytes
+}
+
+// SizeOf returns the estimated size of the item with the given key, if it's in the queue.
+func (p *queue[T]) SizeOf(key string) (int64, bool) {
+	item, ok := p.items[key]
+	if !ok {
+		return 0, false
+	}
+	return item.size, true
+}
+
+func (p *queue[T]) setValue(item *queueItem[T], r T) {
+	size := p.itemSize(r)
+	p.bytes += size - item.size
+	item.value = r
+	item.size = size
+}
+
+func (p *queue[T]) itemSize(r T) int64 {
+	if p.sizeFn == nil {
+		return 0
+	}
+	return p.sizeFn(r)
+}
+
 type queueItem[T queueable] struct {
 	value T
 
+	// Estimated size of the item, in bytes.
+	size int64
+
+	// Sequence number assigned when the item was inserted, if the queue is in FIFO mode; 0 otherwise.
+	// Replacing or updating the item doesn't change it.
+	seq uint64
+
 	// The index of the item in the heap. This is maintained by the heap.Interface methods.
 	index int
 }
 
+// before returns true if the item must be popped before o.
+// Items with the same scheduled time are ordered by their sequence number, which is 0 when FIFO mode is disabled.
+func (item *queueItem[T]) before(o *queueItem[T]) bool {
+	it, ot := item.value.ScheduledTime(), o.value.ScheduledTime()
+	if it.Equal(ot) {
+		return item.seq < o.seq
+	}
+	return it.Before(ot)
+}
+
 type queueHeap[T queueable] []*queueItem[T]
 
 func (pq queueHeap[T]) Len() int {
@@ -136,7 +260,7 @@ func (pq queueHeap[T]) Len() int {
 }
 
 func (pq queueHeap[T]) Less(i, j int) bool {
-	return pq[i].value.ScheduledTime().Before(pq[j].value.ScheduledTime())
+	return pq[i].before(pq[j])
 }
 
 func (pq queueHeap[T]) Swap(i, j int) {
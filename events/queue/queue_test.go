@@ -14,6 +14,7 @@ limitations under the License.
 package queue
 
 import (
+	"math/rand"
 	"strconv"
 	"testing"
 	"time"
@@ -282,6 +283,31 @@ func TestQueuePeek(t *testing.T) {
 	peekAndCompare(t, &queue, 1, "2021-01-01T01:01:01Z")
 }
 
+func TestQueuePeekN(t *testing.T) {
+	queue := newQueue[*queueableItem]()
+	assert.Empty(t, queue.PeekN(3))
+
+	start := time.Now()
+	for _, i := range rand.Perm(100) {
+		queue.Insert(newTestItem(i, start.Add(time.Duration(i)*time.Second)), false)
+	}
+
+	next := queue.PeekN(10)
+	require.Len(t, next, 10)
+	for i, r := range next {
+		assert.Equal(t, strconv.Itoa(i), r.Name)
+	}
+	assert.Len(t, queue.PeekN(200), 100)
+
+	// The queue is not modified
+	require.Equal(t, 100, queue.Len())
+	for i := 0; i < 100; i++ {
+		popped, ok := queue.Pop()
+		require.True(t, ok)
+		assert.Equal(t, strconv.Itoa(i), popped.Name)
+	}
+}
+
 func newTestItem(n int, dueTime any) *queueableItem {
 	r := &queueableItem{
 		Name: strconv.Itoa(n),
@@ -314,3 +340,75 @@ func peekAndCompare(t *testing.T, q *queue[*queueableItem], expectN int, expectD
 	assert.Equal(t, strconv.Itoa(expectN), r.Name)
 	assert.Equal(t, expectDueTime, r.ScheduledTime().Format(time.RFC3339))
 }
+
+func TestQueueStrictFIFO(t *testing.T) {
+	queue := newQueue[*queueableItem]()
+	queue.fifo = true
+
+	// Add many items with the same scheduled time, and one earlier and one later
+	const n = 50
+	queue.Insert(newTestItem(-1, "2029-09-09T09:09:09Z"), false)
+	for i := 0; i < n; i++ {
+		queue.Insert(newTestItem(i, "2023-03-03T03:03:03Z"), false)
+	}
+	queue.Insert(newTestItem(-2, "2021-01-01T01:01:01Z"), false)
+
+	// Replacing an item keeps its position
+	queue.Insert(newTestItem(0, "2023-03-03T03:03:03Z"), true)
+	queue.Update(newTestItem(1, "2023-03-03T03:03:03Z"))
+
+	popAndCompare(t, &queue, -2, "2021-01-01T01:01:01Z")
+	for i := 0; i < n; i++ {
+		popAndCompare(t, &queue, i, "2023-03-03T03:03:03Z")
+	}
+	popAndCompare(t, &queue, -1, "2029-09-09T09:09:09Z")
+
+	_, ok := queue.Pop()
+	require.False(t, ok)
+}
+
+func TestQueueExtractPrefixStrictFIFO(t *testing.T) {
+	queue := newQueue[*queueableItem]()
+	queue.fifo = true
+
+	for i := 0; i < 20; i++ {
+		queue.Insert(newTestItem(i, "2023-03-03T03:03:03Z"), false)
+	}
+
+	extracted := queue.ExtractPrefix("")
+	require.Len(t, extracted, 20)
+	for i, r := range extracted {
+		assert.Equal(t, strconv.Itoa(i), r.Name)
+	}
+}
+
+func TestQueueExtractPrefix(t *testing.T) {
+	queue := newQueue[*queueableItem]()
+
+	items := map[string]string{
+		"actor||a||1": "2023-03-03T03:03:03Z",
+		"actor||a||2": "2021-01-01T01:01:01Z",
+		"actor||b||1": "2022-02-02T02:02:02Z",
+		"other||a||1": "2020-01-01T01:01:01Z",
+	}
+	for name, due := range items {
+		r := newTestItem(0, due)
+		r.Name = name
+		queue.Insert(r, false)
+	}
+
+	extracted := queue.ExtractPrefix("actor||a||")
+	require.Len(t, extracted, 2)
+	assert.Equal(t, "actor||a||2", extracted[0].Name)
+	assert.Equal(t, "actor||a||1", extracted[1].Name)
+	assert.Equal(t, 2, queue.Len())
+
+	assert.Empty(t, queue.ExtractPrefix("actor||a||"))
+
+	r, ok := queue.Pop()
+	require.True(t, ok)
+	assert.Equal(t, "other||a||1", r.Name)
+	r, ok = queue.Pop()
+	require.True(t, ok)
+	assert.Equal(t, "actor||b||1", r.Name)
+}
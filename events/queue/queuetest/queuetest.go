@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package queuetest contains helpers to verify the correctness of queue implementations.
+// The helpers can be used in property-based and fuzz tests, by applying arbitrary sequences of operations to a
+// queue and checking its invariants after each one.
+package queuetest
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dapr/kit/events/queue"
+)
+
+// Inspector is implemented by queues that can return a snapshot of their internal state.
+type Inspector interface {
+	Snapshot() queue.Snapshot
+}
+
+// Target is a queue that can be driven by RunOperations.
+type Target[T any] interface {
+	Inspector
+	Enqueue(r T) error
+	Dequeue(key string) error
+}
+
+// CheckInvariants verifies that the internal state of q is consistent:
+//   - Items in the heap are in heap order, by scheduled time.
+//   - Each item stores its own position in the heap.
+//   - The index map contains exactly the items in the heap, each with its correct position.
+func CheckInvariants(q Inspector) error {
+	s := q.Snapshot()
+
+	if len(s.Heap) != len(s.Index) {
+		return fmt.Errorf("heap has %d items but index map has %d", len(s.Heap), len(s.Index))
+	}
+
+	for i, item := range s.Heap {
+		if item.Index != i {
+			return fmt.Errorf("item '%s' is at position %d in the heap but stores index %d", item.Key, i, item.Index)
+		}
+		idx, ok := s.Index[item.Key]
+		if !ok {
+			return fmt.Errorf("item '%s' is in the heap but not in the index map", item.Key)
+		}
+		if idx != i {
+			return fmt.Errorf("item '%s' is at position %d in the heap but the index map has %d", item.Key, i, idx)
+		}
+		if i > 0 {
+			parent := s.Heap[(i-1)/2]
+			if item.ScheduledTime.Before(parent.ScheduledTime) {
+				return fmt.Errorf("heap order violated: item '%s' at position %d is scheduled before its parent '%s'", item.Key, i, parent.Key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RunOperations decodes a sequence of operations from data and applies them to q, checking the invariants after
+// each operation. This is meant to be used with inputs from a fuzzer or a random source.
+// Each operation is encoded in 3 bytes: the operation (even for enqueue, odd for dequeue), the key (one of 16), and
+// for enqueues the scheduled time, as an offset in seconds that is passed to newItem.
+func RunOperations[T any](q Target[T], data []byte, newItem func(key string, offset time.Duration) T) error {
+	for i := 0; i+3 <= len(data); i += 3 {
+		op, key, offset := data[i], strconv.Itoa(int(data[i+1]%16)), time.Duration(data[i+2])*time.Second
+
+		var err error
+		if op%2 == 0 {
+			err = q.Enqueue(newItem(key, offset))
+		} else {
+			err = q.Dequeue(key)
+		}
+		if err != nil {
+			return fmt.Errorf("operation %d failed: %w", i/3, err)
+		}
+
+		err = CheckInvariants(q)
+		if err != nil {
+			return fmt.Errorf("invariants violated after operation %d: %w", i/3, err)
+		}
+	}
+	return nil
+}
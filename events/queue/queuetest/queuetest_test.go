@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queuetest
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/kit/events/queue"
+)
+
+type testItem struct {
+	key           string
+	scheduledTime time.Time
+}
+
+func (r *testItem) Key() string {
+	return r.key
+}
+
+func (r *testItem) ScheduledTime() time.Time {
+	return r.scheduledTime
+}
+
+func newTestProcessor(t *testing.T) (*queue.Processor[*testItem], func(key string, offset time.Duration) *testItem) {
+	t.Helper()
+
+	// Items are scheduled far in the future, so the clock never reaches them
+	clock := clocktesting.NewFakeClock(time.Now())
+	base := clock.Now().Add(time.Hour)
+	p := queue.NewProcessor[*testItem](func(r *testItem) {}).WithClock(clock)
+	t.Cleanup(func() {
+		p.Close()
+	})
+
+	return p, func(key string, offset time.Duration) *testItem {
+		return &testItem{key: key, scheduledTime: base.Add(offset)}
+	}
+}
+
+type fakeInspector queue.Snapshot
+
+func (f fakeInspector) Snapshot() queue.Snapshot {
+	return queue.Snapshot(f)
+}
+
+func TestCheckInvariants(t *testing.T) {
+	now := time.Now()
+
+	t.Run("valid", func(t *testing.T) {
+		require.NoError(t, CheckInvariants(fakeInspector{
+			Heap: []queue.SnapshotItem{
+				{Key: "a", ScheduledTime: now, Index: 0},
+				{Key: "b", ScheduledTime: now.Add(2 * time.Second), Index: 1},
+				{Key: "c", ScheduledTime: now.Add(time.Second), Index: 2},
+			},
+			Index: map[string]int{"a": 0, "b": 1, "c": 2},
+		}))
+	})
+
+	tests := map[string]fakeInspector{
+		"heap order": {
+			Heap: []queue.SnapshotItem{
+				{Key: "a", ScheduledTime: now.Add(time.Second), Index: 0},
+				{Key: "b", ScheduledTime: now, Index: 1},
+			},
+			Index: map[string]int{"a": 0, "b": 1},
+		},
+		"item index": {
+			Heap: []queue.SnapshotItem{
+				{Key: "a", ScheduledTime: now, Index: 1},
+			},
+			Index: map[string]int{"a": 1},
+		},
+		"index map": {
+			Heap: []queue.SnapshotItem{
+				{Key: "a", ScheduledTime: now, Index: 0},
+				{Key: "b", ScheduledTime: now, Index: 1},
+			},
+			Index: map[string]int{"a": 1, "b": 0},
+		},
+		"missing from index map": {
+			Heap: []queue.SnapshotItem{
+				{Key: "a", ScheduledTime: now, Index: 0},
+			},
+			Index: map[string]int{"b": 0},
+		},
+		"length mismatch": {
+			Heap:  []queue.SnapshotItem{},
+			Index: map[string]int{"a": 0},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, CheckInvariants(tc))
+		})
+	}
+}
+
+func TestRunOperations(t *testing.T) {
+	p, newItem := newTestProcessor(t)
+
+	//nolint:gosec
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	data := make([]byte, 3*1000)
+	_, _ = r.Read(data)
+	require.NoError(t, RunOperations[*testItem](p, data, newItem))
+}
+
+func FuzzRunOperations(f *testing.F) {
+	f.Add([]byte{0, 1, 10, 0, 2, 5, 0, 3, 1, 1, 2, 0, 0, 1, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, newItem := newTestProcessor(t)
+		require.NoError(t, RunOperations[*testItem](p, data, newItem))
+	})
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"time"
+)
+
+// Snapshot is a point-in-time copy of the internal state of a queue.
+// It's used to verify the correctness of the queue, for example with the helpers in the queuetest package.
+type Snapshot struct {
+	// Heap contains the items in the order they are stored in the heap.
+	Heap []SnapshotItem
+	// Index maps the key of each item to its position in the heap, as tracked by the queue.
+	Index map[string]int
+}
+
+// SnapshotItem is an item in a Snapshot.
+type SnapshotItem struct {
+	// Key of the item.
+	Key string
+	// ScheduledTime of the item.
+	ScheduledTime time.Time
+	// Index is the position of the item in the heap, as stored in the item itself.
+	Index int
+}
+
+// Snapshot returns a copy of the internal state of the queue.
+func (p *Processor[T]) Snapshot() Snapshot {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.queue.snapshot()
+}
+
+func (p *queue[T]) snapshot() Snapshot {
+	s := Snapshot{
+		Heap:  make([]SnapshotItem, len(*p.heap)),
+		Index: make(map[string]int, len(p.items)),
+	}
+	for i, item := range *p.heap {
+		s.Heap[i] = SnapshotItem{
+			Key:           item.value.Key(),
+			ScheduledTime: item.value.ScheduledTime(),
+			Index:         item.index,
+		}
+	}
+	for key, item := range p.items {
+		s.Index[key] = item.index
+	}
+	return s
+}
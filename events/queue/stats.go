@@ -0,0 +1,165 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	// StatsWindow is the window over which recent executions are counted in Stats.
+	StatsWindow = time.Minute
+	// StatsNextItems is the number of upcoming items included in Stats.
+	StatsNextItems = 10
+
+	// Number of buckets for the executions in StatsWindow (one per second)
+	statsBuckets = 60
+)
+
+// Stats contains statistics about a Processor, and it can be serialized to JSON to be exposed by a debug endpoint.
+type Stats struct {
+	// Pending is the number of items in the queue.
+	Pending int `json:"pending"`
+	// Executed is the number of items executed since the processor was created.
+	Executed uint64 `json:"executed"`
+	// Failed is the number of executions that returned an error.
+	Failed uint64 `json:"failed"`
+	// RecentExecutions is the number of items executed in the last StatsWindow.
+	RecentExecutions int `json:"recentExecutions"`
+	// Lag is how late the next item in the queue is, if its scheduled time has passed.
+	Lag time.Duration `json:"-"`
+	// Bytes is the estimated size of all items in the queue. See Sizer.
+	Bytes int64 `json:"bytes"`
+	// MaxBytes is the memory limit of the processor, or 0 if there's no limit.
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+	// Next contains the next items in the queue, up to StatsNextItems.
+	// Only keys and scheduled times are included, never the items themselves; keys are redacted unless the processor
+	// was created with WithStatsKeys.
+	Next []ScheduledItem `json:"next"`
+}
+
+// ScheduledItem contains the key and scheduled time of an item in the queue.
+type ScheduledItem struct {
+	// Key of the item. When redacted, it's a fingerprint derived from the key with RedactKey, which can be used to
+	// recognize the same item across calls without exposing the key.
+	Key           string    `json:"key"`
+	ScheduledTime time.Time `json:"scheduledTime"`
+}
+
+// MarshalJSON implements json.Marshaler and formats the lag as a string.
+func (s Stats) MarshalJSON() ([]byte, error) {
+	type alias Stats
+	return json.Marshal(struct {
+		alias
+		Lag    string `json:"lag"`
+		Window string `json:"window"`
+	}{
+		alias:  alias(s),
+		Lag:    s.Lag.String(),
+		Window: StatsWindow.String(),
+	})
+}
+
+// Stats returns statistics about the processor.
+func (p *Processor[T]) Stats() Stats {
+	now := p.clock.Now()
+
+	p.lock.Lock()
+	s := Stats{
+		Pending:  p.queue.Len(),
+		Bytes:    p.queue.Bytes(),
+		MaxBytes: p.memoryLimit.MaxBytes,
+	}
+	next := p.queue.PeekN(StatsNextItems)
+	s.Next = make([]ScheduledItem, len(next))
+	for i, r := range next {
+		s.Next[i] = ScheduledItem{
+			Key:           r.Key(),
+			ScheduledTime: r.ScheduledTime(),
+		}
+		if !p.statsKeys {
+			s.Next[i].Key = RedactKey(s.Next[i].Key)
+		}
+	}
+	p.lock.Unlock()
+
+	s.Executed, s.Failed, s.RecentExecutions = p.stats.get(now)
+	if len(s.Next) > 0 && s.Next[0].ScheduledTime.Before(now) {
+		s.Lag = now.Sub(s.Next[0].ScheduledTime)
+	}
+
+	return s
+}
+
+// RedactKey returns the fingerprint that replaces key in Stats: the first 8 bytes of the SHA-256 hash of the key,
+// hex-encoded.
+// Callers can use it to find an item they know the key of in the stats.
+func RedactKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:8])
+}
+
+// processorStats counts the executions of a Processor.
+type processorStats struct {
+	lock     sync.Mutex
+	executed uint64
+	failed   uint64
+	buckets  [statsBuckets]int
+	// Index of the bucket for the latest update, in seconds since the epoch
+	last int64
+}
+
+func (s *processorStats) record(now time.Time, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.executed++
+	if err != nil {
+		s.failed++
+	}
+	s.advance(now)
+	s.buckets[s.last%statsBuckets]++
+}
+
+func (s *processorStats) get(now time.Time) (executed uint64, failed uint64, recent int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.advance(now)
+	for _, b := range s.buckets {
+		recent += b
+	}
+	return s.executed, s.failed, recent
+}
+
+// advance clears the buckets that are out of the window.
+// This must be invoked while the caller has a lock.
+func (s *processorStats) advance(now time.Time) {
+	idx := now.Unix()
+	if idx <= s.last {
+		return
+	}
+	if s.last == 0 || idx-s.last >= statsBuckets {
+		s.buckets = [statsBuckets]int{}
+	} else {
+		for i := s.last + 1; i <= idx; i++ {
+			s.buckets[i%statsBuckets] = 0
+		}
+	}
+	s.last = idx
+}
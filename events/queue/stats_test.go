@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestProcessorStats(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	processor := NewProcessorWithErrors(func(r *queueableItem) error {
+		if r.Name == "2" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	processor.clock = clock
+	defer processor.Close()
+
+	for i := 1; i <= 15; i++ {
+		require.NoError(t, processor.Enqueue(newTestItem(i, clock.Now().Add(time.Duration(i)*time.Second))))
+	}
+
+	stats := processor.Stats()
+	assert.Equal(t, 15, stats.Pending)
+	assert.Zero(t, stats.Executed)
+	assert.Zero(t, stats.Lag)
+	require.Len(t, stats.Next, StatsNextItems)
+	for i, item := range stats.Next {
+		assert.Equal(t, RedactKey(strconv.Itoa(i+1)), item.Key)
+	}
+
+	// Execute the first 3 items
+	for i := 1; i <= 3; i++ {
+		require.Eventually(t, clock.HasWaiters, time.Second, 10*time.Millisecond)
+		clock.Step(time.Second)
+	}
+	require.Eventually(t, func() bool {
+		return processor.Stats().Executed == 3
+	}, time.Second, 10*time.Millisecond)
+
+	stats = processor.Stats()
+	assert.Equal(t, 12, stats.Pending)
+	assert.Equal(t, uint64(1), stats.Failed)
+	assert.Equal(t, 3, stats.RecentExecutions)
+	assert.Equal(t, RedactKey("4"), stats.Next[0].Key)
+
+	// Recent executions age out of the window
+	clock.SetTime(clock.Now().Add(-time.Second).Add(StatsWindow + time.Second))
+	stats = processor.Stats()
+	assert.Equal(t, uint64(3), stats.Executed)
+	assert.Equal(t, 0, stats.RecentExecutions)
+
+	enc, err := json.Marshal(stats)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(enc, &decoded))
+	assert.Equal(t, "1m0s", decoded["window"])
+	assert.Contains(t, decoded, "lag")
+	assert.Len(t, decoded["next"], StatsNextItems)
+}
+
+func TestProcessorStatsLag(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	processor := NewProcessor(func(r *queueableItem) {})
+	processor.clock = clock
+	defer processor.Close()
+
+	// Insert the item directly so the processing loop is not started
+	processor.lock.Lock()
+	processor.queue.Insert(newTestItem(1, clock.Now().Add(-5*time.Second)), false)
+	processor.lock.Unlock()
+
+	assert.Equal(t, 5*time.Second, processor.Stats().Lag)
+}
+
+func TestProcessorStatsKeys(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	processor := NewProcessor(func(r *queueableItem) {}).WithStatsKeys()
+	processor.clock = clock
+	defer processor.Close()
+
+	processor.lock.Lock()
+	processor.queue.Insert(newTestItem(1, clock.Now().Add(time.Second)), false)
+	processor.lock.Unlock()
+
+	stats := processor.Stats()
+	require.Len(t, stats.Next, 1)
+	assert.Equal(t, "1", stats.Next[0].Key)
+	assert.NotEqual(t, "1", RedactKey("1"))
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package features contains a process-wide registry of the features that are enabled.
+// Features are identified by name and are typically enabled at startup, for example from configuration or feature flags.
+package features
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	enabled     = map[string]struct{}{}
+	enabledLock sync.RWMutex
+)
+
+// Enable marks the features with the given names as enabled.
+func Enable(names ...string) {
+	enabledLock.Lock()
+	defer enabledLock.Unlock()
+	for _, n := range names {
+		enabled[n] = struct{}{}
+	}
+}
+
+// Disable marks the features with the given names as disabled.
+func Disable(names ...string) {
+	enabledLock.Lock()
+	defer enabledLock.Unlock()
+	for _, n := range names {
+		delete(enabled, n)
+	}
+}
+
+// IsEnabled returns true if the feature with the given name is enabled.
+func IsEnabled(name string) bool {
+	enabledLock.RLock()
+	defer enabledLock.RUnlock()
+	_, ok := enabled[name]
+	return ok
+}
+
+// Enabled returns the names of all enabled features, sorted alphabetically.
+func Enabled() []string {
+	enabledLock.RLock()
+	defer enabledLock.RUnlock()
+	res := make([]string, 0, len(enabled))
+	for n := range enabled {
+		res = append(res, n)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatures(t *testing.T) {
+	defer Disable("b", "a", "c")
+
+	assert.Empty(t, Enabled())
+	assert.False(t, IsEnabled("a"))
+
+	Enable("b", "a")
+	Enable("c")
+	assert.True(t, IsEnabled("a"))
+	assert.Equal(t, []string{"a", "b", "c"}, Enabled())
+
+	Disable("b")
+	assert.False(t, IsEnabled("b"))
+	assert.Equal(t, []string{"a", "c"}, Enabled())
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fswatcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DataSymlink is the name of the symlink that Kubernetes swaps atomically when the content of a ConfigMap or Secret
+// volume is updated.
+const DataSymlink = "..data"
+
+// SwapEvent is sent by WatchAtomicSwap when the DataSymlink in a directory has been swapped.
+type SwapEvent struct {
+	// Dir is the watched directory.
+	Dir string
+	// RealPath is the directory that DataSymlink points to after the swap, with all symlinks resolved.
+	RealPath string
+}
+
+// WatchAtomicSwap watches a directory that is updated with the atomic symlink swap used by Kubernetes for ConfigMap
+// and Secret volumes, and sends a notification to eventCh every time the swap completes.
+//
+// With this pattern, the files in dir are symlinks to "..data/<name>", and "..data" is itself a symlink to a
+// timestamped directory. Kubernetes writes the new content in a new timestamped directory, then atomically replaces
+// "..data" and finally deletes the old directory. While that generates many filesystem events, WatchAtomicSwap sends
+// a single event after "..data" has been replaced, so the files are never observed in a partial state.
+// The directory doesn't need to contain a "..data" symlink when the watch starts.
+func WatchAtomicSwap(ctx context.Context, dir string, eventCh chan<- SwapEvent) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	err = watcher.Add(dir)
+	if err != nil {
+		return fmt.Errorf("watcher error: %w", err)
+	}
+
+	return watchSwaps(ctx, watcher, dir, func(e SwapEvent) {
+		select {
+		case eventCh <- e:
+		case <-ctx.Done():
+		}
+	})
+}
+
+// watchSwaps invokes fn every time the DataSymlink in dir points to a new directory.
+func watchSwaps(ctx context.Context, watcher *fsnotify.Watcher, dir string, fn func(SwapEvent)) error {
+	dataPath := filepath.Join(dir, DataSymlink)
+
+	// Resolve the current target, so we don't notify if "..data" is re-created with the same target
+	last, _ := filepath.EvalSymlinks(dataPath)
+
+	for {
+		select {
+		// Watch for events
+		case event := <-watcher.Events:
+			// The swap is completed when "..data" is renamed over, which is reported as a create event
+			if event.Op&fsnotify.Create != fsnotify.Create || filepath.Clean(event.Name) != dataPath {
+				continue
+			}
+
+			realPath, err := filepath.EvalSymlinks(dataPath)
+			if err != nil {
+				// The symlink may have been replaced again already; we will get another event in that case
+				continue
+			}
+			if realPath == last {
+				continue
+			}
+			last = realPath
+
+			fn(SwapEvent{
+				Dir:      dir,
+				RealPath: realPath,
+			})
+
+		// Abort in case of errors
+		case err := <-watcher.Errors:
+			return fmt.Errorf("watcher listen error: %w", err)
+
+		// Stop on context canceled
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// hasDataSymlink returns true if dir contains a DataSymlink.
+func hasDataSymlink(dir string) bool {
+	info, err := os.Lstat(filepath.Join(dir, DataSymlink))
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fswatcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchAtomicSwap(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks are not reliably available on Windows")
+	}
+
+	baseDir := t.TempDir()
+	first := writeDataDir(t, baseDir, "..2023_01_01_00_00_00.000000001", "v1")
+	require.NoError(t, os.Symlink(filepath.Base(first), filepath.Join(baseDir, DataSymlink)))
+	require.NoError(t, os.Symlink(filepath.Join(DataSymlink, "key"), filepath.Join(baseDir, "key")))
+	require.True(t, hasDataSymlink(baseDir))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventCh := make(chan SwapEvent, 10)
+	doneCh := make(chan error, 1)
+	go func() {
+		doneCh <- WatchAtomicSwap(ctx, baseDir, eventCh)
+	}()
+
+	// Wait for the watcher to start before swapping
+	time.Sleep(500 * time.Millisecond)
+
+	second := swapDataDir(t, baseDir, first, "..2023_01_01_00_00_01.000000001", "v2")
+
+	select {
+	case e := <-eventCh:
+		assert.Equal(t, baseDir, e.Dir)
+		expect, err := filepath.EvalSymlinks(second)
+		require.NoError(t, err)
+		assert.Equal(t, expect, e.RealPath)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("did not get event within 2 seconds")
+	}
+
+	content, err := os.ReadFile(filepath.Join(baseDir, "key"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+
+	// There should be no other event for the same swap
+	select {
+	case e := <-eventCh:
+		t.Fatalf("got unexpected event: %v", e)
+	case <-time.After(time.Second):
+	}
+
+	cancel()
+	select {
+	case err := <-doneCh:
+		assert.True(t, errors.Is(err, context.Canceled))
+	case <-time.After(2 * time.Second):
+		t.Fatalf("did not stop within 2 seconds")
+	}
+}
+
+func TestWatchDataSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks are not reliably available on Windows")
+	}
+
+	baseDir := t.TempDir()
+	first := writeDataDir(t, baseDir, "..2023_01_01_00_00_00.000000001", "v1")
+	require.NoError(t, os.Symlink(filepath.Base(first), filepath.Join(baseDir, DataSymlink)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventCh := make(chan struct{}, 10)
+	go func() {
+		_ = Watch(ctx, baseDir, eventCh)
+	}()
+
+	// Wait for the watcher to start before swapping
+	time.Sleep(500 * time.Millisecond)
+
+	swapDataDir(t, baseDir, first, "..2023_01_01_00_00_01.000000001", "v2")
+
+	select {
+	case <-eventCh:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("did not get event within 2 seconds")
+	}
+
+	// Changes other than swaps are not notified
+	touchFile(baseDir, "other")
+	select {
+	case <-eventCh:
+		t.Fatalf("got more than 1 change notification")
+	case <-time.After(time.Second):
+	}
+}
+
+// writeDataDir creates a timestamped data directory like the ones created by Kubernetes.
+func writeDataDir(t *testing.T, base, name, value string) string {
+	t.Helper()
+
+	dir := filepath.Join(base, name)
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key"), []byte(value), 0o644))
+	return dir
+}
+
+// swapDataDir updates the content of base with the same steps that Kubernetes uses for ConfigMap and Secret volumes.
+func swapDataDir(t *testing.T, base, old, name, value string) string {
+	t.Helper()
+
+	dir := writeDataDir(t, base, name, value)
+	tmpLink := filepath.Join(base, "..data_tmp")
+	require.NoError(t, os.Symlink(name, tmpLink))
+	require.NoError(t, os.Rename(tmpLink, filepath.Join(base, DataSymlink)))
+	require.NoError(t, os.RemoveAll(old))
+	return dir
+}
@@ -27,6 +27,9 @@ import (
 // Watch for changes to a directory on the filesystem and sends a notification to eventCh every time a file in the folder is changed.
 // Although it's possible to watch for individual files, that's not recommended; watch for the file's parent folder instead.
 // Note that changes are batched for 0.5 seconds before notifications are sent
+//
+// If dir is a Kubernetes-style ConfigMap or Secret mount (see WatchAtomicSwap), only the swaps of the "..data"
+// symlink are notified, once each, instead of the burst of events for the intermediate files and directories.
 func Watch(ctx context.Context, dir string, eventCh chan<- struct{}) error {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -39,6 +42,15 @@ func Watch(ctx context.Context, dir string, eventCh chan<- struct{}) error {
 		return fmt.Errorf("watcher error: %w", err)
 	}
 
+	if hasDataSymlink(dir) {
+		return watchSwaps(ctx, watcher, dir, func(SwapEvent) {
+			select {
+			case eventCh <- struct{}{}:
+			case <-ctx.Done():
+			}
+		})
+	}
+
 	batchCh := make(chan struct{}, 1)
 	defer close(batchCh)
 
@@ -0,0 +1,520 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package grpcutil contains utilities for gRPC clients.
+package grpcutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	kclock "k8s.io/utils/clock"
+
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// DefaultHealthCheckInterval is the default interval between health checks of connections.
+	DefaultHealthCheckInterval = 10 * time.Second
+	// DefaultHealthCheckTimeout is the default timeout for each health check.
+	DefaultHealthCheckTimeout = 5 * time.Second
+	// DefaultUnhealthyCloseDelay is the default delay before unhealthy connections are closed.
+	DefaultUnhealthyCloseDelay = 30 * time.Second
+)
+
+var (
+	// ErrConnManagerClosed is returned when using a ConnManager that was closed.
+	ErrConnManagerClosed = errors.New("connection manager is closed")
+	// ErrNoHealthyConnection is returned when all connections to a target are unhealthy.
+	ErrNoHealthyConnection = errors.New("no healthy connection available")
+	// ErrTargetRemoved is returned by Get when the target is removed while its connections are being dialed.
+	ErrTargetRemoved = errors.New("target was removed")
+)
+
+// Selection is the strategy used to pick a connection from a pool.
+type Selection int
+
+const (
+	// SelectionRoundRobin cycles through all healthy connections.
+	SelectionRoundRobin Selection = iota
+	// SelectionPickFirst always returns the first healthy connection.
+	SelectionPickFirst
+)
+
+// DialFn creates a new connection to the target.
+type DialFn func(ctx context.Context, target string) (*grpc.ClientConn, error)
+
+// HealthCheckFn checks whether a connection is healthy.
+type HealthCheckFn func(ctx context.Context, conn *grpc.ClientConn) error
+
+// ConnManagerOptions contains the options for a ConnManager.
+type ConnManagerOptions struct {
+	// Dial creates new connections. If nil, grpc.DialContext is used with DialOptions.
+	Dial DialFn
+	// DialOptions are passed to grpc.DialContext when Dial is nil.
+	DialOptions []grpc.DialOption
+	// PoolSize is the number of connections maintained for each target. Default is 1.
+	PoolSize int
+	// Selection is the strategy used to pick a connection. Default is SelectionRoundRobin.
+	Selection Selection
+	// HealthCheck checks whether a connection is healthy. If nil, DefaultHealthCheck is used.
+	HealthCheck HealthCheckFn
+	// HealthCheckInterval is the interval between health checks. Default is DefaultHealthCheckInterval.
+	// Set to a negative value to disable health checks.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout is the timeout for each health check. Default is DefaultHealthCheckTimeout.
+	HealthCheckTimeout time.Duration
+	// UnhealthyCloseDelay is the delay before connections that failed a health check are closed, after they're
+	// removed from the pool, so RPCs that are in progress on them can complete. Default is DefaultUnhealthyCloseDelay.
+	// Set to a negative value to close them immediately.
+	UnhealthyCloseDelay time.Duration
+	// BackOff returns the backoff used when re-dialing unhealthy connections.
+	// If nil, an exponential backoff that never stops is used.
+	BackOff func() backoff.BackOff
+	// Log is used to log unhealthy connections. Optional.
+	Log logger.Logger
+}
+
+// DefaultHealthCheck checks a connection using the standard gRPC health checking protocol.
+// If the server doesn't implement the health service, the connection is considered healthy unless its connectivity
+// state is TransientFailure or Shutdown.
+func DefaultHealthCheck(ctx context.Context, conn *grpc.ClientConn) error {
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		switch s := conn.GetState(); s {
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("connection is in state %s", s)
+		default:
+			return nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("server is %s", res.GetStatus())
+	}
+	return nil
+}
+
+// ConnManager maintains a pool of client connections for each target.
+// Connections are health-checked periodically; unhealthy ones are closed and re-dialed in background with a backoff.
+type ConnManager struct {
+	opts  ConnManagerOptions
+	clock kclock.WithTicker
+
+	lock   sync.Mutex
+	pools  map[string]*connPool
+	closed bool
+}
+
+// NewConnManager returns a new ConnManager.
+func NewConnManager(opts ConnManagerOptions) *ConnManager {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 1
+	}
+	if opts.HealthCheck == nil {
+		opts.HealthCheck = DefaultHealthCheck
+	}
+	if opts.HealthCheckInterval == 0 {
+		opts.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+	if opts.HealthCheckTimeout <= 0 {
+		opts.HealthCheckTimeout = DefaultHealthCheckTimeout
+	}
+	if opts.UnhealthyCloseDelay == 0 {
+		opts.UnhealthyCloseDelay = DefaultUnhealthyCloseDelay
+	}
+	if opts.BackOff == nil {
+		opts.BackOff = func() backoff.BackOff {
+			b := backoff.NewExponentialBackOff()
+			b.MaxElapsedTime = 0
+			return b
+		}
+	}
+	if opts.Dial == nil {
+		dialOpts := opts.DialOptions
+		opts.Dial = func(ctx context.Context, target string) (*grpc.ClientConn, error) {
+			return grpc.DialContext(ctx, target, dialOpts...)
+		}
+	}
+
+	return &ConnManager{
+		opts:  opts,
+		clock: kclock.RealClock{},
+		pools: map[string]*connPool{},
+	}
+}
+
+// WithClock sets the clock used for health checks and backoffs. Used for testing.
+func (m *ConnManager) WithClock(clock kclock.WithTicker) *ConnManager {
+	m.clock = clock
+	return m
+}
+
+// Get returns a healthy connection to the target.
+// The first time a target is requested, the pool of connections is dialed; if all dials fail, the error is returned.
+// Callers should invoke Get for each operation rather than holding on to connections: connections that fail a health
+// check are closed after UnhealthyCloseDelay, and the connections to a target are closed right away when the target
+// is removed or the ConnManager is closed.
+func (m *ConnManager) Get(ctx context.Context, target string) (*grpc.ClientConn, error) {
+	m.lock.Lock()
+	if m.closed {
+		m.lock.Unlock()
+		return nil, ErrConnManagerClosed
+	}
+	p, ok := m.pools[target]
+	if !ok {
+		p = newConnPool(m, target)
+		m.pools[target] = p
+	}
+	m.lock.Unlock()
+
+	err := p.init(ctx)
+	if err != nil {
+		m.lock.Lock()
+		if m.pools[target] == p {
+			delete(m.pools, target)
+		}
+		m.lock.Unlock()
+		p.close()
+		return nil, err
+	}
+
+	return p.pick()
+}
+
+// Remove closes all connections to the target and removes its pool.
+func (m *ConnManager) Remove(target string) error {
+	m.lock.Lock()
+	p, ok := m.pools[target]
+	delete(m.pools, target)
+	m.lock.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return p.close()
+}
+
+// Targets returns the targets that have a pool.
+func (m *ConnManager) Targets() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	res := make([]string, 0, len(m.pools))
+	for t := range m.pools {
+		res = append(res, t)
+	}
+	return res
+}
+
+// Close closes all connections. The ConnManager cannot be used after it's closed.
+func (m *ConnManager) Close() error {
+	m.lock.Lock()
+	if m.closed {
+		m.lock.Unlock()
+		return nil
+	}
+	m.closed = true
+	pools := m.pools
+	m.pools = map[string]*connPool{}
+	m.lock.Unlock()
+
+	errs := make([]error, 0)
+	for _, p := range pools {
+		if err := p.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// connPool is the pool of connections for a target.
+type connPool struct {
+	m      *ConnManager
+	target string
+
+	initOnce sync.Once
+	initErr  error
+	next     atomic.Uint32
+
+	lock   sync.Mutex
+	slots  []*connSlot
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type connSlot struct {
+	conn      *grpc.ClientConn
+	healthy   bool
+	redialing bool
+}
+
+func newConnPool(m *ConnManager, target string) *connPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &connPool{
+		m:      m,
+		target: target,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// init dials all connections in the pool, once.
+func (p *connPool) init(ctx context.Context) error {
+	p.initOnce.Do(func() {
+		p.initErr = p.dialAll(ctx)
+	})
+	return p.initErr
+}
+
+func (p *connPool) dialAll(ctx context.Context) error {
+	size := p.m.opts.PoolSize
+	slots := make([]*connSlot, size)
+	errs := make([]error, 0)
+	for i := 0; i < size; i++ {
+		slots[i] = &connSlot{}
+		conn, err := p.m.opts.Dial(ctx, p.target)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		slots[i].conn = conn
+		slots[i].healthy = true
+	}
+	if len(errs) == size {
+		return fmt.Errorf("failed to connect to '%s': %w", p.target, errors.Join(errs...))
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	// The pool could have been closed while dialing, if the target was removed or the manager was closed
+	if p.ctx.Err() != nil {
+		for _, s := range slots {
+			if s.conn != nil {
+				_ = s.conn.Close()
+			}
+		}
+		return p.closedErr()
+	}
+	p.slots = slots
+	for _, s := range slots {
+		if !s.healthy {
+			p.startRedial(s)
+		}
+	}
+
+	if p.m.opts.HealthCheckInterval > 0 {
+		p.wg.Add(1)
+		go p.healthCheckLoop()
+	}
+	return nil
+}
+
+// closedErr returns the error for a pool that was closed.
+func (p *connPool) closedErr() error {
+	p.m.lock.Lock()
+	defer p.m.lock.Unlock()
+	if p.m.closed {
+		return ErrConnManagerClosed
+	}
+	return fmt.Errorf("%w: %s", ErrTargetRemoved, p.target)
+}
+
+// pick returns a healthy connection according to the selection strategy.
+func (p *connPool) pick() (*grpc.ClientConn, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.ctx.Err() != nil {
+		return nil, p.closedErr()
+	}
+
+	healthy := make([]*grpc.ClientConn, 0, len(p.slots))
+	for _, s := range p.slots {
+		if s.healthy {
+			healthy = append(healthy, s.conn)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoHealthyConnection, p.target)
+	}
+
+	if p.m.opts.Selection == SelectionPickFirst {
+		return healthy[0], nil
+	}
+	n := p.next.Add(1) - 1
+	return healthy[n%uint32(len(healthy))], nil
+}
+
+func (p *connPool) healthCheckLoop() {
+	defer p.wg.Done()
+
+	t := p.m.clock.NewTicker(p.m.opts.HealthCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-t.C():
+			p.checkAll()
+		}
+	}
+}
+
+// checkAll checks the health of all healthy connections, and starts re-dialing those that failed.
+func (p *connPool) checkAll() {
+	p.lock.Lock()
+	slots := make([]*connSlot, 0, len(p.slots))
+	for _, s := range p.slots {
+		if s.healthy {
+			slots = append(slots, s)
+		}
+	}
+	p.lock.Unlock()
+
+	for _, s := range slots {
+		ctx, cancel := context.WithTimeout(p.ctx, p.m.opts.HealthCheckTimeout)
+		err := p.m.opts.HealthCheck(ctx, s.conn)
+		cancel()
+		if err == nil || p.ctx.Err() != nil {
+			continue
+		}
+
+		if p.m.opts.Log != nil {
+			p.m.opts.Log.Warnf("Connection to '%s' is unhealthy, re-dialing: %v", p.target, err)
+		}
+		p.lock.Lock()
+		s.healthy = false
+		p.closeLater(s.conn)
+		s.conn = nil
+		p.startRedial(s)
+		p.lock.Unlock()
+	}
+}
+
+// closeLater closes a connection that was removed from the pool after UnhealthyCloseDelay, or when the pool is
+// closed, whichever comes first.
+// This must be invoked while the caller has a lock.
+func (p *connPool) closeLater(conn *grpc.ClientConn) {
+	delay := p.m.opts.UnhealthyCloseDelay
+	if delay < 0 || p.ctx.Err() != nil {
+		_ = conn.Close()
+		return
+	}
+
+	t := p.m.clock.NewTimer(delay)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		select {
+		case <-t.C():
+		case <-p.ctx.Done():
+			t.Stop()
+		}
+		_ = conn.Close()
+	}()
+}
+
+// startRedial starts re-dialing the connection in the slot in background.
+// This must be invoked while the caller has a lock.
+func (p *connPool) startRedial(s *connSlot) {
+	if s.redialing || p.ctx.Err() != nil {
+		return
+	}
+	s.redialing = true
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.redial(s)
+	}()
+}
+
+func (p *connPool) redial(s *connSlot) {
+	bo := p.m.opts.BackOff()
+	for {
+		d := bo.NextBackOff()
+		if d == backoff.Stop {
+			p.lock.Lock()
+			s.redialing = false
+			p.lock.Unlock()
+			return
+		}
+
+		t := p.m.clock.NewTimer(d)
+		select {
+		case <-p.ctx.Done():
+			if !t.Stop() {
+				<-t.C()
+			}
+			return
+		case <-t.C():
+		}
+
+		conn, err := p.m.opts.Dial(p.ctx, p.target)
+		if err != nil {
+			if p.m.opts.Log != nil {
+				p.m.opts.Log.Debugf("Failed to re-dial '%s': %v", p.target, err)
+			}
+			continue
+		}
+
+		p.lock.Lock()
+		if p.ctx.Err() != nil {
+			p.lock.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conn = conn
+		s.healthy = true
+		s.redialing = false
+		p.lock.Unlock()
+		return
+	}
+}
+
+// close stops the background goroutines and closes all connections.
+func (p *connPool) close() error {
+	// Cancel while holding the lock, so no goroutine is added to wg after it's checked that the pool is not closed
+	p.lock.Lock()
+	p.cancel()
+	p.lock.Unlock()
+	p.wg.Wait()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	errs := make([]error, 0)
+	for _, s := range p.slots {
+		if s.conn == nil {
+			continue
+		}
+		if err := s.conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		s.conn = nil
+		s.healthy = false
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func startTestServer(t *testing.T) (*bufconn.Listener, *health.Server) {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+	return lis, hs
+}
+
+func bufconnDialOptions(lis *bufconn.Listener) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+}
+
+func TestConnManagerSelection(t *testing.T) {
+	lis, _ := startTestServer(t)
+
+	t.Run("round robin", func(t *testing.T) {
+		m := NewConnManager(ConnManagerOptions{
+			DialOptions:         bufconnDialOptions(lis),
+			PoolSize:            3,
+			HealthCheckInterval: -1,
+		})
+		defer m.Close()
+
+		seen := map[*grpc.ClientConn]int{}
+		for i := 0; i < 6; i++ {
+			conn, err := m.Get(context.Background(), "bufnet")
+			require.NoError(t, err)
+			seen[conn]++
+		}
+		assert.Len(t, seen, 3)
+		for _, n := range seen {
+			assert.Equal(t, 2, n)
+		}
+		assert.Equal(t, []string{"bufnet"}, m.Targets())
+	})
+
+	t.Run("pick first", func(t *testing.T) {
+		m := NewConnManager(ConnManagerOptions{
+			DialOptions:         bufconnDialOptions(lis),
+			PoolSize:            3,
+			Selection:           SelectionPickFirst,
+			HealthCheckInterval: -1,
+		})
+		defer m.Close()
+
+		first, err := m.Get(context.Background(), "bufnet")
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			conn, err := m.Get(context.Background(), "bufnet")
+			require.NoError(t, err)
+			assert.Same(t, first, conn)
+		}
+	})
+}
+
+func TestConnManagerHealth(t *testing.T) {
+	lis, _ := startTestServer(t)
+	clock := clocktesting.NewFakeClock(time.Now())
+
+	var unhealthy atomic.Bool
+	m := NewConnManager(ConnManagerOptions{
+		DialOptions: bufconnDialOptions(lis),
+		HealthCheck: func(ctx context.Context, conn *grpc.ClientConn) error {
+			if unhealthy.Load() {
+				return errors.New("unhealthy")
+			}
+			return nil
+		},
+		HealthCheckInterval: 10 * time.Second,
+		BackOff: func() backoff.BackOff {
+			return backoff.NewConstantBackOff(time.Second)
+		},
+	}).WithClock(clock)
+	defer m.Close()
+
+	first, err := m.Get(context.Background(), "bufnet")
+	require.NoError(t, err)
+
+	// Fail the health check
+	unhealthy.Store(true)
+	require.Eventually(t, clock.HasWaiters, time.Second, 5*time.Millisecond)
+	clock.Step(10 * time.Second)
+	require.Eventually(t, func() bool {
+		_, err = m.Get(context.Background(), "bufnet")
+		return errors.Is(err, ErrNoHealthyConnection)
+	}, time.Second, 5*time.Millisecond)
+
+	// The connection is re-dialed after the backoff
+	unhealthy.Store(false)
+	require.Eventually(t, func() bool {
+		clock.Step(time.Second)
+		_, err = m.Get(context.Background(), "bufnet")
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+
+	conn, err := m.Get(context.Background(), "bufnet")
+	require.NoError(t, err)
+	assert.NotSame(t, first, conn)
+}
+
+func TestConnManagerUnhealthyCloseDelay(t *testing.T) {
+	lis, _ := startTestServer(t)
+	clock := clocktesting.NewFakeClock(time.Now())
+
+	var unhealthy atomic.Bool
+	m := NewConnManager(ConnManagerOptions{
+		DialOptions: bufconnDialOptions(lis),
+		HealthCheck: func(ctx context.Context, conn *grpc.ClientConn) error {
+			if unhealthy.Load() {
+				return errors.New("unhealthy")
+			}
+			return nil
+		},
+		HealthCheckInterval: 10 * time.Second,
+		UnhealthyCloseDelay: time.Minute,
+		BackOff: func() backoff.BackOff {
+			return &backoff.StopBackOff{}
+		},
+	}).WithClock(clock)
+	defer m.Close()
+
+	conn, err := m.Get(context.Background(), "bufnet")
+	require.NoError(t, err)
+
+	unhealthy.Store(true)
+	require.Eventually(t, clock.HasWaiters, time.Second, 5*time.Millisecond)
+	clock.Step(10 * time.Second)
+	require.Eventually(t, func() bool {
+		_, err = m.Get(context.Background(), "bufnet")
+		return errors.Is(err, ErrNoHealthyConnection)
+	}, time.Second, 5*time.Millisecond)
+
+	// The connection that was handed out is not closed until the delay has passed
+	assert.NotEqual(t, "SHUTDOWN", conn.GetState().String())
+	clock.Step(time.Minute)
+	assert.Eventually(t, func() bool {
+		return conn.GetState().String() == "SHUTDOWN"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestConnManagerRemoveWhileDialing(t *testing.T) {
+	lis, _ := startTestServer(t)
+
+	dialingCh := make(chan struct{})
+	releaseCh := make(chan struct{})
+	var dialed atomic.Pointer[grpc.ClientConn]
+	m := NewConnManager(ConnManagerOptions{
+		Dial: func(ctx context.Context, target string) (*grpc.ClientConn, error) {
+			close(dialingCh)
+			<-releaseCh
+			conn, err := grpc.DialContext(ctx, target, bufconnDialOptions(lis)...)
+			dialed.Store(conn)
+			return conn, err
+		},
+		HealthCheckInterval: -1,
+	})
+	defer m.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := m.Get(context.Background(), "bufnet")
+		errCh <- err
+	}()
+
+	<-dialingCh
+	require.NoError(t, m.Remove("bufnet"))
+	close(releaseCh)
+
+	require.ErrorIs(t, <-errCh, ErrTargetRemoved)
+	assert.Empty(t, m.Targets())
+	// The connection dialed after the target was removed is closed
+	require.NotNil(t, dialed.Load())
+	assert.Equal(t, "SHUTDOWN", dialed.Load().GetState().String())
+}
+
+func TestConnManagerDialError(t *testing.T) {
+	dialErr := errors.New("simulated")
+	var dials atomic.Int32
+	m := NewConnManager(ConnManagerOptions{
+		Dial: func(ctx context.Context, target string) (*grpc.ClientConn, error) {
+			dials.Add(1)
+			return nil, dialErr
+		},
+		PoolSize: 2,
+	})
+	defer m.Close()
+
+	_, err := m.Get(context.Background(), "target")
+	require.ErrorIs(t, err, dialErr)
+	assert.Equal(t, int32(2), dials.Load())
+	assert.Empty(t, m.Targets())
+
+	// Failed pools are not cached
+	_, err = m.Get(context.Background(), "target")
+	require.ErrorIs(t, err, dialErr)
+	assert.Equal(t, int32(4), dials.Load())
+}
+
+func TestConnManagerClose(t *testing.T) {
+	lis, _ := startTestServer(t)
+	m := NewConnManager(ConnManagerOptions{
+		DialOptions: bufconnDialOptions(lis),
+	})
+
+	conn, err := m.Get(context.Background(), "bufnet")
+	require.NoError(t, err)
+
+	require.NoError(t, m.Remove("bufnet"))
+	assert.Empty(t, m.Targets())
+	assert.Equal(t, "SHUTDOWN", conn.GetState().String())
+
+	_, err = m.Get(context.Background(), "bufnet")
+	require.NoError(t, err)
+
+	require.NoError(t, m.Close())
+	_, err = m.Get(context.Background(), "bufnet")
+	require.ErrorIs(t, err, ErrConnManagerClosed)
+}
+
+func TestDefaultHealthCheck(t *testing.T) {
+	lis, hs := startTestServer(t)
+	conn, err := grpc.Dial("bufnet", bufconnDialOptions(lis)...)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, DefaultHealthCheck(context.Background(), conn))
+
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	err = DefaultHealthCheck(context.Background(), conn)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "NOT_SERVING")
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hashutil contains utilities to compute stable hashes of Go values.
+package hashutil
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Type tags written before each value, so values of different kinds never produce the same encoding.
+const (
+	tagNil byte = iota
+	tagBool
+	tagInt
+	tagUint
+	tagFloat
+	tagString
+	tagText
+	tagList
+	tagMap
+	tagStruct
+)
+
+// ErrCycle is returned when the value contains a reference cycle.
+var ErrCycle = errors.New("value contains a reference cycle")
+
+var typeTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// HashAny returns a 64-bit hash of v that is deterministic across process restarts, so it can be used for
+// change-detection of values such as decoded configuration.
+// The hash is computed on a canonical encoding of v, where:
+//
+//   - map entries are sorted, so the hash doesn't depend on the iteration order
+//   - all signed integers, all unsigned integers, and all floats are respectively encoded as the same type
+//   - nil pointers, slices and maps are encoded the same way; non-nil pointers are followed
+//   - only exported fields of structs are included, together with their names
+//   - types implementing encoding.TextMarshaler (such as time.Time) are encoded using their text representation
+//
+// Channels, functions and complex numbers are not supported and cause an error to be returned.
+func HashAny(v any) (uint64, error) {
+	enc := &encoder{
+		visiting: map[uintptr]struct{}{},
+	}
+	err := enc.encode(reflect.ValueOf(v))
+	if err != nil {
+		return 0, err
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write(enc.buf.Bytes())
+	return h.Sum64(), nil
+}
+
+// encoder computes the canonical encoding of a value.
+type encoder struct {
+	buf bytes.Buffer
+	// Pointers and maps currently being visited, used to detect cycles
+	visiting map[uintptr]struct{}
+}
+
+func (e *encoder) writeUint(tag byte, u uint64) {
+	var b [binary.MaxVarintLen64 + 1]byte
+	b[0] = tag
+	n := binary.PutUvarint(b[1:], u)
+	e.buf.Write(b[:n+1])
+}
+
+func (e *encoder) writeBytes(tag byte, b []byte) {
+	e.writeUint(tag, uint64(len(b)))
+	e.buf.Write(b)
+}
+
+//nolint:cyclop
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf.WriteByte(tagNil)
+		return nil
+	}
+
+	if v.Type().Implements(typeTextMarshaler) && v.CanInterface() {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			e.buf.WriteByte(tagNil)
+			return nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", v.Type(), err)
+		}
+		e.writeBytes(tagText, text)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.writeUint(tagBool, 1)
+		} else {
+			e.writeUint(tagBool, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeUint(tagInt, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeUint(tagUint, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.writeUint(tagFloat, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.writeBytes(tagString, []byte(v.String()))
+	case reflect.Interface:
+		if v.IsNil() {
+			e.buf.WriteByte(tagNil)
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			e.buf.WriteByte(tagNil)
+			return nil
+		}
+		leave, err := e.enter(v)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		return e.encode(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			e.buf.WriteByte(tagNil)
+			return nil
+		}
+		e.writeUint(tagList, uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			err := e.encode(v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf.WriteByte(tagNil)
+			return nil
+		}
+		leave, err := e.enter(v)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// enter marks the pointer or map v as being visited, returning ErrCycle if it's already on the stack.
+// The returned function must be invoked when done visiting v.
+func (e *encoder) enter(v reflect.Value) (leave func(), err error) {
+	ptr := v.Pointer()
+	if _, ok := e.visiting[ptr]; ok {
+		return nil, ErrCycle
+	}
+	e.visiting[ptr] = struct{}{}
+	return func() {
+		delete(e.visiting, ptr)
+	}, nil
+}
+
+func (e *encoder) encodeMap(v reflect.Value) error {
+	type entry struct {
+		key []byte
+		val []byte
+	}
+
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		sub := &encoder{visiting: e.visiting}
+		err := sub.encode(iter.Key())
+		if err != nil {
+			return err
+		}
+		key := sub.buf.Bytes()
+
+		sub = &encoder{visiting: e.visiting}
+		err = sub.encode(iter.Value())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: key, val: sub.buf.Bytes()})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	e.writeUint(tagMap, uint64(len(entries)))
+	for _, en := range entries {
+		e.buf.Write(en.key)
+		e.buf.Write(en.val)
+	}
+	return nil
+}
+
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	t := v.Type()
+
+	n := 0
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			n++
+		}
+	}
+
+	e.writeUint(tagStruct, uint64(n))
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		e.writeBytes(tagString, []byte(f.Name))
+		err := e.encode(v.Field(i))
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hashutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStruct struct {
+	Name     string
+	Values   map[string]int
+	Items    []any
+	Nested   *testStruct
+	Start    time.Time
+	internal string
+}
+
+func mustHash(t *testing.T, v any) uint64 {
+	t.Helper()
+	h, err := HashAny(v)
+	require.NoError(t, err)
+	return h
+}
+
+func TestHashAny(t *testing.T) {
+	start := time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)
+	newValue := func() testStruct {
+		return testStruct{
+			Name: "foo",
+			Values: map[string]int{
+				"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8,
+			},
+			Items: []any{"x", 1, 2.5, true, nil, map[int]string{1: "one", 2: "two"}},
+			Nested: &testStruct{
+				Name: "bar",
+			},
+			Start: start,
+		}
+	}
+
+	t.Run("deterministic", func(t *testing.T) {
+		expect := mustHash(t, newValue())
+		for i := 0; i < 20; i++ {
+			assert.Equal(t, expect, mustHash(t, newValue()))
+		}
+	})
+
+	t.Run("stable across releases", func(t *testing.T) {
+		// This value must never change, as hashes may be persisted
+		assert.Equal(t, uint64(0x42403999f5244bed), mustHash(t, map[string]any{"foo": "bar", "n": 1}))
+	})
+
+	t.Run("unexported fields are ignored", func(t *testing.T) {
+		v := newValue()
+		v.internal = "changed"
+		assert.Equal(t, mustHash(t, newValue()), mustHash(t, v))
+	})
+
+	t.Run("changes are detected", func(t *testing.T) {
+		base := mustHash(t, newValue())
+
+		v := newValue()
+		v.Values["a"] = 100
+		assert.NotEqual(t, base, mustHash(t, v))
+
+		v = newValue()
+		v.Nested.Name = "baz"
+		assert.NotEqual(t, base, mustHash(t, v))
+
+		v = newValue()
+		v.Items = v.Items[1:]
+		assert.NotEqual(t, base, mustHash(t, v))
+
+		v = newValue()
+		v.Start = v.Start.Add(time.Nanosecond)
+		assert.NotEqual(t, base, mustHash(t, v))
+	})
+
+	t.Run("kinds are distinguished", func(t *testing.T) {
+		assert.NotEqual(t, mustHash(t, "1"), mustHash(t, 1))
+		assert.NotEqual(t, mustHash(t, 1), mustHash(t, uint(1)))
+		assert.NotEqual(t, mustHash(t, []string{"ab", "c"}), mustHash(t, []string{"a", "bc"}))
+		assert.NotEqual(t, mustHash(t, map[string]string{"a": "b"}), mustHash(t, map[string]string{"b": "a"}))
+	})
+
+	t.Run("canonical numeric types", func(t *testing.T) {
+		assert.Equal(t, mustHash(t, int8(1)), mustHash(t, int64(1)))
+		assert.Equal(t, mustHash(t, uint16(1)), mustHash(t, uint64(1)))
+		assert.Equal(t, mustHash(t, float32(0.5)), mustHash(t, float64(0.5)))
+	})
+
+	t.Run("pointers are followed", func(t *testing.T) {
+		s := "foo"
+		assert.Equal(t, mustHash(t, "foo"), mustHash(t, &s))
+		assert.Equal(t, mustHash(t, nil), mustHash(t, (*string)(nil)))
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		v := newValue()
+		v.Nested = &v
+		_, err := HashAny(v)
+		require.ErrorIs(t, err, ErrCycle)
+
+		m := map[string]any{}
+		m["self"] = m
+		_, err = HashAny(m)
+		require.ErrorIs(t, err, ErrCycle)
+
+		// Shared references that are not cycles are allowed
+		shared := &testStruct{Name: "shared"}
+		_, err = HashAny([]*testStruct{shared, shared})
+		require.NoError(t, err)
+	})
+
+	t.Run("unsupported types", func(t *testing.T) {
+		_, err := HashAny(make(chan int))
+		require.Error(t, err)
+		_, err = HashAny(map[string]any{"fn": func() {}})
+		require.Error(t, err)
+	})
+}
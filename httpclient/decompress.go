@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxDecompressedSize is the default maximum size of decompressed response bodies.
+const DefaultMaxDecompressedSize = 64 << 20
+
+// ErrResponseTooLarge is returned when a response exceeds the limits set with WithDecompression.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// ResponseTooLargeError is returned when a response exceeds the limits set with WithDecompression, either when the
+// response is received (if its Content-Length is too large), or while reading its body.
+// It matches ErrResponseTooLarge with errors.Is.
+type ResponseTooLargeError struct {
+	// Limit is the limit that was exceeded, in bytes.
+	Limit int64
+	// Decompressed is true if the limit is the one on the decompressed size of the body.
+	Decompressed bool
+}
+
+// Error implements error.
+func (e *ResponseTooLargeError) Error() string {
+	if e.Decompressed {
+		return fmt.Sprintf("%s: decompressed body exceeds the limit of %d bytes", ErrResponseTooLarge, e.Limit)
+	}
+	return fmt.Sprintf("%s: body exceeds the limit of %d bytes", ErrResponseTooLarge, e.Limit)
+}
+
+// Is allows matching the error with ErrResponseTooLarge.
+func (e *ResponseTooLargeError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// Decoder returns a reader that decompresses the data read from r.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+// DecompressionOptions contains the options for WithDecompression.
+type DecompressionOptions struct {
+	// MaxDecompressedSize is the maximum size of decompressed response bodies. Default is DefaultMaxDecompressedSize.
+	// A negative value disables the limit.
+	MaxDecompressedSize int64
+	// MaxContentLength is the maximum size of response bodies as received, before decompression. If 0, there's no
+	// limit. Responses whose Content-Length exceeds it are rejected before the body is read.
+	MaxContentLength int64
+	// Decoders contains additional decoders, by content coding. Decoders for "gzip" and "deflate" are included.
+	// A "zstd" decoder is not included, to avoid a dependency on a zstd implementation: callers that need it must
+	// supply one, for example with a decoder from github.com/klauspost/compress/zstd.
+	Decoders map[string]Decoder
+}
+
+// WithDecompression is a TransportOption that transparently decompresses responses, enforcing limits on their size to
+// protect against decompression bombs, which is important when fetching untrusted URLs.
+// Unless the request already has an Accept-Encoding header, the supported content codings are advertised, and
+// responses that use them are decompressed, removing their Content-Encoding and Content-Length headers; otherwise,
+// responses are returned as-is, but MaxContentLength still applies.
+// Reading a body that exceeds the limits returns a *ResponseTooLargeError.
+func WithDecompression(opts DecompressionOptions) TransportOption {
+	if opts.MaxDecompressedSize == 0 {
+		opts.MaxDecompressedSize = DefaultMaxDecompressedSize
+	}
+
+	decoders := map[string]Decoder{
+		"gzip": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		"deflate": decodeDeflate,
+	}
+	for coding, dec := range opts.Decoders {
+		decoders[strings.ToLower(coding)] = dec
+	}
+
+	codings := make([]string, 0, len(decoders))
+	for coding := range decoders {
+		codings = append(codings, coding)
+	}
+	sort.Strings(codings)
+
+	d := &decompression{
+		opts:           opts,
+		decoders:       decoders,
+		acceptEncoding: strings.Join(codings, ", "),
+	}
+	return func(t *transport) {
+		t.decompression = d
+	}
+}
+
+// decodeDeflate decodes the "deflate" content coding, which is DEFLATE data in the zlib format (RFC 9110, section
+// 8.4.1.2). Some servers send raw DEFLATE data instead, so that's decoded too if the data doesn't have a zlib header.
+func decodeDeflate(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+type decompression struct {
+	opts           DecompressionOptions
+	decoders       map[string]Decoder
+	acceptEncoding string
+}
+
+// prepareRequest advertises the supported content codings, if the request doesn't have an Accept-Encoding header.
+// The returned boolean is true if the response must be decompressed.
+func (d *decompression) prepareRequest(req *http.Request) (*http.Request, bool) {
+	if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" {
+		return req, false
+	}
+	// Per the http.RoundTripper contract, we must not modify the original request
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", d.acceptEncoding)
+	return req, true
+}
+
+// processResponse enforces the limits on the response, and decompresses its body if decode is true.
+func (d *decompression) processResponse(res *http.Response, decode bool) (*http.Response, error) {
+	if d.opts.MaxContentLength > 0 {
+		if res.ContentLength > d.opts.MaxContentLength {
+			_ = res.Body.Close()
+			return nil, &ResponseTooLargeError{Limit: d.opts.MaxContentLength}
+		}
+		res.Body = &limitedBody{
+			ReadCloser: res.Body,
+			remaining:  d.opts.MaxContentLength,
+			err:        &ResponseTooLargeError{Limit: d.opts.MaxContentLength},
+		}
+	}
+
+	if !decode {
+		return res, nil
+	}
+	coding := strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding")))
+	dec, ok := d.decoders[coding]
+	if coding == "" || !ok || res.Body == nil || res.Body == http.NoBody {
+		return res, nil
+	}
+
+	res.Body = &decodedBody{
+		raw:     res.Body,
+		decoder: dec,
+		limit:   d.opts.MaxDecompressedSize,
+	}
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+	return res, nil
+}
+
+// limitedBody is a body that returns err if more than remaining bytes are read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	err       error
+}
+
+// Read implements io.Reader.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, b.err
+	}
+	// Read one byte more than the limit, to detect if it's exceeded
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), b.err
+	}
+	return n, err
+}
+
+// decodedBody decompresses the raw body, lazily creating the decoder on the first read, so that errors reading the
+// header of the compressed stream are returned by Read.
+type decodedBody struct {
+	raw     io.ReadCloser
+	decoder Decoder
+	limit   int64
+
+	r   io.Reader
+	dec io.ReadCloser
+	err error
+}
+
+// Read implements io.Reader.
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.r == nil {
+		b.dec, b.err = b.decoder(b.raw)
+		if b.err != nil {
+			b.dec = nil
+			b.err = fmt.Errorf("failed to decompress response body: %w", b.err)
+			return 0, b.err
+		}
+		b.r = b.dec
+		if b.limit > 0 {
+			b.r = &limitedBody{
+				ReadCloser: io.NopCloser(b.dec),
+				remaining:  b.limit,
+				err:        &ResponseTooLargeError{Limit: b.limit, Decompressed: true},
+			}
+		}
+	}
+
+	n, err := b.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.err = err
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *decodedBody) Close() error {
+	if b.dec != nil {
+		_ = b.dec.Close()
+	}
+	return b.raw.Close()
+}
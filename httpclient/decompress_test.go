@@ -0,0 +1,205 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func deflateData(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func rawDeflateData(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDecompression(t *testing.T) {
+	message := []byte(strings.Repeat("hello world ", 100))
+	bomb := bytes.Repeat([]byte{0}, 1<<20)
+
+	var acceptEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+
+		var body []byte
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			body = gzipData(t, message)
+		case "/deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			body = deflateData(t, message)
+		case "/deflate-raw":
+			w.Header().Set("Content-Encoding", "deflate")
+			body = rawDeflateData(t, message)
+		case "/reverse":
+			w.Header().Set("Content-Encoding", "reverse")
+			body = []byte("olleh")
+		case "/bomb":
+			w.Header().Set("Content-Encoding", "gzip")
+			body = gzipData(t, bomb)
+		case "/invalid":
+			w.Header().Set("Content-Encoding", "gzip")
+			body = []byte("not gzip")
+		case "/chunked":
+			// Flushing before writing the body makes the response chunked, without a Content-Length
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			body = message
+		default:
+			body = message
+		}
+		if r.URL.Path != "/chunked" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Transport: NewTransport(nil, WithDecompression(DecompressionOptions{
+			MaxDecompressedSize: 64 << 10,
+			Decoders: map[string]Decoder{
+				"reverse": func(r io.Reader) (io.ReadCloser, error) {
+					data, err := io.ReadAll(r)
+					if err != nil {
+						return nil, err
+					}
+					for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+						data[i], data[j] = data[j], data[i]
+					}
+					return io.NopCloser(bytes.NewReader(data)), nil
+				},
+			},
+		})),
+	}
+
+	get := func(t *testing.T, path string, header http.Header) (*http.Response, []byte, error) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return res, body, err
+	}
+
+	for _, path := range []string{"/gzip", "/deflate", "/deflate-raw", "/plain"} {
+		t.Run("decompresses "+path, func(t *testing.T) {
+			res, body, err := get(t, path, nil)
+			require.NoError(t, err)
+			assert.Equal(t, message, body)
+			assert.Equal(t, "deflate, gzip, reverse", acceptEncoding)
+			assert.Empty(t, res.Header.Get("Content-Encoding"))
+		})
+	}
+
+	t.Run("additional decoders", func(t *testing.T) {
+		res, body, err := get(t, "/reverse", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(body))
+		assert.True(t, res.Uncompressed)
+		assert.Equal(t, int64(-1), res.ContentLength)
+	})
+
+	t.Run("decompression bomb", func(t *testing.T) {
+		_, body, err := get(t, "/bomb", nil)
+		require.ErrorIs(t, err, ErrResponseTooLarge)
+		var tooLarge *ResponseTooLargeError
+		require.True(t, errors.As(err, &tooLarge))
+		assert.True(t, tooLarge.Decompressed)
+		assert.Equal(t, int64(64<<10), tooLarge.Limit)
+		assert.Len(t, body, 64<<10)
+	})
+
+	t.Run("invalid compressed data", func(t *testing.T) {
+		_, _, err := get(t, "/invalid", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decompress response body")
+	})
+
+	t.Run("explicit Accept-Encoding is not decoded", func(t *testing.T) {
+		res, body, err := get(t, "/gzip", http.Header{"Accept-Encoding": []string{"gzip"}})
+		require.NoError(t, err)
+		assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+		assert.Equal(t, gzipData(t, message), body)
+	})
+
+	t.Run("content length limit", func(t *testing.T) {
+		limited := &http.Client{
+			Transport: NewTransport(nil, WithDecompression(DecompressionOptions{
+				MaxContentLength: 100,
+			})),
+		}
+
+		_, err := limited.Get(srv.URL + "/plain")
+		require.ErrorIs(t, err, ErrResponseTooLarge)
+
+		// Without a Content-Length, the limit is enforced while reading
+		res, err := limited.Get(srv.URL + "/chunked")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.ErrorIs(t, err, ErrResponseTooLarge)
+		assert.Len(t, body, 100)
+	})
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Signer signs outgoing HTTP requests, for example by adding an Authorization header.
+// body contains the full request body, which has already been read; signers must not read req.Body.
+type Signer interface {
+	Sign(req *http.Request, body []byte) error
+}
+
+// SignerFunc is a function that implements Signer.
+type SignerFunc func(req *http.Request, body []byte) error
+
+// Sign implements Signer.
+func (f SignerFunc) Sign(req *http.Request, body []byte) error {
+	return f(req, body)
+}
+
+// WithSigner is a TransportOption that signs all outgoing requests with signer.
+// To make the body available to the signer, it's read in memory before the request is sent.
+func WithSigner(signer Signer) TransportOption {
+	return func(t *transport) {
+		t.signer = signer
+	}
+}
+
+func (t *transport) sign(req *http.Request) (*http.Request, error) {
+	// Per the http.RoundTripper contract, we must not modify the original request
+	req = req.Clone(req.Context())
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body for signing: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.ContentLength = int64(len(body))
+	}
+
+	err := t.signer.Sign(req, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+	return req, nil
+}
+
+// CanonicalPath returns the canonical form of the path of the URL, which is escaped and never empty.
+func CanonicalPath(u *url.URL) string {
+	p := u.EscapedPath()
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+// CanonicalQuery returns the canonical form of the query string of the URL: parameters are sorted by name and then
+// value, and names and values are percent-encoded as per RFC 3986.
+func CanonicalQuery(u *url.URL) string {
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(query))
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, escapeRFC3986(k)+"="+escapeRFC3986(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// CanonicalHeaders returns the canonical form of the given headers of the request, with one "name:value" line for
+// each header. Names are lowercased and sorted; multiple values are joined with commas, and whitespace is trimmed.
+// The "host" header is read from the request's Host field if not set explicitly.
+// It also returns the list of signed headers, as a semicolon-separated string.
+func CanonicalHeaders(req *http.Request, names []string) (canonical string, signed string) {
+	lower := make([]string, len(names))
+	for i, n := range names {
+		lower[i] = strings.ToLower(n)
+	}
+	sort.Strings(lower)
+
+	var b strings.Builder
+	for _, n := range lower {
+		var value string
+		if n == "host" && req.Header.Get("Host") == "" {
+			value = req.Host
+			if value == "" && req.URL != nil {
+				value = req.URL.Host
+			}
+		} else {
+			values := req.Header.Values(n)
+			trimmed := make([]string, len(values))
+			for i, v := range values {
+				trimmed[i] = strings.Join(strings.Fields(v), " ")
+			}
+			value = strings.Join(trimmed, ",")
+		}
+		b.WriteString(n)
+		b.WriteByte(':')
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+	return b.String(), strings.Join(lower, ";")
+}
+
+// CanonicalRequest returns the canonical form of the request, which includes the method, path, query string, the
+// given headers, and the hex-encoded SHA-256 hash of the body, on separate lines.
+func CanonicalRequest(req *http.Request, body []byte, headers []string) string {
+	canonicalHeaders, signedHeaders := CanonicalHeaders(req, headers)
+	return strings.Join([]string{
+		req.Method,
+		CanonicalPath(req.URL),
+		CanonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		HashBody(body),
+	}, "\n")
+}
+
+// HashBody returns the hex-encoded SHA-256 hash of the body.
+func HashBody(body []byte) string {
+	h := sha256.Sum256(body)
+	return hex.EncodeToString(h[:])
+}
+
+func escapeRFC3986(s string) string {
+	// url.QueryEscape encodes spaces as "+" and doesn't encode "~"
+	return strings.ReplaceAll(strings.ReplaceAll(url.QueryEscape(s), "+", "%20"), "%7E", "~")
+}
+
+// HMACSignerOptions contains the options for NewHMACSigner.
+type HMACSignerOptions struct {
+	// KeyID identifies the key, and it's included in the signature header.
+	KeyID string
+	// Headers to sign. The "host" and "x-signature-date" headers are always included.
+	Headers []string
+	// Clock returns the current time. Default is time.Now.
+	Clock func() time.Time
+}
+
+const (
+	// HMACSignatureHeader is the header containing the signature added by the HMAC signer.
+	HMACSignatureHeader = "Signature"
+	// HMACDateHeader is the header containing the signature time added by the HMAC signer.
+	HMACDateHeader = "X-Signature-Date"
+	// HMACContentHashHeader is the header containing the hash of the body added by the HMAC signer.
+	HMACContentHashHeader = "X-Content-Sha256"
+)
+
+// NewHMACSigner returns a Signer that signs requests with HMAC-SHA256, using the given key.
+// The signature covers the canonical request (see CanonicalRequest) and the signature date. The signer adds headers
+// with the hash of the body, the signature date (in RFC 3339 format, UTC), and the signature, in the format:
+//
+//	Signature: keyId="<id>",algorithm="hmac-sha256",headers="<signed headers>",signature="<base64>"
+func NewHMACSigner(key []byte, opts HMACSignerOptions) Signer {
+	clock := opts.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	headers := append([]string{"host", strings.ToLower(HMACDateHeader), strings.ToLower(HMACContentHashHeader)}, opts.Headers...)
+
+	return SignerFunc(func(req *http.Request, body []byte) error {
+		req.Header.Set(HMACDateHeader, clock().UTC().Format(time.RFC3339))
+		req.Header.Set(HMACContentHashHeader, HashBody(body))
+
+		canonical := CanonicalRequest(req, body, headers)
+		_, signedHeaders := CanonicalHeaders(req, headers)
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(canonical))
+		signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+		req.Header.Set(HMACSignatureHeader, fmt.Sprintf(
+			`keyId="%s",algorithm="hmac-sha256",headers="%s",signature="%s"`,
+			opts.KeyID, signedHeaders, signature,
+		))
+		return nil
+	})
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalization(t *testing.T) {
+	u, err := url.Parse("https://example.com/a%20b/c?z=1&a=2&a=1&sp=x y&t=~")
+	require.NoError(t, err)
+
+	t.Run("path", func(t *testing.T) {
+		assert.Equal(t, "/a%20b/c", CanonicalPath(u))
+		assert.Equal(t, "/", CanonicalPath(&url.URL{Host: "example.com"}))
+	})
+
+	t.Run("query", func(t *testing.T) {
+		assert.Equal(t, "a=1&a=2&sp=x%20y&t=~&z=1", CanonicalQuery(u))
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+		req.Header.Add("X-Multi", "a")
+		req.Header.Add("X-Multi", "  b   c ")
+		req.Header.Set("Content-Type", "text/plain")
+
+		canonical, signed := CanonicalHeaders(req, []string{"X-Multi", "Host", "content-type"})
+		assert.Equal(t, "content-type:text/plain\nhost:example.com\nx-multi:a,b c\n", canonical)
+		assert.Equal(t, "content-type;host;x-multi", signed)
+	})
+
+	t.Run("request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "https://example.com/path?b=1&a=2", nil)
+		canonical := CanonicalRequest(req, []byte("hello"), []string{"host"})
+		assert.Equal(t, "POST\n/path\na=2&b=1\nhost:example.com\n\nhost\n"+HashBody([]byte("hello")), canonical)
+	})
+}
+
+func TestSigningTransport(t *testing.T) {
+	key := []byte("secret")
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var (
+		received     *http.Request
+		receivedBody string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		b, _ := io.ReadAll(r.Body)
+		receivedBody = string(b)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Transport: NewTransport(nil, WithSigner(NewHMACSigner(key, HMACSignerOptions{
+			KeyID:   "mykey",
+			Headers: []string{"content-type"},
+			Clock: func() time.Time {
+				return now
+			},
+		}))),
+	}
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/invoke?x=1", strings.NewReader("payload"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	// The original request is not modified
+	assert.Empty(t, req.Header.Get(HMACSignatureHeader))
+
+	require.NotNil(t, received)
+	assert.Equal(t, "payload", receivedBody)
+	assert.Equal(t, "2023-01-02T03:04:05Z", received.Header.Get(HMACDateHeader))
+	assert.Equal(t, HashBody([]byte("payload")), received.Header.Get(HMACContentHashHeader))
+
+	// Verify the signature on the server side
+	headers := []string{"host", "x-signature-date", "x-content-sha256", "content-type"}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(CanonicalRequest(received, []byte(receivedBody), headers)))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	assert.Equal(t,
+		`keyId="mykey",algorithm="hmac-sha256",headers="content-type;host;x-content-sha256;x-signature-date",signature="`+expected+`"`,
+		received.Header.Get(HMACSignatureHeader),
+	)
+}
+
+func TestSigningTransportWithDecompression(t *testing.T) {
+	key := []byte("secret")
+
+	var received *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+	}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Transport: NewTransport(nil,
+			WithSigner(NewHMACSigner(key, HMACSignerOptions{Headers: []string{"accept-encoding"}})),
+			WithDecompression(DecompressionOptions{}),
+		),
+	}
+	res, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	// The Accept-Encoding header added for decompression is covered by the signature
+	require.NotNil(t, received)
+	assert.Equal(t, "deflate, gzip", received.Header.Get("Accept-Encoding"))
+	headers := []string{"host", "x-signature-date", "x-content-sha256", "accept-encoding"}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(CanonicalRequest(received, nil, headers)))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	assert.Contains(t, received.Header.Get(HMACSignatureHeader), `signature="`+expected+`"`)
+}
+
+func TestSigningTransportError(t *testing.T) {
+	client := &http.Client{
+		Transport: NewTransport(nil, WithSigner(SignerFunc(func(req *http.Request, body []byte) error {
+			return errors.New("no credentials")
+		}))),
+	}
+	res, err := client.Get("http://127.0.0.1:1/")
+	if res != nil {
+		res.Body.Close()
+	}
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to sign request: no credentials")
+}
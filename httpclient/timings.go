@@ -0,0 +1,197 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/dapr/kit/logger"
+)
+
+// Timings contains the connection-level timings of an outbound request, collected with net/http/httptrace.
+// Phases that didn't happen, for example DNS resolution and connection when a connection is reused, are 0.
+type Timings struct {
+	// Method and URL of the request.
+	Method string
+	URL    string
+	// DNS is the time spent resolving the host name.
+	DNS time.Duration
+	// Connect is the time spent establishing the TCP connection.
+	Connect time.Duration
+	// TLSHandshake is the time spent in the TLS handshake.
+	TLSHandshake time.Duration
+	// TTFB (time to first byte) is the time from when the request was started to when the first byte of the response
+	// was received.
+	TTFB time.Duration
+	// Total is the time from when the request was started to when the response headers were received.
+	Total time.Duration
+	// ConnReused is true if the request was sent on a connection that was reused.
+	ConnReused bool
+	// RemoteAddr is the address of the server.
+	RemoteAddr string
+	// Err is the error returned by the round trip, if any.
+	Err error
+}
+
+// Fields returns the timings as fields for a logger, with durations in milliseconds.
+func (t Timings) Fields() map[string]any {
+	f := map[string]any{
+		"method":      t.Method,
+		"url":         t.URL,
+		"dns_ms":      durationMs(t.DNS),
+		"connect_ms":  durationMs(t.Connect),
+		"tls_ms":      durationMs(t.TLSHandshake),
+		"ttfb_ms":     durationMs(t.TTFB),
+		"total_ms":    durationMs(t.Total),
+		"conn_reused": t.ConnReused,
+		"remote_addr": t.RemoteAddr,
+	}
+	if t.Err != nil {
+		f["error"] = t.Err.Error()
+	}
+	return f
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// TimingsFn is invoked with the timings of each request, after the response headers are received or the round trip
+// failed.
+type TimingsFn func(req *http.Request, timings Timings)
+
+// WithTimings is a TransportOption that collects connection-level timings (DNS, connect, TLS, TTFB) for each request
+// using net/http/httptrace, and invokes fn with them.
+func WithTimings(fn TimingsFn) TransportOption {
+	return func(t *transport) {
+		t.timingsFn = fn
+	}
+}
+
+// LogTimings returns a TimingsFn that logs the timings of each request at debug level, as structured fields.
+func LogTimings(log logger.Logger) TimingsFn {
+	return func(req *http.Request, timings Timings) {
+		if !log.IsOutputLevelEnabled(logger.DebugLevel) {
+			return
+		}
+		log.WithFields(timings.Fields()).Debug("Outbound HTTP request timings")
+	}
+}
+
+// roundTripWithTimings performs the round trip with a httptrace.ClientTrace that collects the timings.
+func (t *transport) roundTripWithTimings(req *http.Request) (*http.Response, error) {
+	c := &timingsCollector{}
+	start := time.Now()
+	c.start = start
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), c.clientTrace()))
+
+	res, err := t.base.RoundTrip(req)
+
+	timings := c.timings()
+	timings.Method = req.Method
+	if req.URL != nil {
+		timings.URL = req.URL.Redacted()
+	}
+	timings.Total = time.Since(start)
+	timings.Err = err
+	t.timingsFn(req, timings)
+
+	return res, err
+}
+
+// timingsCollector collects timings from the httptrace callbacks, which may be invoked from other goroutines.
+type timingsCollector struct {
+	lock       sync.Mutex
+	start      time.Time
+	dnsStart   time.Time
+	dns        time.Duration
+	connStart  time.Time
+	connect    time.Duration
+	tlsStart   time.Time
+	tls        time.Duration
+	ttfb       time.Duration
+	reused     bool
+	remoteAddr string
+}
+
+func (c *timingsCollector) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			c.lock.Lock()
+			c.dnsStart = time.Now()
+			c.lock.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			c.lock.Lock()
+			c.dns = time.Since(c.dnsStart)
+			c.lock.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			c.lock.Lock()
+			// With multiple addresses, keep the time from the first attempt
+			if c.connStart.IsZero() {
+				c.connStart = time.Now()
+			}
+			c.lock.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err != nil {
+				return
+			}
+			c.lock.Lock()
+			c.connect = time.Since(c.connStart)
+			c.lock.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			c.lock.Lock()
+			c.tlsStart = time.Now()
+			c.lock.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			c.lock.Lock()
+			c.tls = time.Since(c.tlsStart)
+			c.lock.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.lock.Lock()
+			c.reused = info.Reused
+			if info.Conn != nil {
+				c.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+			c.lock.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			c.lock.Lock()
+			c.ttfb = time.Since(c.start)
+			c.lock.Unlock()
+		},
+	}
+}
+
+func (c *timingsCollector) timings() Timings {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return Timings{
+		DNS:          c.dns,
+		Connect:      c.connect,
+		TLSHandshake: c.tls,
+		TTFB:         c.ttfb,
+		ConnReused:   c.reused,
+		RemoteAddr:   c.remoteAddr,
+	}
+}
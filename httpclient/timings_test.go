@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+func TestTimings(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var (
+		lock    sync.Mutex
+		results []Timings
+	)
+	client := &http.Client{
+		Transport: NewTransport(srv.Client().Transport, WithTimings(func(req *http.Request, timings Timings) {
+			lock.Lock()
+			results = append(results, timings)
+			lock.Unlock()
+		})),
+	}
+
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(srv.URL + "/path")
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+
+	require.Len(t, results, 2)
+
+	first := results[0]
+	assert.Equal(t, http.MethodGet, first.Method)
+	assert.Equal(t, srv.URL+"/path", first.URL)
+	assert.False(t, first.ConnReused)
+	assert.Positive(t, first.Connect)
+	assert.Positive(t, first.TLSHandshake)
+	assert.Positive(t, first.TTFB)
+	assert.GreaterOrEqual(t, first.Total, first.TTFB)
+	assert.Equal(t, srv.Listener.Addr().String(), first.RemoteAddr)
+	require.NoError(t, first.Err)
+
+	second := results[1]
+	assert.True(t, second.ConnReused)
+	assert.Zero(t, second.Connect)
+	assert.Zero(t, second.TLSHandshake)
+	assert.Positive(t, second.TTFB)
+}
+
+func TestTimingsError(t *testing.T) {
+	var timings Timings
+	client := &http.Client{
+		Transport: NewTransport(nil, WithTimings(func(req *http.Request, ti Timings) {
+			timings = ti
+		})),
+	}
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	_, err := client.Get(url)
+	require.Error(t, err)
+	require.Error(t, timings.Err)
+	assert.Zero(t, timings.TTFB)
+}
+
+func TestLogTimings(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLogger("test")
+	log.SetOutput(&buf)
+	log.EnableJSONOutput(true)
+	log.SetOutputLevel(logger.DebugLevel)
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/foo", nil)
+	LogTimings(log)(req, Timings{
+		Method:     http.MethodPost,
+		URL:        "http://example.com/foo",
+		TTFB:       1500 * time.Microsecond,
+		ConnReused: true,
+	})
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "Outbound HTTP request timings", record["msg"])
+	assert.Equal(t, "POST", record["method"])
+	assert.InDelta(t, 1.5, record["ttfb_ms"], 0.0001)
+	assert.Equal(t, true, record["conn_reused"])
+
+	// Nothing is logged when debug is disabled
+	buf.Reset()
+	log.SetOutputLevel(logger.InfoLevel)
+	LogTimings(log)(req, Timings{})
+	assert.Empty(t, buf.String())
+}
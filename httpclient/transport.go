@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package httpclient contains utilities for HTTP clients, such as a http.RoundTripper that can sign requests,
+// collect connection-level timings, and safely decompress responses.
+package httpclient
+
+import (
+	"net/http"
+)
+
+// TransportOption is an option for NewTransport.
+type TransportOption func(t *transport)
+
+// NewTransport returns a http.RoundTripper that wraps base and applies the given options.
+// If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper, opts ...TransportOption) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &transport{
+		base: base,
+	}
+	for _, o := range opts {
+		o(t)
+	}
+	return t
+}
+
+type transport struct {
+	base          http.RoundTripper
+	signer        Signer
+	timingsFn     TimingsFn
+	decompression *decompression
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Headers are added before signing the request, so signers can cover them
+	var decode bool
+	if t.decompression != nil {
+		req, decode = t.decompression.prepareRequest(req)
+	}
+
+	if t.signer != nil {
+		var err error
+		req, err = t.sign(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		res *http.Response
+		err error
+	)
+	if t.timingsFn != nil {
+		res, err = t.roundTripWithTimings(req)
+	} else {
+		res, err = t.base.RoundTrip(req)
+	}
+	if err != nil || t.decompression == nil {
+		return res, err
+	}
+
+	return t.decompression.processResponse(res, decode)
+}
@@ -0,0 +1,396 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jobs contains a manager for long-running background routines.
+// Each job is registered with a name and the manager keeps track of its status (running, last error, restarts,
+// uptime), restarts it when it fails, and allows starting and stopping individual jobs.
+// The status of all jobs can be rendered as a JSON report, to make internals debuggable at runtime.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kclock "k8s.io/utils/clock"
+
+	"github.com/dapr/kit/logger"
+)
+
+const defaultRestartDelay = 5 * time.Second
+
+var (
+	// ErrJobExists is returned when adding a job with a name that is already registered.
+	ErrJobExists = errors.New("job already exists")
+	// ErrJobNotFound is returned when a job with the given name is not registered.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobRunning is returned when trying to start a job that is already running.
+	ErrJobRunning = errors.New("job is already running")
+	// ErrManagerNotRunning is returned when trying to start a job before the manager is started.
+	ErrManagerNotRunning = errors.New("manager is not running")
+)
+
+// Func is a background routine. It should run until ctx is canceled.
+// If it returns an error, the job is restarted after a delay; if it returns nil before ctx is canceled, the job is
+// considered completed and it's not restarted.
+type Func func(ctx context.Context) error
+
+// State of a job.
+type State string
+
+const (
+	// StatePending is the state of jobs that have not been started yet.
+	StatePending State = "pending"
+	// StateRunning is the state of jobs that are running.
+	StateRunning State = "running"
+	// StateRestarting is the state of jobs that failed and are waiting to be restarted.
+	StateRestarting State = "restarting"
+	// StateCompleted is the state of jobs that returned without an error.
+	StateCompleted State = "completed"
+	// StateStopped is the state of jobs that were stopped.
+	StateStopped State = "stopped"
+)
+
+// Manager runs and tracks background jobs.
+type Manager struct {
+	log          logger.Logger
+	clock        kclock.Clock
+	restartDelay time.Duration
+
+	jobs    map[string]*job
+	order   []string
+	ctx     context.Context
+	lock    sync.Mutex
+	wg      sync.WaitGroup
+	running atomic.Bool
+}
+
+// NewManager returns a new Manager.
+func NewManager(log logger.Logger) *Manager {
+	return &Manager{
+		log:          log,
+		clock:        kclock.RealClock{},
+		restartDelay: defaultRestartDelay,
+		jobs:         map[string]*job{},
+	}
+}
+
+// WithClock sets the clock used by the manager. Used for testing.
+func (m *Manager) WithClock(clock kclock.Clock) *Manager {
+	m.clock = clock
+	return m
+}
+
+// SetRestartDelay sets the delay before a failed job is restarted.
+func (m *Manager) SetRestartDelay(restartDelay time.Duration) {
+	m.restartDelay = restartDelay
+}
+
+// Add registers a new job with the given name.
+// If the manager is running, the job is started right away.
+func (m *Manager) Add(name string, fn Func) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, name)
+	}
+
+	j := &job{
+		name:  name,
+		fn:    fn,
+		state: StatePending,
+	}
+	m.jobs[name] = j
+	m.order = append(m.order, name)
+
+	if m.running.Load() {
+		m.startJob(j)
+	}
+	return nil
+}
+
+// Start all registered jobs.
+// This method blocks until the context is canceled, then it stops all jobs and waits for them to return.
+func (m *Manager) Start(ctx context.Context) error {
+	if !m.running.CompareAndSwap(false, true) {
+		return errors.New("manager is already running")
+	}
+
+	m.lock.Lock()
+	m.ctx = ctx
+	for _, name := range m.order {
+		m.startJob(m.jobs[name])
+	}
+	m.lock.Unlock()
+
+	<-ctx.Done()
+
+	m.lock.Lock()
+	m.running.Store(false)
+	m.lock.Unlock()
+
+	m.wg.Wait()
+	return nil
+}
+
+// StartJob starts the job with the given name, if it's not running.
+func (m *Manager) StartJob(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.running.Load() {
+		return ErrManagerNotRunning
+	}
+	j, ok := m.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if j.isActive() {
+		return fmt.Errorf("%w: %s", ErrJobRunning, name)
+	}
+
+	m.startJob(j)
+	return nil
+}
+
+// StopJob stops the job with the given name and waits for it to return.
+// It's a no-op if the job is not running.
+func (m *Manager) StopJob(name string) error {
+	m.lock.Lock()
+	j, ok := m.jobs[name]
+	m.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	j.stop()
+	return nil
+}
+
+// startJob starts the job in a background goroutine.
+// This must be invoked while the caller has a lock.
+func (m *Manager) startJob(j *job) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	doneCh := make(chan struct{})
+
+	// The state is set before the goroutine starts, so concurrent calls to StartJob see the job as active
+	j.lock.Lock()
+	j.cancel = cancel
+	j.doneCh = doneCh
+	j.state = StateRunning
+	j.startedAt = m.clock.Now()
+	j.lock.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer close(doneCh)
+		defer cancel()
+		m.runJob(ctx, j)
+	}()
+}
+
+// runJob runs the job, restarting it on failures, until it completes or ctx is canceled.
+func (m *Manager) runJob(ctx context.Context, j *job) {
+	for {
+		j.setRunning(m.clock.Now())
+		err := j.fn(ctx)
+
+		if ctx.Err() != nil {
+			j.setState(StateStopped)
+			return
+		}
+		if err == nil {
+			j.setState(StateCompleted)
+			return
+		}
+
+		m.log.Errorf("Job '%s' failed, restarting in %v: %v", j.name, m.restartDelay, err)
+		j.setFailed(err, m.clock.Now())
+
+		t := m.clock.NewTimer(m.restartDelay)
+		select {
+		case <-t.C():
+			j.addRestart()
+		case <-ctx.Done():
+			if !t.Stop() {
+				<-t.C()
+			}
+			j.setState(StateStopped)
+			return
+		}
+	}
+}
+
+// Status returns the status of all jobs, in the order they were added.
+func (m *Manager) Status() []JobStatus {
+	m.lock.Lock()
+	jobs := make([]*job, len(m.order))
+	for i, name := range m.order {
+		jobs[i] = m.jobs[name]
+	}
+	m.lock.Unlock()
+
+	now := m.clock.Now()
+	res := make([]JobStatus, len(jobs))
+	for i, j := range jobs {
+		res[i] = j.status(now)
+	}
+	return res
+}
+
+// Report returns a JSON-encoded report with the status of all jobs.
+func (m *Manager) Report() ([]byte, error) {
+	return json.Marshal(Report{Jobs: m.Status()})
+}
+
+// ServeHTTP implements http.Handler and responds with the JSON report.
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report, err := m.Report()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(report)
+}
+
+// Report is the status report of all jobs.
+type Report struct {
+	Jobs []JobStatus `json:"jobs"`
+}
+
+// JobStatus contains the status of a job.
+type JobStatus struct {
+	// Name of the job.
+	Name string `json:"name"`
+	// State of the job.
+	State State `json:"state"`
+	// LastError is the last error returned by the job, if any.
+	LastError string `json:"lastError,omitempty"`
+	// LastErrorTime is the time when the last error was returned.
+	LastErrorTime *time.Time `json:"lastErrorTime,omitempty"`
+	// Restarts is the number of times the job was restarted after a failure.
+	Restarts int `json:"restarts"`
+	// StartedAt is the time the job was last (re-)started.
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	// Uptime is the time since the job was last (re-)started, if it's running.
+	Uptime time.Duration `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler and formats the uptime as a string.
+func (s JobStatus) MarshalJSON() ([]byte, error) {
+	type alias JobStatus
+	var uptime string
+	if s.Uptime > 0 {
+		uptime = s.Uptime.String()
+	}
+	return json.Marshal(struct {
+		alias
+		Uptime string `json:"uptime,omitempty"`
+	}{
+		alias:  alias(s),
+		Uptime: uptime,
+	})
+}
+
+type job struct {
+	name string
+	fn   Func
+
+	lock        sync.Mutex
+	state       State
+	lastErr     error
+	lastErrTime time.Time
+	restarts    int
+	startedAt   time.Time
+	cancel      context.CancelFunc
+	doneCh      chan struct{}
+}
+
+// isActive returns true if the job has a goroutine running, including when it's waiting to be restarted.
+func (j *job) isActive() bool {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.state == StateRunning || j.state == StateRestarting
+}
+
+func (j *job) stop() {
+	j.lock.Lock()
+	cancel := j.cancel
+	doneCh := j.doneCh
+	j.lock.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-doneCh
+}
+
+func (j *job) setRunning(now time.Time) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.state = StateRunning
+	j.startedAt = now
+}
+
+func (j *job) setFailed(err error, now time.Time) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.state = StateRestarting
+	j.lastErr = err
+	j.lastErrTime = now
+}
+
+func (j *job) setState(state State) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.state = state
+}
+
+func (j *job) addRestart() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.restarts++
+}
+
+func (j *job) status(now time.Time) JobStatus {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	s := JobStatus{
+		Name:     j.name,
+		State:    j.state,
+		Restarts: j.restarts,
+	}
+	if j.lastErr != nil {
+		s.LastError = j.lastErr.Error()
+		t := j.lastErrTime
+		s.LastErrorTime = &t
+	}
+	if !j.startedAt.IsZero() {
+		t := j.startedAt
+		s.StartedAt = &t
+	}
+	if j.state == StateRunning {
+		s.Uptime = now.Sub(j.startedAt)
+	}
+	return s
+}
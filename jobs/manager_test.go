@@ -0,0 +1,228 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/kit/logger"
+)
+
+func newTestManager(t *testing.T) (*Manager, *clocktesting.FakeClock, context.CancelFunc) {
+	t.Helper()
+
+	clock := clocktesting.NewFakeClock(time.Now())
+	m := NewManager(logger.NewLogger("test")).WithClock(clock)
+	m.SetRestartDelay(time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		assert.NoError(t, m.Start(ctx))
+	}()
+	require.Eventually(t, m.running.Load, time.Second, 10*time.Millisecond)
+
+	return m, clock, func() {
+		cancel()
+		select {
+		case <-doneCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("manager did not stop in time")
+		}
+	}
+}
+
+func waitForState(t *testing.T, m *Manager, name string, state State) JobStatus {
+	t.Helper()
+	var status JobStatus
+	require.Eventually(t, func() bool {
+		for _, s := range m.Status() {
+			if s.Name == name {
+				status = s
+				return s.State == state
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond, "job %s did not reach state %s", name, state)
+	return status
+}
+
+func blockingJob(started *atomic.Int32) Func {
+	return func(ctx context.Context) error {
+		started.Add(1)
+		<-ctx.Done()
+		return nil
+	}
+}
+
+func TestManager(t *testing.T) {
+	t.Run("jobs are started and stopped with the manager", func(t *testing.T) {
+		m := NewManager(logger.NewLogger("test"))
+		var started atomic.Int32
+		require.NoError(t, m.Add("a", blockingJob(&started)))
+		require.NoError(t, m.Add("b", blockingJob(&started)))
+		require.ErrorIs(t, m.Add("a", blockingJob(&started)), ErrJobExists)
+		require.ErrorIs(t, m.StartJob("a"), ErrManagerNotRunning)
+
+		status := m.Status()
+		require.Len(t, status, 2)
+		assert.Equal(t, StatePending, status[0].State)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		doneCh := make(chan struct{})
+		go func() {
+			defer close(doneCh)
+			assert.NoError(t, m.Start(ctx))
+		}()
+
+		waitForState(t, m, "a", StateRunning)
+		waitForState(t, m, "b", StateRunning)
+		assert.Eventually(t, func() bool { return started.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		<-doneCh
+		assert.Equal(t, StateStopped, m.Status()[0].State)
+		assert.Equal(t, StateStopped, m.Status()[1].State)
+	})
+
+	t.Run("jobs added while running are started", func(t *testing.T) {
+		m, _, stop := newTestManager(t)
+		defer stop()
+
+		var started atomic.Int32
+		require.NoError(t, m.Add("late", blockingJob(&started)))
+		waitForState(t, m, "late", StateRunning)
+	})
+
+	t.Run("failed jobs are restarted", func(t *testing.T) {
+		m, clock, stop := newTestManager(t)
+		defer stop()
+
+		var calls atomic.Int32
+		require.NoError(t, m.Add("flaky", func(ctx context.Context) error {
+			if calls.Add(1) <= 2 {
+				return errors.New("boom")
+			}
+			<-ctx.Done()
+			return nil
+		}))
+
+		for i := 0; i < 2; i++ {
+			status := waitForState(t, m, "flaky", StateRestarting)
+			assert.Equal(t, "boom", status.LastError)
+			assert.NotNil(t, status.LastErrorTime)
+			require.Eventually(t, clock.HasWaiters, time.Second, 10*time.Millisecond)
+			clock.Step(time.Second)
+		}
+
+		status := waitForState(t, m, "flaky", StateRunning)
+		assert.Equal(t, 2, status.Restarts)
+		assert.Equal(t, "boom", status.LastError)
+
+		clock.Step(time.Minute)
+		status = waitForState(t, m, "flaky", StateRunning)
+		assert.Equal(t, time.Minute, status.Uptime)
+	})
+
+	t.Run("completed jobs are not restarted", func(t *testing.T) {
+		m, _, stop := newTestManager(t)
+		defer stop()
+
+		require.NoError(t, m.Add("oneshot", func(ctx context.Context) error {
+			return nil
+		}))
+		status := waitForState(t, m, "oneshot", StateCompleted)
+		assert.Equal(t, 0, status.Restarts)
+		assert.Zero(t, status.Uptime)
+	})
+
+	t.Run("start and stop individual jobs", func(t *testing.T) {
+		m, _, stop := newTestManager(t)
+		defer stop()
+
+		var started atomic.Int32
+		require.NoError(t, m.Add("job", blockingJob(&started)))
+		waitForState(t, m, "job", StateRunning)
+		require.ErrorIs(t, m.StartJob("job"), ErrJobRunning)
+
+		require.NoError(t, m.StopJob("job"))
+		assert.Equal(t, StateStopped, m.Status()[0].State)
+		// Stopping again is a no-op
+		require.NoError(t, m.StopJob("job"))
+
+		require.NoError(t, m.StartJob("job"))
+		waitForState(t, m, "job", StateRunning)
+		// The job is reported as running as soon as it's started, before its function is invoked
+		assert.Eventually(t, func() bool { return started.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+		require.ErrorIs(t, m.StartJob("missing"), ErrJobNotFound)
+		require.ErrorIs(t, m.StopJob("missing"), ErrJobNotFound)
+	})
+
+	t.Run("starting a job twice in a row", func(t *testing.T) {
+		m, _, stop := newTestManager(t)
+		defer stop()
+
+		var started atomic.Int32
+		require.NoError(t, m.Add("job", blockingJob(&started)))
+		waitForState(t, m, "job", StateRunning)
+		require.NoError(t, m.StopJob("job"))
+
+		// The second call must fail even if the goroutine of the first one hasn't started yet
+		require.NoError(t, m.StartJob("job"))
+		require.ErrorIs(t, m.StartJob("job"), ErrJobRunning)
+		assert.Eventually(t, func() bool { return started.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+		// The job can still be stopped
+		require.NoError(t, m.StopJob("job"))
+		assert.Equal(t, StateStopped, m.Status()[0].State)
+		assert.Equal(t, int32(2), started.Load())
+	})
+}
+
+func TestReport(t *testing.T) {
+	m, _, stop := newTestManager(t)
+	defer stop()
+
+	var started atomic.Int32
+	require.NoError(t, m.Add("job", blockingJob(&started)))
+	waitForState(t, m, "job", StateRunning)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var report map[string][]map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Len(t, report["jobs"], 1)
+	job := report["jobs"][0]
+	assert.Equal(t, "job", job["name"])
+	assert.Equal(t, "running", job["state"])
+	assert.Equal(t, float64(0), job["restarts"])
+	assert.NotEmpty(t, job["startedAt"])
+	assert.NotContains(t, job, "lastError")
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package loadshed implements priority-based load shedding.
+// A Shedder tracks one or more utilization signals (such as queue depth, latency, or memory usage) and, as
+// utilization grows, first degrades and then rejects requests, starting from the ones with the lowest priority.
+// Rejected requests receive a kit error with code Unavailable and a RetryInfo detail.
+// The Shedder can be used directly or as HTTP middleware and gRPC interceptor.
+package loadshed
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+const (
+	// ErrorReasonLoadShed is the reason of errors returned when a request is shed.
+	ErrorReasonLoadShed = "LOAD_SHED"
+
+	defaultRetryAfter = time.Second
+)
+
+// ErrLoadShed is the error wrapped by the kit errors returned when a request is shed.
+var ErrLoadShed = errors.New("request rejected because the server is overloaded")
+
+// Priority of a request.
+type Priority int
+
+const (
+	// PriorityLow is for requests that can be shed first, such as background work.
+	PriorityLow Priority = iota
+	// PriorityNormal is the default priority.
+	PriorityNormal
+	// PriorityHigh is for requests that should be shed only when the system is close to saturation.
+	PriorityHigh
+	// PriorityCritical is for requests that are never shed, such as health checks.
+	PriorityCritical
+)
+
+// String implements fmt.Stringer and is used for debugging.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return strconv.Itoa(int(p))
+	}
+}
+
+// Decision is the outcome of the evaluation of a request.
+type Decision int
+
+const (
+	// DecisionAccept means that the request can be processed normally.
+	DecisionAccept Decision = iota
+	// DecisionDegrade means that the request should be processed in degraded mode, for example skipping optional work.
+	DecisionDegrade
+	// DecisionShed means that the request must be rejected.
+	DecisionShed
+)
+
+// Options for the Shedder.
+type Options struct {
+	// Signals that are used to compute the utilization. The utilization of the Shedder is the maximum of all signals.
+	Signals []Signal
+	// ShedThresholds contains the utilization at or above which requests with a given priority are shed.
+	// Priorities that are not in the map are never shed.
+	// If nil, the defaults are: low=0.8, normal=0.9, high=0.98.
+	ShedThresholds map[Priority]float64
+	// DegradeThresholds contains the utilization at or above which requests with a given priority are degraded.
+	// Priorities that are not in the map are never degraded.
+	// If nil, the defaults are: low=0.6, normal=0.75, high=0.9.
+	DegradeThresholds map[Priority]float64
+	// RetryAfter is the delay suggested to clients whose requests are shed. Default is 1s.
+	RetryAfter time.Duration
+}
+
+// Shedder decides whether requests should be accepted, degraded, or shed based on the current utilization.
+type Shedder struct {
+	signals           []Signal
+	shedThresholds    map[Priority]float64
+	degradeThresholds map[Priority]float64
+	retryAfter        time.Duration
+}
+
+// New returns a new Shedder.
+func New(opts Options) *Shedder {
+	s := &Shedder{
+		signals:           opts.Signals,
+		shedThresholds:    opts.ShedThresholds,
+		degradeThresholds: opts.DegradeThresholds,
+		retryAfter:        opts.RetryAfter,
+	}
+	if s.shedThresholds == nil {
+		s.shedThresholds = map[Priority]float64{
+			PriorityLow:    0.8,
+			PriorityNormal: 0.9,
+			PriorityHigh:   0.98,
+		}
+	}
+	if s.degradeThresholds == nil {
+		s.degradeThresholds = map[Priority]float64{
+			PriorityLow:    0.6,
+			PriorityNormal: 0.75,
+			PriorityHigh:   0.9,
+		}
+	}
+	if s.retryAfter <= 0 {
+		s.retryAfter = defaultRetryAfter
+	}
+	return s
+}
+
+// Utilization returns the current utilization, which is the maximum of the utilization of all signals.
+func (s *Shedder) Utilization() float64 {
+	var u float64
+	for _, sig := range s.signals {
+		if v := sig.Utilization(); v > u {
+			u = v
+		}
+	}
+	return u
+}
+
+// Decide returns the decision for a request with the given priority.
+func (s *Shedder) Decide(p Priority) Decision {
+	u := s.Utilization()
+	if t, ok := s.shedThresholds[p]; ok && u >= t {
+		return DecisionShed
+	}
+	if t, ok := s.degradeThresholds[p]; ok && u >= t {
+		return DecisionDegrade
+	}
+	return DecisionAccept
+}
+
+// Allow returns the decision for a request with the given priority and, if the request must be shed, a kit error
+// with code Unavailable and a RetryInfo detail. Callers that return the error to clients should emit it with
+// kiterrors.Emit.
+func (s *Shedder) Allow(p Priority) (Decision, error) {
+	d := s.Decide(p)
+	if d == DecisionShed {
+		return d, s.shedError(p)
+	}
+	return d, nil
+}
+
+// RetryAfter returns the delay suggested to clients whose requests are shed.
+func (s *Shedder) RetryAfter() time.Duration {
+	return s.retryAfter
+}
+
+func (s *Shedder) shedError(p Priority) *kiterrors.Error {
+	return kiterrors.New(ErrLoadShed, nil,
+		kiterrors.WithErrorReason(ErrorReasonLoadShed, codes.Unavailable),
+		kiterrors.WithMetadata(map[string]string{
+			"priority": p.String(),
+		}),
+		kiterrors.WithRetryInfo(s.retryAfter),
+	)
+}
+
+// started notifies the signals that observe requests that a request has started.
+func (s *Shedder) started() {
+	for _, sig := range s.signals {
+		if o, ok := sig.(RequestObserver); ok {
+			o.RequestStarted()
+		}
+	}
+}
+
+// done notifies the signals that observe requests that a request has completed.
+func (s *Shedder) done(latency time.Duration) {
+	for _, sig := range s.signals {
+		if o, ok := sig.(RequestObserver); ok {
+			o.RequestDone(latency)
+		}
+	}
+}
+
+type degradedContextKey struct{}
+
+// IsDegraded returns true if the request whose context is ctx should be processed in degraded mode.
+// This is set by the HTTP middleware and gRPC interceptors.
+func IsDegraded(ctx context.Context) bool {
+	v, _ := ctx.Value(degradedContextKey{}).(bool)
+	return v
+}
+
+func withDegraded(ctx context.Context, d Decision) context.Context {
+	if d != DecisionDegrade {
+		return ctx
+	}
+	return context.WithValue(ctx, degradedContextKey{}, true)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadshed
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// staticSignal is a Signal with a utilization that can be changed by tests.
+type staticSignal struct {
+	u float64
+}
+
+func (s *staticSignal) Utilization() float64 {
+	return s.u
+}
+
+func TestDecide(t *testing.T) {
+	sig := &staticSignal{}
+	s := New(Options{
+		Signals: []Signal{sig, SignalFunc(func() float64 { return 0.1 })},
+	})
+
+	tests := []struct {
+		utilization float64
+		expect      map[Priority]Decision
+	}{
+		{0.05, map[Priority]Decision{PriorityLow: DecisionAccept, PriorityNormal: DecisionAccept, PriorityHigh: DecisionAccept, PriorityCritical: DecisionAccept}},
+		{0.6, map[Priority]Decision{PriorityLow: DecisionDegrade, PriorityNormal: DecisionAccept, PriorityHigh: DecisionAccept, PriorityCritical: DecisionAccept}},
+		{0.8, map[Priority]Decision{PriorityLow: DecisionShed, PriorityNormal: DecisionDegrade, PriorityHigh: DecisionAccept, PriorityCritical: DecisionAccept}},
+		{0.95, map[Priority]Decision{PriorityLow: DecisionShed, PriorityNormal: DecisionShed, PriorityHigh: DecisionDegrade, PriorityCritical: DecisionAccept}},
+		{2, map[Priority]Decision{PriorityLow: DecisionShed, PriorityNormal: DecisionShed, PriorityHigh: DecisionShed, PriorityCritical: DecisionAccept}},
+	}
+
+	for _, tc := range tests {
+		sig.u = tc.utilization
+		for p, d := range tc.expect {
+			assert.Equal(t, d, s.Decide(p), "utilization=%v priority=%v", tc.utilization, p)
+		}
+	}
+
+	// Utilization is the max of all signals
+	sig.u = 0
+	assert.InDelta(t, 0.1, s.Utilization(), 0.0001)
+}
+
+func TestCustomThresholds(t *testing.T) {
+	sig := &staticSignal{u: 0.5}
+	s := New(Options{
+		Signals:           []Signal{sig},
+		ShedThresholds:    map[Priority]float64{PriorityNormal: 0.5},
+		DegradeThresholds: map[Priority]float64{},
+	})
+
+	assert.Equal(t, DecisionShed, s.Decide(PriorityNormal))
+	assert.Equal(t, DecisionAccept, s.Decide(PriorityLow))
+}
+
+func TestAllow(t *testing.T) {
+	sig := &staticSignal{u: 0.85}
+	s := New(Options{
+		Signals:    []Signal{sig},
+		RetryAfter: 3 * time.Second,
+	})
+
+	d, err := s.Allow(PriorityNormal)
+	require.NoError(t, err)
+	assert.Equal(t, DecisionDegrade, d)
+
+	d, err = s.Allow(PriorityLow)
+	require.Error(t, err)
+	assert.Equal(t, DecisionShed, d)
+	assert.True(t, errors.Is(err, ErrLoadShed))
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.Unavailable, st.Code())
+
+	var gotRetryInfo, gotErrorInfo bool
+	for _, detail := range st.Details() {
+		switch x := detail.(type) {
+		case *errdetails.RetryInfo:
+			gotRetryInfo = true
+			assert.Equal(t, 3*time.Second, x.GetRetryDelay().AsDuration())
+		case *errdetails.ErrorInfo:
+			gotErrorInfo = true
+			assert.Equal(t, ErrorReasonLoadShed, x.GetReason())
+			assert.Equal(t, "low", x.GetMetadata()["priority"])
+		}
+	}
+	assert.True(t, gotRetryInfo)
+	assert.True(t, gotErrorInfo)
+}
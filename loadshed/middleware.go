@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadshed
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+// HTTPPriorityFn returns the priority of an HTTP request.
+type HTTPPriorityFn func(r *http.Request) Priority
+
+// GRPCPriorityFn returns the priority of a gRPC call, given its context and full method name.
+type GRPCPriorityFn func(ctx context.Context, fullMethod string) Priority
+
+// HTTPMiddleware returns a HTTP middleware that sheds requests based on their priority.
+// If priorityFn is nil, all requests have PriorityNormal.
+// Shed requests receive the JSON-encoded kit error and a Retry-After header.
+func (s *Shedder) HTTPMiddleware(priorityFn HTTPPriorityFn) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := PriorityNormal
+			if priorityFn != nil {
+				p = priorityFn(r)
+			}
+
+			d := s.Decide(p)
+			if d == DecisionShed {
+				kerr := s.shedError(p)
+				kerr.Emit()
+				code, body := kerr.ToHTTP()
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(s.retryAfter.Seconds()))))
+				w.WriteHeader(code)
+				_, _ = w.Write(body)
+				return
+			}
+
+			s.started()
+			start := time.Now()
+			defer func() {
+				s.done(time.Since(start))
+			}()
+			next.ServeHTTP(w, r.WithContext(withDegraded(r.Context(), d)))
+		})
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor that sheds calls based on their priority.
+// If priorityFn is nil, all calls have PriorityNormal.
+func (s *Shedder) UnaryServerInterceptor(priorityFn GRPCPriorityFn) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		p := PriorityNormal
+		if priorityFn != nil {
+			p = priorityFn(ctx, info.FullMethod)
+		}
+
+		d, err := s.Allow(p)
+		if err != nil {
+			kiterrors.Emit(err)
+			return nil, err
+		}
+
+		s.started()
+		start := time.Now()
+		defer func() {
+			s.done(time.Since(start))
+		}()
+		return handler(withDegraded(ctx, d), req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC stream server interceptor that sheds streams based on their priority.
+// If priorityFn is nil, all streams have PriorityNormal.
+func (s *Shedder) StreamServerInterceptor(priorityFn GRPCPriorityFn) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		p := PriorityNormal
+		if priorityFn != nil {
+			p = priorityFn(ss.Context(), info.FullMethod)
+		}
+
+		d, err := s.Allow(p)
+		if err != nil {
+			kiterrors.Emit(err)
+			return err
+		}
+
+		s.started()
+		start := time.Now()
+		defer func() {
+			s.done(time.Since(start))
+		}()
+		if d == DecisionDegrade {
+			ss = &degradedServerStream{ServerStream: ss, ctx: withDegraded(ss.Context(), d)}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// degradedServerStream is a grpc.ServerStream whose context is marked as degraded.
+type degradedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *degradedServerStream) Context() context.Context {
+	return s.ctx
+}
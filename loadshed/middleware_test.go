@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadshed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	sig := &staticSignal{}
+	inFlight := NewInFlightSignal(10)
+	s := New(Options{
+		Signals:    []Signal{sig, inFlight},
+		RetryAfter: 1500 * time.Millisecond,
+	})
+
+	var degraded bool
+	handler := s.HTTPMiddleware(func(r *http.Request) Priority {
+		if r.Header.Get("x-priority") == "low" {
+			return PriorityLow
+		}
+		return PriorityNormal
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		degraded = IsDegraded(r.Context())
+		assert.InDelta(t, 0.1, inFlight.Utilization(), 0.0001)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	t.Run("accepted", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.False(t, degraded)
+		assert.Zero(t, inFlight.Utilization())
+	})
+
+	t.Run("degraded", func(t *testing.T) {
+		sig.u = 0.85
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, degraded)
+	})
+
+	t.Run("shed", func(t *testing.T) {
+		var emitted []string
+		remove := kiterrors.AddObserver(kiterrors.ObserverFunc(func(e *kiterrors.Error) {
+			emitted = append(emitted, e.Reason())
+		}))
+		defer remove()
+
+		sig.u = 0.85
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("x-priority", "low")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, []string{ErrorReasonLoadShed}, emitted)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), ErrorReasonLoadShed)
+		assert.Contains(t, rec.Body.String(), "google.rpc.RetryInfo")
+	})
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	sig := &staticSignal{}
+	s := New(Options{Signals: []Signal{sig}})
+
+	interceptor := s.UnaryServerInterceptor(func(ctx context.Context, fullMethod string) Priority {
+		if fullMethod == "/test/Background" {
+			return PriorityLow
+		}
+		return PriorityCritical
+	})
+	handler := func(ctx context.Context, req any) (any, error) {
+		return IsDegraded(ctx), nil
+	}
+
+	sig.u = 0.7
+	res, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/Background"}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, true, res)
+
+	sig.u = 5
+	res, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/Health"}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, false, res)
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/Background"}, handler)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+}
+
+func (testServerStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	sig := &staticSignal{}
+	s := New(Options{Signals: []Signal{sig}})
+	interceptor := s.StreamServerInterceptor(nil)
+
+	var degraded bool
+	handler := func(srv any, ss grpc.ServerStream) error {
+		degraded = IsDegraded(ss.Context())
+		return nil
+	}
+	info := &grpc.StreamServerInfo{FullMethod: "/test/Stream"}
+
+	require.NoError(t, interceptor(nil, testServerStream{}, info, handler))
+	assert.False(t, degraded)
+
+	sig.u = 0.8
+	require.NoError(t, interceptor(nil, testServerStream{}, info, handler))
+	assert.True(t, degraded)
+
+	sig.u = 0.9
+	err := interceptor(nil, testServerStream{}, info, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadshed
+
+import (
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Signal reports a utilization value. A value of 0 means idle and 1 means fully saturated; values above 1 are allowed.
+type Signal interface {
+	Utilization() float64
+}
+
+// RequestObserver is implemented by signals that are updated with each request processed by the middleware.
+type RequestObserver interface {
+	// RequestStarted is invoked when a request is accepted.
+	RequestStarted()
+	// RequestDone is invoked when a request that was accepted has completed.
+	RequestDone(latency time.Duration)
+}
+
+// SignalFunc is a function that implements Signal.
+type SignalFunc func() float64
+
+// Utilization implements Signal.
+func (f SignalFunc) Utilization() float64 {
+	return f()
+}
+
+// QueueDepth returns a Signal that reports the ratio between the value returned by depth and capacity.
+func QueueDepth(depth func() int, capacity int) Signal {
+	return SignalFunc(func() float64 {
+		if capacity <= 0 {
+			return 0
+		}
+		return float64(depth()) / float64(capacity)
+	})
+}
+
+// InFlightSignal is a Signal that reports the ratio between the number of in-flight requests and a capacity.
+type InFlightSignal struct {
+	capacity int64
+	inFlight atomic.Int64
+}
+
+// NewInFlightSignal returns a new InFlightSignal for the given maximum number of concurrent requests.
+func NewInFlightSignal(capacity int) *InFlightSignal {
+	return &InFlightSignal{capacity: int64(capacity)}
+}
+
+// Utilization implements Signal.
+func (s *InFlightSignal) Utilization() float64 {
+	if s.capacity <= 0 {
+		return 0
+	}
+	return float64(s.inFlight.Load()) / float64(s.capacity)
+}
+
+// RequestStarted implements RequestObserver.
+func (s *InFlightSignal) RequestStarted() {
+	s.inFlight.Add(1)
+}
+
+// RequestDone implements RequestObserver.
+func (s *InFlightSignal) RequestDone(time.Duration) {
+	s.inFlight.Add(-1)
+}
+
+// LatencySignal is a Signal that reports the ratio between the moving average of request latencies and a target.
+type LatencySignal struct {
+	target time.Duration
+	alpha  float64
+	avg    float64
+	lock   sync.Mutex
+}
+
+// NewLatencySignal returns a new LatencySignal.
+// The average is an exponentially-weighted moving average where each new observation has weight alpha, between 0 and 1.
+func NewLatencySignal(target time.Duration, alpha float64) *LatencySignal {
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.1
+	}
+	return &LatencySignal{
+		target: target,
+		alpha:  alpha,
+	}
+}
+
+// Utilization implements Signal.
+func (s *LatencySignal) Utilization() float64 {
+	if s.target <= 0 {
+		return 0
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.avg / float64(s.target)
+}
+
+// Observe adds a latency observation.
+func (s *LatencySignal) Observe(latency time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.avg == 0 {
+		s.avg = float64(latency)
+		return
+	}
+	s.avg = s.alpha*float64(latency) + (1-s.alpha)*s.avg
+}
+
+// RequestStarted implements RequestObserver.
+func (s *LatencySignal) RequestStarted() {}
+
+// RequestDone implements RequestObserver.
+func (s *LatencySignal) RequestDone(latency time.Duration) {
+	s.Observe(latency)
+}
+
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// MemorySignal returns a Signal that reports the ratio between the memory used by heap objects and limit, in bytes.
+func MemorySignal(limit uint64) Signal {
+	return SignalFunc(func() float64 {
+		if limit == 0 {
+			return 0
+		}
+		sample := []metrics.Sample{{Name: heapObjectsMetric}}
+		metrics.Read(sample)
+		if sample[0].Value.Kind() != metrics.KindUint64 {
+			return 0
+		}
+		return float64(sample[0].Value.Uint64()) / float64(limit)
+	})
+}
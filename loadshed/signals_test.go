@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadshed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueDepth(t *testing.T) {
+	depth := 5
+	sig := QueueDepth(func() int { return depth }, 10)
+	assert.InDelta(t, 0.5, sig.Utilization(), 0.0001)
+	depth = 20
+	assert.InDelta(t, 2, sig.Utilization(), 0.0001)
+
+	assert.Zero(t, QueueDepth(func() int { return 1 }, 0).Utilization())
+}
+
+func TestInFlightSignal(t *testing.T) {
+	sig := NewInFlightSignal(4)
+	assert.Zero(t, sig.Utilization())
+	sig.RequestStarted()
+	sig.RequestStarted()
+	assert.InDelta(t, 0.5, sig.Utilization(), 0.0001)
+	sig.RequestDone(time.Second)
+	assert.InDelta(t, 0.25, sig.Utilization(), 0.0001)
+}
+
+func TestLatencySignal(t *testing.T) {
+	sig := NewLatencySignal(100*time.Millisecond, 0.5)
+	assert.Zero(t, sig.Utilization())
+
+	// First observation initializes the average
+	sig.Observe(50 * time.Millisecond)
+	assert.InDelta(t, 0.5, sig.Utilization(), 0.0001)
+
+	sig.RequestDone(150 * time.Millisecond)
+	assert.InDelta(t, 1, sig.Utilization(), 0.0001)
+}
+
+func TestMemorySignal(t *testing.T) {
+	assert.Greater(t, MemorySignal(1).Utilization(), float64(1))
+	assert.Less(t, MemorySignal(1<<62).Utilization(), float64(1))
+	assert.Zero(t, MemorySignal(0).Utilization())
+}
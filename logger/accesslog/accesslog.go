@@ -0,0 +1,293 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package accesslog produces access logs for HTTP and gRPC servers, in the Common Log Format, the Combined Log Format,
+// the W3C Extended Log File Format, or as structured records written with a kit logger.
+package accesslog
+
+import (
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	kclock "k8s.io/utils/clock"
+
+	"github.com/dapr/kit/logger"
+)
+
+// Format is the format of access logs.
+type Format int
+
+const (
+	// FormatStructured writes each request as a structured record, with the fields of the entry, using a kit logger.
+	FormatStructured Format = iota
+	// FormatCommon is the NCSA Common Log Format.
+	FormatCommon
+	// FormatCombined is the NCSA Combined Log Format, which adds the referer and the user agent to FormatCommon.
+	FormatCombined
+	// FormatW3C is the W3C Extended Log File Format, with the fields in W3CFields.
+	FormatW3C
+)
+
+// W3CFields are the fields of W3C Extended logs, in order.
+const W3CFields = "date time c-ip cs-method cs-uri-stem cs-uri-query sc-status cs-bytes sc-bytes time-taken cs(User-Agent) cs(Referer)"
+
+// Entry is the summary of a request.
+type Entry struct {
+	// Time the request was received.
+	Time time.Time
+	// RemoteAddr is the address of the client.
+	RemoteAddr string
+	// User is the authenticated user, if any.
+	User string
+	// Method of the request, such as "GET". For gRPC, it's "POST".
+	Method string
+	// Path of the request, URL-escaped as received; for gRPC, the full method name.
+	Path string
+	// Query is the raw query string, without the "?".
+	Query string
+	// Route is the template of the route that matched the request, such as "/v1.0/state/{storeName}". Optional.
+	Route string
+	// Protocol, such as "HTTP/1.1" or "gRPC".
+	Protocol string
+	// Status is the HTTP status code. For gRPC, it's derived from GRPCCode.
+	Status int
+	// GRPCCode is the name of the gRPC status code, such as "NOT_FOUND", for gRPC requests only.
+	GRPCCode string
+	// RequestBytes is the size of the request body.
+	RequestBytes int64
+	// ResponseBytes is the size of the response body.
+	ResponseBytes int64
+	// Latency is the time taken to serve the request.
+	Latency time.Duration
+	// UserAgent of the client.
+	UserAgent string
+	// Referer of the request.
+	Referer string
+}
+
+// Options contains the options for a Logger.
+type Options struct {
+	// Format of the logs. Default is FormatStructured.
+	Format Format
+	// Log is the logger used by FormatStructured. Default is the "dapr.accesslog" logger.
+	Log logger.Logger
+	// Writer is where lines are written for the other formats. Default is os.Stdout.
+	Writer io.Writer
+}
+
+// Logger writes access logs.
+type Logger struct {
+	opts  Options
+	clock kclock.PassiveClock
+
+	lock          sync.Mutex
+	headerWritten bool
+}
+
+// New returns a new Logger.
+func New(opts Options) *Logger {
+	if opts.Format == FormatStructured && opts.Log == nil {
+		opts.Log = logger.NewLogger("dapr.accesslog")
+	}
+	if opts.Writer == nil {
+		opts.Writer = os.Stdout
+	}
+	return &Logger{
+		opts:  opts,
+		clock: kclock.RealClock{},
+	}
+}
+
+// WithClock sets the clock used to measure the time and latency of requests. Used for testing.
+func (l *Logger) WithClock(clock kclock.PassiveClock) *Logger {
+	l.clock = clock
+	return l
+}
+
+// Log writes the access log of a request.
+func (l *Logger) Log(e Entry) {
+	switch l.opts.Format {
+	case FormatCommon:
+		l.writeLine(e.CommonLogFormat())
+	case FormatCombined:
+		l.writeLine(e.CombinedLogFormat())
+	case FormatW3C:
+		l.writeLine(e.W3C())
+	default:
+		l.opts.Log.WithFields(e.Fields()).Info("Access")
+	}
+}
+
+func (l *Logger) writeLine(line string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.opts.Format == FormatW3C && !l.headerWritten {
+		l.headerWritten = true
+		_, _ = io.WriteString(l.opts.Writer, "#Version: 1.0\n#Fields: "+W3CFields+"\n")
+	}
+	_, _ = io.WriteString(l.opts.Writer, line+"\n")
+}
+
+// CommonLogFormat returns the entry in the NCSA Common Log Format:
+//
+//	127.0.0.1 - alice [10/Oct/2023:13:55:36 +0000] "GET /v1.0/state/store HTTP/1.1" 200 2326
+//
+// Quotes, backslashes and control characters in the values are escaped, so clients can't forge log lines.
+func (e Entry) CommonLogFormat() string {
+	var b strings.Builder
+	b.WriteString(orDash(escape(host(e.RemoteAddr))))
+	b.WriteString(" - ")
+	b.WriteString(orDash(escape(e.User)))
+	b.WriteString(" [")
+	b.WriteString(e.Time.Format("02/Jan/2006:15:04:05 -0700"))
+	b.WriteString(`] "`)
+	b.WriteString(escape(e.Method))
+	b.WriteByte(' ')
+	b.WriteString(escape(e.requestURI()))
+	b.WriteByte(' ')
+	b.WriteString(escape(e.Protocol))
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(e.Status))
+	b.WriteByte(' ')
+	if e.ResponseBytes > 0 {
+		b.WriteString(strconv.FormatInt(e.ResponseBytes, 10))
+	} else {
+		b.WriteByte('-')
+	}
+	return b.String()
+}
+
+// CombinedLogFormat returns the entry in the NCSA Combined Log Format, which is the Common Log Format followed by the
+// referer and the user agent.
+func (e Entry) CombinedLogFormat() string {
+	return e.CommonLogFormat() + ` "` + escape(e.Referer) + `" "` + escape(e.UserAgent) + `"`
+}
+
+// W3C returns the entry in the W3C Extended Log File Format, with the fields in W3CFields.
+func (e Entry) W3C() string {
+	t := e.Time.UTC()
+	fields := []string{
+		t.Format("2006-01-02"),
+		t.Format("15:04:05"),
+		host(e.RemoteAddr),
+		e.Method,
+		e.Path,
+		e.Query,
+		strconv.Itoa(e.Status),
+		strconv.FormatInt(e.RequestBytes, 10),
+		strconv.FormatInt(e.ResponseBytes, 10),
+		strconv.FormatFloat(e.Latency.Seconds(), 'f', 3, 64),
+		e.UserAgent,
+		e.Referer,
+	}
+	for i, f := range fields {
+		fields[i] = w3cValue(f)
+	}
+	return strings.Join(fields, " ")
+}
+
+// Fields returns the entry as structured fields.
+func (e Entry) Fields() map[string]any {
+	res := map[string]any{
+		"method":        e.Method,
+		"path":          e.Path,
+		"protocol":      e.Protocol,
+		"status":        e.Status,
+		"requestBytes":  e.RequestBytes,
+		"responseBytes": e.ResponseBytes,
+		"latencyMs":     float64(e.Latency.Microseconds()) / 1000,
+	}
+	optional := map[string]string{
+		"remoteAddr": e.RemoteAddr,
+		"user":       e.User,
+		"query":      e.Query,
+		"route":      e.Route,
+		"grpcCode":   e.GRPCCode,
+		"userAgent":  e.UserAgent,
+		"referer":    e.Referer,
+	}
+	for k, v := range optional {
+		if v != "" {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func (e Entry) requestURI() string {
+	if e.Query == "" {
+		return e.Path
+	}
+	return e.Path + "?" + e.Query
+}
+
+// host returns the host part of an address, without the port.
+func host(addr string) string {
+	h, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		// The address doesn't have a port, such as a bare IPv6 address
+		return strings.Trim(addr, "[]")
+	}
+	return h
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// w3cValue encodes a value for W3C logs, where fields are separated by spaces.
+func w3cValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(escape(s), " ", "+")
+}
+
+// escape escapes quotes and backslashes with a backslash, and control characters as "\xHH", like Apache httpd does.
+func escape(s string) string {
+	n := strings.IndexFunc(s, func(r rune) bool {
+		return r == '"' || r == '\\' || r < 0x20 || r == 0x7f
+	})
+	if n < 0 {
+		return s
+	}
+
+	const hex = "0123456789abcdef"
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	b.WriteString(s[:n])
+	for i := n; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			b.WriteString(`\x`)
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0x0f])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
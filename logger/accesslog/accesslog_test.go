@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/kit/logger"
+)
+
+var testEntry = Entry{
+	Time:          time.Date(2023, 10, 10, 13, 55, 36, 0, time.UTC),
+	RemoteAddr:    "127.0.0.1:51234",
+	User:          "alice",
+	Method:        http.MethodGet,
+	Path:          "/v1.0/state/my store",
+	Query:         "metadata.ttl=10",
+	Route:         "/v1.0/state/{storeName}",
+	Protocol:      "HTTP/1.1",
+	Status:        http.StatusOK,
+	RequestBytes:  0,
+	ResponseBytes: 2326,
+	Latency:       12500 * time.Microsecond,
+	UserAgent:     "curl/8.0",
+	Referer:       "http://example.com/",
+}
+
+func TestFormats(t *testing.T) {
+	assert.Equal(t,
+		`127.0.0.1 - alice [10/Oct/2023:13:55:36 +0000] "GET /v1.0/state/my store?metadata.ttl=10 HTTP/1.1" 200 2326`,
+		testEntry.CommonLogFormat(),
+	)
+	assert.Equal(t,
+		`127.0.0.1 - alice [10/Oct/2023:13:55:36 +0000] "GET /v1.0/state/my store?metadata.ttl=10 HTTP/1.1" 200 2326 "http://example.com/" "curl/8.0"`,
+		testEntry.CombinedLogFormat(),
+	)
+	assert.Equal(t,
+		`2023-10-10 13:55:36 127.0.0.1 GET /v1.0/state/my+store metadata.ttl=10 200 0 2326 0.013 curl/8.0 http://example.com/`,
+		testEntry.W3C(),
+	)
+
+	// Missing values
+	e := Entry{Time: testEntry.Time, RemoteAddr: "[::1]:80", Method: http.MethodPost, Path: "/", Protocol: "HTTP/2.0", Status: 204}
+	assert.Equal(t, `::1 - - [10/Oct/2023:13:55:36 +0000] "POST / HTTP/2.0" 204 -`, e.CommonLogFormat())
+	assert.Equal(t, `2023-10-10 13:55:36 ::1 POST / - 204 0 0 0.000 - -`, e.W3C())
+
+	// Addresses without a port
+	e = Entry{Time: testEntry.Time, RemoteAddr: "::1", Method: http.MethodGet, Path: "/", Protocol: "HTTP/1.1", Status: 200}
+	assert.Equal(t, `::1 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 -`, e.CommonLogFormat())
+	e.RemoteAddr = "2001:db8::1"
+	assert.Equal(t, `2023-10-10 13:55:36 2001:db8::1 GET / - 200 0 0 0.000 - -`, e.W3C())
+	e.RemoteAddr = "192.0.2.1"
+	assert.Equal(t, `2023-10-10 13:55:36 192.0.2.1 GET / - 200 0 0 0.000 - -`, e.W3C())
+
+	fields := testEntry.Fields()
+	assert.Equal(t, "/v1.0/state/{storeName}", fields["route"])
+	assert.Equal(t, 12.5, fields["latencyMs"])
+	assert.NotContains(t, e.Fields(), "user")
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("W3C header is written once", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(Options{Format: FormatW3C, Writer: &buf})
+		l.Log(testEntry)
+		l.Log(testEntry)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 4)
+		assert.Equal(t, "#Version: 1.0", lines[0])
+		assert.Equal(t, "#Fields: "+W3CFields, lines[1])
+		assert.Equal(t, testEntry.W3C(), lines[2])
+	})
+
+	t.Run("structured", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := logger.NewLogger("test.accesslog")
+		log.EnableJSONOutput(true)
+		log.SetOutput(&buf)
+		New(Options{Log: log}).Log(testEntry)
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		assert.Equal(t, "Access", record["msg"])
+		assert.Equal(t, "/v1.0/state/{storeName}", record["route"])
+		assert.Equal(t, float64(200), record["status"])
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	clock := clocktesting.NewFakeClock(testEntry.Time)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		clock.Step(5 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	})
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPut, "/items/1?x=1", strings.NewReader("body"))
+		req.SetBasicAuth("alice", "secret")
+		return req
+	}
+
+	t.Run("lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(Options{Format: FormatCommon, Writer: &buf}).WithClock(clock)
+		rec := httptest.NewRecorder()
+		l.Middleware(nil)(handler).ServeHTTP(rec, newRequest())
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, `192.0.2.1 - alice [10/Oct/2023:13:55:36 +0000] "PUT /items/1?x=1 HTTP/1.1" 201 7`+"\n", buf.String())
+	})
+
+	t.Run("values are escaped", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(Options{Format: FormatCombined, Writer: &buf}).WithClock(clock)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path = "/a\r\n127.0.0.1 - - [10/Oct/2023:13:55:36 +0000] \"GET /admin"
+		req.SetBasicAuth("eve\n\"\\", "secret")
+		req.Header.Set("User-Agent", "agent\"\n")
+		l.Middleware(nil)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t,
+			`192.0.2.1 - eve\x0a\"\\ [10/Oct/2023:13:55:36 +0000] "GET /a%0D%0A127.0.0.1%20-%20-%20%5B10/Oct/2023:13:55:36%20+0000%5D%20%22GET%20/admin HTTP/1.1" 201 7 "" "agent\"\x0a"`+"\n",
+			buf.String(),
+		)
+
+		e := Entry{Time: testEntry.Time, User: "a\"b", Method: http.MethodGet, Path: "/x\ny", UserAgent: "a b\r", Status: 200}
+		assert.Equal(t, `- - a\"b [10/Oct/2023:13:55:36 +0000] "GET /x\x0ay " 200 -`, e.CommonLogFormat())
+		assert.Equal(t, `2023-10-10 13:55:36 - GET /x\x0ay - 200 0 0 0.000 a+b\x0d -`, e.W3C())
+	})
+
+	t.Run("structured", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := logger.NewLogger("test.accesslog.middleware")
+		log.EnableJSONOutput(true)
+		log.SetOutput(&buf)
+		l := New(Options{Log: log}).WithClock(clock)
+		route := func(*http.Request) string { return "/items/{id}" }
+		l.Middleware(route)(handler).ServeHTTP(httptest.NewRecorder(), newRequest())
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		assert.Equal(t, "/items/{id}", record["route"])
+		assert.Equal(t, float64(4), record["requestBytes"])
+		assert.Equal(t, float64(7), record["responseBytes"])
+		assert.Equal(t, float64(5), record["latencyMs"])
+	})
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(Options{Format: FormatW3C, Writer: &buf})
+	interceptor := l.UnaryServerInterceptor()
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}})
+	info := &grpc.UnaryServerInfo{FullMethod: "/dapr.proto.runtime.v1.Dapr/GetState"}
+
+	_, err := interceptor(ctx, wrapperspb.String("key"), info, func(context.Context, any) (any, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	require.Error(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	fields := strings.Split(lines[2], " ")
+	assert.Equal(t, "10.0.0.1", fields[2])
+	assert.Equal(t, "POST", fields[3])
+	assert.Equal(t, "/dapr.proto.runtime.v1.Dapr/GetState", fields[4])
+	assert.Equal(t, "404", fields[6])
+	assert.Equal(t, "5", fields[7])
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accesslog
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	rpccode "google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/dapr/kit/grpccodes"
+	"github.com/dapr/kit/utils/httputil"
+)
+
+// RouteFn returns the template of the route that matched the request, such as "/v1.0/state/{storeName}".
+type RouteFn func(r *http.Request) string
+
+// Middleware returns a HTTP middleware that writes the access log of every request.
+// route is optional.
+func (l *Logger) Middleware(route RouteFn) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := l.clock.Now()
+			rw := httputil.NewResponseWriter(w)
+			body := &countingReader{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+
+			next.ServeHTTP(rw, r)
+
+			e := Entry{
+				Time:          start,
+				RemoteAddr:    r.RemoteAddr,
+				Method:        r.Method,
+				Path:          r.URL.EscapedPath(),
+				Query:         r.URL.RawQuery,
+				Protocol:      r.Proto,
+				Status:        rw.StatusCode(),
+				RequestBytes:  body.n,
+				ResponseBytes: rw.BytesWritten(),
+				Latency:       l.clock.Since(start),
+				UserAgent:     r.UserAgent(),
+				Referer:       r.Referer(),
+			}
+			if user, _, ok := r.BasicAuth(); ok {
+				e.User = user
+			}
+			if route != nil {
+				e.Route = route(r)
+			}
+			l.Log(e)
+		})
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that writes the access log of every unary call.
+func (l *Logger) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := l.clock.Now()
+		res, err := handler(ctx, req)
+
+		code := status.Code(err)
+		e := Entry{
+			Time:     start,
+			Method:   http.MethodPost,
+			Path:     info.FullMethod,
+			Route:    info.FullMethod,
+			Protocol: "gRPC",
+			Status:   grpccodes.HTTPStatusFromCode(code),
+			GRPCCode: rpccode.Code(code).String(),
+			Latency:  l.clock.Since(start),
+		}
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			e.RemoteAddr = p.Addr.String()
+		}
+		if m, ok := req.(proto.Message); ok {
+			e.RequestBytes = int64(proto.Size(m))
+		}
+		if m, ok := res.(proto.Message); ok && err == nil {
+			e.ResponseBytes = int64(proto.Size(m))
+		}
+		l.Log(e)
+
+		return res, err
+	}
+}
+
+// countingReader counts the bytes read from the request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment describes the environment the process runs in, as detected by DetectEnvironment.
+type Environment struct {
+	// TTY is true if stdout is a terminal.
+	TTY bool
+	// Kubernetes is true if the process runs in a Kubernetes pod.
+	Kubernetes bool
+	// Debug is true if debug logging was requested with the DEBUG env var.
+	Debug bool
+	// Level is the log level set with the DAPR_LOG_LEVEL or LOG_LEVEL env vars, if any.
+	Level string
+	// OTel is true if an OpenTelemetry collector is configured with the OTEL_* env vars.
+	OTel bool
+	// ServiceName is the value of the OTEL_SERVICE_NAME env var, if any.
+	ServiceName string
+}
+
+// DetectEnvironment inspects the environment of the process.
+func DetectEnvironment() Environment {
+	return detectEnvironment(os.Getenv, isTerminal(os.Stdout))
+}
+
+func detectEnvironment(getenv func(string) string, tty bool) Environment {
+	env := Environment{
+		TTY:         tty,
+		Kubernetes:  getenv("KUBERNETES_SERVICE_HOST") != "",
+		ServiceName: getenv("OTEL_SERVICE_NAME"),
+	}
+
+	if debug, err := strconv.ParseBool(getenv("DEBUG")); err == nil && debug {
+		env.Debug = true
+	}
+
+	for _, name := range []string{"DAPR_LOG_LEVEL", "LOG_LEVEL"} {
+		if lvl := strings.ToLower(getenv(name)); toLogLevel(lvl) != UndefinedLevel {
+			env.Level = lvl
+			break
+		}
+	}
+
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "OTEL_SERVICE_NAME"} {
+		if getenv(name) != "" {
+			env.OTel = true
+			break
+		}
+	}
+
+	return env
+}
+
+// Options returns the logging options that are suitable for the environment:
+//   - Logs are formatted as JSON unless stdout is a terminal, or when running in Kubernetes or with an OpenTelemetry
+//     collector, where logs are typically collected by a pipeline.
+//   - The level is debug if DEBUG is set to a truthy value, otherwise the value of DAPR_LOG_LEVEL or LOG_LEVEL,
+//     falling back to the default level.
+//   - The app ID is set to OTEL_SERVICE_NAME, if present.
+//   - No sinks are set, so logs are written to the output of the loggers (stdout by default): the environment doesn't
+//     indicate other destinations, as collectors in Kubernetes and OpenTelemetry pipelines read stdout too.
+func (e Environment) Options() Options {
+	o := DefaultOptions()
+	o.JSONFormatEnabled = !e.TTY || e.Kubernetes || e.OTel
+	switch {
+	case e.Debug:
+		o.OutputLevel = string(DebugLevel)
+	case e.Level != "":
+		o.OutputLevel = e.Level
+	}
+	if e.ServiceName != "" {
+		o.SetAppID(e.ServiceName)
+	}
+	return o
+}
+
+// AutoConfigure detects the environment the process runs in and applies sensible logging options to all loggers.
+// Options can be overridden by passing functions that modify them, which are invoked after the options are
+// computed from the environment; for example, they can set Sinks to write logs to files or network connections.
+// It returns the options that were applied.
+func AutoConfigure(overrides ...func(o *Options)) (Options, error) {
+	o := DetectEnvironment().Options()
+	for _, fn := range overrides {
+		fn(&o)
+	}
+	return o, ApplyOptionsToLoggers(&o)
+}
+
+// isTerminal returns true if f is a character device, such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectEnvironment(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		tty          bool
+		expectJSON   bool
+		expectLevel  string
+		expectAppID  string
+		expectDetect Environment
+	}{
+		{
+			name:         "terminal",
+			tty:          true,
+			expectJSON:   false,
+			expectLevel:  defaultOutputLevel,
+			expectDetect: Environment{TTY: true},
+		},
+		{
+			name:         "not a terminal",
+			expectJSON:   true,
+			expectLevel:  defaultOutputLevel,
+			expectDetect: Environment{},
+		},
+		{
+			name:         "kubernetes with terminal",
+			env:          map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"},
+			tty:          true,
+			expectJSON:   true,
+			expectLevel:  defaultOutputLevel,
+			expectDetect: Environment{TTY: true, Kubernetes: true},
+		},
+		{
+			name:         "debug has precedence over level",
+			env:          map[string]string{"DEBUG": "1", "LOG_LEVEL": "warn"},
+			tty:          true,
+			expectLevel:  "debug",
+			expectDetect: Environment{TTY: true, Debug: true, Level: "warn"},
+		},
+		{
+			name:         "level from env",
+			env:          map[string]string{"DAPR_LOG_LEVEL": "ERROR", "LOG_LEVEL": "warn", "DEBUG": "false"},
+			tty:          true,
+			expectLevel:  "error",
+			expectDetect: Environment{TTY: true, Level: "error"},
+		},
+		{
+			name:         "invalid level is ignored",
+			env:          map[string]string{"LOG_LEVEL": "verbose"},
+			tty:          true,
+			expectLevel:  defaultOutputLevel,
+			expectDetect: Environment{TTY: true},
+		},
+		{
+			name:         "otel",
+			env:          map[string]string{"OTEL_SERVICE_NAME": "myapp"},
+			tty:          true,
+			expectJSON:   true,
+			expectLevel:  defaultOutputLevel,
+			expectAppID:  "myapp",
+			expectDetect: Environment{TTY: true, OTel: true, ServiceName: "myapp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := detectEnvironment(func(name string) string {
+				return tt.env[name]
+			}, tt.tty)
+			assert.Equal(t, tt.expectDetect, env)
+
+			o := env.Options()
+			assert.Equal(t, tt.expectJSON, o.JSONFormatEnabled)
+			assert.Equal(t, tt.expectLevel, o.OutputLevel)
+			assert.Equal(t, tt.expectAppID, o.appID)
+		})
+	}
+}
+
+func TestAutoConfigure(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warn")
+	l := NewLogger("testAutoConfigure").(*daprLogger)
+
+	o, err := AutoConfigure(func(o *Options) {
+		o.JSONFormatEnabled = false
+	})
+	require.NoError(t, err)
+	assert.False(t, o.JSONFormatEnabled)
+	assert.Equal(t, "warn", o.OutputLevel)
+	assert.Equal(t, logrus.WarnLevel, l.logger.Logger.GetLevel())
+	_, ok := l.logger.Logger.Formatter.(*logrus.TextFormatter)
+	assert.True(t, ok)
+
+	_, err = AutoConfigure(func(o *Options) {
+		o.OutputLevel = "invalid"
+	})
+	require.Error(t, err)
+
+	// Sinks can be set with overrides
+	var sink bytes.Buffer
+	_, err = AutoConfigure(func(o *Options) {
+		o.Sinks = []Sink{{Name: "buffer", Writer: &sink}}
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		for _, v := range getLoggers() {
+			require.NoError(t, SetSinks(v, SinksOptions{}))
+		}
+	})
+	l.Warn("to the sink")
+	assert.Contains(t, sink.String(), "to the sink")
+
+	_, err = AutoConfigure(func(o *Options) {
+		o.Sinks = []Sink{{Name: "invalid"}}
+	})
+	require.Error(t, err)
+
+	// Reset
+	require.NoError(t, ApplyOptionsToLoggers(&Options{OutputLevel: defaultOutputLevel}))
+}
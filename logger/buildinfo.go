@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/dapr/kit/features"
+)
+
+// DaprGitCommit is the commit the binary was built from.
+// If empty, the VCS revision embedded by the Go toolchain is used, if available.
+var DaprGitCommit = ""
+
+// BuildInfo contains information about the running binary.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	GoVersion string
+	OS        string
+	Arch      string
+	Features  []string
+}
+
+// GetBuildInfo returns the BuildInfo of the running binary.
+// Enabled features are read from the features registry.
+func GetBuildInfo() BuildInfo {
+	return BuildInfo{
+		Version:   DaprVersion,
+		Commit:    buildCommit(),
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Features:  features.Enabled(),
+	}
+}
+
+// Fields returns the build info as structured log fields.
+func (b BuildInfo) Fields() map[string]any {
+	return map[string]any{
+		"version":    b.Version,
+		"commit":     b.Commit,
+		"go_version": b.GoVersion,
+		"goos":       b.OS,
+		"goarch":     b.Arch,
+		"features":   b.Features,
+	}
+}
+
+// LogBuildInfo emits a single structured record, at level Info, with the build info of the running binary.
+// It's meant to be invoked at startup, in place of multi-line banners that break JSON log pipelines.
+func LogBuildInfo(log Logger) {
+	log.WithFields(GetBuildInfo().Fields()).Info("Build info")
+}
+
+func buildCommit() string {
+	if DaprGitCommit != "" {
+		return DaprGitCommit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && s.Value != "" {
+				return s.Value
+			}
+		}
+	}
+	return "unknown"
+}
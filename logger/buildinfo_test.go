@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/features"
+)
+
+func TestLogBuildInfo(t *testing.T) {
+	DaprGitCommit = "abc123"
+	defer func() {
+		DaprGitCommit = ""
+	}()
+	features.Enable("featureB", "featureA")
+	defer features.Disable("featureB", "featureA")
+
+	var buf bytes.Buffer
+	testLogger := getTestLogger(&buf)
+	testLogger.EnableJSONOutput(true)
+
+	LogBuildInfo(testLogger)
+
+	// Must be a single record
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte{'\n'}))
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "Build info", record[logFieldMessage])
+	assert.Equal(t, "info", record[logFieldLevel])
+	assert.Equal(t, DaprVersion, record["version"])
+	assert.Equal(t, "abc123", record["commit"])
+	assert.Equal(t, runtime.Version(), record["go_version"])
+	assert.Equal(t, runtime.GOOS, record["goos"])
+	assert.Equal(t, runtime.GOARCH, record["goarch"])
+	assert.Equal(t, []any{"featureA", "featureB"}, record["features"])
+}
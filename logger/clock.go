@@ -0,0 +1,38 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"errors"
+
+	kclock "k8s.io/utils/clock"
+)
+
+// SetClock sets the clock used for the timestamps of the records logged with l, so they are deterministic in tests,
+// for example when comparing the output with golden files. Passing nil restores the system time.
+// The clock is shared by all loggers derived from l with WithFields and WithLogType, and it's also used by SetSinks
+// when SinksOptions doesn't have a clock.
+func SetClock(l Logger, clock kclock.PassiveClock) error {
+	dl, ok := l.(*daprLogger)
+	if !ok {
+		return errors.New("logger does not support clocks")
+	}
+
+	if clock == nil {
+		dl.clock.Store(nil)
+		return nil
+	}
+	dl.clock.Store(&clock)
+	return nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestSetClock(t *testing.T) {
+	var buf bytes.Buffer
+	l := getTestLogger(&buf)
+	l.EnableJSONOutput(true)
+
+	clock := clocktesting.NewFakePassiveClock(time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC))
+	require.NoError(t, SetClock(l, clock))
+
+	readTime := func() string {
+		t.Helper()
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		buf.Reset()
+		return record[logFieldTimeStamp].(string)
+	}
+
+	l.Info("first")
+	assert.Equal(t, "2023-01-02T03:04:05.000000006Z", readTime())
+
+	// Derived loggers share the clock, including those created before the clock was changed
+	derived := l.WithFields(map[string]any{"k": "v"}).WithLogType(LogTypeRequest)
+	clock.SetTime(time.Date(2023, 1, 2, 3, 4, 6, 0, time.UTC))
+	derived.Warn("second")
+	assert.Equal(t, "2023-01-02T03:04:06Z", readTime())
+
+	// Restore the system time
+	require.NoError(t, SetClock(l, nil))
+	l.Info("third")
+	ts, err := time.Parse(time.RFC3339Nano, readTime())
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), ts, time.Minute)
+
+	assert.Error(t, SetClock(&nopLogger{}, clock))
+}
@@ -14,11 +14,14 @@ limitations under the License.
 package logger
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	kclock "k8s.io/utils/clock"
 )
 
 // daprLogger is the implemention for logrus.
@@ -27,6 +30,8 @@ type daprLogger struct {
 	name string
 	// loger is the instance of logrus logger
 	logger *logrus.Entry
+	// clock is the source of the timestamps of records, shared with derived loggers. If empty, the system time is used.
+	clock *atomic.Pointer[kclock.PassiveClock]
 }
 
 var DaprVersion = "unknown"
@@ -41,6 +46,7 @@ func newDaprLogger(name string) *daprLogger {
 			logFieldScope: name,
 			logFieldType:  LogTypeLog,
 		}),
+		clock: &atomic.Pointer[kclock.PassiveClock]{},
 	}
 
 	dl.EnableJSONOutput(defaultJSONOutput)
@@ -114,6 +120,7 @@ func (l *daprLogger) WithLogType(logType string) Logger {
 	return &daprLogger{
 		name:   l.name,
 		logger: l.logger.WithField(logFieldType, logType),
+		clock:  l.clock,
 	}
 }
 
@@ -122,55 +129,92 @@ func (l *daprLogger) WithFields(fields map[string]any) Logger {
 	return &daprLogger{
 		name:   l.name,
 		logger: l.logger.WithFields(fields),
+		clock:  l.clock,
 	}
 }
 
+// entry returns the entry to log a record with, with the timestamp from the clock if one is set.
+func (l *daprLogger) entry() *logrus.Entry {
+	if c := l.clock.Load(); c != nil {
+		return l.logger.WithTime((*c).Now())
+	}
+	return l.logger
+}
+
+// log logs a message at the given level, applying the redactors registered with AddRedactor.
+func (l *daprLogger) log(level logrus.Level, args ...interface{}) {
+	rs := loadRedactors()
+	if len(rs) == 0 {
+		l.entry().Log(level, args...)
+		return
+	}
+	if !l.logger.Logger.IsLevelEnabled(level) {
+		return
+	}
+	redactEntry(rs, l.entry()).Log(level, redactString(rs, fmt.Sprint(args...)))
+}
+
+// logf formats and logs a message at the given level, applying the redactors registered with AddRedactor.
+func (l *daprLogger) logf(level logrus.Level, format string, args ...interface{}) {
+	rs := loadRedactors()
+	if len(rs) == 0 {
+		l.entry().Logf(level, format, args...)
+		return
+	}
+	if !l.logger.Logger.IsLevelEnabled(level) {
+		return
+	}
+	redactEntry(rs, l.entry()).Log(level, redactString(rs, fmt.Sprintf(format, args...)))
+}
+
 // Info logs a message at level Info.
 func (l *daprLogger) Info(args ...interface{}) {
-	l.logger.Log(logrus.InfoLevel, args...)
+	l.log(logrus.InfoLevel, args...)
 }
 
 // Infof logs a message at level Info.
 func (l *daprLogger) Infof(format string, args ...interface{}) {
-	l.logger.Logf(logrus.InfoLevel, format, args...)
+	l.logf(logrus.InfoLevel, format, args...)
 }
 
 // Debug logs a message at level Debug.
 func (l *daprLogger) Debug(args ...interface{}) {
-	l.logger.Log(logrus.DebugLevel, args...)
+	l.log(logrus.DebugLevel, args...)
 }
 
 // Debugf logs a message at level Debug.
 func (l *daprLogger) Debugf(format string, args ...interface{}) {
-	l.logger.Logf(logrus.DebugLevel, format, args...)
+	l.logf(logrus.DebugLevel, format, args...)
 }
 
 // Warn logs a message at level Warn.
 func (l *daprLogger) Warn(args ...interface{}) {
-	l.logger.Log(logrus.WarnLevel, args...)
+	l.log(logrus.WarnLevel, args...)
 }
 
 // Warnf logs a message at level Warn.
 func (l *daprLogger) Warnf(format string, args ...interface{}) {
-	l.logger.Logf(logrus.WarnLevel, format, args...)
+	l.logf(logrus.WarnLevel, format, args...)
 }
 
 // Error logs a message at level Error.
 func (l *daprLogger) Error(args ...interface{}) {
-	l.logger.Log(logrus.ErrorLevel, args...)
+	l.log(logrus.ErrorLevel, args...)
 }
 
 // Errorf logs a message at level Error.
 func (l *daprLogger) Errorf(format string, args ...interface{}) {
-	l.logger.Logf(logrus.ErrorLevel, format, args...)
+	l.logf(logrus.ErrorLevel, format, args...)
 }
 
 // Fatal logs a message at level Fatal then the process will exit with status set to 1.
 func (l *daprLogger) Fatal(args ...interface{}) {
-	l.logger.Fatal(args...)
+	l.log(logrus.FatalLevel, args...)
+	l.logger.Logger.Exit(1)
 }
 
 // Fatalf logs a message at level Fatal then the process will exit with status set to 1.
 func (l *daprLogger) Fatalf(format string, args ...interface{}) {
-	l.logger.Fatalf(format, args...)
+	l.logf(logrus.FatalLevel, format, args...)
+	l.logger.Logger.Exit(1)
 }
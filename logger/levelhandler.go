@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+)
+
+// maxLevelChangeBody is the maximum size of the body of requests that change levels.
+const maxLevelChangeBody = 64 << 10
+
+// LevelsReport contains the output levels of the registered loggers, by name.
+type LevelsReport struct {
+	Loggers map[string]LogLevel `json:"loggers"`
+}
+
+// LevelHandlerOptions contains the options for LevelHandler.
+type LevelHandlerOptions struct {
+	// ReadOnly disables changing levels, for example when the handler is served without authentication.
+	ReadOnly bool
+}
+
+// LevelChange is the JSON body of POST requests to the handler returned by LevelHandler.
+type LevelChange struct {
+	// Level to set.
+	Level LogLevel `json:"level"`
+	// Loggers to change, by name. Default is all loggers.
+	Loggers []string `json:"loggers,omitempty"`
+}
+
+// LevelHandler returns an http.Handler that reports and changes the output level of the registered loggers at
+// runtime.
+// GET requests respond with a JSON-encoded LevelsReport. Levels are changed with PUT requests, setting the level
+// passed in the "level" query string parameter on the loggers named in the "logger" parameters (or on all loggers if
+// there's none), or with POST requests with a JSON-encoded LevelChange body; both respond with the report.
+// POST requests without a JSON body are rejected, so levels can't be changed by cross-site form submissions.
+func LevelHandler(opts LevelHandlerOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveLevels(w, r, opts)
+	})
+}
+
+func serveLevels(w http.ResponseWriter, r *http.Request, opts LevelHandlerOptions) {
+	var change *LevelChange
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if opts.ReadOnly {
+			http.Error(w, "log levels are read-only", http.StatusForbidden)
+			return
+		}
+
+		change = &LevelChange{}
+		if r.Method == http.MethodPut {
+			q := r.URL.Query()
+			change.Level = LogLevel(q.Get("level"))
+			change.Loggers = q["logger"]
+		} else {
+			if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+				http.Error(w, "content type must be application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+			err := json.NewDecoder(io.LimitReader(r.Body, maxLevelChangeBody)).Decode(change)
+			if err != nil {
+				http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	loggers := getLoggers()
+	if change != nil {
+		code, err := applyLevelChange(loggers, change)
+		if err != nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+	}
+
+	report := LevelsReport{Loggers: make(map[string]LogLevel, len(loggers))}
+	for name, l := range loggers {
+		report.Loggers[name] = outputLevel(l)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// applyLevelChange sets the level on the loggers. Nothing is changed if the level is invalid or a logger doesn't
+// exist; in this case, it returns the HTTP status code of the error.
+func applyLevelChange(loggers map[string]Logger, change *LevelChange) (int, error) {
+	level := toLogLevel(string(change.Level))
+	if level == UndefinedLevel {
+		return http.StatusBadRequest, fmt.Errorf("invalid log level: %s", change.Level)
+	}
+
+	names := change.Loggers
+	if len(names) == 0 {
+		for name := range loggers {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := loggers[name]; !ok {
+			return http.StatusNotFound, fmt.Errorf("logger not found: %s", name)
+		}
+	}
+	for _, name := range names {
+		loggers[name].SetOutputLevel(level)
+	}
+	return http.StatusOK, nil
+}
+
+// outputLevel returns the output level of l, which is the most verbose level that is enabled.
+func outputLevel(l Logger) LogLevel {
+	for _, level := range []LogLevel{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, FatalLevel} {
+		if l.IsOutputLevelEnabled(level) {
+			return level
+		}
+	}
+	return UndefinedLevel
+}
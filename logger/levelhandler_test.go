@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelHandler(t *testing.T) {
+	a := NewLogger("test.levelhandler.a")
+	b := NewLogger("test.levelhandler.b")
+	a.SetOutputLevel(InfoLevel)
+	b.SetOutputLevel(WarnLevel)
+	defer a.SetOutputLevel(InfoLevel)
+	defer b.SetOutputLevel(InfoLevel)
+
+	serve := func(opts LevelHandlerOptions, method string, target string, contentType string, body string) (int, LevelsReport) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		LevelHandler(opts).ServeHTTP(rec, req)
+		var report LevelsReport
+		if rec.Code == http.StatusOK {
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		}
+		return rec.Code, report
+	}
+	rw := LevelHandlerOptions{}
+
+	code, report := serve(rw, http.MethodGet, "/", "", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, InfoLevel, report.Loggers["test.levelhandler.a"])
+	assert.Equal(t, WarnLevel, report.Loggers["test.levelhandler.b"])
+
+	code, report = serve(rw, http.MethodPut, "/?level=debug&logger=test.levelhandler.b", "", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, InfoLevel, report.Loggers["test.levelhandler.a"])
+	assert.Equal(t, DebugLevel, report.Loggers["test.levelhandler.b"])
+	assert.True(t, b.IsOutputLevelEnabled(DebugLevel))
+
+	code, report = serve(rw, http.MethodPost, "/", "application/json", `{"level":"error","loggers":["test.levelhandler.a"]}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ErrorLevel, report.Loggers["test.levelhandler.a"])
+
+	code, _ = serve(rw, http.MethodPut, "/?level=verbose", "", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(rw, http.MethodPut, "/?level=error&logger=test.levelhandler.b&logger=missing", "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	// Nothing is changed if a logger doesn't exist
+	assert.True(t, b.IsOutputLevelEnabled(DebugLevel))
+	code, _ = serve(rw, http.MethodDelete, "/", "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	// POST requests that could be sent by cross-site forms are rejected
+	code, _ = serve(rw, http.MethodPost, "/?level=fatal", "", "")
+	assert.Equal(t, http.StatusUnsupportedMediaType, code)
+	code, _ = serve(rw, http.MethodPost, "/", "application/x-www-form-urlencoded", "level=fatal")
+	assert.Equal(t, http.StatusUnsupportedMediaType, code)
+	code, _ = serve(rw, http.MethodPost, "/", "application/json", "{")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.True(t, b.IsOutputLevelEnabled(DebugLevel))
+
+	// Read-only handlers
+	ro := LevelHandlerOptions{ReadOnly: true}
+	code, _ = serve(ro, http.MethodGet, "/", "", "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(ro, http.MethodPut, "/?level=fatal&logger=test.levelhandler.b", "", "")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.True(t, b.IsOutputLevelEnabled(DebugLevel))
+}
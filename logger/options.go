@@ -33,6 +33,13 @@ type Options struct {
 
 	// OutputLevel is the level of logging
 	OutputLevel string
+
+	// Sinks, if set, are the destinations of the records of all loggers, instead of their output; see SetSinks.
+	// If empty, the sinks of the loggers are not changed.
+	Sinks []Sink
+
+	// SinksOptions are the options for Sinks.
+	SinksOptions SinksOptions
 }
 
 // SetOutputLevel sets the log output level.
@@ -100,5 +107,13 @@ func ApplyOptionsToLoggers(options *Options) error {
 	for _, v := range internalLoggers {
 		v.SetOutputLevel(daprLogLevel)
 	}
+
+	if len(options.Sinks) > 0 {
+		for _, v := range internalLoggers {
+			if err := SetSinks(v, options.SinksOptions, options.Sinks...); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redactor returns s with the sensitive values it contains replaced, for example with masked representations.
+type Redactor func(s string) string
+
+var (
+	redactorsLock sync.Mutex
+	// redactors contains the registered redactors. It's replaced (never modified) when redactors are added or removed,
+	// so records can be logged without locking.
+	redactors atomic.Pointer[[]*redactor]
+)
+
+type redactor struct {
+	fn Redactor
+}
+
+// AddRedactor registers a Redactor that is applied to the messages, and to the string and error fields, of the records
+// of all loggers, before they're written to the output or to sinks.
+// It returns a function that removes the redactor.
+func AddRedactor(fn Redactor) (remove func()) {
+	r := &redactor{fn: fn}
+
+	redactorsLock.Lock()
+	defer redactorsLock.Unlock()
+	var list []*redactor
+	if cur := redactors.Load(); cur != nil {
+		list = append(list, *cur...)
+	}
+	list = append(list, r)
+	redactors.Store(&list)
+
+	return func() {
+		redactorsLock.Lock()
+		defer redactorsLock.Unlock()
+		cur := redactors.Load()
+		if cur == nil {
+			return
+		}
+		list := make([]*redactor, 0, len(*cur))
+		for _, v := range *cur {
+			if v != r {
+				list = append(list, v)
+			}
+		}
+		if len(list) == 0 {
+			redactors.Store(nil)
+			return
+		}
+		redactors.Store(&list)
+	}
+}
+
+// loadRedactors returns the registered redactors, or nil if there are none.
+func loadRedactors() []*redactor {
+	cur := redactors.Load()
+	if cur == nil {
+		return nil
+	}
+	return *cur
+}
+
+// redactString applies all redactors to s.
+func redactString(rs []*redactor, s string) string {
+	for _, r := range rs {
+		s = r.fn(s)
+	}
+	return s
+}
+
+// redactEntry returns an entry whose string and error fields have been redacted.
+// If no field is changed, the entry is returned as-is.
+func redactEntry(rs []*redactor, entry *logrus.Entry) *logrus.Entry {
+	var changed logrus.Fields
+	for k, v := range entry.Data {
+		var s string
+		switch t := v.(type) {
+		case string:
+			s = t
+		case error:
+			s = t.Error()
+		default:
+			continue
+		}
+		if r := redactString(rs, s); r != s {
+			if changed == nil {
+				changed = logrus.Fields{}
+			}
+			changed[k] = r
+		}
+	}
+	if changed == nil {
+		return entry
+	}
+	return entry.WithFields(changed)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddRedactor(t *testing.T) {
+	var buf bytes.Buffer
+	l := getTestLogger(&buf)
+	l.EnableJSONOutput(true)
+
+	readRecord := func() map[string]any {
+		t.Helper()
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		buf.Reset()
+		return record
+	}
+
+	removeA := AddRedactor(func(s string) string {
+		return strings.ReplaceAll(s, "hunter2", "***")
+	})
+	removeB := AddRedactor(func(s string) string {
+		return strings.ReplaceAll(s, "s3cr3t", "###")
+	})
+
+	l.Info("password is ", "hunter2")
+	assert.Equal(t, "password is ***", readRecord()[logFieldMessage])
+
+	l.WithFields(map[string]any{
+		"password": "hunter2",
+		"err":      errors.New("invalid token s3cr3t"),
+		"count":    2,
+	}).Warnf("token %s and password %s", "s3cr3t", "hunter2")
+	record := readRecord()
+	assert.Equal(t, "token ### and password ***", record[logFieldMessage])
+	assert.Equal(t, "***", record["password"])
+	assert.Equal(t, "invalid token ###", record["err"])
+	assert.EqualValues(t, 2, record["count"])
+
+	// Disabled levels are not logged
+	l.Debugf("debug %s", "hunter2")
+	assert.Zero(t, buf.Len())
+
+	removeA()
+	l.Info("hunter2 s3cr3t")
+	assert.Equal(t, "hunter2 ###", readRecord()[logFieldMessage])
+
+	removeB()
+	assert.Nil(t, loadRedactors())
+	l.Info("hunter2 s3cr3t")
+	assert.Equal(t, "hunter2 s3cr3t", readRecord()[logFieldMessage])
+}
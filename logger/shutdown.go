@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"context"
+	"errors"
+)
+
+// Shutdown drains the buffers of the async sinks of all loggers and flushes all sinks, waiting until they're done or
+// ctx is canceled. It should be invoked before the process exits, so the last records are not lost; for example,
+// signals.Manager invokes it at the end of the graceful shutdown.
+// It returns the number of records that were dropped because the buffers of async sinks were full, or because they
+// could not be drained before ctx was canceled. After Shutdown, records are written to async sinks synchronously.
+func Shutdown(ctx context.Context) (dropped uint64, err error) {
+	activeSinksLock.Lock()
+	hooks := make([]*sinksHook, 0, len(activeSinks))
+	for _, slot := range activeSinks {
+		if h := slot.hook.Load(); h != nil {
+			hooks = append(hooks, h)
+		}
+	}
+	activeSinksLock.Unlock()
+
+	var errs []error
+	for _, h := range hooks {
+		n, hErr := h.shutdown(ctx)
+		dropped += n
+		if hErr != nil {
+			errs = append(errs, hErr)
+		}
+	}
+	return dropped, errors.Join(errs...)
+}
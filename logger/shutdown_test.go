@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingWriter blocks writes until unblocked.
+type blockingWriter struct {
+	started chan struct{}
+	unblock chan struct{}
+	lock    sync.Mutex
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.unblock
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.String()
+}
+
+func TestShutdown(t *testing.T) {
+	t.Run("async sinks are drained and flushed", func(t *testing.T) {
+		var out bytes.Buffer
+		bw := bufio.NewWriterSize(&out, 64*1024)
+		l := getTestLogger(&bytes.Buffer{})
+		require.NoError(t, SetSinks(l, SinksOptions{}, Sink{Name: "async", Writer: bw, Async: true}))
+		defer SetSinks(l, SinksOptions{})
+
+		for i := 0; i < 100; i++ {
+			l.Infof("record %d", i)
+		}
+		l.Error("last error")
+
+		dropped, err := Shutdown(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, dropped)
+		assert.Contains(t, out.String(), "record 99")
+		assert.Contains(t, out.String(), "last error")
+
+		// After the shutdown, records are written synchronously
+		l.Info("after shutdown")
+		require.NoError(t, bw.Flush())
+		assert.Contains(t, out.String(), "after shutdown")
+	})
+
+	t.Run("records are dropped when the buffer is full or the deadline is reached", func(t *testing.T) {
+		w := &blockingWriter{
+			started: make(chan struct{}, 10),
+			unblock: make(chan struct{}),
+		}
+		l := getTestLogger(&bytes.Buffer{})
+		require.NoError(t, SetSinks(l, SinksOptions{}, Sink{Name: "slow", Writer: w, Async: true, BufferSize: 1}))
+
+		l.Info("first")
+		<-w.started
+		l.Info("second")
+		l.Info("third")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		dropped, err := Shutdown(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "failed to drain log sink 'slow'")
+		// "second" was still queued, and "third" didn't fit in the buffer
+		assert.Equal(t, uint64(2), dropped)
+
+		close(w.unblock)
+		require.NoError(t, SetSinks(l, SinksOptions{}))
+		assert.Contains(t, w.String(), "first")
+		assert.NotContains(t, w.String(), "third")
+	})
+
+	t.Run("sinks are drained when replaced", func(t *testing.T) {
+		var out bytes.Buffer
+		l := getTestLogger(&bytes.Buffer{})
+		require.NoError(t, SetSinks(l, SinksOptions{}, Sink{Name: "async", Writer: &out, Async: true}))
+		l.Info("before replacing")
+		require.NoError(t, SetSinks(l, SinksOptions{}))
+		assert.Contains(t, out.String(), "before replacing")
+	})
+}
@@ -0,0 +1,357 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	kclock "k8s.io/utils/clock"
+)
+
+const (
+	// DefaultSinkWarningInterval is the minimum interval between two warnings about a failing sink.
+	DefaultSinkWarningInterval = time.Minute
+	// DefaultSinkBufferSize is the default number of records buffered by async sinks.
+	DefaultSinkBufferSize = 1024
+
+	// sinkReplaceTimeout is the maximum time to wait for async sinks to be drained when they're replaced.
+	sinkReplaceTimeout = 5 * time.Second
+)
+
+var (
+	// activeSinks contains the sinks slot of each logrus logger, so sinks can be replaced and flushed on shutdown.
+	activeSinks     = map[*logrus.Logger]*sinksSlot{}
+	activeSinksLock sync.Mutex
+)
+
+// Sink is a destination for log records.
+type Sink struct {
+	// Name of the sink, used in warnings.
+	Name string
+	// Writer that receives formatted records.
+	Writer io.Writer
+	// Level is the minimum level of records written to the sink. If empty, all records that pass the logger's output
+	// level are written.
+	Level LogLevel
+	// Async makes records be written to the sink by a background goroutine, so slow sinks (such as network
+	// connections) don't block logging. Up to BufferSize records are buffered; when the buffer is full, records are
+	// dropped, and they're counted in the result of Shutdown.
+	Async bool
+	// BufferSize is the number of records buffered by async sinks. Default is DefaultSinkBufferSize.
+	BufferSize int
+}
+
+// SinksOptions contains the options for SetSinks.
+type SinksOptions struct {
+	// Fallback receives the records that a sink failed to write. Default is os.Stderr.
+	Fallback io.Writer
+	// WarningInterval is the minimum interval between two warnings about the same failing sink.
+	// Default is DefaultSinkWarningInterval.
+	WarningInterval time.Duration
+	// Clock used to rate-limit warnings. Used for testing.
+	Clock kclock.PassiveClock
+}
+
+// SetSinks configures the logger to write records to the given sinks, instead of its output.
+// Records are first filtered by the logger's output level, then each sink applies its own minimum level.
+// When writing to a sink fails (for example because the disk is full or the network is down), the record is written
+// to the fallback writer instead, together with a rate-limited warning, so records are not dropped silently.
+// Invoking SetSinks without sinks removes them and restores the output that the logger had before sinks were set.
+// Sinks are shared by all loggers derived from l with WithFields and WithLogType.
+// Async sinks that are replaced are drained first; use Shutdown to drain them before the process exits.
+// Other hooks of the logger are not affected.
+func SetSinks(l Logger, opts SinksOptions, sinks ...Sink) error {
+	dl, ok := l.(*daprLogger)
+	if !ok {
+		return errors.New("logger does not support sinks")
+	}
+
+	if len(sinks) == 0 {
+		replaceActiveSinks(dl.logger.Logger, nil)
+		return nil
+	}
+
+	h := &sinksHook{
+		fallback:        opts.Fallback,
+		warningInterval: opts.WarningInterval,
+		clock:           opts.Clock,
+		sinks:           make([]*sinkWriter, len(sinks)),
+	}
+	if h.fallback == nil {
+		h.fallback = os.Stderr
+	}
+	if h.warningInterval <= 0 {
+		h.warningInterval = DefaultSinkWarningInterval
+	}
+	if h.clock == nil {
+		if c := dl.clock.Load(); c != nil {
+			h.clock = *c
+		} else {
+			h.clock = kclock.RealClock{}
+		}
+	}
+	for i, s := range sinks {
+		if s.Writer == nil {
+			return fmt.Errorf("sink '%s' has no writer", s.Name)
+		}
+		level := logrus.TraceLevel
+		if s.Level != "" {
+			if toLogLevel(string(s.Level)) == UndefinedLevel {
+				return fmt.Errorf("sink '%s' has an invalid level: %s", s.Name, s.Level)
+			}
+			level = toLogrusLevel(s.Level)
+		}
+		h.sinks[i] = &sinkWriter{Sink: s, level: level}
+	}
+	for _, s := range h.sinks {
+		if s.Async {
+			s.startAsync(h)
+		}
+	}
+
+	replaceActiveSinks(dl.logger.Logger, h)
+	return nil
+}
+
+// replaceActiveSinks sets h as the sinks hook of logger, draining the previous one, if any.
+// The first time it's invoked for a logger, it adds a sinksSlot to the hooks of the logger, which is never removed:
+// replacing the sinks only swaps the hook in the slot, so the other hooks of the logger are preserved.
+// When sinks are installed, the output of the logger is saved and replaced with io.Discard; when they're removed, the
+// saved output is restored.
+func replaceActiveSinks(logger *logrus.Logger, h *sinksHook) {
+	activeSinksLock.Lock()
+	slot, ok := activeSinks[logger]
+	if !ok {
+		if h == nil {
+			activeSinksLock.Unlock()
+			return
+		}
+		slot = &sinksSlot{}
+		activeSinks[logger] = slot
+		logger.AddHook(slot)
+	}
+	prev := slot.hook.Swap(h)
+	switch {
+	case h != nil && prev == nil:
+		slot.output = logger.Out
+		logger.SetOutput(io.Discard)
+	case h == nil && prev != nil:
+		logger.SetOutput(slot.output)
+		slot.output = nil
+	}
+	activeSinksLock.Unlock()
+
+	if prev != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), sinkReplaceTimeout)
+		defer cancel()
+		_, _ = prev.shutdown(ctx)
+	}
+}
+
+// sinksSlot is the logrus hook that is added to loggers with sinks. It forwards records to the current sinks hook,
+// if any.
+type sinksSlot struct {
+	hook atomic.Pointer[sinksHook]
+	// Output of the logger before sinks were installed, restored when they're removed.
+	// Guarded by activeSinksLock.
+	output io.Writer
+}
+
+func (s *sinksSlot) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (s *sinksSlot) Fire(entry *logrus.Entry) error {
+	h := s.hook.Load()
+	if h == nil {
+		return nil
+	}
+	return h.Fire(entry)
+}
+
+// sinksHook writes records to the sinks.
+type sinksHook struct {
+	sinks           []*sinkWriter
+	fallback        io.Writer
+	warningInterval time.Duration
+	clock           kclock.PassiveClock
+	fallbackLock    sync.Mutex
+}
+
+type sinkWriter struct {
+	Sink
+	level    logrus.Level
+	lock     sync.Mutex
+	lastWarn time.Time
+
+	// Used by async sinks
+	queue     chan []byte
+	queueLock sync.RWMutex
+	closed    bool
+	doneCh    chan struct{}
+	dropped   atomic.Uint64
+}
+
+func (h *sinksHook) Fire(entry *logrus.Entry) error {
+	var (
+		record []byte
+		err    error
+	)
+	for _, s := range h.sinks {
+		// Lower levels are more severe in logrus
+		if entry.Level > s.level {
+			continue
+		}
+		if record == nil {
+			record, err = entry.Bytes()
+			if err != nil {
+				return err
+			}
+		}
+
+		if s.Async && s.enqueue(record) {
+			continue
+		}
+		s.write(h, record)
+	}
+	return nil
+}
+
+// write writes the record to the sink, or to the fallback writer if that fails.
+func (s *sinkWriter) write(h *sinksHook, record []byte) {
+	s.lock.Lock()
+	_, err := s.Writer.Write(record)
+	s.lock.Unlock()
+	if err != nil {
+		h.writeFallback(s, record, err)
+	}
+}
+
+// startAsync starts the goroutine that writes the records of an async sink.
+func (s *sinkWriter) startAsync(h *sinksHook) {
+	size := s.BufferSize
+	if size <= 0 {
+		size = DefaultSinkBufferSize
+	}
+	s.queue = make(chan []byte, size)
+	s.doneCh = make(chan struct{})
+	go func() {
+		defer close(s.doneCh)
+		for record := range s.queue {
+			s.write(h, record)
+		}
+	}()
+}
+
+// enqueue adds the record to the queue of an async sink.
+// It returns false if the sink was stopped, in which case the record must be written synchronously.
+func (s *sinkWriter) enqueue(record []byte) bool {
+	s.queueLock.RLock()
+	defer s.queueLock.RUnlock()
+
+	if s.closed {
+		return false
+	}
+	select {
+	case s.queue <- append([]byte(nil), record...):
+	default:
+		s.dropped.Add(1)
+	}
+	return true
+}
+
+// shutdown stops the async sinks, waiting until they're drained or ctx is canceled, then flushes all sinks.
+// It returns the number of records that were dropped.
+// After shutdown, records are written to async sinks synchronously.
+func (h *sinksHook) shutdown(ctx context.Context) (uint64, error) {
+	var (
+		dropped uint64
+		errs    []error
+	)
+	for _, s := range h.sinks {
+		if s.Async {
+			s.queueLock.Lock()
+			if !s.closed {
+				s.closed = true
+				close(s.queue)
+			}
+			s.queueLock.Unlock()
+
+			select {
+			case <-s.doneCh:
+				dropped += s.dropped.Swap(0)
+			case <-ctx.Done():
+				// Records that are still in the queue may not be written, and the writer is likely stuck so it can't
+				// be flushed either
+				dropped += s.dropped.Swap(0) + uint64(len(s.queue))
+				errs = append(errs, fmt.Errorf("failed to drain log sink '%s': %w", s.Name, ctx.Err()))
+				continue
+			}
+		}
+
+		err := s.flush()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush log sink '%s': %w", s.Name, err))
+		}
+	}
+
+	return dropped, errors.Join(errs...)
+}
+
+// flush flushes the writer of the sink, if it supports it, for example a *bufio.Writer or an *os.File.
+func (s *sinkWriter) flush() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch w := s.Writer.(type) {
+	case interface{ Flush() error }:
+		return w.Flush()
+	case interface{ Sync() error }:
+		err := w.Sync()
+		// Syncing is not supported by all files, such as pipes and terminals
+		if err != nil && (errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTSUP)) {
+			return nil
+		}
+		return err
+	default:
+		return nil
+	}
+}
+
+// writeFallback writes the record to the fallback writer, preceded by a warning if one wasn't logged recently.
+func (h *sinksHook) writeFallback(s *sinkWriter, record []byte, err error) {
+	now := h.clock.Now()
+	s.lock.Lock()
+	warn := s.lastWarn.IsZero() || now.Sub(s.lastWarn) >= h.warningInterval
+	if warn {
+		s.lastWarn = now
+	}
+	s.lock.Unlock()
+
+	h.fallbackLock.Lock()
+	defer h.fallbackLock.Unlock()
+	if warn {
+		fmt.Fprintf(h.fallback, "Failed to write to log sink '%s', writing records to the fallback output: %v\n", s.Name, err)
+	}
+	_, _ = h.fallback.Write(record)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+type failingWriter struct {
+	fail bool
+	buf  bytes.Buffer
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.fail {
+		return 0, errors.New("disk full")
+	}
+	return w.buf.Write(p)
+}
+
+func TestSinks(t *testing.T) {
+	t.Run("per-sink levels", func(t *testing.T) {
+		var output, all, errorsOnly bytes.Buffer
+		l := getTestLogger(&output)
+		l.SetOutputLevel(DebugLevel)
+
+		require.NoError(t, SetSinks(l, SinksOptions{},
+			Sink{Name: "all", Writer: &all},
+			Sink{Name: "errors", Writer: &errorsOnly, Level: ErrorLevel},
+		))
+
+		l.Debug("debug message")
+		l.WithFields(map[string]any{"k": "v"}).Error("error message")
+
+		assert.Empty(t, output.String())
+		assert.Contains(t, all.String(), "debug message")
+		assert.Contains(t, all.String(), "error message")
+		assert.NotContains(t, errorsOnly.String(), "debug message")
+		assert.Contains(t, errorsOnly.String(), "error message")
+		assert.Contains(t, errorsOnly.String(), "k=v")
+	})
+
+	t.Run("logger level applies first", func(t *testing.T) {
+		var sink bytes.Buffer
+		l := getTestLogger(&bytes.Buffer{})
+		l.SetOutputLevel(WarnLevel)
+		require.NoError(t, SetSinks(l, SinksOptions{}, Sink{Name: "debug", Writer: &sink, Level: DebugLevel}))
+
+		l.Info("info message")
+		assert.Empty(t, sink.String())
+	})
+
+	t.Run("failing sinks fall back", func(t *testing.T) {
+		var fallback bytes.Buffer
+		w := &failingWriter{fail: true}
+		clock := clocktesting.NewFakePassiveClock(time.Now())
+		l := getTestLogger(&bytes.Buffer{})
+		require.NoError(t, SetSinks(l, SinksOptions{
+			Fallback:        &fallback,
+			WarningInterval: time.Minute,
+			Clock:           clock,
+		}, Sink{Name: "file", Writer: w}))
+
+		l.Info("first")
+		l.Info("second")
+		assert.Equal(t, 1, strings.Count(fallback.String(), "Failed to write to log sink 'file'"))
+		assert.Contains(t, fallback.String(), "disk full")
+		assert.Contains(t, fallback.String(), "first")
+		assert.Contains(t, fallback.String(), "second")
+
+		clock.SetTime(clock.Now().Add(time.Minute))
+		l.Info("third")
+		assert.Equal(t, 2, strings.Count(fallback.String(), "Failed to write to log sink 'file'"))
+
+		// When the sink recovers, records are written to it again
+		w.fail = false
+		fallback.Reset()
+		l.Info("fourth")
+		assert.Empty(t, fallback.String())
+		assert.Contains(t, w.buf.String(), "fourth")
+	})
+
+	t.Run("removing sinks", func(t *testing.T) {
+		var sink bytes.Buffer
+		l := getTestLogger(&bytes.Buffer{})
+		require.NoError(t, SetSinks(l, SinksOptions{}, Sink{Name: "sink", Writer: &sink}))
+		require.NoError(t, SetSinks(l, SinksOptions{}))
+
+		var output bytes.Buffer
+		l.SetOutput(&output)
+		l.Info("message")
+		assert.Empty(t, sink.String())
+		assert.Contains(t, output.String(), "message")
+	})
+
+	t.Run("removing sinks restores the previous output", func(t *testing.T) {
+		var output, sink bytes.Buffer
+		l := getTestLogger(&output)
+		require.NoError(t, SetSinks(l, SinksOptions{}, Sink{Name: "sink", Writer: &sink}))
+		require.NoError(t, SetSinks(l, SinksOptions{}, Sink{Name: "other", Writer: &sink}))
+		l.Info("first")
+		assert.Empty(t, output.String())
+
+		require.NoError(t, SetSinks(l, SinksOptions{}))
+		l.Info("second")
+		assert.NotContains(t, sink.String(), "second")
+		assert.Contains(t, output.String(), "second")
+
+		// Removing sinks from a logger without sinks doesn't change its output
+		require.NoError(t, SetSinks(l, SinksOptions{}))
+		l.Info("third")
+		assert.Contains(t, output.String(), "third")
+	})
+
+	t.Run("other hooks are preserved", func(t *testing.T) {
+		l := getTestLogger(&bytes.Buffer{})
+		hook := &countingHook{}
+		l.logger.Logger.AddHook(hook)
+
+		var sink1, sink2 bytes.Buffer
+		require.NoError(t, SetSinks(l, SinksOptions{}, Sink{Name: "sink1", Writer: &sink1}))
+		l.Info("first")
+		require.NoError(t, SetSinks(l, SinksOptions{}, Sink{Name: "sink2", Writer: &sink2}))
+		l.Info("second")
+		require.NoError(t, SetSinks(l, SinksOptions{}))
+		l.Info("third")
+
+		assert.Equal(t, 3, hook.count)
+		assert.Contains(t, sink1.String(), "first")
+		assert.NotContains(t, sink1.String(), "second")
+		assert.Contains(t, sink2.String(), "second")
+		assert.NotContains(t, sink2.String(), "third")
+	})
+
+	t.Run("invalid sinks", func(t *testing.T) {
+		l := getTestLogger(&bytes.Buffer{})
+		require.Error(t, SetSinks(l, SinksOptions{}, Sink{Name: "nowriter"}))
+		require.Error(t, SetSinks(l, SinksOptions{}, Sink{Name: "bad", Writer: &bytes.Buffer{}, Level: "verbose"}))
+		require.Error(t, SetSinks(&nopLogger{}, SinksOptions{}, Sink{Name: "sink", Writer: &bytes.Buffer{}}))
+	})
+}
+
+// countingHook is a logrus hook that counts the records it receives.
+type countingHook struct {
+	count int
+}
+
+func (h *countingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *countingHook) Fire(*logrus.Entry) error {
+	h.count++
+	return nil
+}
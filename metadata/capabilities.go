@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metadata contains helpers for the metadata of components, such as the capabilities they declare.
+package metadata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+// Capability is an optional feature that a component can support.
+type Capability string
+
+// Well-known capabilities.
+const (
+	// CapabilityTTL is the ability to set a time-to-live on records.
+	CapabilityTTL Capability = "TTL"
+	// CapabilityETag is the ability to use ETags for optimistic concurrency control.
+	CapabilityETag Capability = "ETAG"
+	// CapabilityQuery is the ability to query records.
+	CapabilityQuery Capability = "QUERY"
+	// CapabilityTransactions is the ability to execute multiple operations in a transaction.
+	CapabilityTransactions Capability = "TRANSACTIONAL"
+)
+
+const (
+	// ErrorReasonCapabilityNotSupported is the reason of the errors returned when a component is asked to use
+	// capabilities it doesn't support.
+	ErrorReasonCapabilityNotSupported = "DAPR_COMPONENT_CAPABILITY_NOT_SUPPORTED"
+	// PreconditionTypeCapability is the type of the PreconditionFailure violations for unsupported capabilities.
+	PreconditionTypeCapability = "CAPABILITY"
+)
+
+// Declarer is implemented by components that declare their capabilities.
+type Declarer interface {
+	Capabilities() Capabilities
+}
+
+// Capabilities is a set of capabilities.
+// The zero value is an empty set.
+type Capabilities struct {
+	set map[Capability]struct{}
+}
+
+// NewCapabilities returns a set with the given capabilities.
+func NewCapabilities(caps ...Capability) Capabilities {
+	c := Capabilities{
+		set: make(map[Capability]struct{}, len(caps)),
+	}
+	for _, capability := range caps {
+		c.set[capability] = struct{}{}
+	}
+	return c
+}
+
+// Has returns true if the capability is in the set.
+func (c Capabilities) Has(capability Capability) bool {
+	_, ok := c.set[capability]
+	return ok
+}
+
+// List returns the capabilities in the set, sorted alphabetically.
+func (c Capabilities) List() []Capability {
+	res := make([]Capability, 0, len(c.set))
+	for capability := range c.set {
+		res = append(res, capability)
+	}
+	sortCapabilities(res)
+	return res
+}
+
+// Intersect splits the requested capabilities in those that are in the set, and those that are not.
+// Both results are sorted alphabetically, without duplicates.
+func (c Capabilities) Intersect(requested ...Capability) (supported []Capability, unsupported []Capability) {
+	seen := make(map[Capability]struct{}, len(requested))
+	for _, capability := range requested {
+		if _, ok := seen[capability]; ok {
+			continue
+		}
+		seen[capability] = struct{}{}
+
+		if c.Has(capability) {
+			supported = append(supported, capability)
+		} else {
+			unsupported = append(unsupported, capability)
+		}
+	}
+	sortCapabilities(supported)
+	sortCapabilities(unsupported)
+	return supported, unsupported
+}
+
+// Require returns an error if any of the requested capabilities is not in the set, or nil otherwise.
+// The error is a kit error with the Unimplemented code, a ResourceInfo detail for the component, and a
+// PreconditionFailure detail with a violation for each unsupported capability.
+func (c Capabilities) Require(component kiterrors.ResourceInfo, requested ...Capability) error {
+	_, unsupported := c.Intersect(requested...)
+	if len(unsupported) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(unsupported))
+	violations := make([]*errdetails.PreconditionFailure_Violation, len(unsupported))
+	for i, capability := range unsupported {
+		names[i] = string(capability)
+		violations[i] = &errdetails.PreconditionFailure_Violation{
+			Type:        PreconditionTypeCapability,
+			Subject:     string(capability),
+			Description: fmt.Sprintf("%s %s does not support %s", component.Type, component.Name, capability),
+		}
+	}
+
+	return kiterrors.New(
+		fmt.Errorf("%s %s does not support: %s", component.Type, component.Name, strings.Join(names, ", ")),
+		nil,
+		kiterrors.WithErrorReason(ErrorReasonCapabilityNotSupported, codes.Unimplemented),
+		kiterrors.WithResourceInfo(&component),
+		kiterrors.WithPreconditionFailure(violations...),
+	)
+}
+
+// RequireOf returns an error if the component doesn't declare any of the requested capabilities.
+// Components that don't implement Declarer have no capabilities.
+func RequireOf(component any, info kiterrors.ResourceInfo, requested ...Capability) error {
+	var caps Capabilities
+	if d, ok := component.(Declarer); ok {
+		caps = d.Capabilities()
+	}
+	return caps.Require(info, requested...)
+}
+
+func sortCapabilities(caps []Capability) {
+	sort.Slice(caps, func(i, j int) bool {
+		return caps[i] < caps[j]
+	})
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+func TestCapabilities(t *testing.T) {
+	caps := NewCapabilities(CapabilityTTL, CapabilityETag)
+	assert.True(t, caps.Has(CapabilityTTL))
+	assert.False(t, caps.Has(CapabilityQuery))
+	assert.Equal(t, []Capability{CapabilityETag, CapabilityTTL}, caps.List())
+
+	supported, unsupported := caps.Intersect(CapabilityTTL, CapabilityTransactions, CapabilityQuery, CapabilityTTL)
+	assert.Equal(t, []Capability{CapabilityTTL}, supported)
+	assert.Equal(t, []Capability{CapabilityQuery, CapabilityTransactions}, unsupported)
+
+	// The zero value is an empty set
+	var empty Capabilities
+	assert.False(t, empty.Has(CapabilityTTL))
+	assert.Empty(t, empty.List())
+}
+
+func TestRequire(t *testing.T) {
+	info := kiterrors.ResourceInfo{Type: "state", Name: "statestore"}
+	caps := NewCapabilities(CapabilityTTL, CapabilityETag)
+
+	require.NoError(t, caps.Require(info, CapabilityETag, CapabilityTTL))
+	require.NoError(t, caps.Require(info))
+
+	err := caps.Require(info, CapabilityTTL, CapabilityTransactions, CapabilityQuery)
+	require.Error(t, err)
+	assert.Equal(t, "state statestore does not support: QUERY, TRANSACTIONAL", err.Error())
+
+	var kitErr *kiterrors.Error
+	require.True(t, errors.As(err, &kitErr))
+	assert.Equal(t, ErrorReasonCapabilityNotSupported, kitErr.Reason())
+	assert.Equal(t, http.StatusNotImplemented, kitErr.HTTPCode())
+
+	st := kitErr.GRPCStatus()
+	assert.Equal(t, codes.Unimplemented, st.Code())
+	var pf *errdetails.PreconditionFailure
+	var ri *errdetails.ResourceInfo
+	for _, d := range st.Details() {
+		switch v := d.(type) {
+		case *errdetails.PreconditionFailure:
+			pf = v
+		case *errdetails.ResourceInfo:
+			ri = v
+		}
+	}
+	require.NotNil(t, ri)
+	assert.Equal(t, "statestore", ri.GetResourceName())
+	require.NotNil(t, pf)
+	require.Len(t, pf.GetViolations(), 2)
+	assert.Equal(t, PreconditionTypeCapability, pf.GetViolations()[0].GetType())
+	assert.Equal(t, "QUERY", pf.GetViolations()[0].GetSubject())
+	assert.Equal(t, "TRANSACTIONAL", pf.GetViolations()[1].GetSubject())
+}
+
+type declaringComponent struct{}
+
+func (declaringComponent) Capabilities() Capabilities {
+	return NewCapabilities(CapabilityQuery)
+}
+
+func TestRequireOf(t *testing.T) {
+	info := kiterrors.ResourceInfo{Type: "state", Name: "statestore"}
+	require.NoError(t, RequireOf(declaringComponent{}, info, CapabilityQuery))
+	require.Error(t, RequireOf(declaringComponent{}, info, CapabilityTTL))
+	require.Error(t, RequireOf(struct{}{}, info, CapabilityQuery))
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/valyala/fasthttp"
+	"google.golang.org/grpc/status"
+
+	kiterrors "github.com/dapr/kit/errors"
+	"github.com/dapr/kit/grpccodes"
+)
+
+// fasthttpContextKey is the key of the user value that contains the context of fasthttp requests.
+type fasthttpContextKey struct{}
+
+// FastHTTP returns a fasthttp middleware that runs the calls through the middlewares of the pipeline that apply to
+// TransportFastHTTP.
+// Errors returned by middlewares before the handler is invoked are sent to the client like with HTTP.
+// fasthttp handlers don't receive a context.Context, so the one passed by the middlewares to the next handler is stored
+// in the request: handlers can get it with FastHTTPContext.
+func (p Pipeline) FastHTTP() func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	h := p.Then(TransportFastHTTP, invoke)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(reqCtx *fasthttp.RequestCtx) {
+			invoked := false
+			call := &Call{
+				Transport: TransportFastHTTP,
+				Operation: string(reqCtx.Method()) + " " + string(reqCtx.Path()),
+				Header:    fasthttpHeader{h: &reqCtx.Request.Header},
+				invoke: func(ctx context.Context, call *Call) error {
+					invoked = true
+					reqCtx.SetUserValue(fasthttpContextKey{}, ctx)
+					next(reqCtx)
+					call.HTTPStatus = reqCtx.Response.StatusCode()
+					return nil
+				},
+			}
+
+			err := h(FastHTTPContext(reqCtx), call)
+			if err != nil && !invoked {
+				writeFastHTTPError(reqCtx, err)
+			}
+		}
+	}
+}
+
+// FastHTTPContext returns the context of a fasthttp request, as passed to the handler by the middlewares of the
+// pipeline. Without middlewares, it's the request itself, which implements context.Context.
+func FastHTTPContext(reqCtx *fasthttp.RequestCtx) context.Context {
+	if ctx, ok := reqCtx.UserValue(fasthttpContextKey{}).(context.Context); ok {
+		return ctx
+	}
+	return reqCtx
+}
+
+// fasthttpHeader implements Header for the headers of fasthttp requests.
+type fasthttpHeader struct {
+	h *fasthttp.RequestHeader
+}
+
+// Get implements Header.
+func (h fasthttpHeader) Get(key string) string {
+	return string(h.h.Peek(key))
+}
+
+func writeFastHTTPError(reqCtx *fasthttp.RequestCtx, err error) {
+	var kerr *kiterrors.Error
+	if errors.As(err, &kerr) {
+		kerr.Emit()
+		code, body := kerr.ToHTTP()
+		reqCtx.SetContentType("application/json")
+		reqCtx.SetStatusCode(code)
+		reqCtx.SetBody(body)
+		return
+	}
+	if st, ok := status.FromError(err); ok {
+		reqCtx.Error(st.Message(), grpccodes.HTTPStatusFromCode(st.Code()))
+		return
+	}
+	reqCtx.Error(http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
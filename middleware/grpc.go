@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor that runs the calls through the middlewares of the
+// pipeline that apply to TransportGRPC. Errors returned by middlewares are returned to the client; kit errors
+// include their status details, and they're emitted to the error observers.
+func (p Pipeline) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	h := p.Then(TransportGRPC, invoke)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var res any
+		call := newGRPCCall(ctx, info.FullMethod, func(ctx context.Context, call *Call) error {
+			var err error
+			res, err = handler(ctx, req)
+			call.GRPCCode = status.Code(err)
+			return err
+		})
+
+		err := h(ctx, call)
+		if err != nil {
+			kiterrors.Emit(err)
+			return nil, err
+		}
+		return res, nil
+	}
+}
+
+// StreamServerInterceptor returns a gRPC stream server interceptor that runs the streams through the middlewares of
+// the pipeline that apply to TransportGRPC.
+func (p Pipeline) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	h := p.Then(TransportGRPC, invoke)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		call := newGRPCCall(ss.Context(), info.FullMethod, func(ctx context.Context, call *Call) error {
+			err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+			call.GRPCCode = status.Code(err)
+			return err
+		})
+		err := h(ss.Context(), call)
+		kiterrors.Emit(err)
+		return err
+	}
+}
+
+func newGRPCCall(ctx context.Context, fullMethod string, invoke func(ctx context.Context, call *Call) error) *Call {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return &Call{
+		Transport: TransportGRPC,
+		Operation: fullMethod,
+		Header:    metadataHeader(md),
+		invoke:    invoke,
+	}
+}
+
+// metadataHeader implements Header for gRPC metadata.
+type metadataHeader metadata.MD
+
+// Get implements Header.
+func (h metadataHeader) Get(key string) string {
+	v := metadata.MD(h).Get(key)
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+// serverStream is a grpc.ServerStream with the context returned by the middlewares.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context implements grpc.ServerStream.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/status"
+
+	kiterrors "github.com/dapr/kit/errors"
+	"github.com/dapr/kit/grpccodes"
+	"github.com/dapr/kit/utils/httputil"
+)
+
+// HTTP returns a net/http middleware that runs the calls through the middlewares of the pipeline that apply to
+// TransportHTTP.
+// If a middleware returns an error before the response is written, the error is sent to the client: kit errors as
+// their JSON representation, gRPC status errors with the corresponding HTTP status code, and other errors as
+// "500 Internal Server Error".
+func (p Pipeline) HTTP() func(next http.Handler) http.Handler {
+	h := p.Then(TransportHTTP, invoke)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := httputil.NewResponseWriter(w)
+			call := &Call{
+				Transport: TransportHTTP,
+				Operation: r.Method + " " + r.URL.Path,
+				Header:    r.Header,
+				invoke: func(ctx context.Context, call *Call) error {
+					next.ServeHTTP(rw, r.WithContext(ctx))
+					call.HTTPStatus = rw.StatusCode()
+					return nil
+				},
+			}
+
+			err := h(r.Context(), call)
+			if err != nil && !rw.WroteHeader() {
+				writeHTTPError(rw, err)
+			}
+		})
+	}
+}
+
+// HTTPStatusFromError returns the HTTP status code of the response for err, as written by the HTTP adapter.
+func HTTPStatusFromError(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var kerr *kiterrors.Error
+	if errors.As(err, &kerr) {
+		return kerr.HTTPCode()
+	}
+	if st, ok := status.FromError(err); ok {
+		return grpccodes.HTTPStatusFromCode(st.Code())
+	}
+	return http.StatusInternalServerError
+}
+
+func writeHTTPError(w http.ResponseWriter, err error) {
+	var kerr *kiterrors.Error
+	if errors.As(err, &kerr) {
+		kerr.Emit()
+		code, body := kerr.ToHTTP()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_, _ = w.Write(body)
+		return
+	}
+	if st, ok := status.FromError(err); ok {
+		http.Error(w, st.Message(), grpccodes.HTTPStatusFromCode(st.Code()))
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
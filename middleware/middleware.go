@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package middleware contains a transport-agnostic model of middlewares, which wrap the handling of calls to servers,
+// and adapters that turn pipelines of middlewares into net/http and fasthttp middlewares, and gRPC interceptors.
+// Middlewares such as recovery, logging, metrics, rate limiting and load shedding are written once, against Handler,
+// and compose identically across servers. Pipelines can be built declaratively from a Spec, with the middlewares
+// registered by name in a Registry.
+package middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Transport identifies the transport of a call.
+type Transport string
+
+const (
+	// TransportHTTP is the transport of calls to HTTP servers.
+	TransportHTTP Transport = "http"
+	// TransportGRPC is the transport of calls to gRPC servers.
+	TransportGRPC Transport = "grpc"
+	// TransportFastHTTP is the transport of calls to fasthttp servers.
+	TransportFastHTTP Transport = "fasthttp"
+)
+
+// Header contains the headers of HTTP requests, or the metadata of gRPC calls.
+// http.Header implements it.
+type Header interface {
+	// Get returns the first value associated with the key, or an empty string.
+	Get(key string) string
+}
+
+// Call is the transport-agnostic view of a call, shared by all middlewares of a pipeline.
+type Call struct {
+	// Transport of the call.
+	Transport Transport
+	// Operation is the method and path of HTTP requests, such as "GET /v1.0/state/store", or the full method of gRPC
+	// calls, such as "/dapr.proto.runtime.v1.Dapr/GetState".
+	Operation string
+	// Header contains the headers of HTTP requests, or the incoming metadata of gRPC calls.
+	Header Header
+
+	// HTTPStatus is the status code of the response of HTTP requests. It's set when the handler returns.
+	HTTPStatus int
+	// GRPCCode is the code of the status of gRPC calls. It's set when the handler returns.
+	GRPCCode codes.Code
+
+	// invoke calls the handler of the server. It's set by the adapters.
+	invoke func(ctx context.Context, call *Call) error
+}
+
+// invoke is the innermost Handler of the pipelines, which calls the handler of the server.
+func invoke(ctx context.Context, call *Call) error {
+	return call.invoke(ctx, call)
+}
+
+// Handler handles a call. Errors returned by middlewares are sent to the client by the adapters: see HTTP, FastHTTP and
+// UnaryServerInterceptor. Since HTTPStatus and GRPCCode are not set when a middleware short-circuits a call, use
+// HTTPStatusFromError and status.Code to get the status of errors.
+type Handler func(ctx context.Context, call *Call) error
+
+// Middleware wraps a Handler. Middlewares can modify the context passed to the next handler, short-circuit the call
+// by returning an error without invoking next, or observe the outcome of the call after next returns.
+type Middleware func(next Handler) Handler
+
+// Pipeline is an ordered list of middlewares. The first middleware is the outermost one.
+type Pipeline struct {
+	entries []entry
+}
+
+type entry struct {
+	name       string
+	middleware Middleware
+	transports []Transport
+}
+
+// Chain returns a Pipeline with the given middlewares, in order. The first middleware is the outermost one.
+func Chain(middlewares ...Middleware) Pipeline {
+	p := Pipeline{entries: make([]entry, len(middlewares))}
+	for i, m := range middlewares {
+		p.entries[i] = entry{middleware: m}
+	}
+	return p
+}
+
+// Len returns the number of middlewares in the pipeline.
+func (p Pipeline) Len() int {
+	return len(p.entries)
+}
+
+// Names returns the names of the middlewares in the pipeline, in order, for pipelines built from a Spec.
+// Middlewares added with Chain have an empty name.
+func (p Pipeline) Names() []string {
+	names := make([]string, len(p.entries))
+	for i, e := range p.entries {
+		names[i] = e.name
+	}
+	return names
+}
+
+// Then returns a Handler that invokes the middlewares of the pipeline that apply to the transport, and finally h.
+func (p Pipeline) Then(transport Transport, h Handler) Handler {
+	for i := len(p.entries) - 1; i >= 0; i-- {
+		if p.entries[i].appliesTo(transport) {
+			h = p.entries[i].middleware(h)
+		}
+	}
+	return h
+}
+
+// appliesTo returns true if the middleware applies to calls on the transport.
+func (e entry) appliesTo(transport Transport) bool {
+	if len(e.transports) == 0 {
+		return true
+	}
+	for _, t := range e.transports {
+		if t == transport {
+			return true
+		}
+	}
+	return false
+}
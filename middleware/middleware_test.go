@@ -0,0 +1,353 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+type ctxKey string
+
+// recorder returns a middleware that appends its name to calls, before and after the next handler, and records the
+// status observed after the next handler returns.
+func recorder(name string, calls *[]string, observed *Call) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			*calls = append(*calls, name+">")
+			err := next(context.WithValue(ctx, ctxKey(name), true), call)
+			*calls = append(*calls, "<"+name)
+			if observed != nil {
+				*observed = *call
+			}
+			return err
+		}
+	}
+}
+
+// reject returns a middleware that short-circuits calls with err.
+func reject(err error) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			return err
+		}
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	t.Run("middlewares wrap the handler in order", func(t *testing.T) {
+		var (
+			calls    []string
+			observed Call
+		)
+		p := Chain(recorder("a", &calls, &observed), recorder("b", &calls, nil))
+		h := p.HTTP()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+			// The context is passed through
+			assert.Equal(t, true, r.Context().Value(ctxKey("a")))
+			assert.Equal(t, true, r.Context().Value(ctxKey("b")))
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/v1.0/state/store", nil)
+		req.Header.Set("dapr-app-id", "myapp")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, []string{"a>", "b>", "handler", "<b", "<a"}, calls)
+		assert.Equal(t, TransportHTTP, observed.Transport)
+		assert.Equal(t, "POST /v1.0/state/store", observed.Operation)
+		assert.Equal(t, "myapp", observed.Header.Get("dapr-app-id"))
+		assert.Equal(t, http.StatusCreated, observed.HTTPStatus)
+	})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be invoked")
+	})
+
+	t.Run("kit errors", func(t *testing.T) {
+		kerr := kiterrors.New(errors.New("too many requests"), nil,
+			kiterrors.WithErrorReason("RATE_LIMITED", codes.ResourceExhausted),
+		)
+		rec := httptest.NewRecorder()
+		Chain(reject(kerr)).HTTP()(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, http.StatusTooManyRequests, HTTPStatusFromError(kerr))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), "RATE_LIMITED")
+	})
+
+	t.Run("gRPC status errors", func(t *testing.T) {
+		err := status.Error(codes.Unauthenticated, "missing token")
+		rec := httptest.NewRecorder()
+		Chain(reject(err)).HTTP()(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, http.StatusUnauthorized, HTTPStatusFromError(err))
+		assert.Equal(t, "missing token\n", rec.Body.String())
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		err := errors.New("boom")
+		rec := httptest.NewRecorder()
+		Chain(reject(err)).HTTP()(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, http.StatusInternalServerError, HTTPStatusFromError(err))
+		assert.NotContains(t, rec.Body.String(), "boom")
+		assert.Equal(t, http.StatusOK, HTTPStatusFromError(nil))
+	})
+}
+
+func TestFastHTTP(t *testing.T) {
+	newRequest := func(method string, uri string) *fasthttp.RequestCtx {
+		reqCtx := &fasthttp.RequestCtx{}
+		reqCtx.Request.Header.SetMethod(method)
+		reqCtx.Request.SetRequestURI(uri)
+		return reqCtx
+	}
+
+	t.Run("middlewares wrap the handler in order", func(t *testing.T) {
+		var (
+			calls    []string
+			observed Call
+		)
+		p := Chain(recorder("a", &calls, &observed), recorder("b", &calls, nil))
+		h := p.FastHTTP()(func(reqCtx *fasthttp.RequestCtx) {
+			calls = append(calls, "handler")
+			// The context is passed through
+			ctx := FastHTTPContext(reqCtx)
+			assert.Equal(t, true, ctx.Value(ctxKey("a")))
+			assert.Equal(t, true, ctx.Value(ctxKey("b")))
+			reqCtx.SetStatusCode(fasthttp.StatusCreated)
+		})
+
+		reqCtx := newRequest(http.MethodPost, "/v1.0/state/store")
+		reqCtx.Request.Header.Set("dapr-app-id", "myapp")
+		h(reqCtx)
+
+		assert.Equal(t, http.StatusCreated, reqCtx.Response.StatusCode())
+		assert.Equal(t, []string{"a>", "b>", "handler", "<b", "<a"}, calls)
+		assert.Equal(t, TransportFastHTTP, observed.Transport)
+		assert.Equal(t, "POST /v1.0/state/store", observed.Operation)
+		assert.Equal(t, "myapp", observed.Header.Get("dapr-app-id"))
+		assert.Equal(t, http.StatusCreated, observed.HTTPStatus)
+	})
+
+	handler := func(*fasthttp.RequestCtx) {
+		t.Fatal("handler must not be invoked")
+	}
+
+	t.Run("kit errors", func(t *testing.T) {
+		kerr := kiterrors.New(errors.New("too many requests"), nil,
+			kiterrors.WithErrorReason("RATE_LIMITED", codes.ResourceExhausted),
+		)
+		reqCtx := newRequest(http.MethodGet, "/")
+		Chain(reject(kerr)).FastHTTP()(handler)(reqCtx)
+
+		assert.Equal(t, http.StatusTooManyRequests, reqCtx.Response.StatusCode())
+		assert.Equal(t, "application/json", string(reqCtx.Response.Header.ContentType()))
+		assert.Contains(t, string(reqCtx.Response.Body()), "RATE_LIMITED")
+	})
+
+	t.Run("gRPC status errors", func(t *testing.T) {
+		reqCtx := newRequest(http.MethodGet, "/")
+		Chain(reject(status.Error(codes.Unauthenticated, "missing token"))).FastHTTP()(handler)(reqCtx)
+
+		assert.Equal(t, http.StatusUnauthorized, reqCtx.Response.StatusCode())
+		assert.Equal(t, "missing token", string(reqCtx.Response.Body()))
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		reqCtx := newRequest(http.MethodGet, "/")
+		Chain(reject(errors.New("boom"))).FastHTTP()(handler)(reqCtx)
+
+		assert.Equal(t, http.StatusInternalServerError, reqCtx.Response.StatusCode())
+		assert.NotContains(t, string(reqCtx.Response.Body()), "boom")
+	})
+
+	t.Run("without middlewares", func(t *testing.T) {
+		reqCtx := newRequest(http.MethodGet, "/")
+		assert.Equal(t, reqCtx, FastHTTPContext(reqCtx))
+	})
+}
+
+func TestTransports(t *testing.T) {
+	var calls []string
+	p := Pipeline{entries: []entry{
+		{name: "all", middleware: recorder("all", &calls, nil)},
+		{name: "grpc", middleware: recorder("grpc", &calls, nil), transports: []Transport{TransportGRPC}},
+		{name: "http", middleware: recorder("http", &calls, nil), transports: []Transport{TransportHTTP}},
+	}}
+	assert.Equal(t, 3, p.Len())
+	assert.Equal(t, []string{"all", "grpc", "http"}, p.Names())
+
+	h := p.HTTP()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"all>", "http>", "<http", "<all"}, calls)
+
+	calls = nil
+	_, err := p.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"},
+		func(ctx context.Context, req any) (any, error) {
+			return nil, nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"all>", "grpc>", "<grpc", "<all"}, calls)
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestGRPC(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("dapr-app-id", "myapp"))
+
+	t.Run("unary", func(t *testing.T) {
+		var (
+			calls    []string
+			observed Call
+		)
+		p := Chain(recorder("a", &calls, &observed), recorder("b", &calls, nil))
+		res, err := p.UnaryServerInterceptor()(ctx, "req", &grpc.UnaryServerInfo{FullMethod: "/svc/Method"},
+			func(ctx context.Context, req any) (any, error) {
+				calls = append(calls, "handler")
+				assert.Equal(t, true, ctx.Value(ctxKey("b")))
+				assert.Equal(t, "req", req)
+				return "res", nil
+			})
+		require.NoError(t, err)
+		assert.Equal(t, "res", res)
+		assert.Equal(t, []string{"a>", "b>", "handler", "<b", "<a"}, calls)
+		assert.Equal(t, TransportGRPC, observed.Transport)
+		assert.Equal(t, "/svc/Method", observed.Operation)
+		assert.Equal(t, "myapp", observed.Header.Get("dapr-app-id"))
+		assert.Empty(t, observed.Header.Get("missing"))
+		assert.Equal(t, codes.OK, observed.GRPCCode)
+
+		// Errors of the handler are observed by the middlewares
+		_, err = p.UnaryServerInterceptor()(ctx, "req", &grpc.UnaryServerInfo{FullMethod: "/svc/Method"},
+			func(ctx context.Context, req any) (any, error) {
+				return "ignored", status.Error(codes.NotFound, "not found")
+			})
+		require.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, codes.NotFound, observed.GRPCCode)
+	})
+
+	t.Run("unary short-circuited", func(t *testing.T) {
+		_, err := Chain(reject(status.Error(codes.ResourceExhausted, "slow down"))).UnaryServerInterceptor()(
+			ctx, "req", &grpc.UnaryServerInfo{FullMethod: "/svc/Method"},
+			func(ctx context.Context, req any) (any, error) {
+				t.Fatal("handler must not be invoked")
+				return nil, nil
+			})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var (
+			calls    []string
+			observed Call
+		)
+		p := Chain(recorder("a", &calls, &observed))
+		err := p.StreamServerInterceptor()(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/svc/Stream"},
+			func(srv any, ss grpc.ServerStream) error {
+				calls = append(calls, "handler")
+				assert.Equal(t, true, ss.Context().Value(ctxKey("a")))
+				return status.Error(codes.Canceled, "canceled")
+			})
+		require.Equal(t, codes.Canceled, status.Code(err))
+		assert.Equal(t, []string{"a>", "handler", "<a"}, calls)
+		assert.Equal(t, "/svc/Stream", observed.Operation)
+		assert.Equal(t, codes.Canceled, observed.GRPCCode)
+	})
+}
+
+func TestErrorsAreEmitted(t *testing.T) {
+	var emitted []string
+	remove := kiterrors.AddObserver(kiterrors.ObserverFunc(func(e *kiterrors.Error) {
+		emitted = append(emitted, e.Reason())
+	}))
+	defer remove()
+
+	kerr := kiterrors.New(errors.New("too many requests"), nil,
+		kiterrors.WithErrorReason("RATE_LIMITED", codes.ResourceExhausted),
+	)
+	p := Chain(reject(kerr))
+
+	p.HTTP()(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	p.FastHTTP()(func(*fasthttp.RequestCtx) {})(&fasthttp.RequestCtx{})
+	_, err := p.UnaryServerInterceptor()(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/svc/Method"},
+		func(ctx context.Context, req any) (any, error) { return nil, nil })
+	require.ErrorIs(t, err, kerr)
+	err = p.StreamServerInterceptor()(nil, &serverStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/svc/Stream"},
+		func(srv any, stream grpc.ServerStream) error { return nil })
+	require.ErrorIs(t, err, kerr)
+
+	// Other errors are not emitted
+	_, _ = Chain(reject(errors.New("boom"))).UnaryServerInterceptor()(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/svc/Method"},
+		func(ctx context.Context, req any) (any, error) { return nil, nil })
+
+	assert.Equal(t, []string{"RATE_LIMITED", "RATE_LIMITED", "RATE_LIMITED", "RATE_LIMITED"}, emitted)
+}
+
+func TestRecovery(t *testing.T) {
+	p := Chain(Recovery(nil))
+
+	t.Run("HTTP", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		p.HTTP()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrorReasonPanic)
+		assert.NotContains(t, rec.Body.String(), "boom")
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			p.HTTP()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic(http.ErrAbortHandler)
+			})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+
+	t.Run("gRPC", func(t *testing.T) {
+		_, err := p.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"},
+			func(ctx context.Context, req any) (any, error) {
+				panic("boom")
+			})
+		assert.Equal(t, codes.Internal, status.Code(err))
+		var kerr *kiterrors.Error
+		require.ErrorAs(t, err, &kerr)
+		assert.Equal(t, ErrorReasonPanic, kerr.Reason())
+	})
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"google.golang.org/grpc/codes"
+
+	kiterrors "github.com/dapr/kit/errors"
+	"github.com/dapr/kit/logger"
+)
+
+// ErrorReasonPanic is the reason of the errors returned by Recovery.
+const ErrorReasonPanic = "INTERNAL_PANIC"
+
+// Recovery returns a middleware that recovers from panics of the next handlers, and returns an Internal kit error
+// instead. The panic and the stack are logged with log, if not nil. Panics with http.ErrAbortHandler are re-raised.
+// It should be the first middleware of pipelines.
+func Recovery(log logger.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				// http.ErrAbortHandler is used to abort HTTP responses, and it must be handled by the server
+				if r == http.ErrAbortHandler { //nolint:errorlint
+					panic(r)
+				}
+				if log != nil {
+					log.Errorf("Panic in handler of %s: %v\n%s", call.Operation, r, debug.Stack())
+				}
+				err = kiterrors.New(fmt.Errorf("panic: %v", r), nil,
+					kiterrors.WithErrorReason(ErrorReasonPanic, codes.Internal),
+					kiterrors.WithDescription("internal error"),
+				)
+			}()
+			return next(ctx, call)
+		}
+	}
+}
+
+// RecoveryFactory is the Factory of the Recovery middleware, which doesn't have any configuration.
+func RecoveryFactory(log logger.Logger) Factory {
+	return func(config map[string]any) (Middleware, error) {
+		if len(config) > 0 {
+			return nil, errors.New("recovery middleware doesn't have any configuration")
+		}
+		return Recovery(log), nil
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrMiddlewareExists is returned when registering a middleware with a name that is already registered.
+	ErrMiddlewareExists = errors.New("middleware already registered")
+	// ErrMiddlewareNotFound is returned when a spec references a middleware that is not registered.
+	ErrMiddlewareNotFound = errors.New("middleware not registered")
+)
+
+// Factory creates a middleware from its configuration, for example decoded with config.Decode.
+type Factory func(config map[string]any) (Middleware, error)
+
+// Spec is the declarative configuration of a pipeline.
+type Spec struct {
+	// Middlewares of the pipeline.
+	Middlewares []MiddlewareSpec `json:"middlewares" mapstructure:"middlewares"`
+}
+
+// MiddlewareSpec is the configuration of a middleware in a pipeline.
+type MiddlewareSpec struct {
+	// Name of the middleware in the Registry.
+	Name string `json:"name" mapstructure:"name"`
+	// Order of the middleware in the pipeline: middlewares with a lower order are invoked first. Middlewares with the
+	// same order are invoked in the order they are declared.
+	Order int `json:"order,omitempty" mapstructure:"order"`
+	// Disabled middlewares are not included in the pipeline.
+	Disabled bool `json:"disabled,omitempty" mapstructure:"disabled"`
+	// Transports the middleware applies to. Default is all transports.
+	Transports []Transport `json:"transports,omitempty" mapstructure:"transports"`
+	// Config is passed to the factory of the middleware.
+	Config map[string]any `json:"config,omitempty" mapstructure:"config"`
+}
+
+// Registry contains the factories of middlewares, by name.
+type Registry struct {
+	lock      sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry returns a new Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]Factory),
+	}
+}
+
+// Register adds the factory of a middleware with the given name.
+func (r *Registry) Register(name string, factory Factory) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.factories[name]; ok {
+		return fmt.Errorf("%w: %s", ErrMiddlewareExists, name)
+	}
+	r.factories[name] = factory
+	return nil
+}
+
+// Names returns the names of the registered middlewares, sorted alphabetically.
+func (r *Registry) Names() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build returns the Pipeline described by spec, creating each enabled middleware with its factory.
+func (r *Registry) Build(spec Spec) (Pipeline, error) {
+	specs := make([]MiddlewareSpec, 0, len(spec.Middlewares))
+	for _, m := range spec.Middlewares {
+		if !m.Disabled {
+			specs = append(specs, m)
+		}
+	}
+	sort.SliceStable(specs, func(i, j int) bool {
+		return specs[i].Order < specs[j].Order
+	})
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	p := Pipeline{entries: make([]entry, len(specs))}
+	for i, m := range specs {
+		factory, ok := r.factories[m.Name]
+		if !ok {
+			return Pipeline{}, fmt.Errorf("%w: %s", ErrMiddlewareNotFound, m.Name)
+		}
+		for _, t := range m.Transports {
+			if t != TransportHTTP && t != TransportGRPC && t != TransportFastHTTP {
+				return Pipeline{}, fmt.Errorf("invalid transport for middleware '%s': %s", m.Name, t)
+			}
+		}
+
+		mw, err := factory(m.Config)
+		if err != nil {
+			return Pipeline{}, fmt.Errorf("failed to create middleware '%s': %w", m.Name, err)
+		}
+		p.entries[i] = entry{
+			name:       m.Name,
+			middleware: mw,
+			transports: m.Transports,
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/config"
+)
+
+func TestRegistry(t *testing.T) {
+	var calls []string
+	r := NewRegistry()
+	for _, name := range []string{"logging", "metrics", "ratelimit"} {
+		name := name
+		require.NoError(t, r.Register(name, func(config map[string]any) (Middleware, error) {
+			if config["fail"] == true {
+				return nil, errors.New("invalid config")
+			}
+			return recorder(name, &calls, nil), nil
+		}))
+	}
+	require.NoError(t, r.Register("recovery", RecoveryFactory(nil)))
+	require.ErrorIs(t, r.Register("logging", nil), ErrMiddlewareExists)
+	assert.Equal(t, []string{"logging", "metrics", "ratelimit", "recovery"}, r.Names())
+
+	t.Run("build with order and disabled middlewares", func(t *testing.T) {
+		calls = nil
+		p, err := r.Build(Spec{Middlewares: []MiddlewareSpec{
+			{Name: "ratelimit", Order: 10},
+			{Name: "metrics", Disabled: true},
+			{Name: "logging"},
+			{Name: "recovery", Order: -1},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"recovery", "logging", "ratelimit"}, p.Names())
+
+		p.HTTP()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+			ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, []string{"logging>", "ratelimit>", "<ratelimit", "<logging"}, calls)
+	})
+
+	t.Run("transports", func(t *testing.T) {
+		calls = nil
+		p, err := r.Build(Spec{Middlewares: []MiddlewareSpec{
+			{Name: "logging", Transports: []Transport{TransportGRPC}},
+			{Name: "metrics"},
+		}})
+		require.NoError(t, err)
+		err = p.Then(TransportHTTP, func(ctx context.Context, call *Call) error {
+			return nil
+		})(context.Background(), &Call{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"metrics>", "<metrics"}, calls)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := r.Build(Spec{Middlewares: []MiddlewareSpec{{Name: "missing"}}})
+		require.ErrorIs(t, err, ErrMiddlewareNotFound)
+		_, err = r.Build(Spec{Middlewares: []MiddlewareSpec{{Name: "logging", Transports: []Transport{"websocket"}}}})
+		require.Error(t, err)
+		_, err = r.Build(Spec{Middlewares: []MiddlewareSpec{{Name: "logging", Transports: []Transport{TransportFastHTTP}}}})
+		require.NoError(t, err)
+		_, err = r.Build(Spec{Middlewares: []MiddlewareSpec{{Name: "logging", Config: map[string]any{"fail": true}}}})
+		require.ErrorContains(t, err, "invalid config")
+		_, err = r.Build(Spec{Middlewares: []MiddlewareSpec{{Name: "recovery", Config: map[string]any{"key": "value"}}}})
+		require.Error(t, err)
+
+		// Disabled middlewares are not validated
+		_, err = r.Build(Spec{Middlewares: []MiddlewareSpec{{Name: "missing", Disabled: true}}})
+		require.NoError(t, err)
+	})
+}
+
+func TestDecodeSpec(t *testing.T) {
+	var spec Spec
+	err := config.Decode(map[string]any{
+		"middlewares": []any{
+			map[string]any{"name": "logging", "order": "2", "transports": []any{"http"}},
+			map[string]any{"name": "ratelimit", "disabled": true, "config": map[string]any{"rps": 100}},
+		},
+	}, &spec)
+	require.NoError(t, err)
+	assert.Equal(t, Spec{Middlewares: []MiddlewareSpec{
+		{Name: "logging", Order: 2, Transports: []Transport{TransportHTTP}},
+		{Name: "ratelimit", Disabled: true, Config: map[string]any{"rps": 100}},
+	}}, spec)
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package netutil contains networking utilities.
+package netutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultAttemptDelay is the delay between connection attempts recommended by RFC 8305.
+const DefaultAttemptDelay = 250 * time.Millisecond
+
+// Resolver resolves host names to IP addresses. It's implemented by *net.Resolver.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// ContextDialer dials a single address. It's implemented by *net.Dialer.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Attempt contains the outcome of a connection attempt.
+type Attempt struct {
+	// Address that was dialed, including the port.
+	Address string
+	// Duration of the attempt.
+	Duration time.Duration
+	// Err is the error returned by the attempt, or nil if it succeeded.
+	Err error
+}
+
+// DialError is returned by HappyEyeballsDialer when all connection attempts fail.
+type DialError struct {
+	// Network and Address passed to DialContext.
+	Network string
+	Address string
+	// Attempts that completed before the dialer returned.
+	Attempts []Attempt
+	// Err is set if the dial failed before any attempt could complete, for example because the name couldn't be
+	// resolved or the context was canceled.
+	Err error
+}
+
+// Error implements error.
+func (e *DialError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to dial %s %s", e.Network, e.Address)
+	if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+	for i, a := range e.Attempts {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s (after %v): %v", a.Address, a.Duration, a.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors of all attempts.
+func (e *DialError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Attempts)+1)
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	for _, a := range e.Attempts {
+		errs = append(errs, a.Err)
+	}
+	return errs
+}
+
+// HappyEyeballsDialer dials hosts that resolve to multiple addresses by racing connection attempts, as described in
+// RFC 8305 ("Happy Eyeballs Version 2").
+// Addresses are sorted alternating IPv6 and IPv4 and attempts are started one after the other, each after
+// AttemptDelay or as soon as the previous attempt fails. The first connection that succeeds is returned.
+// The zero value is ready to use.
+type HappyEyeballsDialer struct {
+	// Resolver used to look up host names. Default is net.DefaultResolver.
+	Resolver Resolver
+	// Dialer used for connection attempts. Default is a *net.Dialer with default options.
+	Dialer ContextDialer
+	// AttemptDelay is the delay before starting the next connection attempt. Default is DefaultAttemptDelay.
+	AttemptDelay time.Duration
+	// AttemptTimeout is the timeout for each connection attempt. If 0, attempts are limited only by the context.
+	AttemptTimeout time.Duration
+	// OnAttempt is invoked when each connection attempt completes, for example to collect metrics.
+	// It can be invoked concurrently, including after DialContext has returned. Optional.
+	OnAttempt func(a Attempt)
+}
+
+type attemptResult struct {
+	attempt Attempt
+	conn    net.Conn
+}
+
+// DialContext connects to the address on the named network, which must be "tcp", "tcp4", or "tcp6".
+func (d *HappyEyeballsDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	addrs, err := d.resolve(ctx, network, address)
+	if err != nil {
+		return nil, &DialError{Network: network, Address: address, Err: err}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	delay := d.AttemptDelay
+	if delay <= 0 {
+		delay = DefaultAttemptDelay
+	}
+
+	results := make(chan attemptResult, len(addrs))
+	var (
+		next     int
+		inFlight int
+		attempts []Attempt
+	)
+	startAttempt := func() {
+		addr := addrs[next]
+		next++
+		inFlight++
+		go d.attempt(ctx, network, addr, results)
+	}
+	closeLate := func() {
+		// Close connections that succeed after we've returned
+		n := inFlight
+		go func() {
+			for i := 0; i < n; i++ {
+				res := <-results
+				if res.conn != nil {
+					res.conn.Close()
+				}
+			}
+		}()
+	}
+
+	startAttempt()
+	for {
+		var (
+			timer   *time.Timer
+			delayCh <-chan time.Time
+		)
+		if next < len(addrs) {
+			timer = time.NewTimer(delay)
+			delayCh = timer.C
+		}
+
+		select {
+		case <-delayCh:
+			startAttempt()
+
+		case res := <-results:
+			inFlight--
+			if res.conn != nil {
+				if timer != nil {
+					timer.Stop()
+				}
+				cancel()
+				closeLate()
+				return res.conn, nil
+			}
+			attempts = append(attempts, res.attempt)
+			if next < len(addrs) {
+				startAttempt()
+			} else if inFlight == 0 {
+				if timer != nil {
+					timer.Stop()
+				}
+				return nil, &DialError{Network: network, Address: address, Attempts: attempts}
+			}
+
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			closeLate()
+			return nil, &DialError{Network: network, Address: address, Attempts: attempts, Err: ctx.Err()}
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (d *HappyEyeballsDialer) attempt(ctx context.Context, network string, addr string, results chan<- attemptResult) {
+	if d.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.AttemptTimeout)
+		defer cancel()
+	}
+
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, network, addr)
+	a := Attempt{
+		Address:  addr,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if d.OnAttempt != nil {
+		d.OnAttempt(a)
+	}
+	results <- attemptResult{attempt: a, conn: conn}
+}
+
+// resolve returns the list of addresses to dial, in the order they should be attempted.
+func (d *HappyEyeballsDialer) resolve(ctx context.Context, network, address string) ([]string, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unsupported network: %s", network)
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IPAddr
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IPAddr{{IP: ip}}
+	} else {
+		resolver := d.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		ips, err = resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var v6, v4 []string
+	for _, ip := range ips {
+		hostPort := net.JoinHostPort(ip.String(), port)
+		if ip.IP.To4() != nil {
+			if network != "tcp6" {
+				v4 = append(v4, hostPort)
+			}
+		} else if network != "tcp4" {
+			v6 = append(v6, hostPort)
+		}
+	}
+
+	// Interleave address families, starting with IPv6
+	res := make([]string, 0, len(v6)+len(v4))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			res = append(res, v6[i])
+		}
+		if i < len(v4) {
+			res = append(res, v4[i])
+		}
+	}
+	if len(res) == 0 {
+		return nil, errors.New("no addresses found for network " + network)
+	}
+	return res, nil
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	res := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		res[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return res, nil
+}
+
+type fakeBehavior struct {
+	delay time.Duration
+	err   error
+	hang  bool
+}
+
+type fakeDialer struct {
+	behaviors map[string]fakeBehavior
+	lock      sync.Mutex
+	dialed    []string
+}
+
+func (d *fakeDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.lock.Lock()
+	d.dialed = append(d.dialed, address)
+	b := d.behaviors[address]
+	d.lock.Unlock()
+
+	if b.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	select {
+	case <-time.After(b.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	c, _ := net.Pipe()
+	return &fakeConn{Conn: c, addr: address}, nil
+}
+
+func (d *fakeDialer) getDialed() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]string(nil), d.dialed...)
+}
+
+type fakeConn struct {
+	net.Conn
+	addr string
+}
+
+func TestHappyEyeballsDialer(t *testing.T) {
+	resolver := fakeResolver{
+		"dual": {"10.0.0.1", "10.0.0.2", "fd00::1", "fd00::2"},
+	}
+
+	t.Run("addresses are interleaved", func(t *testing.T) {
+		errBoom := errors.New("boom")
+		dialer := &fakeDialer{behaviors: map[string]fakeBehavior{
+			"[fd00::1]:80": {err: errBoom},
+			"10.0.0.1:80":  {err: errBoom},
+			"[fd00::2]:80": {err: errBoom},
+			"10.0.0.2:80":  {err: errBoom},
+		}}
+		var attempts []Attempt
+		var lock sync.Mutex
+		d := &HappyEyeballsDialer{
+			Resolver:     resolver,
+			Dialer:       dialer,
+			AttemptDelay: time.Second,
+			OnAttempt: func(a Attempt) {
+				lock.Lock()
+				attempts = append(attempts, a)
+				lock.Unlock()
+			},
+		}
+
+		_, err := d.DialContext(context.Background(), "tcp", "dual:80")
+		require.Error(t, err)
+		require.ErrorIs(t, err, errBoom)
+
+		var dialErr *DialError
+		require.ErrorAs(t, err, &dialErr)
+		require.Len(t, dialErr.Attempts, 4)
+		assert.Equal(t, []string{"[fd00::1]:80", "10.0.0.1:80", "[fd00::2]:80", "10.0.0.2:80"}, dialer.getDialed())
+		assert.Contains(t, err.Error(), "failed to dial tcp dual:80: [fd00::1]:80")
+		assert.Len(t, attempts, 4)
+	})
+
+	t.Run("race to the first success", func(t *testing.T) {
+		dialer := &fakeDialer{behaviors: map[string]fakeBehavior{
+			"[fd00::1]:80": {hang: true},
+			"10.0.0.1:80":  {},
+		}}
+		d := &HappyEyeballsDialer{
+			Resolver:     resolver,
+			Dialer:       dialer,
+			AttemptDelay: 20 * time.Millisecond,
+		}
+
+		conn, err := d.DialContext(context.Background(), "tcp", "dual:80")
+		require.NoError(t, err)
+		defer conn.Close()
+		assert.Equal(t, "10.0.0.1:80", conn.(*fakeConn).addr)
+		assert.Equal(t, []string{"[fd00::1]:80", "10.0.0.1:80"}, dialer.getDialed())
+	})
+
+	t.Run("per-attempt timeouts", func(t *testing.T) {
+		dialer := &fakeDialer{behaviors: map[string]fakeBehavior{
+			"[fd00::1]:80": {hang: true},
+			"10.0.0.1:80":  {hang: true},
+			"[fd00::2]:80": {hang: true},
+			"10.0.0.2:80":  {hang: true},
+		}}
+		d := &HappyEyeballsDialer{
+			Resolver:       resolver,
+			Dialer:         dialer,
+			AttemptDelay:   time.Second,
+			AttemptTimeout: 10 * time.Millisecond,
+		}
+
+		start := time.Now()
+		_, err := d.DialContext(context.Background(), "tcp", "dual:80")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("network filtering", func(t *testing.T) {
+		dialer := &fakeDialer{behaviors: map[string]fakeBehavior{}}
+		d := &HappyEyeballsDialer{
+			Resolver: resolver,
+			Dialer:   dialer,
+		}
+
+		conn, err := d.DialContext(context.Background(), "tcp4", "dual:80")
+		require.NoError(t, err)
+		conn.Close()
+		assert.Equal(t, "10.0.0.1:80", conn.(*fakeConn).addr)
+
+		conn, err = d.DialContext(context.Background(), "tcp", "[fd00::9]:80")
+		require.NoError(t, err)
+		conn.Close()
+		assert.Equal(t, "[fd00::9]:80", conn.(*fakeConn).addr)
+
+		_, err = d.DialContext(context.Background(), "tcp6", "10.0.0.1:80")
+		require.Error(t, err)
+		_, err = d.DialContext(context.Background(), "udp", "dual:80")
+		require.Error(t, err)
+		_, err = d.DialContext(context.Background(), "tcp", "missing:80")
+		require.Error(t, err)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		dialer := &fakeDialer{behaviors: map[string]fakeBehavior{
+			"[fd00::1]:80": {hang: true},
+		}}
+		d := &HappyEyeballsDialer{
+			Resolver:     resolver,
+			Dialer:       dialer,
+			AttemptDelay: time.Second,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := d.DialContext(ctx, "tcp", "dual:80")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("real connection", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+		go func() {
+			c, err := ln.Accept()
+			if err == nil {
+				c.Close()
+			}
+		}()
+
+		d := &HappyEyeballsDialer{}
+		conn, err := d.DialContext(context.Background(), "tcp", ln.Addr().String())
+		require.NoError(t, err)
+		conn.Close()
+	})
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package refcount manages the lifetime of shared resources, such as connections and caches, that are used by
+// multiple components: the resource is closed when the last component that holds a reference releases it.
+package refcount
+
+import (
+	"errors"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by Acquire when the resource was closed already.
+var ErrClosed = errors.New("resource is closed")
+
+// RefCounted is a resource whose lifetime is managed by counting references.
+// References are obtained with Acquire; when the last reference is released, the resource is closed, and it can't be
+// acquired anymore.
+type RefCounted[T any] struct {
+	value   T
+	closeFn func(T) error
+
+	lock    sync.Mutex
+	count   int
+	closed  bool
+	nextID  uint64
+	debug   bool
+	holders map[uint64]Holder
+}
+
+// Ref is a reference to a RefCounted resource.
+type Ref[T any] struct {
+	parent   *RefCounted[T]
+	id       uint64
+	released atomic.Bool
+}
+
+// Holder is an outstanding reference, tracked in debug mode.
+type Holder struct {
+	// ID of the reference, in acquisition order.
+	ID uint64
+	// Stack of the goroutine that acquired the reference.
+	Stack string
+}
+
+// New returns a new RefCounted for value. closeFn is invoked, once, when the last reference is released. It can be nil.
+func New[T any](value T, closeFn func(T) error) *RefCounted[T] {
+	return &RefCounted[T]{
+		value:   value,
+		closeFn: closeFn,
+	}
+}
+
+// WithDebug enables tracking the stack trace of the holders of outstanding references, which are returned by Holders.
+// This is useful to find leaked references, but it has a significant overhead.
+func (r *RefCounted[T]) WithDebug() *RefCounted[T] {
+	r.lock.Lock()
+	r.debug = true
+	r.holders = map[uint64]Holder{}
+	r.lock.Unlock()
+	return r
+}
+
+// Acquire returns a new reference to the resource.
+// It returns ErrClosed if the resource was closed because all references were released.
+func (r *RefCounted[T]) Acquire() (*Ref[T], error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed {
+		return nil, ErrClosed
+	}
+	r.count++
+	r.nextID++
+	ref := &Ref[T]{parent: r, id: r.nextID}
+	if r.debug {
+		r.holders[ref.id] = Holder{ID: ref.id, Stack: string(debug.Stack())}
+	}
+	return ref, nil
+}
+
+// Count returns the number of outstanding references.
+func (r *RefCounted[T]) Count() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.count
+}
+
+// Closed returns true if the resource was closed.
+func (r *RefCounted[T]) Closed() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.closed
+}
+
+// Holders returns the outstanding references, in acquisition order. It returns nil unless debug mode is enabled.
+func (r *RefCounted[T]) Holders() []Holder {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.debug {
+		return nil
+	}
+	res := make([]Holder, 0, len(r.holders))
+	for _, h := range r.holders {
+		res = append(res, h)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// Value returns the resource.
+// It must not be used after the reference is released.
+func (ref *Ref[T]) Value() T {
+	return ref.parent.value
+}
+
+// Release releases the reference. If it was the last one, the resource is closed, and the result of closeFn is
+// returned.
+// It's safe to invoke Release more than once: only the first invocation releases the reference.
+func (ref *Ref[T]) Release() error {
+	if !ref.released.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	r := ref.parent
+	r.lock.Lock()
+	r.count--
+	if r.debug {
+		delete(r.holders, ref.id)
+	}
+	if r.count > 0 {
+		r.lock.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.lock.Unlock()
+
+	if r.closeFn != nil {
+		return r.closeFn(r.value)
+	}
+	return nil
+}
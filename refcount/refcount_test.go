@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package refcount
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type resource struct {
+	closed int
+}
+
+func TestRefCounted(t *testing.T) {
+	res := &resource{}
+	errClose := errors.New("close failed")
+	rc := New(res, func(r *resource) error {
+		r.closed++
+		return errClose
+	})
+
+	a, err := rc.Acquire()
+	require.NoError(t, err)
+	b, err := rc.Acquire()
+	require.NoError(t, err)
+	assert.Equal(t, 2, rc.Count())
+	assert.Same(t, res, a.Value())
+
+	require.NoError(t, a.Release())
+	// Releasing twice is a nop
+	require.NoError(t, a.Release())
+	assert.Equal(t, 1, rc.Count())
+	assert.False(t, rc.Closed())
+	assert.Zero(t, res.closed)
+
+	// The last release closes the resource
+	require.ErrorIs(t, b.Release(), errClose)
+	assert.True(t, rc.Closed())
+	assert.Equal(t, 1, res.closed)
+
+	_, err = rc.Acquire()
+	require.ErrorIs(t, err, ErrClosed)
+	require.NoError(t, b.Release())
+	assert.Equal(t, 1, res.closed)
+}
+
+func TestRefCountedConcurrent(t *testing.T) {
+	var closed int
+	rc := New(struct{}{}, func(struct{}) error {
+		closed++
+		return nil
+	})
+	first, err := rc.Acquire()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ref, err := rc.Acquire()
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NoError(t, ref.Release())
+		}()
+	}
+	wg.Wait()
+
+	assert.Zero(t, closed)
+	require.NoError(t, first.Release())
+	assert.Equal(t, 1, closed)
+}
+
+func TestRefCountedDebug(t *testing.T) {
+	rc := New(1, nil)
+	_, err := rc.Acquire()
+	require.NoError(t, err)
+	assert.Nil(t, rc.Holders())
+
+	rc = New(1, nil).WithDebug()
+	a, err := rc.Acquire()
+	require.NoError(t, err)
+	b, err := rc.Acquire()
+	require.NoError(t, err)
+
+	holders := rc.Holders()
+	require.Len(t, holders, 2)
+	assert.Less(t, holders[0].ID, holders[1].ID)
+	assert.Contains(t, holders[0].Stack, "TestRefCountedDebug")
+
+	require.NoError(t, a.Release())
+	holders = rc.Holders()
+	require.Len(t, holders, 1)
+	require.NoError(t, b.Release())
+	assert.Empty(t, rc.Holders())
+}
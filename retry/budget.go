@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoDeadline is returned by WithBudgetSplit when the parent context doesn't have a deadline.
+var ErrNoDeadline = errors.New("context has no deadline")
+
+// BudgetStage is a named stage that receives a share of a timeout budget.
+type BudgetStage struct {
+	// Name of the stage.
+	Name string
+	// Share of the total budget assigned to the stage, between 0 and 1.
+	Share float64
+}
+
+// Stage returns a BudgetStage with the given name and share of the budget, between 0 and 1.
+func Stage(name string, share float64) BudgetStage {
+	return BudgetStage{Name: name, Share: share}
+}
+
+// Budget splits the time remaining until a parent context's deadline into budgets for sequential stages, such as
+// dialing, sending a request, and draining the response.
+// Stages are assigned consecutive windows of time in the order they were declared, so time that is not used by a
+// stage carries over to the following ones.
+type Budget struct {
+	parent    context.Context
+	deadlines map[string]time.Time
+	budgets   map[string]time.Duration
+}
+
+// WithBudgetSplit returns a Budget that splits the time remaining until the deadline of ctx across stages.
+// For example:
+//
+//	budget, err := retry.WithBudgetSplit(ctx, retry.Stage("dial", 0.2), retry.Stage("request", 0.7), retry.Stage("drain", 0.1))
+//	dialCtx, cancel := budget.Context("dial")
+//
+// The sum of the shares must not be greater than 1.
+func WithBudgetSplit(ctx context.Context, stages ...BudgetStage) (*Budget, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil, ErrNoDeadline
+	}
+	return newBudget(ctx, time.Now(), deadline, stages)
+}
+
+// WithBudgetSplitTimeout is like WithBudgetSplit, but uses a total timeout for contexts that don't have a deadline.
+// If ctx has a deadline that is sooner than the timeout, the deadline is used.
+func WithBudgetSplitTimeout(ctx context.Context, timeout time.Duration, stages ...BudgetStage) (*Budget, error) {
+	now := time.Now()
+	deadline := now.Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	return newBudget(ctx, now, deadline, stages)
+}
+
+func newBudget(ctx context.Context, now time.Time, deadline time.Time, stages []BudgetStage) (*Budget, error) {
+	total := deadline.Sub(now)
+	if total < 0 {
+		total = 0
+	}
+
+	b := &Budget{
+		parent:    ctx,
+		deadlines: make(map[string]time.Time, len(stages)),
+		budgets:   make(map[string]time.Duration, len(stages)),
+	}
+	var cumulative float64
+	for _, s := range stages {
+		if s.Share < 0 || s.Share > 1 {
+			return nil, fmt.Errorf("invalid share for stage '%s': %v", s.Name, s.Share)
+		}
+		if _, ok := b.deadlines[s.Name]; ok {
+			return nil, fmt.Errorf("duplicate stage '%s'", s.Name)
+		}
+		cumulative += s.Share
+		// Allow for some rounding errors
+		if cumulative > 1.000001 {
+			return nil, errors.New("the sum of the shares of all stages is greater than 1")
+		}
+
+		b.budgets[s.Name] = time.Duration(s.Share * float64(total))
+		b.deadlines[s.Name] = now.Add(time.Duration(cumulative * float64(total)))
+	}
+
+	return b, nil
+}
+
+// Deadline returns the deadline of the stage with the given name.
+// The returned boolean value is false if the stage doesn't exist.
+func (b *Budget) Deadline(stage string) (time.Time, bool) {
+	d, ok := b.deadlines[stage]
+	return d, ok
+}
+
+// Timeout returns the budget assigned to the stage with the given name, not including time carried over from
+// previous stages. It returns 0 if the stage doesn't exist.
+func (b *Budget) Timeout(stage string) time.Duration {
+	return b.budgets[stage]
+}
+
+// Context returns a context derived from the parent one, whose deadline is the end of the stage with the given name.
+// If the stage doesn't exist, the returned context has the same deadline as the parent.
+func (b *Budget) Context(stage string) (context.Context, context.CancelFunc) {
+	d, ok := b.deadlines[stage]
+	if !ok {
+		return context.WithCancel(b.parent)
+	}
+	return context.WithDeadline(b.parent, d)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/retry"
+)
+
+func TestBudgetSplit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	parentDeadline, _ := ctx.Deadline()
+
+	budget, err := retry.WithBudgetSplit(ctx, retry.Stage("dial", 0.2), retry.Stage("request", 0.7), retry.Stage("drain", 0.1))
+	require.NoError(t, err)
+
+	assert.InDelta(t, 2*time.Second, budget.Timeout("dial"), float64(50*time.Millisecond))
+	assert.InDelta(t, 7*time.Second, budget.Timeout("request"), float64(50*time.Millisecond))
+	assert.InDelta(t, time.Second, budget.Timeout("drain"), float64(50*time.Millisecond))
+	assert.Zero(t, budget.Timeout("missing"))
+
+	// Deadlines are cumulative
+	dial, ok := budget.Deadline("dial")
+	require.True(t, ok)
+	request, _ := budget.Deadline("request")
+	drain, _ := budget.Deadline("drain")
+	assert.InDelta(t, 2*time.Second, time.Until(dial), float64(50*time.Millisecond))
+	assert.InDelta(t, 9*time.Second, time.Until(request), float64(50*time.Millisecond))
+	assert.WithinDuration(t, parentDeadline, drain, 50*time.Millisecond)
+	_, ok = budget.Deadline("missing")
+	assert.False(t, ok)
+
+	stageCtx, stageCancel := budget.Context("dial")
+	defer stageCancel()
+	d, _ := stageCtx.Deadline()
+	assert.Equal(t, dial, d)
+
+	missingCtx, missingCancel := budget.Context("missing")
+	defer missingCancel()
+	d, _ = missingCtx.Deadline()
+	assert.Equal(t, parentDeadline, d)
+
+	// Canceling the parent cancels the stages
+	cancel()
+	<-stageCtx.Done()
+}
+
+func TestBudgetSplitErrors(t *testing.T) {
+	_, err := retry.WithBudgetSplit(context.Background(), retry.Stage("dial", 1))
+	require.ErrorIs(t, err, retry.ErrNoDeadline)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = retry.WithBudgetSplit(ctx, retry.Stage("a", 0.6), retry.Stage("b", 0.6))
+	require.Error(t, err)
+	_, err = retry.WithBudgetSplit(ctx, retry.Stage("a", 0.1), retry.Stage("a", 0.1))
+	require.Error(t, err)
+	_, err = retry.WithBudgetSplit(ctx, retry.Stage("a", -0.1))
+	require.Error(t, err)
+}
+
+func TestBudgetSplitTimeout(t *testing.T) {
+	budget, err := retry.WithBudgetSplitTimeout(context.Background(), 4*time.Second, retry.Stage("a", 0.5), retry.Stage("b", 0.5))
+	require.NoError(t, err)
+	assert.InDelta(t, 2*time.Second, budget.Timeout("a"), float64(50*time.Millisecond))
+
+	// A sooner deadline on the context has precedence
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	budget, err = retry.WithBudgetSplitTimeout(ctx, time.Hour, retry.Stage("a", 0.5), retry.Stage("b", 0.5))
+	require.NoError(t, err)
+	assert.InDelta(t, 500*time.Millisecond, budget.Timeout("a"), float64(50*time.Millisecond))
+}
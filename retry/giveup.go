@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"google.golang.org/grpc/codes"
+
+	kiterrors "github.com/dapr/kit/errors"
+)
+
+// ErrorReasonRetriesExhausted is the reason of the errors returned by GiveUpError.
+const ErrorReasonRetriesExhausted = "DAPR_RETRIES_EXHAUSTED"
+
+// Keys of the metadata included in the errors returned by GiveUpError.
+const (
+	GiveUpMetadataAttempts   = "attempts"
+	GiveUpMetadataLastCode   = "lastCode"
+	GiveUpMetadataLastReason = "lastReason"
+)
+
+// GiveUpError wraps err, the last failure of an operation that was retried until the policy gave up, into a kit
+// Error that callers can act upon.
+// The error has code ResourceExhausted if the last failure was ResourceExhausted too (for example, because the
+// remote side is rate-limiting requests), and Unavailable otherwise. It includes the number of attempts and the code
+// of the last failure in its metadata and, if retryAfter is positive, a RetryInfo detail suggesting when to retry.
+//
+// The returned error wraps err, so errors.Is and errors.As work on the last failure.
+func GiveUpError(err error, attempts int, retryAfter time.Duration) *kiterrors.Error {
+	if err == nil {
+		return nil
+	}
+
+	lastCode, _ := kiterrors.CodeOf(err)
+	code := codes.Unavailable
+	if lastCode == codes.ResourceExhausted {
+		code = codes.ResourceExhausted
+	}
+
+	md := map[string]string{
+		GiveUpMetadataAttempts: strconv.Itoa(attempts),
+		GiveUpMetadataLastCode: lastCode.String(),
+	}
+	var kitErr *kiterrors.Error
+	if errors.As(err, &kitErr) && kitErr.Reason() != "" {
+		md[GiveUpMetadataLastReason] = kitErr.Reason()
+	}
+
+	opts := []kiterrors.Option{
+		kiterrors.WithErrorReason(ErrorReasonRetriesExhausted, code),
+		kiterrors.WithDescription(fmt.Sprintf("giving up after %d attempts: %v", attempts, err)),
+		kiterrors.WithMetadata(md),
+	}
+	if retryAfter > 0 {
+		opts = append(opts, kiterrors.WithRetryInfo(retryAfter))
+	}
+
+	return kiterrors.New(err, nil, opts...)
+}
+
+// RetryAfter returns the delay that callers should wait before trying an operation again, after the policy gave up
+// on it. This is the longest delay between two attempts: Duration for the constant policy, and MaxInterval (or
+// InitialInterval, if MaxInterval is not set) for the exponential one.
+func (c *Config) RetryAfter() time.Duration {
+	switch c.Policy {
+	case PolicyExponential:
+		if c.MaxInterval > 0 {
+			return c.MaxInterval
+		}
+		return c.InitialInterval
+	default:
+		return c.Duration
+	}
+}
+
+// NotifyRecoverWithGiveUp is like NotifyRecover, but when the back off gives up, the last failure is returned wrapped
+// with GiveUpError, suggesting callers to retry after retryAfter (see Config.RetryAfter).
+// Permanent errors, including those marked with `PossiblyCommitted`, and context cancellations are returned as-is.
+func NotifyRecoverWithGiveUp(operation backoff.Operation, b backoff.BackOff, notify backoff.Notify, recovered func(), retryAfter time.Duration) error {
+	_, err := NotifyRecoverWithDataAndGiveUp(func() (struct{}, error) {
+		return struct{}{}, operation()
+	}, b, notify, recovered, retryAfter)
+	return err
+}
+
+// NotifyRecoverWithDataAndGiveUp is a variant of NotifyRecoverWithGiveUp that also returns data in addition to an error.
+func NotifyRecoverWithDataAndGiveUp[T any](operation backoff.OperationWithData[T], b backoff.BackOff, notify backoff.Notify, recovered func(), retryAfter time.Duration) (T, error) {
+	var (
+		attempts  int
+		permanent bool
+	)
+
+	// Operations are invoked sequentially, so there's no need to synchronize access to the counters
+	res, err := NotifyRecoverWithData(func() (T, error) {
+		attempts++
+		res, err := operation()
+		permanent = isPermanent(err)
+		return res, err
+	}, b, notify, recovered)
+
+	if err == nil || permanent || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return res, err
+	}
+
+	return res, GiveUpError(err, attempts, retryAfter)
+}
+
+// isPermanent returns true if err stops the retries, either because it's a permanent error or because it's marked
+// with `PossiblyCommitted` and the operation is not idempotent.
+func isPermanent(err error) bool {
+	if err == nil {
+		return false
+	}
+	var permanent *backoff.PermanentError
+	return errors.As(guardCommitted(err), &permanent)
+}
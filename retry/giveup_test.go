@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+
+	kiterrors "github.com/dapr/kit/errors"
+	"github.com/dapr/kit/retry"
+)
+
+func TestGiveUpError(t *testing.T) {
+	t.Run("nil error", func(t *testing.T) {
+		assert.Nil(t, retry.GiveUpError(nil, 3, time.Second))
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		err := retry.GiveUpError(errRetry, 3, 5*time.Second)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, errRetry)
+		assert.Equal(t, retry.ErrorReasonRetriesExhausted, err.Reason())
+		assert.Equal(t, http.StatusServiceUnavailable, err.HTTPCode())
+
+		st := err.GRPCStatus()
+		assert.Equal(t, codes.Unavailable, st.Code())
+		assert.Contains(t, st.Message(), "giving up after 3 attempts")
+
+		var (
+			info      *errdetails.ErrorInfo
+			retryInfo *errdetails.RetryInfo
+		)
+		for _, d := range st.Details() {
+			switch v := d.(type) {
+			case *errdetails.ErrorInfo:
+				info = v
+			case *errdetails.RetryInfo:
+				retryInfo = v
+			}
+		}
+		require.NotNil(t, info)
+		assert.Equal(t, "3", info.GetMetadata()[retry.GiveUpMetadataAttempts])
+		assert.Equal(t, codes.Unknown.String(), info.GetMetadata()[retry.GiveUpMetadataLastCode])
+		require.NotNil(t, retryInfo)
+		assert.Equal(t, 5*time.Second, retryInfo.GetRetryDelay().AsDuration())
+	})
+
+	t.Run("resource exhausted", func(t *testing.T) {
+		last := kiterrors.New(errRetry, nil, kiterrors.WithErrorReason("RATE_LIMITED", codes.ResourceExhausted))
+		err := retry.GiveUpError(last, 2, 0)
+		assert.Equal(t, http.StatusTooManyRequests, err.HTTPCode())
+
+		st := err.GRPCStatus()
+		assert.Equal(t, codes.ResourceExhausted, st.Code())
+		for _, d := range st.Details() {
+			_, isRetryInfo := d.(*errdetails.RetryInfo)
+			assert.False(t, isRetryInfo, "no RetryInfo expected when retryAfter is 0")
+			if info, ok := d.(*errdetails.ErrorInfo); ok {
+				assert.Equal(t, "RATE_LIMITED", info.GetMetadata()[retry.GiveUpMetadataLastReason])
+			}
+		}
+	})
+}
+
+func TestConfigRetryAfter(t *testing.T) {
+	config := retry.DefaultConfig()
+	assert.Equal(t, config.Duration, config.RetryAfter())
+
+	config.Policy = retry.PolicyExponential
+	assert.Equal(t, config.MaxInterval, config.RetryAfter())
+
+	config.MaxInterval = 0
+	assert.Equal(t, config.InitialInterval, config.RetryAfter())
+}
+
+func TestNotifyRecoverWithGiveUp(t *testing.T) {
+	config := retry.DefaultConfig()
+	config.MaxRetries = 2
+	config.Duration = 1
+
+	t.Run("gives up", func(t *testing.T) {
+		var calls int
+		err := retry.NotifyRecoverWithGiveUp(func() error {
+			calls++
+			return errRetry
+		}, config.NewBackOff(), func(error, time.Duration) {}, func() {}, time.Second)
+
+		assert.Equal(t, 3, calls)
+		assert.ErrorIs(t, err, errRetry)
+		var kitErr *kiterrors.Error
+		require.ErrorAs(t, err, &kitErr)
+		assert.Equal(t, retry.ErrorReasonRetriesExhausted, kitErr.Reason())
+		assert.Equal(t, codes.Unavailable, kitErr.GRPCStatus().Code())
+	})
+
+	t.Run("succeeds", func(t *testing.T) {
+		var calls, recovered int
+		err := retry.NotifyRecoverWithGiveUp(func() error {
+			calls++
+			if calls == 1 {
+				return errRetry
+			}
+			return nil
+		}, config.NewBackOff(), func(error, time.Duration) {}, func() { recovered++ }, time.Second)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, 1, recovered)
+	})
+
+	t.Run("permanent errors are not wrapped", func(t *testing.T) {
+		err := retry.NotifyRecoverWithGiveUp(func() error {
+			return backoff.Permanent(errRetry)
+		}, config.NewBackOff(), func(error, time.Duration) {}, func() {}, time.Second)
+		assert.Equal(t, errRetry, err)
+
+		err = retry.NotifyRecoverWithGiveUp(func() error {
+			return retry.PossiblyCommitted(errRetry)
+		}, config.NewBackOff(), func(error, time.Duration) {}, func() {}, time.Second)
+		assert.True(t, retry.IsPossiblyCommitted(err))
+		var kitErr *kiterrors.Error
+		assert.False(t, errors.As(err, &kitErr))
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := retry.NotifyRecoverWithGiveUp(func() error {
+			return errRetry
+		}, config.NewBackOffWithContext(ctx), func(error, time.Duration) {}, func() {}, time.Second)
+		assert.ErrorIs(t, err, context.Canceled)
+		var kitErr *kiterrors.Error
+		assert.False(t, errors.As(err, &kitErr))
+	})
+}
+
+func TestNotifyRecoverWithDataAndGiveUp(t *testing.T) {
+	config := retry.DefaultConfig()
+	config.MaxRetries = 1
+	config.Duration = 1
+
+	res, err := retry.NotifyRecoverWithDataAndGiveUp(func() (int, error) {
+		return 42, errRetry
+	}, config.NewBackOff(), func(error, time.Duration) {}, func() {}, config.RetryAfter())
+
+	assert.Equal(t, 42, res)
+	var kitErr *kiterrors.Error
+	require.ErrorAs(t, err, &kitErr)
+	assert.Equal(t, retry.ErrorReasonRetriesExhausted, kitErr.Reason())
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"errors"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// possiblyCommittedError wraps an error returned by an operation that may have
+// produced side effects before failing, for example a timeout received after
+// the request was sent.
+type possiblyCommittedError struct {
+	err error
+}
+
+func (e *possiblyCommittedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *possiblyCommittedError) Unwrap() error {
+	return e.err
+}
+
+// idempotentError wraps an error returned by an operation that was declared
+// idempotent with `Idempotent`.
+type idempotentError struct {
+	err error
+}
+
+func (e *idempotentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *idempotentError) Unwrap() error {
+	return e.err
+}
+
+// PossiblyCommitted marks err as a failure that happened after the operation
+// may have already been applied on the remote side.
+// Unless the operation is declared with `Idempotent`, the retry functions in
+// this package stop retrying as soon as they receive such an error, to avoid
+// duplicate side effects.
+func PossiblyCommitted(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &possiblyCommittedError{err: err}
+}
+
+// IsPossiblyCommitted returns true if err, or any error in its chain, was
+// marked with `PossiblyCommitted`.
+func IsPossiblyCommitted(err error) bool {
+	var pce *possiblyCommittedError
+	return errors.As(err, &pce)
+}
+
+// Idempotent declares that operation can safely be executed more than once,
+// so it is retried even after a failure marked with `PossiblyCommitted`.
+func Idempotent(operation backoff.Operation) backoff.Operation {
+	return func() error {
+		return markIdempotent(operation())
+	}
+}
+
+// IdempotentWithData is a variant of Idempotent for operations that also
+// return data in addition to an error.
+func IdempotentWithData[T any](operation backoff.OperationWithData[T]) backoff.OperationWithData[T] {
+	return func() (T, error) {
+		res, err := operation()
+		return res, markIdempotent(err)
+	}
+}
+
+func markIdempotent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &idempotentError{err: err}
+}
+
+// guardCommitted converts errors that may have been committed into permanent
+// errors, unless the operation was declared idempotent.
+func guardCommitted(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if ie, ok := err.(*idempotentError); ok { //nolint:errorlint
+		return ie.err
+	}
+
+	if IsPossiblyCommitted(err) {
+		return backoff.Permanent(err)
+	}
+
+	return err
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/kit/retry"
+)
+
+func TestPossiblyCommitted(t *testing.T) {
+	assert.NoError(t, retry.PossiblyCommitted(nil))
+
+	err := retry.PossiblyCommitted(errRetry)
+	assert.True(t, retry.IsPossiblyCommitted(err))
+	assert.True(t, retry.IsPossiblyCommitted(fmt.Errorf("wrapped: %w", err)))
+	assert.True(t, errors.Is(err, errRetry))
+	assert.Equal(t, errRetry.Error(), err.Error())
+	assert.False(t, retry.IsPossiblyCommitted(errRetry))
+}
+
+func TestNotifyRecoverNonIdempotent(t *testing.T) {
+	config := retry.DefaultConfig()
+	config.MaxRetries = 3
+	config.Duration = 1
+
+	var operationCalls, notifyCalls int
+
+	err := retry.NotifyRecover(func() error {
+		operationCalls++
+
+		return retry.PossiblyCommitted(errRetry)
+	}, config.NewBackOff(), func(err error, d time.Duration) {
+		notifyCalls++
+	}, func() {})
+
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, errRetry))
+	assert.True(t, retry.IsPossiblyCommitted(err))
+	assert.Equal(t, 1, operationCalls)
+	assert.Equal(t, 0, notifyCalls)
+}
+
+func TestNotifyRecoverIdempotent(t *testing.T) {
+	config := retry.DefaultConfig()
+	config.MaxRetries = 3
+	config.Duration = 1
+
+	var operationCalls, notifyCalls, recoveryCalls int
+
+	err := retry.NotifyRecover(retry.Idempotent(func() error {
+		operationCalls++
+
+		if operationCalls >= 3 {
+			return nil
+		}
+
+		return retry.PossiblyCommitted(errRetry)
+	}), config.NewBackOff(), func(err error, d time.Duration) {
+		notifyCalls++
+	}, func() {
+		recoveryCalls++
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, operationCalls)
+	assert.Equal(t, 1, notifyCalls)
+	assert.Equal(t, 1, recoveryCalls)
+}
+
+func TestNotifyRecoverIdempotentMaxRetries(t *testing.T) {
+	config := retry.DefaultConfig()
+	config.MaxRetries = 2
+	config.Duration = 1
+
+	var operationCalls int
+
+	err := retry.NotifyRecover(retry.Idempotent(func() error {
+		operationCalls++
+
+		return retry.PossiblyCommitted(errRetry)
+	}), config.NewBackOff(), func(err error, d time.Duration) {}, func() {})
+
+	assert.Error(t, err)
+	assert.True(t, retry.IsPossiblyCommitted(err))
+	assert.Equal(t, 3, operationCalls)
+}
+
+func TestNotifyRecoverWithDataIdempotency(t *testing.T) {
+	config := retry.DefaultConfig()
+	config.MaxRetries = 3
+	config.Duration = 1
+
+	t.Run("not idempotent", func(t *testing.T) {
+		var operationCalls int
+		res, err := retry.NotifyRecoverWithData(func() (int, error) {
+			operationCalls++
+			return 0, retry.PossiblyCommitted(errRetry)
+		}, config.NewBackOff(), func(err error, d time.Duration) {}, func() {})
+
+		assert.Error(t, err)
+		assert.Equal(t, 0, res)
+		assert.Equal(t, 1, operationCalls)
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		var operationCalls int
+		res, err := retry.NotifyRecoverWithData(retry.IdempotentWithData(func() (int, error) {
+			operationCalls++
+			if operationCalls >= 2 {
+				return 42, nil
+			}
+			return 0, retry.PossiblyCommitted(errRetry)
+		}), config.NewBackOff(), func(err error, d time.Duration) {}, func() {})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 42, res)
+		assert.Equal(t, 2, operationCalls)
+	})
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// Kicker allows an external event, such as a notification that a backend is healthy again, to cut short the backoff
+// sleeps of retry loops, so the next attempt is made right away.
+// Retry loops use the timers returned by Timer, for example with NotifyRecoverWithTimer; a single Kicker can be shared
+// by any number of retry loops.
+type Kicker struct {
+	lock   sync.Mutex
+	timers map[*kickTimer]struct{}
+}
+
+// NewKicker returns a new Kicker.
+func NewKicker() *Kicker {
+	return &Kicker{
+		timers: make(map[*kickTimer]struct{}),
+	}
+}
+
+// Kick interrupts the backoff sleep of all retry loops that are currently waiting, which retry immediately.
+// Retry loops that are running an attempt are not affected: if the attempt fails, they sleep as usual.
+func (k *Kicker) Kick() {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	for t := range k.timers {
+		t.fire(0, true)
+	}
+}
+
+// Timer returns a new timer for a retry loop, which implements backoff.Timer, and is interrupted by Kick.
+// A timer must be used by a single retry loop; it's released when the retry loop stops it.
+func (k *Kicker) Timer() backoff.Timer {
+	t := &kickTimer{
+		kicker: k,
+		c:      make(chan time.Time, 1),
+	}
+	k.lock.Lock()
+	k.timers[t] = struct{}{}
+	k.lock.Unlock()
+	return t
+}
+
+// kickTimer implements backoff.Timer.
+type kickTimer struct {
+	kicker *Kicker
+
+	lock     sync.Mutex
+	c        chan time.Time
+	timer    *time.Timer
+	gen      uint64
+	sleeping bool
+}
+
+// Start implements backoff.Timer.
+func (t *kickTimer) Start(duration time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	// Drain the channel in case it was not consumed
+	select {
+	case <-t.c:
+	default:
+	}
+
+	t.gen++
+	gen := t.gen
+	t.sleeping = true
+	t.timer = time.AfterFunc(duration, func() {
+		t.fire(gen, false)
+	})
+}
+
+// Stop implements backoff.Timer.
+func (t *kickTimer) Stop() {
+	t.lock.Lock()
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.sleeping = false
+	t.lock.Unlock()
+
+	t.kicker.lock.Lock()
+	delete(t.kicker.timers, t)
+	t.kicker.lock.Unlock()
+}
+
+// C implements backoff.Timer.
+func (t *kickTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// fire wakes up the retry loop if it's sleeping. Unless kicked is true, it's a nop if the timer was restarted after
+// generation gen.
+func (t *kickTimer) fire(gen uint64, kicked bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.sleeping || (!kicked && gen != t.gen) {
+		return
+	}
+	t.sleeping = false
+	if kicked && t.timer != nil {
+		t.timer.Stop()
+	}
+	select {
+	case t.c <- time.Now():
+	default:
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/retry"
+)
+
+func TestKicker(t *testing.T) {
+	t.Run("kick interrupts the sleep", func(t *testing.T) {
+		config := retry.DefaultConfig()
+		config.Duration = time.Hour
+		kicker := retry.NewKicker()
+
+		var attempts, notified, recovered atomic.Int32
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- retry.NotifyRecoverWithTimer(func() error {
+				if attempts.Add(1) < 3 {
+					return errRetry
+				}
+				return nil
+			}, config.NewBackOff(), func(error, time.Duration) {
+				notified.Add(1)
+			}, func() {
+				recovered.Add(1)
+			}, kicker.Timer())
+		}()
+
+		// Kicks while an attempt is running are ignored, so keep kicking until the loop completes
+		var err error
+		assert.Eventually(t, func() bool {
+			kicker.Kick()
+			select {
+			case err = <-errCh:
+				return true
+			default:
+				return false
+			}
+		}, 5*time.Second, 10*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), attempts.Load())
+		assert.Equal(t, int32(1), notified.Load())
+		assert.Equal(t, int32(1), recovered.Load())
+	})
+
+	t.Run("timer fires without kicks", func(t *testing.T) {
+		config := retry.DefaultConfig()
+		config.Duration = 5 * time.Millisecond
+		config.MaxRetries = 2
+		kicker := retry.NewKicker()
+
+		var attempts atomic.Int32
+		res, err := retry.NotifyRecoverWithDataAndTimer(func() (int, error) {
+			attempts.Add(1)
+			return 0, errRetry
+		}, config.NewBackOff(), func(error, time.Duration) {}, func() {}, kicker.Timer())
+		require.ErrorIs(t, err, errRetry)
+		assert.Equal(t, 0, res)
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("kick with no sleeping loops", func(t *testing.T) {
+		kicker := retry.NewKicker()
+		kicker.Kick()
+
+		timer := kicker.Timer()
+		kicker.Kick()
+		timer.Start(time.Hour)
+		select {
+		case <-timer.C():
+			t.Fatal("timer fired because of a kick received before it was started")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		kicker.Kick()
+		select {
+		case <-timer.C():
+		case <-time.After(time.Second):
+			t.Fatal("timer was not kicked")
+		}
+		timer.Stop()
+	})
+
+}
@@ -115,6 +115,12 @@ func DecodeConfigWithPrefix(c *Config, input interface{}, prefix string) error {
 // `NewBackOff` or `NewBackOffWithContext` should be called each time
 // `RetryNotifyRecover` or `backoff.RetryNotify` is used.
 func (c *Config) NewBackOff() backoff.BackOff {
+	return c.NewBackOffWithClock(backoff.SystemClock)
+}
+
+// NewBackOffWithClock is like `NewBackOff`, but the elapsed time for the
+// exponential policy is measured with the given clock. Used for testing.
+func (c *Config) NewBackOffWithClock(clock backoff.Clock) backoff.BackOff {
 	var b backoff.BackOff
 	switch c.Policy {
 	case PolicyConstant:
@@ -126,6 +132,8 @@ func (c *Config) NewBackOff() backoff.BackOff {
 		eb.Multiplier = float64(c.Multiplier)
 		eb.MaxInterval = c.MaxInterval
 		eb.MaxElapsedTime = c.MaxElapsedTime
+		eb.Clock = clock
+		eb.Reset()
 		b = eb
 	}
 
@@ -153,11 +161,19 @@ func (c *Config) NewBackOffWithContext(ctx context.Context) backoff.BackOff {
 // previously failed but has since recovered. The main purpose of this wrapper is to call `notify` only when
 // the operations fails the first time and `recovered` when it finally succeeds. This can be helpful in limiting
 // log messages to only the events that operators need to be alerted on.
+//
+// Errors marked with `PossiblyCommitted` are not retried, unless the operation was declared with `Idempotent`.
 func NotifyRecover(operation backoff.Operation, b backoff.BackOff, notify backoff.Notify, recovered func()) error {
+	return NotifyRecoverWithTimer(operation, b, notify, recovered, nil)
+}
+
+// NotifyRecoverWithTimer is like NotifyRecover, but it uses the given timer to wait between attempts, for example
+// one returned by Kicker.Timer. If timer is nil, the default timer is used.
+func NotifyRecoverWithTimer(operation backoff.Operation, b backoff.BackOff, notify backoff.Notify, recovered func(), timer backoff.Timer) error {
 	notified := atomic.Bool{}
 
-	return backoff.RetryNotify(func() error {
-		err := operation()
+	return backoff.RetryNotifyWithTimer(func() error {
+		err := guardCommitted(operation())
 
 		if err == nil && notified.CompareAndSwap(true, false) {
 			recovered()
@@ -168,15 +184,23 @@ func NotifyRecover(operation backoff.Operation, b backoff.BackOff, notify backof
 		if notified.CompareAndSwap(false, true) {
 			notify(err, d)
 		}
-	})
+	}, timer)
 }
 
 // NotifyRecoverWithData is a variant of NotifyRecover that also returns data in addition to an error.
+// Non-idempotent operations are handled like in NotifyRecover; use `IdempotentWithData` to declare idempotency.
 func NotifyRecoverWithData[T any](operation backoff.OperationWithData[T], b backoff.BackOff, notify backoff.Notify, recovered func()) (T, error) {
+	return NotifyRecoverWithDataAndTimer(operation, b, notify, recovered, nil)
+}
+
+// NotifyRecoverWithDataAndTimer is like NotifyRecoverWithData, but it uses the given timer to wait between attempts,
+// for example one returned by Kicker.Timer. If timer is nil, the default timer is used.
+func NotifyRecoverWithDataAndTimer[T any](operation backoff.OperationWithData[T], b backoff.BackOff, notify backoff.Notify, recovered func(), timer backoff.Timer) (T, error) {
 	notified := atomic.Bool{}
 
-	return backoff.RetryNotifyWithData(func() (T, error) {
+	return backoff.RetryNotifyWithTimerAndData(func() (T, error) {
 		res, err := operation()
+		err = guardCommitted(err)
 
 		if err == nil && notified.CompareAndSwap(true, false) {
 			recovered()
@@ -187,7 +211,7 @@ func NotifyRecoverWithData[T any](operation backoff.OperationWithData[T], b back
 		if notified.CompareAndSwap(false, true) {
 			notify(err, d)
 		}
-	})
+	}, timer)
 }
 
 // DecodeString handles converting a string value to `p`.
@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package retrytest contains helpers for testing retry policies.
+// Simulate returns the sequence of delays a policy produces for a scripted failure pattern, without sleeping, so
+// changes to resiliency policies can be validated in unit tests and documentation examples.
+package retrytest
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/dapr/kit/retry"
+)
+
+// DefaultMaxAttempts is the maximum number of attempts that Simulate performs, unless changed with WithMaxAttempts.
+// This prevents endless simulations for policies that retry forever.
+const DefaultMaxAttempts = 1000
+
+// FailurePattern returns true if the attempt with the given number (starting from 1) fails.
+type FailurePattern func(attempt int) bool
+
+// FailTimes returns a FailurePattern in which the first n attempts fail, and the following ones succeed.
+func FailTimes(n int) FailurePattern {
+	return func(attempt int) bool {
+		return attempt <= n
+	}
+}
+
+// AlwaysFail returns a FailurePattern in which all attempts fail.
+func AlwaysFail() FailurePattern {
+	return func(int) bool {
+		return true
+	}
+}
+
+// Pattern returns a FailurePattern from a string in which each character is an attempt: "F" for a failure, and any
+// other character for a success. For example, "FFS" means that the first two attempts fail and the third succeeds.
+// Attempts beyond the end of the string repeat the last character.
+func Pattern(s string) FailurePattern {
+	s = strings.ToUpper(s)
+	return func(attempt int) bool {
+		if len(s) == 0 {
+			return false
+		}
+		if attempt > len(s) {
+			attempt = len(s)
+		}
+		return s[attempt-1] == 'F'
+	}
+}
+
+// Result of a simulation.
+type Result struct {
+	// Attempts is the number of times the operation was invoked.
+	Attempts int
+	// Delays contains the delay before each retry.
+	Delays []time.Duration
+	// TotalTime is the total time spent, including delays and the duration of each attempt.
+	TotalTime time.Duration
+	// Succeeded is true if the last attempt succeeded.
+	Succeeded bool
+	// GaveUp is true if the policy stopped retrying after a failure.
+	GaveUp bool
+	// Truncated is true if the simulation was stopped after reaching the maximum number of attempts.
+	Truncated bool
+}
+
+// Option for Simulate.
+type Option func(o *options)
+
+type options struct {
+	attemptDuration time.Duration
+	maxAttempts     int
+}
+
+// WithAttemptDuration sets how long each attempt takes in the simulation. This counts towards the policy's maximum
+// elapsed time. Default is 0.
+func WithAttemptDuration(d time.Duration) Option {
+	return func(o *options) {
+		o.attemptDuration = d
+	}
+}
+
+// WithMaxAttempts sets the maximum number of attempts to simulate. Default is DefaultMaxAttempts.
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// Simulate runs the policy against the failure pattern and returns the delays it produces, without sleeping.
+// For exponential policies, set RandomizationFactor to 0 to obtain deterministic results.
+func Simulate(policy retry.Config, pattern FailurePattern, opts ...Option) Result {
+	o := options{
+		maxAttempts: DefaultMaxAttempts,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	clock := &simulatedClock{now: time.Unix(0, 0)}
+	b := policy.NewBackOffWithClock(clock)
+	start := clock.now
+
+	var res Result
+	for {
+		res.Attempts++
+		clock.now = clock.now.Add(o.attemptDuration)
+		if !pattern(res.Attempts) {
+			res.Succeeded = true
+			break
+		}
+		if res.Attempts >= o.maxAttempts {
+			res.Truncated = true
+			break
+		}
+
+		d := b.NextBackOff()
+		if d == backoff.Stop {
+			res.GaveUp = true
+			break
+		}
+		res.Delays = append(res.Delays, d)
+		clock.now = clock.now.Add(d)
+	}
+
+	res.TotalTime = clock.now.Sub(start)
+	return res
+}
+
+// simulatedClock implements backoff.Clock with a time that is moved forward by the simulation.
+type simulatedClock struct {
+	now time.Time
+}
+
+func (c *simulatedClock) Now() time.Time {
+	return c.now
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retrytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/kit/retry"
+)
+
+func TestSimulate(t *testing.T) {
+	t.Run("constant policy", func(t *testing.T) {
+		policy := retry.DefaultConfig()
+		policy.Duration = 2 * time.Second
+		policy.MaxRetries = 3
+
+		res := Simulate(policy, FailTimes(2))
+		assert.Equal(t, Result{
+			Attempts:  3,
+			Delays:    []time.Duration{2 * time.Second, 2 * time.Second},
+			TotalTime: 4 * time.Second,
+			Succeeded: true,
+		}, res)
+
+		res = Simulate(policy, AlwaysFail())
+		assert.Equal(t, 4, res.Attempts)
+		assert.Len(t, res.Delays, 3)
+		assert.True(t, res.GaveUp)
+		assert.False(t, res.Succeeded)
+	})
+
+	t.Run("exponential policy", func(t *testing.T) {
+		policy := retry.DefaultConfig()
+		policy.Policy = retry.PolicyExponential
+		policy.InitialInterval = time.Second
+		policy.Multiplier = 2
+		policy.RandomizationFactor = 0
+		policy.MaxInterval = 5 * time.Second
+		policy.MaxElapsedTime = 0
+
+		res := Simulate(policy, Pattern("FFFFS"), WithAttemptDuration(100*time.Millisecond))
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, res.Delays)
+		assert.Equal(t, 5, res.Attempts)
+		assert.Equal(t, 12*time.Second+500*time.Millisecond, res.TotalTime)
+		assert.True(t, res.Succeeded)
+	})
+
+	t.Run("max elapsed time", func(t *testing.T) {
+		policy := retry.DefaultConfig()
+		policy.Policy = retry.PolicyExponential
+		policy.InitialInterval = time.Second
+		policy.Multiplier = 2
+		policy.RandomizationFactor = 0
+		policy.MaxInterval = time.Minute
+		policy.MaxElapsedTime = 10 * time.Second
+
+		res := Simulate(policy, AlwaysFail())
+		// 1+2+4 = 7s elapsed, next delay would exceed the max elapsed time
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, res.Delays)
+		assert.True(t, res.GaveUp)
+	})
+
+	t.Run("endless retries are truncated", func(t *testing.T) {
+		policy := retry.DefaultConfig()
+		res := Simulate(policy, AlwaysFail(), WithMaxAttempts(10))
+		assert.Equal(t, 10, res.Attempts)
+		assert.Len(t, res.Delays, 9)
+		assert.True(t, res.Truncated)
+	})
+}
+
+func TestPattern(t *testing.T) {
+	p := Pattern("fsF")
+	assert.True(t, p(1))
+	assert.False(t, p(2))
+	assert.True(t, p(3))
+	assert.True(t, p(10))
+	assert.False(t, Pattern("")(1))
+}
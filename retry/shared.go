@@ -0,0 +1,235 @@
+/*
+Copyright 2021 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	kclock "k8s.io/utils/clock"
+)
+
+// SharedBackOff keeps a backoff state for each target, such as a host or a component, that is shared by all the
+// retry loops calling that target.
+// When a call fails, the backoff of the target escalates once, and the other callers that fail while the target is
+// backing off wait until the end of the same window instead of escalating again or retrying on their own schedule.
+// When any call succeeds, the target recovers for all callers. If the backoff of the target stops, for example because
+// MaxElapsedTime is reached, retry loops stop retrying the target until it recovers or the stop cool-down has passed.
+// Targets that haven't failed for the idle TTL are forgotten, so the state doesn't grow with every target ever called.
+//
+//	shared := retry.NewSharedBackOff(config)
+//	err := retry.NotifyRecover(shared.Operation("statestore", op), shared.BackOff("statestore"), notify, recovered)
+type SharedBackOff struct {
+	config       Config
+	clock        kclock.Clock
+	idleTTL      time.Duration
+	stopCooldown time.Duration
+
+	lock      sync.Mutex
+	targets   map[string]*sharedTarget
+	lastSweep time.Time
+}
+
+// sharedTarget is the backoff state of a failing target.
+type sharedTarget struct {
+	backoff   backoff.BackOff
+	retryAt   time.Time
+	lastSeen  time.Time
+	stopped   bool
+	stoppedAt time.Time
+}
+
+const (
+	// DefaultSharedIdleTTL is the default time after which a target that hasn't failed is forgotten.
+	DefaultSharedIdleTTL = 10 * time.Minute
+	// DefaultSharedStopCooldown is the default time after which a stopped target is retried again.
+	DefaultSharedStopCooldown = time.Minute
+)
+
+// NewSharedBackOff returns a SharedBackOff that uses the policy in config for every target.
+// MaxRetries is applied to each retry loop individually.
+func NewSharedBackOff(config Config) *SharedBackOff {
+	return &SharedBackOff{
+		config:       config,
+		clock:        kclock.RealClock{},
+		idleTTL:      DefaultSharedIdleTTL,
+		stopCooldown: DefaultSharedStopCooldown,
+		targets:      make(map[string]*sharedTarget),
+	}
+}
+
+// WithClock sets the clock used by the shared backoff. Used for testing.
+func (s *SharedBackOff) WithClock(clock kclock.Clock) *SharedBackOff {
+	s.clock = clock
+	return s
+}
+
+// WithIdleTTL sets how long a target must go without failures before its state is dropped.
+// A value of 0 or less keeps targets until they recover.
+func (s *SharedBackOff) WithIdleTTL(ttl time.Duration) *SharedBackOff {
+	s.idleTTL = ttl
+	return s
+}
+
+// WithStopCooldown sets how long retry loops stop retrying a target whose backoff stopped, before its backoff
+// starts over. A value of 0 or less keeps the target stopped until it recovers.
+func (s *SharedBackOff) WithStopCooldown(cooldown time.Duration) *SharedBackOff {
+	s.stopCooldown = cooldown
+	return s
+}
+
+// BackOff returns a backoff.BackOff for a retry loop calling the target.
+// A new instance must be used by each retry loop; resetting it doesn't affect the state of the target.
+func (s *SharedBackOff) BackOff(target string) backoff.BackOff {
+	return &sharedBackOff{
+		shared: s,
+		target: target,
+	}
+}
+
+// Operation wraps operation so that a successful call marks the target as recovered.
+func (s *SharedBackOff) Operation(target string, operation backoff.Operation) backoff.Operation {
+	return func() error {
+		err := operation()
+		if err == nil {
+			s.Recovered(target)
+		}
+		return err
+	}
+}
+
+// Recovered resets the backoff state of the target, for example after a successful call.
+func (s *SharedBackOff) Recovered(target string) {
+	s.lock.Lock()
+	delete(s.targets, target)
+	s.lock.Unlock()
+}
+
+// Delay returns how long callers of the target should wait before the next attempt. It's 0 if the target is not
+// backing off.
+func (s *SharedBackOff) Delay(target string) time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Now()
+	s.sweep(now)
+	t, ok := s.targets[target]
+	if !ok {
+		return 0
+	}
+	d := t.retryAt.Sub(now)
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Wait blocks until the target is not backing off, or ctx is canceled.
+// New calls can invoke it before their first attempt, so they don't hit a target that other callers are backing off
+// from.
+func (s *SharedBackOff) Wait(ctx context.Context, target string) error {
+	d := s.Delay(target)
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := s.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// next returns the delay before the next attempt of a caller whose call to the target just failed.
+func (s *SharedBackOff) next(target string) time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Now()
+	s.sweep(now)
+	t, ok := s.targets[target]
+	if !ok {
+		cfg := s.config
+		cfg.MaxRetries = -1
+		t = &sharedTarget{
+			backoff: cfg.NewBackOffWithClock(s.clock),
+		}
+		s.targets[target] = t
+	}
+	t.lastSeen = now
+	if t.stopped {
+		if s.stopCooldown <= 0 || now.Sub(t.stoppedAt) < s.stopCooldown {
+			return backoff.Stop
+		}
+		// The cool-down has passed: start backing off from the beginning
+		t.backoff.Reset()
+		t.stopped = false
+		t.retryAt = time.Time{}
+	}
+
+	// The target is already backing off because of another caller: wait for the same window
+	if now.Before(t.retryAt) {
+		return t.retryAt.Sub(now)
+	}
+
+	d := t.backoff.NextBackOff()
+	if d == backoff.Stop {
+		t.stopped = true
+		t.stoppedAt = now
+		return backoff.Stop
+	}
+	t.retryAt = now.Add(d)
+	return d
+}
+
+// sweep drops the targets that haven't failed for the idle TTL.
+// To keep calls cheap, the targets are scanned at most every half TTL. Must be called with the lock held.
+func (s *SharedBackOff) sweep(now time.Time) {
+	if s.idleTTL <= 0 || now.Sub(s.lastSweep) < s.idleTTL/2 {
+		return
+	}
+	s.lastSweep = now
+	for key, t := range s.targets {
+		if now.Sub(t.lastSeen) >= s.idleTTL && !now.Before(t.retryAt) {
+			delete(s.targets, key)
+		}
+	}
+}
+
+// sharedBackOff implements backoff.BackOff for a single retry loop.
+type sharedBackOff struct {
+	shared  *SharedBackOff
+	target  string
+	retries int64
+}
+
+// NextBackOff implements backoff.BackOff.
+func (b *sharedBackOff) NextBackOff() time.Duration {
+	if b.shared.config.MaxRetries >= 0 && b.retries >= b.shared.config.MaxRetries {
+		return backoff.Stop
+	}
+	b.retries++
+	return b.shared.next(b.target)
+}
+
+// Reset implements backoff.BackOff.
+// It resets the retries of the retry loop only, and not the state of the target.
+func (b *sharedBackOff) Reset() {
+	b.retries = 0
+}
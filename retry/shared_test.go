@@ -0,0 +1,173 @@
+/*
+Copyright 2021 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/kit/retry"
+)
+
+func newSharedConfig() retry.Config {
+	config := retry.DefaultConfig()
+	config.Policy = retry.PolicyExponential
+	config.InitialInterval = time.Second
+	config.RandomizationFactor = 0
+	config.Multiplier = 2
+	config.MaxInterval = time.Minute
+	config.MaxElapsedTime = 0
+	return config
+}
+
+func TestSharedBackOff(t *testing.T) {
+	t.Run("concurrent callers escalate together", func(t *testing.T) {
+		clock := clocktesting.NewFakeClock(time.Now())
+		shared := retry.NewSharedBackOff(newSharedConfig()).WithClock(clock)
+		a := shared.BackOff("host")
+		b := shared.BackOff("host")
+
+		assert.Equal(t, time.Second, a.NextBackOff())
+		// The second caller waits for the same window, without escalating
+		clock.Step(400 * time.Millisecond)
+		assert.Equal(t, 600*time.Millisecond, b.NextBackOff())
+		assert.Equal(t, 600*time.Millisecond, shared.Delay("host"))
+
+		// Both retry at the end of the window and fail again
+		clock.Step(600 * time.Millisecond)
+		assert.Equal(t, 2*time.Second, b.NextBackOff())
+		assert.Equal(t, 2*time.Second, a.NextBackOff())
+
+		// Other targets are independent
+		assert.Equal(t, time.Second, shared.BackOff("other").NextBackOff())
+		assert.Zero(t, shared.Delay("missing"))
+	})
+
+	t.Run("callers recover together", func(t *testing.T) {
+		clock := clocktesting.NewFakeClock(time.Now())
+		shared := retry.NewSharedBackOff(newSharedConfig()).WithClock(clock)
+		a := shared.BackOff("host")
+		b := shared.BackOff("host")
+
+		assert.Equal(t, time.Second, a.NextBackOff())
+		clock.Step(time.Second)
+		assert.Equal(t, 2*time.Second, a.NextBackOff())
+
+		// Resetting a retry loop doesn't reset the target
+		b.Reset()
+		assert.Equal(t, 2*time.Second, b.NextBackOff())
+
+		require.NoError(t, shared.Operation("host", func() error { return nil })())
+		assert.Zero(t, shared.Delay("host"))
+		assert.Equal(t, time.Second, b.NextBackOff())
+
+		// Failed operations don't recover the target
+		require.Error(t, shared.Operation("host", func() error { return errRetry })())
+		assert.Equal(t, time.Second, shared.Delay("host"))
+	})
+
+	t.Run("max retries apply to each retry loop", func(t *testing.T) {
+		config := newSharedConfig()
+		config.MaxRetries = 1
+		shared := retry.NewSharedBackOff(config).WithClock(clocktesting.NewFakeClock(time.Now()))
+		a := shared.BackOff("host")
+		b := shared.BackOff("host")
+
+		assert.Equal(t, time.Second, a.NextBackOff())
+		assert.Equal(t, backoff.Stop, a.NextBackOff())
+		assert.Equal(t, time.Second, b.NextBackOff())
+		assert.Equal(t, backoff.Stop, b.NextBackOff())
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		clock := clocktesting.NewFakeClock(time.Now())
+		shared := retry.NewSharedBackOff(newSharedConfig()).WithClock(clock)
+		require.NoError(t, shared.Wait(context.Background(), "host"))
+
+		shared.BackOff("host").NextBackOff()
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- shared.Wait(context.Background(), "host")
+		}()
+		assert.Eventually(t, clock.HasWaiters, time.Second, time.Millisecond)
+		clock.Step(time.Second)
+		require.NoError(t, <-errCh)
+
+		shared.BackOff("host").NextBackOff()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, shared.Wait(ctx, "host"), context.Canceled)
+	})
+
+	t.Run("idle targets are forgotten", func(t *testing.T) {
+		clock := clocktesting.NewFakeClock(time.Now())
+		shared := retry.NewSharedBackOff(newSharedConfig()).WithClock(clock).WithIdleTTL(time.Minute)
+		a := shared.BackOff("host")
+
+		assert.Equal(t, time.Second, a.NextBackOff())
+		clock.Step(time.Second)
+		assert.Equal(t, 2*time.Second, a.NextBackOff())
+
+		// Failures within the TTL keep escalating
+		clock.Step(30 * time.Second)
+		assert.Equal(t, 4*time.Second, a.NextBackOff())
+
+		// After the TTL without failures, the target starts over
+		clock.Step(time.Minute)
+		assert.Zero(t, shared.Delay("other"))
+		assert.Equal(t, time.Second, a.NextBackOff())
+	})
+
+	t.Run("stopped targets are retried after the cool-down", func(t *testing.T) {
+		config := newSharedConfig()
+		config.MaxElapsedTime = 2 * time.Second
+		clock := clocktesting.NewFakeClock(time.Now())
+		shared := retry.NewSharedBackOff(config).WithClock(clock).WithStopCooldown(time.Minute)
+		a := shared.BackOff("host")
+
+		assert.Equal(t, time.Second, a.NextBackOff())
+		clock.Step(3 * time.Second)
+		assert.Equal(t, backoff.Stop, a.NextBackOff())
+
+		clock.Step(30 * time.Second)
+		assert.Equal(t, backoff.Stop, shared.BackOff("host").NextBackOff())
+
+		clock.Step(30 * time.Second)
+		assert.Equal(t, time.Second, shared.BackOff("host").NextBackOff())
+	})
+
+	t.Run("with NotifyRecover", func(t *testing.T) {
+		config := newSharedConfig()
+		config.InitialInterval = time.Millisecond
+		shared := retry.NewSharedBackOff(config)
+
+		attempts := 0
+		err := retry.NotifyRecover(shared.Operation("host", func() error {
+			attempts++
+			if attempts < 3 {
+				return errRetry
+			}
+			return nil
+		}), shared.BackOff("host"), func(error, time.Duration) {}, func() {})
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Zero(t, shared.Delay("host"))
+	})
+}
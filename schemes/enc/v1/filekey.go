@@ -18,7 +18,6 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/hmac"
-	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
@@ -29,6 +28,8 @@ import (
 
 	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/hkdf"
+
+	kitcrypto "github.com/dapr/kit/crypto"
 )
 
 // fileKey holds the fileKey and uses that (and the haeaderKey and payloadKey it derives from it)
@@ -49,7 +50,7 @@ type fileKey struct {
 func newFileKey(cipher Cipher) (fileKey, error) {
 	// Read 39 random bytes for the file key (256 bits) and nonce prefix (56 bits)
 	rnd := make([]byte, 39)
-	_, err := io.ReadFull(rand.Reader, rnd)
+	_, err := io.ReadFull(kitcrypto.Rand(), rnd)
 	if err != nil {
 		return fileKey{}, fmt.Errorf("failed to generate file key: %w", err)
 	}
@@ -0,0 +1,238 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signals implements the graceful shutdown of a process, when it receives a termination signal or, optionally, when one of its goroutines panics.
+package signals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// DefaultShutdownTimeout is the default maximum time the shutdown hooks can take.
+	DefaultShutdownTimeout = 10 * time.Second
+	// DefaultLogShutdownTimeout is the default maximum time to flush the log sinks, after the shutdown hooks.
+	DefaultLogShutdownTimeout = 5 * time.Second
+)
+
+// ErrShutdownTimeout is returned by Shutdown when the hooks don't complete in time.
+var ErrShutdownTimeout = errors.New("shutdown hooks did not complete in time")
+
+// ShutdownHook is invoked during the graceful shutdown, for example to flush logs or close listeners.
+// The context is canceled when the shutdown timeout is reached.
+type ShutdownHook func(ctx context.Context) error
+
+// ManagerOptions contains the options for a Manager.
+type ManagerOptions struct {
+	// Signals that trigger the graceful shutdown. Default is SIGINT and SIGTERM.
+	Signals []os.Signal
+	// ShutdownTimeout is the maximum time the shutdown hooks can take. Default is DefaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+	// ShutdownOnPanic, when true, makes unrecovered panics in goroutines started with Go run the graceful shutdown
+	// before the panic is re-raised, so buffered telemetry is flushed and listeners are closed before the process
+	// crashes.
+	ShutdownOnPanic bool
+	// Log is used to log signals and panics. Optional.
+	Log logger.Logger
+	// LogShutdownTimeout is the maximum time to flush the log sinks with logger.Shutdown, after the shutdown hooks
+	// have completed. Default is DefaultLogShutdownTimeout.
+	LogShutdownTimeout time.Duration
+	// SkipLogShutdown disables flushing the log sinks at the end of the shutdown, for example when the process
+	// flushes them itself.
+	SkipLogShutdown bool
+}
+
+// Manager coordinates the graceful shutdown of a process.
+// When a signal is received, or Shutdown is invoked, the context returned by Context is canceled and the registered
+// hooks are invoked in the reverse order they were added.
+type Manager struct {
+	opts   ManagerOptions
+	ctx    context.Context
+	cancel context.CancelFunc
+	sigCh  chan os.Signal
+	wg     sync.WaitGroup
+
+	lock     sync.Mutex
+	hooks    []ShutdownHook
+	once     sync.Once
+	doneCh   chan struct{}
+	shutdown error
+
+	// Used to re-raise panics. Replaced in tests.
+	repanic func(v any)
+}
+
+// NewManager returns a new Manager whose context is derived from parent, and starts listening for signals.
+func NewManager(parent context.Context, opts ManagerOptions) *Manager {
+	if len(opts.Signals) == 0 {
+		opts.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if opts.LogShutdownTimeout <= 0 {
+		opts.LogShutdownTimeout = DefaultLogShutdownTimeout
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	m := &Manager{
+		opts:    opts,
+		ctx:     ctx,
+		cancel:  cancel,
+		sigCh:   make(chan os.Signal, 1),
+		doneCh:  make(chan struct{}),
+		repanic: func(v any) { panic(v) },
+	}
+
+	signal.Notify(m.sigCh, opts.Signals...)
+	go func() {
+		select {
+		case sig := <-m.sigCh:
+			if m.opts.Log != nil {
+				m.opts.Log.Infof("Received signal '%s'; beginning shutdown", sig)
+			}
+			_ = m.Shutdown()
+		case <-m.doneCh:
+		}
+	}()
+
+	return m
+}
+
+// Context returns a context that is canceled when the shutdown begins.
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// OnShutdown registers hooks that are invoked during the shutdown.
+// Hooks are invoked sequentially, in the reverse order they were added.
+func (m *Manager) OnShutdown(hooks ...ShutdownHook) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.hooks = append(m.hooks, hooks...)
+}
+
+// OnShutdownClose registers closers, such as listeners, that are closed during the shutdown.
+func (m *Manager) OnShutdownClose(closers ...io.Closer) {
+	for _, c := range closers {
+		c := c
+		m.OnShutdown(func(context.Context) error {
+			return c.Close()
+		})
+	}
+}
+
+// Go runs fn in a background goroutine, passing the manager's context, which is canceled when the shutdown begins.
+// If ShutdownOnPanic is enabled and fn panics, the graceful shutdown is completed before the panic is re-raised.
+func (m *Manager) Go(fn func(ctx context.Context)) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if m.opts.ShutdownOnPanic {
+			defer m.recoverAndShutdown()
+		}
+		fn(m.ctx)
+	}()
+}
+
+// Wait blocks until all goroutines started with Go have returned.
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// Shutdown cancels the manager's context and invokes the shutdown hooks, then flushes the log sinks, so the last
+// records logged before the process exits are not lost.
+// It's safe to invoke Shutdown more than once: hooks are invoked only the first time, and every call returns the
+// same result.
+func (m *Manager) Shutdown() error {
+	m.once.Do(func() {
+		signal.Stop(m.sigCh)
+		close(m.doneCh)
+		m.cancel()
+
+		m.lock.Lock()
+		hooks := m.hooks
+		m.hooks = nil
+		m.lock.Unlock()
+
+		err := m.runHooks(hooks)
+		if !m.opts.SkipLogShutdown {
+			err = errors.Join(err, m.shutdownLogs())
+		}
+		m.shutdown = err
+	})
+	return m.shutdown
+}
+
+func (m *Manager) shutdownLogs() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.LogShutdownTimeout)
+	defer cancel()
+
+	dropped, err := logger.Shutdown(ctx)
+	// After logger.Shutdown, records are written synchronously
+	if dropped > 0 && m.opts.Log != nil {
+		m.opts.Log.Warnf("%d log records were dropped", dropped)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to flush logs: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) runHooks(hooks []ShutdownHook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.ShutdownTimeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errs := make([]error, 0, len(hooks))
+		for i := len(hooks) - 1; i >= 0; i-- {
+			errs = append(errs, hooks[i](ctx))
+		}
+		errCh <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ErrShutdownTimeout
+	}
+}
+
+func (m *Manager) recoverAndShutdown() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	if m.opts.Log != nil {
+		m.opts.Log.Errorf("Panic in goroutine; shutting down before re-panicking: %v\n%s", r, debug.Stack())
+	}
+	if err := m.Shutdown(); err != nil && m.opts.Log != nil {
+		m.opts.Log.Errorf("Error during shutdown: %v", err)
+	}
+
+	m.repanic(r)
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signals
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+func TestManagerShutdown(t *testing.T) {
+	m := NewManager(context.Background(), ManagerOptions{})
+
+	var order []int
+	errHook := errors.New("hook failed")
+	m.OnShutdown(
+		func(context.Context) error {
+			order = append(order, 1)
+			return nil
+		},
+		func(context.Context) error {
+			order = append(order, 2)
+			return errHook
+		},
+	)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	m.OnShutdownClose(ln)
+
+	err = m.Shutdown()
+	assert.ErrorIs(t, err, errHook)
+	assert.Equal(t, []int{2, 1}, order)
+	assert.Error(t, m.Context().Err())
+
+	// The listener was closed
+	_, err = ln.Accept()
+	assert.ErrorIs(t, err, net.ErrClosed)
+
+	// Hooks are invoked only once
+	assert.ErrorIs(t, m.Shutdown(), errHook)
+	assert.Equal(t, []int{2, 1}, order)
+}
+
+func TestManagerShutdownTimeout(t *testing.T) {
+	m := NewManager(context.Background(), ManagerOptions{
+		ShutdownTimeout: 50 * time.Millisecond,
+	})
+	m.OnShutdown(func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	assert.ErrorIs(t, m.Shutdown(), ErrShutdownTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestManagerShutdownOnPanic(t *testing.T) {
+	t.Run("panics trigger the shutdown before re-panicking", func(t *testing.T) {
+		m := NewManager(context.Background(), ManagerOptions{
+			ShutdownOnPanic: true,
+		})
+
+		var flushed bool
+		m.OnShutdown(func(context.Context) error {
+			flushed = true
+			return nil
+		})
+
+		repanicCh := make(chan any, 1)
+		m.repanic = func(v any) {
+			// Shutdown is completed before the panic is re-raised
+			assert.True(t, flushed)
+			assert.Error(t, m.Context().Err())
+			repanicCh <- v
+		}
+
+		m.Go(func(context.Context) {
+			panic("boom")
+		})
+		m.Wait()
+
+		select {
+		case v := <-repanicCh:
+			assert.Equal(t, "boom", v)
+		default:
+			t.Fatal("panic was not re-raised")
+		}
+	})
+
+	t.Run("goroutines stop on shutdown", func(t *testing.T) {
+		m := NewManager(context.Background(), ManagerOptions{
+			ShutdownOnPanic: true,
+		})
+		m.repanic = func(v any) {
+			t.Errorf("unexpected panic: %v", v)
+		}
+
+		m.Go(func(ctx context.Context) {
+			<-ctx.Done()
+		})
+		require.NoError(t, m.Shutdown())
+		m.Wait()
+	})
+}
+
+func TestManagerShutdownFlushesLogs(t *testing.T) {
+	var out bytes.Buffer
+	log := logger.NewLogger("test.signals.shutdown")
+	require.NoError(t, logger.SetSinks(log, logger.SinksOptions{}, logger.Sink{Name: "async", Writer: &out, Async: true}))
+	defer logger.SetSinks(log, logger.SinksOptions{})
+
+	m := NewManager(context.Background(), ManagerOptions{})
+	m.OnShutdown(func(context.Context) error {
+		log.Error("last error before exit")
+		return nil
+	})
+
+	require.NoError(t, m.Shutdown())
+	assert.Contains(t, out.String(), "last error before exit")
+}
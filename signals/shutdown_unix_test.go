@@ -0,0 +1,50 @@
+//go:build !windows
+
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signals
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerSignal(t *testing.T) {
+	m := NewManager(context.Background(), ManagerOptions{
+		Signals: []os.Signal{syscall.SIGUSR1},
+	})
+	hookCh := make(chan struct{})
+	m.OnShutdown(func(context.Context) error {
+		close(hookCh)
+		return nil
+	})
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+
+	select {
+	case <-m.Context().Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("context was not canceled after the signal")
+	}
+	select {
+	case <-hookCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("hook was not invoked after the signal")
+	}
+}
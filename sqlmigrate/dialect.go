@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlmigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"hash/fnv"
+)
+
+// Dialect contains the statements and the locking logic that are specific to a database.
+// Table names are interpolated into the statements, so they must be trusted values.
+type Dialect interface {
+	// CreateVersionTable returns the statement that creates the table of applied versions, if it doesn't exist.
+	CreateVersionTable(table string) string
+	// SelectVersions returns the query that selects the applied versions.
+	SelectVersions(table string) string
+	// InsertVersion returns the statement that records a version, with the version and name as parameters.
+	InsertVersion(table string) string
+	// Lock acquires the advisory lock identified by key on the connection, blocking until it's available or ctx is
+	// canceled.
+	Lock(ctx context.Context, conn *sql.Conn, key string) error
+	// Unlock releases the advisory lock.
+	Unlock(ctx context.Context, conn *sql.Conn, key string) error
+}
+
+// Built-in dialects.
+var (
+	// Postgres is the dialect for PostgreSQL, which uses session-level advisory locks.
+	Postgres Dialect = postgresDialect{}
+	// MySQL is the dialect for MySQL and MariaDB, which uses named locks.
+	MySQL Dialect = mysqlDialect{}
+	// SQLite is the dialect for SQLite. SQLite has no advisory locks; concurrent writers are serialized by the
+	// database itself, and applying the same migration twice fails on the primary key of the versions table.
+	SQLite Dialect = sqliteDialect{}
+)
+
+type postgresDialect struct{}
+
+func (postgresDialect) CreateVersionTable(table string) string {
+	return "CREATE TABLE IF NOT EXISTS " + table + " (version BIGINT PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+}
+
+func (postgresDialect) SelectVersions(table string) string {
+	return "SELECT version FROM " + table
+}
+
+func (postgresDialect) InsertVersion(table string) string {
+	return "INSERT INTO " + table + " (version, name) VALUES ($1, $2)"
+}
+
+func (postgresDialect) Lock(ctx context.Context, conn *sql.Conn, key string) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID(key))
+	return err
+}
+
+func (postgresDialect) Unlock(ctx context.Context, conn *sql.Conn, key string) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockID(key))
+	return err
+}
+
+// lockID returns the numeric ID of the Postgres advisory lock for key.
+func lockID(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) CreateVersionTable(table string) string {
+	return "CREATE TABLE IF NOT EXISTS " + table + " (version BIGINT PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+}
+
+func (mysqlDialect) SelectVersions(table string) string {
+	return "SELECT version FROM " + table
+}
+
+func (mysqlDialect) InsertVersion(table string) string {
+	return "INSERT INTO " + table + " (version, name) VALUES (?, ?)"
+}
+
+func (mysqlDialect) Lock(ctx context.Context, conn *sql.Conn, key string) error {
+	// GET_LOCK with a negative timeout waits indefinitely; cancellation is handled by ctx
+	var res sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, -1)", key).Scan(&res)
+	if err != nil {
+		return err
+	}
+	if !res.Valid || res.Int64 != 1 {
+		return errors.New("lock was not acquired")
+	}
+	return nil
+}
+
+func (mysqlDialect) Unlock(ctx context.Context, conn *sql.Conn, key string) error {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", key)
+	return err
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) CreateVersionTable(table string) string {
+	return "CREATE TABLE IF NOT EXISTS " + table + " (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+}
+
+func (sqliteDialect) SelectVersions(table string) string {
+	return "SELECT version FROM " + table
+}
+
+func (sqliteDialect) InsertVersion(table string) string {
+	return "INSERT INTO " + table + " (version, name) VALUES (?, ?)"
+}
+
+func (sqliteDialect) Lock(context.Context, *sql.Conn, string) error {
+	return nil
+}
+
+func (sqliteDialect) Unlock(context.Context, *sql.Conn, string) error {
+	return nil
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sqlmigrate applies ordered migration scripts to SQL databases.
+// Applied versions are recorded in a table, and migrations run while holding an advisory lock, so multiple instances
+// of an application can start at the same time safely.
+package sqlmigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// DefaultTable is the default name of the table where applied versions are recorded.
+	DefaultTable = "dapr_schema_migrations"
+	// unlockTimeout is the maximum time to release the lock after the migrations complete.
+	unlockTimeout = 10 * time.Second
+)
+
+// ErrInvalidMigrations is returned when the list of migrations is not valid.
+var ErrInvalidMigrations = errors.New("invalid migrations")
+
+// Migration is a migration script.
+type Migration struct {
+	// Version of the migration. Versions must be positive and unique, and migrations are applied in ascending order.
+	Version int64
+	// Name is a short description of the migration, recorded with the version.
+	Name string
+	// Up is the SQL script that applies the migration. Either Up or UpFn must be set.
+	Up string
+	// UpFn applies the migration in the transaction, for migrations that can't be expressed as a single script.
+	UpFn func(ctx context.Context, tx *sql.Tx) error
+}
+
+// Options contains the options for Migrate.
+type Options struct {
+	// Dialect of the database. Required.
+	Dialect Dialect
+	// Migrations to apply. They don't need to be sorted.
+	Migrations []Migration
+	// Table is the name of the table where applied versions are recorded. Default is DefaultTable.
+	Table string
+	// LockKey identifies the advisory lock. Default is the name of the table.
+	LockKey string
+	// Log is used to log the migrations that are applied. Optional.
+	Log logger.Logger
+}
+
+// Migrate applies the migrations that haven't been applied yet, in order, and returns their versions.
+// Each migration runs in its own transaction, together with the recording of its version; if a migration fails, the
+// previous ones remain applied and the error is returned. Note that some databases, such as MySQL, implicitly commit
+// transactions that contain DDL statements, so scripts should be idempotent where possible.
+func Migrate(ctx context.Context, db *sql.DB, opts Options) ([]int64, error) {
+	if opts.Dialect == nil {
+		return nil, errors.New("dialect is required")
+	}
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	if opts.LockKey == "" {
+		opts.LockKey = opts.Table
+	}
+	migrations, err := sortMigrations(opts.Migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	// Advisory locks are bound to the session, so everything runs on the same connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain a connection: %w", err)
+	}
+	defer conn.Close()
+
+	err = opts.Dialect.Lock(ctx, conn, opts.LockKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire the migrations lock: %w", err)
+	}
+	defer func() {
+		unlockCtx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+		unlockErr := opts.Dialect.Unlock(unlockCtx, conn, opts.LockKey)
+		if unlockErr != nil && opts.Log != nil {
+			opts.Log.Warnf("Failed to release the migrations lock: %v", unlockErr)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, opts.Dialect.CreateVersionTable(opts.Table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the migrations table: %w", err)
+	}
+	current, err := appliedVersions(ctx, conn, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []int64
+	for _, m := range migrations {
+		if _, ok := current[m.Version]; ok {
+			continue
+		}
+
+		if opts.Log != nil {
+			opts.Log.Infof("Applying migration %d (%s)", m.Version, m.Name)
+		}
+		err = apply(ctx, conn, opts, m)
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m.Version)
+	}
+
+	if opts.Log != nil && len(migrations) > 0 {
+		latest := migrations[len(migrations)-1].Version
+		for v := range current {
+			if v > latest {
+				opts.Log.Warnf("Database has migration %d applied, which is newer than the latest known migration %d", v, latest)
+				break
+			}
+		}
+	}
+
+	return applied, nil
+}
+
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	res := make([]Migration, len(migrations))
+	copy(res, migrations)
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+
+	for i, m := range res {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("%w: version %d is not positive", ErrInvalidMigrations, m.Version)
+		}
+		if i > 0 && res[i-1].Version == m.Version {
+			return nil, fmt.Errorf("%w: version %d is duplicated", ErrInvalidMigrations, m.Version)
+		}
+		if (m.Up == "") == (m.UpFn == nil) {
+			return nil, fmt.Errorf("%w: migration %d must have either Up or UpFn", ErrInvalidMigrations, m.Version)
+		}
+	}
+	return res, nil
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn, opts Options) (map[int64]struct{}, error) {
+	rows, err := conn.QueryContext(ctx, opts.Dialect.SelectVersions(opts.Table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	res := map[int64]struct{}{}
+	for rows.Next() {
+		var v int64
+		err = rows.Scan(&v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the applied migrations: %w", err)
+		}
+		res[v] = struct{}{}
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the applied migrations: %w", err)
+	}
+	return res, nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, opts Options, m Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if m.UpFn != nil {
+		err = m.UpFn(ctx, tx)
+	} else {
+		_, err = tx.ExecContext(ctx, m.Up)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, opts.Dialect.InsertVersion(opts.Table), m.Version, m.Name)
+	if err != nil {
+		return fmt.Errorf("failed to record version: %w", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlmigrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB is the state of a database of the fake driver, which records executed statements and applied versions.
+type fakeDB struct {
+	lock       sync.Mutex
+	versions   map[int64]string
+	statements []string
+}
+
+func (db *fakeDB) executed() []string {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	return append([]string(nil), db.statements...)
+}
+
+var (
+	fakeDBs     = map[string]*fakeDB{}
+	fakeDBsLock sync.Mutex
+)
+
+func init() {
+	sql.Register("sqlmigratetest", fakeDriver{})
+}
+
+func newFakeDB(t *testing.T) (*sql.DB, *fakeDB) {
+	t.Helper()
+
+	state := &fakeDB{versions: map[int64]string{}}
+	fakeDBsLock.Lock()
+	fakeDBs[t.Name()] = state
+	fakeDBsLock.Unlock()
+
+	db, err := sql.Open("sqlmigratetest", t.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, state
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsLock.Lock()
+	defer fakeDBsLock.Unlock()
+	return &fakeConn{db: fakeDBs[name]}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeTx
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.tx = &fakeTx{conn: c, versions: map[int64]string{}}
+	return c.tx, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "FAIL") {
+		return nil, errors.New("syntax error")
+	}
+	if c.tx != nil {
+		c.tx.statements = append(c.tx.statements, query)
+		if strings.HasPrefix(query, "INSERT INTO") {
+			c.tx.versions[args[0].Value.(int64)] = args[1].Value.(string)
+		}
+		return driver.RowsAffected(1), nil
+	}
+
+	c.db.lock.Lock()
+	c.db.statements = append(c.db.statements, query)
+	c.db.lock.Unlock()
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if !strings.HasPrefix(query, "SELECT version FROM") {
+		return nil, errors.New("unsupported query")
+	}
+	c.db.lock.Lock()
+	defer c.db.lock.Unlock()
+	rows := &fakeRows{}
+	for v := range c.db.versions {
+		rows.values = append(rows.values, v)
+	}
+	return rows, nil
+}
+
+type fakeTx struct {
+	conn       *fakeConn
+	versions   map[int64]string
+	statements []string
+}
+
+func (tx *fakeTx) Commit() error {
+	db := tx.conn.db
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	db.statements = append(db.statements, tx.statements...)
+	for v, n := range tx.versions {
+		db.versions[v] = n
+	}
+	tx.conn.tx = nil
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.conn.tx = nil
+	return nil
+}
+
+type fakeRows struct {
+	values []int64
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"version"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
+
+// lockingDialect records the calls to Lock and Unlock.
+type lockingDialect struct {
+	Dialect
+	calls []string
+}
+
+func (d *lockingDialect) Lock(_ context.Context, _ *sql.Conn, key string) error {
+	d.calls = append(d.calls, "lock "+key)
+	return nil
+}
+
+func (d *lockingDialect) Unlock(_ context.Context, _ *sql.Conn, key string) error {
+	d.calls = append(d.calls, "unlock "+key)
+	return nil
+}
+
+var testMigrations = []Migration{
+	{Version: 2, Name: "add index", Up: "CREATE INDEX idx ON state (key)"},
+	{Version: 1, Name: "create table", Up: "CREATE TABLE state (key TEXT)"},
+	{Version: 3, Name: "backfill", UpFn: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE state SET key = key")
+		return err
+	}},
+}
+
+func TestMigrate(t *testing.T) {
+	t.Run("applies migrations in order", func(t *testing.T) {
+		db, state := newFakeDB(t)
+		dialect := &lockingDialect{Dialect: SQLite}
+
+		applied, err := Migrate(context.Background(), db, Options{Dialect: dialect, Migrations: testMigrations})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, applied)
+		assert.Equal(t, []string{"lock " + DefaultTable, "unlock " + DefaultTable}, dialect.calls)
+		assert.Equal(t, map[int64]string{1: "create table", 2: "add index", 3: "backfill"}, state.versions)
+
+		stmts := state.executed()
+		require.Len(t, stmts, 7)
+		assert.True(t, strings.HasPrefix(stmts[0], "CREATE TABLE IF NOT EXISTS "+DefaultTable))
+		assert.Equal(t, "CREATE TABLE state (key TEXT)", stmts[1])
+		assert.Equal(t, "INSERT INTO "+DefaultTable+" (version, name) VALUES (?, ?)", stmts[2])
+		assert.Equal(t, "CREATE INDEX idx ON state (key)", stmts[3])
+		assert.Equal(t, "UPDATE state SET key = key", stmts[5])
+
+		// Running again is a nop
+		applied, err = Migrate(context.Background(), db, Options{Dialect: dialect, Migrations: testMigrations})
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("only new migrations are applied", func(t *testing.T) {
+		db, state := newFakeDB(t)
+		state.versions[1] = "create table"
+
+		applied, err := Migrate(context.Background(), db, Options{Dialect: Postgres, Migrations: testMigrations, Table: "migrations"})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2, 3}, applied)
+		assert.Contains(t, state.executed(), "INSERT INTO migrations (version, name) VALUES ($1, $2)")
+	})
+
+	t.Run("failed migrations are not recorded", func(t *testing.T) {
+		db, state := newFakeDB(t)
+		migrations := []Migration{
+			{Version: 1, Name: "ok", Up: "CREATE TABLE state (key TEXT)"},
+			{Version: 2, Name: "broken", Up: "FAIL"},
+			{Version: 3, Name: "never", Up: "CREATE TABLE other (key TEXT)"},
+		}
+
+		applied, err := Migrate(context.Background(), db, Options{Dialect: SQLite, Migrations: migrations})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "failed to apply migration 2 (broken)")
+		assert.Equal(t, []int64{1}, applied)
+		assert.Equal(t, map[int64]string{1: "ok"}, state.versions)
+		assert.NotContains(t, state.executed(), "CREATE TABLE other (key TEXT)")
+	})
+
+	t.Run("invalid migrations", func(t *testing.T) {
+		db, _ := newFakeDB(t)
+		for _, migrations := range [][]Migration{
+			{{Version: 0, Up: "SELECT 1"}},
+			{{Version: 1, Up: "SELECT 1"}, {Version: 1, Up: "SELECT 2"}},
+			{{Version: 1}},
+			{{Version: 1, Up: "SELECT 1", UpFn: func(context.Context, *sql.Tx) error { return nil }}},
+		} {
+			_, err := Migrate(context.Background(), db, Options{Dialect: SQLite, Migrations: migrations})
+			require.ErrorIs(t, err, ErrInvalidMigrations)
+		}
+
+		_, err := Migrate(context.Background(), db, Options{Migrations: testMigrations})
+		require.Error(t, err)
+	})
+}
+
+func TestLockID(t *testing.T) {
+	assert.Equal(t, lockID("a"), lockID("a"))
+	assert.NotEqual(t, lockID("a"), lockID("b"))
+}
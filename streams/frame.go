@@ -0,0 +1,275 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+// FramePrefix is the encoding of the length prefix of frames.
+type FramePrefix int
+
+const (
+	// FramePrefixVarint encodes the length of frames as an unsigned varint, as in protobuf streams.
+	FramePrefixVarint FramePrefix = iota
+	// FramePrefixUint32 encodes the length of frames as a 4-byte big-endian integer.
+	FramePrefixUint32
+	// FramePrefixUint16 encodes the length of frames as a 2-byte big-endian integer, so frames are limited to 64KiB.
+	FramePrefixUint16
+)
+
+const (
+	// DefaultMaxFrameSize is the default maximum size of the payload of a frame.
+	DefaultMaxFrameSize = 4 << 20
+	// maxPooledFrameBuffer is the maximum capacity of buffers that are returned to the pool, so the pool doesn't retain
+	// the buffers of large frames.
+	maxPooledFrameBuffer = 64 << 10
+	// defaultFrameBufferSize is the capacity of new buffers in the pool.
+	defaultFrameBufferSize = 4 << 10
+)
+
+// maxUint32Frame is the maximum size of frames with a FramePrefixUint32 prefix. It's a variable so it can be
+// converted to int on 32-bit platforms.
+var maxUint32Frame uint64 = math.MaxUint32
+
+// ErrFrameTooLarge is returned when a frame exceeds the maximum size.
+var ErrFrameTooLarge = errors.New("frame exceeds the maximum size")
+
+// frameBufPool contains the buffers used to read and write frames.
+var frameBufPool = sync.Pool{
+	New: func() any {
+		// Return a pointer here
+		// See https://github.com/dominikh/go-tools/issues/1336 for explanation
+		b := make([]byte, 0, defaultFrameBufferSize)
+		return &b
+	},
+}
+
+// getFrameBuf returns a buffer from the pool with length n.
+func getFrameBuf(n int) *[]byte {
+	b := frameBufPool.Get().(*[]byte)
+	if cap(*b) < n {
+		*b = make([]byte, n)
+	}
+	*b = (*b)[:n]
+	return b
+}
+
+// putFrameBuf returns a buffer to the pool.
+func putFrameBuf(b *[]byte) {
+	if cap(*b) > maxPooledFrameBuffer {
+		return
+	}
+	*b = (*b)[:0]
+	frameBufPool.Put(b)
+}
+
+// FrameOptions contains the options for FrameReader and FrameWriter. Both ends of a stream must use the same options.
+type FrameOptions struct {
+	// Prefix is the encoding of the length prefix of frames. Default is FramePrefixVarint.
+	Prefix FramePrefix
+	// MaxFrameSize is the maximum size of the payload of a frame. Default is DefaultMaxFrameSize.
+	MaxFrameSize int
+}
+
+// maxFrameSize returns the maximum frame size, taking into account the limits of the prefix.
+func (o FrameOptions) maxFrameSize() int {
+	limit := o.MaxFrameSize
+	if limit <= 0 {
+		limit = DefaultMaxFrameSize
+	}
+	switch o.Prefix {
+	case FramePrefixUint16:
+		if limit > math.MaxUint16 {
+			limit = math.MaxUint16
+		}
+	case FramePrefixUint32:
+		// On 32-bit platforms int can't exceed the limit, and the constant doesn't fit in an int
+		if math.MaxInt > math.MaxUint32 && uint64(limit) > math.MaxUint32 {
+			limit = int(maxUint32Frame)
+		}
+	}
+	return limit
+}
+
+// prefixLen returns the maximum length of the prefix.
+func (o FrameOptions) prefixLen() int {
+	switch o.Prefix {
+	case FramePrefixUint16:
+		return 2
+	case FramePrefixUint32:
+		return 4
+	default:
+		return binary.MaxVarintLen64
+	}
+}
+
+// Frame is a frame read by a FrameReader.
+// Its buffer is taken from a pool: invoke Release when Data is no longer used.
+type Frame struct {
+	// Data is the payload of the frame.
+	Data []byte
+
+	buf *[]byte
+}
+
+// Release returns the buffer of the frame to the pool. Data must not be used afterwards.
+func (f *Frame) Release() {
+	if f.buf == nil {
+		return
+	}
+	putFrameBuf(f.buf)
+	f.buf = nil
+	f.Data = nil
+}
+
+// FrameReader reads length-prefixed frames from a stream.
+// Reads from the underlying stream are buffered. FrameReader is not safe for concurrent use.
+type FrameReader struct {
+	r    *bufio.Reader
+	opts FrameOptions
+	max  int
+	err  error
+}
+
+// NewFrameReader returns a FrameReader that reads frames from r.
+func NewFrameReader(r io.Reader, opts FrameOptions) *FrameReader {
+	return &FrameReader{
+		r:    bufio.NewReader(r),
+		opts: opts,
+		max:  opts.maxFrameSize(),
+	}
+}
+
+// ReadFrame reads the next frame.
+// It returns io.EOF when the stream ends before a frame, and io.ErrUnexpectedEOF when it ends in the middle of one.
+// Frames larger than the maximum size return an error matching ErrFrameTooLarge. Because the stream can't be
+// resynchronized after, all errors are sticky.
+func (fr *FrameReader) ReadFrame() (*Frame, error) {
+	if fr.err != nil {
+		return nil, fr.err
+	}
+
+	frame, err := fr.readFrame()
+	if err != nil {
+		fr.err = err
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (fr *FrameReader) readFrame() (*Frame, error) {
+	size, err := fr.readPrefix()
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(fr.max) {
+		return nil, fmt.Errorf("%w: frame has %d bytes, maximum is %d", ErrFrameTooLarge, size, fr.max)
+	}
+
+	buf := getFrameBuf(int(size))
+	_, err = io.ReadFull(fr.r, *buf)
+	if err != nil {
+		putFrameBuf(buf)
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return &Frame{Data: *buf, buf: buf}, nil
+}
+
+func (fr *FrameReader) readPrefix() (uint64, error) {
+	switch fr.opts.Prefix {
+	case FramePrefixUint16, FramePrefixUint32:
+		var prefix [4]byte
+		p := prefix[:fr.opts.prefixLen()]
+		n, err := io.ReadFull(fr.r, p)
+		if err != nil {
+			if n > 0 && errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		if fr.opts.Prefix == FramePrefixUint16 {
+			return uint64(binary.BigEndian.Uint16(p)), nil
+		}
+		return uint64(binary.BigEndian.Uint32(p)), nil
+	default:
+		// ReadUvarint returns io.EOF only if no byte was read, and io.ErrUnexpectedEOF otherwise
+		return binary.ReadUvarint(fr.r)
+	}
+}
+
+// FrameWriter writes length-prefixed frames to a stream.
+// Each frame is written to the underlying stream with a single Write call. FrameWriter is safe for concurrent use.
+type FrameWriter struct {
+	lock sync.Mutex
+	w    io.Writer
+	opts FrameOptions
+	max  int
+}
+
+// NewFrameWriter returns a FrameWriter that writes frames to w.
+func NewFrameWriter(w io.Writer, opts FrameOptions) *FrameWriter {
+	return &FrameWriter{
+		w:    w,
+		opts: opts,
+		max:  opts.maxFrameSize(),
+	}
+}
+
+// WriteFrame writes p as a frame.
+// It returns an error matching ErrFrameTooLarge if p exceeds the maximum size; in this case, nothing is written.
+func (fw *FrameWriter) WriteFrame(p []byte) error {
+	if len(p) > fw.max {
+		return fmt.Errorf("%w: frame has %d bytes, maximum is %d", ErrFrameTooLarge, len(p), fw.max)
+	}
+
+	buf := getFrameBuf(fw.opts.prefixLen() + len(p))
+	defer putFrameBuf(buf)
+
+	var n int
+	switch fw.opts.Prefix {
+	case FramePrefixUint16:
+		binary.BigEndian.PutUint16(*buf, uint16(len(p)))
+		n = 2
+	case FramePrefixUint32:
+		binary.BigEndian.PutUint32(*buf, uint32(len(p)))
+		n = 4
+	default:
+		n = binary.PutUvarint(*buf, uint64(len(p)))
+	}
+	n += copy((*buf)[n:], p)
+
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+	_, err := fw.w.Write((*buf)[:n])
+	return err
+}
+
+// Write implements io.Writer, writing p as a single frame.
+func (fw *FrameWriter) Write(p []byte) (int, error) {
+	err := fw.WriteFrame(p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
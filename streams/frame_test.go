@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrames(t *testing.T) {
+	frames := [][]byte{
+		[]byte("hello"),
+		{},
+		bytes.Repeat([]byte("x"), 300),
+		bytes.Repeat([]byte("y"), 100<<10),
+	}
+
+	for name, prefix := range map[string]FramePrefix{
+		"varint": FramePrefixVarint,
+		"uint32": FramePrefixUint32,
+		"uint16": FramePrefixUint16,
+	} {
+		prefix := prefix
+		t.Run(name, func(t *testing.T) {
+			opts := FrameOptions{Prefix: prefix}
+			var buf bytes.Buffer
+			fw := NewFrameWriter(&buf, opts)
+			for _, f := range frames {
+				if prefix == FramePrefixUint16 && len(f) > 64<<10 {
+					require.ErrorIs(t, fw.WriteFrame(f), ErrFrameTooLarge)
+					continue
+				}
+				require.NoError(t, fw.WriteFrame(f))
+			}
+
+			fr := NewFrameReader(iotest.HalfReader(&buf), opts)
+			for _, f := range frames {
+				if prefix == FramePrefixUint16 && len(f) > 64<<10 {
+					continue
+				}
+				frame, err := fr.ReadFrame()
+				require.NoError(t, err)
+				assert.Equal(t, f, frame.Data)
+				frame.Release()
+				assert.Nil(t, frame.Data)
+			}
+			_, err := fr.ReadFrame()
+			require.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestFrameWriterEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := NewFrameWriter(&buf, FrameOptions{}).Write([]byte("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{2, 'h', 'i'}, buf.Bytes())
+
+	buf.Reset()
+	require.NoError(t, NewFrameWriter(&buf, FrameOptions{Prefix: FramePrefixUint32}).WriteFrame([]byte("hi")))
+	assert.Equal(t, []byte{0, 0, 0, 2, 'h', 'i'}, buf.Bytes())
+
+	buf.Reset()
+	require.NoError(t, NewFrameWriter(&buf, FrameOptions{Prefix: FramePrefixUint16}).WriteFrame([]byte("hi")))
+	assert.Equal(t, []byte{0, 2, 'h', 'i'}, buf.Bytes())
+}
+
+func TestFrameMaxSize(t *testing.T) {
+	opts := FrameOptions{MaxFrameSize: 4}
+
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf, opts)
+	n, err := fw.Write([]byte("hello"))
+	require.ErrorIs(t, err, ErrFrameTooLarge)
+	assert.Zero(t, n)
+	assert.Zero(t, buf.Len())
+
+	// Frames written by a peer with a larger limit are rejected by the reader, and the error is sticky
+	require.NoError(t, NewFrameWriter(&buf, FrameOptions{}).WriteFrame([]byte("hello")))
+	require.NoError(t, NewFrameWriter(&buf, FrameOptions{}).WriteFrame([]byte("hi")))
+	fr := NewFrameReader(&buf, opts)
+	_, err = fr.ReadFrame()
+	require.ErrorIs(t, err, ErrFrameTooLarge)
+	_, err = fr.ReadFrame()
+	require.ErrorIs(t, err, ErrFrameTooLarge)
+}
+
+func TestFrameReaderTruncated(t *testing.T) {
+	for name, data := range map[string]string{
+		"varint prefix": "\x80",
+		"payload":       "\x05hel",
+	} {
+		data := data
+		t.Run(name, func(t *testing.T) {
+			_, err := NewFrameReader(strings.NewReader(data), FrameOptions{}).ReadFrame()
+			require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		})
+	}
+
+	t.Run("fixed prefix", func(t *testing.T) {
+		_, err := NewFrameReader(strings.NewReader("\x00\x00"), FrameOptions{Prefix: FramePrefixUint32}).ReadFrame()
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		_, err = NewFrameReader(strings.NewReader(""), FrameOptions{Prefix: FramePrefixUint32}).ReadFrame()
+		require.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestFrameWriterConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf, FrameOptions{})
+
+	const writers, perWriter = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			payload := bytes.Repeat([]byte{byte('a' + i)}, 100+i)
+			for j := 0; j < perWriter; j++ {
+				assert.NoError(t, fw.WriteFrame(payload))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Frames are not interleaved
+	fr := NewFrameReader(&buf, FrameOptions{})
+	for i := 0; i < writers*perWriter; i++ {
+		frame, err := fr.ReadFrame()
+		require.NoError(t, err)
+		require.Len(t, frame.Data, 100+int(frame.Data[0]-'a'))
+		assert.Equal(t, bytes.Repeat(frame.Data[:1], len(frame.Data)), frame.Data)
+		frame.Release()
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const proxyBufferSize = 32 << 10
+
+// ErrProxyIdleTimeout is returned by Proxy when no data was transferred for longer than the idle timeout.
+var ErrProxyIdleTimeout = errors.New("proxy idle timeout")
+
+// ProxyOptions contains the options for Proxy.
+type ProxyOptions struct {
+	// IdleTimeout is the maximum time without data transferred in either direction, after which the connections are
+	// closed and Proxy returns ErrProxyIdleTimeout. If 0, there's no idle timeout.
+	IdleTimeout time.Duration
+	// BufferSize is the size of the buffer used for each direction. Default is 32KB.
+	BufferSize int
+}
+
+// ProxyStats contains the number of bytes transferred by Proxy.
+type ProxyStats struct {
+	// AToB is the number of bytes read from a and written to b.
+	AToB int64
+	// BToA is the number of bytes read from b and written to a.
+	BToA int64
+}
+
+// closeWriter is implemented by connections that support half-close, such as *net.TCPConn and *net.UnixConn.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// Proxy copies data between a and b in both directions, until both directions are done, an error occurs, ctx is
+// canceled, or the idle timeout expires. Both a and b are closed when Proxy returns.
+//
+// When a side reaches EOF, the write side of the other one is closed if it supports half-close (it implements
+// CloseWrite, like TCP and Unix connections), so the other direction can continue until it's done too. If it doesn't,
+// both connections are closed right away.
+//
+// Proxy returns the number of bytes transferred in each direction, and the first error that occurred, if any.
+func Proxy(ctx context.Context, a, b io.ReadWriteCloser, opts ProxyOptions) (ProxyStats, error) {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = proxyBufferSize
+	}
+
+	p := &proxy{
+		errCh: make(chan error, 2),
+	}
+	p.lastActivity.Store(time.Now().UnixNano())
+	p.closeFn = func() {
+		p.closed.Store(true)
+		_ = a.Close()
+		_ = b.Close()
+	}
+
+	var aToB, bToA atomic.Int64
+	go p.copy(b, a, &aToB, opts.BufferSize)
+	go p.copy(a, b, &bToA, opts.BufferSize)
+
+	var (
+		idleTimer *time.Timer
+		idleCh    <-chan time.Time
+	)
+	if opts.IdleTimeout > 0 {
+		idleTimer = time.NewTimer(opts.IdleTimeout)
+		defer idleTimer.Stop()
+		idleCh = idleTimer.C
+	}
+
+	var err error
+	doneCh := ctx.Done()
+	for done := 0; done < 2; {
+		select {
+		case copyErr := <-p.errCh:
+			done++
+			if copyErr != nil && err == nil {
+				err = copyErr
+				p.close()
+			}
+		case <-doneCh:
+			doneCh = nil
+			if err == nil {
+				err = ctx.Err()
+			}
+			p.close()
+		case <-idleCh:
+			idle := time.Since(time.Unix(0, p.lastActivity.Load()))
+			if idle < opts.IdleTimeout {
+				idleTimer.Reset(opts.IdleTimeout - idle)
+				continue
+			}
+			idleCh = nil
+			if err == nil {
+				err = ErrProxyIdleTimeout
+			}
+			p.close()
+		}
+	}
+	p.close()
+
+	return ProxyStats{
+		AToB: aToB.Load(),
+		BToA: bToA.Load(),
+	}, err
+}
+
+type proxy struct {
+	errCh        chan error
+	lastActivity atomic.Int64
+	closed       atomic.Bool
+	closeOnce    sync.Once
+	closeFn      func()
+}
+
+// close closes both connections, once.
+func (p *proxy) close() {
+	p.closeOnce.Do(p.closeFn)
+}
+
+// copy copies data from src to dst, then sends the result to errCh.
+// Errors that occur after the connections are closed by the proxy are not reported.
+func (p *proxy) copy(dst io.Writer, src io.Reader, counter *atomic.Int64, bufSize int) {
+	buf := make([]byte, bufSize)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			p.lastActivity.Store(time.Now().UnixNano())
+			written, werr := dst.Write(buf[:n])
+			counter.Add(int64(written))
+			if werr == nil && written < n {
+				werr = io.ErrShortWrite
+			}
+			if werr != nil {
+				p.errCh <- p.filterErr(werr)
+				return
+			}
+			p.lastActivity.Store(time.Now().UnixNano())
+		}
+
+		if errors.Is(err, io.EOF) {
+			// Propagate the half-close if possible, otherwise close both connections
+			if cw, ok := dst.(closeWriter); ok {
+				err = cw.CloseWrite()
+				p.errCh <- p.filterErr(err)
+			} else {
+				p.close()
+				p.errCh <- nil
+			}
+			return
+		}
+		if err != nil {
+			p.errCh <- p.filterErr(err)
+			return
+		}
+	}
+}
+
+func (p *proxy) filterErr(err error) error {
+	if p.closed.Load() {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tcpPair returns the two ends of a TCP connection.
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	acceptCh := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := ln.Accept()
+		acceptCh <- conn
+	}()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	server := <-acceptCh
+	require.NotNil(t, server)
+
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client.(*net.TCPConn), server.(*net.TCPConn)
+}
+
+type proxyResult struct {
+	stats ProxyStats
+	err   error
+}
+
+func startProxy(ctx context.Context, a, b io.ReadWriteCloser, opts ProxyOptions) <-chan proxyResult {
+	resCh := make(chan proxyResult, 1)
+	go func() {
+		stats, err := Proxy(ctx, a, b, opts)
+		resCh <- proxyResult{stats, err}
+	}()
+	return resCh
+}
+
+func waitProxy(t *testing.T, resCh <-chan proxyResult) proxyResult {
+	t.Helper()
+
+	select {
+	case res := <-resCh:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy did not return")
+		return proxyResult{}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	t.Run("bidirectional copy with half-close", func(t *testing.T) {
+		client, a := tcpPair(t)
+		b, server := tcpPair(t)
+		resCh := startProxy(context.Background(), a, b, ProxyOptions{BufferSize: 4})
+
+		_, err := client.Write([]byte("hello proxy"))
+		require.NoError(t, err)
+		require.NoError(t, client.CloseWrite())
+
+		// The server receives EOF after the data, and can still respond
+		read, err := io.ReadAll(server)
+		require.NoError(t, err)
+		assert.Equal(t, "hello proxy", string(read))
+
+		_, err = server.Write([]byte("hi"))
+		require.NoError(t, err)
+		require.NoError(t, server.Close())
+
+		read, err = io.ReadAll(client)
+		require.NoError(t, err)
+		assert.Equal(t, "hi", string(read))
+
+		res := waitProxy(t, resCh)
+		require.NoError(t, res.err)
+		assert.Equal(t, ProxyStats{AToB: 11, BToA: 2}, res.stats)
+	})
+
+	t.Run("without half-close", func(t *testing.T) {
+		client, a := net.Pipe()
+		b, server := net.Pipe()
+		resCh := startProxy(context.Background(), a, b, ProxyOptions{})
+
+		go func() {
+			_, _ = client.Write([]byte("hello"))
+			_ = client.Close()
+		}()
+		read, err := io.ReadAll(server)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(read))
+
+		res := waitProxy(t, resCh)
+		require.NoError(t, res.err)
+		assert.Equal(t, int64(5), res.stats.AToB)
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		client, a := net.Pipe()
+		b, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+		resCh := startProxy(context.Background(), a, b, ProxyOptions{IdleTimeout: 100 * time.Millisecond})
+
+		// Activity resets the idle timeout
+		go func() {
+			buf := make([]byte, 1)
+			for {
+				if _, err := server.Read(buf); err != nil {
+					return
+				}
+			}
+		}()
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			time.Sleep(50 * time.Millisecond)
+			_, err := client.Write([]byte("x"))
+			require.NoError(t, err)
+		}
+
+		res := waitProxy(t, resCh)
+		require.ErrorIs(t, res.err, ErrProxyIdleTimeout)
+		assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+		assert.Equal(t, int64(3), res.stats.AToB)
+
+		// Connections are closed
+		_, err := client.Write([]byte("x"))
+		require.Error(t, err)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		client, a := net.Pipe()
+		b, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		resCh := startProxy(ctx, a, b, ProxyOptions{})
+		cancel()
+
+		res := waitProxy(t, resCh)
+		require.ErrorIs(t, res.err, context.Canceled)
+	})
+}
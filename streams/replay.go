@@ -0,0 +1,206 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package streams contains utilities for working with streams of data.
+package streams
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	replayChunkSize = 32 << 10
+	// maxEmptyReads is the maximum number of consecutive reads from the source that can return no data and no error.
+	maxEmptyReads = 100
+)
+
+// ErrReplayBufferClosed is returned when reading from a ReplayBuffer that is closed.
+var ErrReplayBufferClosed = errors.New("replay buffer is closed")
+
+// ReplayBuffer allows a stream, such as a request body, to be read multiple times, for example to retry a request or
+// to verify a signature before forwarding the body.
+// Data is read from the source lazily, as readers need it, and it's buffered in memory up to a limit; beyond that,
+// the buffer spills to a temporary file, which is removed when the ReplayBuffer is closed.
+type ReplayBuffer struct {
+	src      io.Reader
+	memLimit int64
+	tmpDir   string
+
+	lock   sync.Mutex
+	mem    bytes.Buffer
+	file   *os.File
+	size   int64
+	srcErr error
+	closed bool
+}
+
+// NewReplayBuffer returns a new ReplayBuffer that reads from r.
+// Up to memLimit bytes are buffered in memory; additional data is stored in a temporary file created in tmpDir (if
+// empty, the default directory for temporary files is used).
+// Callers must invoke Close when done, to release resources.
+func NewReplayBuffer(r io.Reader, memLimit int64, tmpDir string) *ReplayBuffer {
+	return &ReplayBuffer{
+		src:      r,
+		memLimit: memLimit,
+		tmpDir:   tmpDir,
+	}
+}
+
+// NewReader returns a new reader that reads the stream from the beginning.
+// Multiple readers can be used, including concurrently.
+func (b *ReplayBuffer) NewReader() io.Reader {
+	return &replayReader{buf: b}
+}
+
+// Spilled returns true if the data was spilled to a temporary file.
+func (b *ReplayBuffer) Spilled() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.file != nil
+}
+
+// Close the buffer, removing the temporary file if any.
+// If the source implements io.Closer, it's closed too.
+func (b *ReplayBuffer) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	b.mem = bytes.Buffer{}
+
+	var errs []error
+	if b.file != nil {
+		name := b.file.Name()
+		errs = append(errs, b.file.Close(), os.Remove(name))
+		b.file = nil
+	}
+	if c, ok := b.src.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// readAt reads data at the given offset, reading more from the source if needed.
+func (b *ReplayBuffer) readAt(p []byte, off int64) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return 0, ErrReplayBufferClosed
+	}
+
+	if off >= b.size {
+		if b.srcErr != nil {
+			return 0, b.srcErr
+		}
+		err := b.fill()
+		if err != nil {
+			return 0, err
+		}
+		if off >= b.size {
+			return 0, b.srcErr
+		}
+	}
+
+	// Read from the buffered data
+	if b.file != nil {
+		n, err := b.file.ReadAt(p[:min(int64(len(p)), b.size-off)], off)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		return n, err
+	}
+	return copy(p, b.mem.Bytes()[off:]), nil
+}
+
+// fill reads the next chunk from the source, retrying reads that return no data and no error.
+// After maxEmptyReads of them, the source is considered broken and io.ErrNoProgress is returned to readers.
+// This must be invoked while the caller has a lock.
+func (b *ReplayBuffer) fill() error {
+	chunk := make([]byte, replayChunkSize)
+	var (
+		n   int
+		err error
+	)
+	for i := 0; n == 0 && err == nil; i++ {
+		if i == maxEmptyReads {
+			err = io.ErrNoProgress
+			break
+		}
+		n, err = b.src.Read(chunk)
+	}
+	if err != nil {
+		b.srcErr = err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	if b.file == nil && int64(b.mem.Len()+n) > b.memLimit {
+		err = b.spill()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.file != nil {
+		_, err = b.file.WriteAt(chunk[:n], b.size)
+		if err != nil {
+			return fmt.Errorf("failed to write to temporary file: %w", err)
+		}
+	} else {
+		b.mem.Write(chunk[:n])
+	}
+	b.size += int64(n)
+	return nil
+}
+
+// spill moves the data buffered in memory to a temporary file.
+// This must be invoked while the caller has a lock.
+func (b *ReplayBuffer) spill() error {
+	f, err := os.CreateTemp(b.tmpDir, "replay-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	_, err = f.Write(b.mem.Bytes())
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("failed to write to temporary file: %w", err)
+	}
+	b.file = f
+	b.mem = bytes.Buffer{}
+	return nil
+}
+
+type replayReader struct {
+	buf *ReplayBuffer
+	off int64
+}
+
+func (r *replayReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, err := r.buf.readAt(p, r.off)
+	r.off += int64(n)
+	return n, err
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayBuffer(t *testing.T) {
+	t.Run("in memory", func(t *testing.T) {
+		dir := t.TempDir()
+		b := NewReplayBuffer(bytes.NewReader([]byte("hello world")), 1024, dir)
+		defer b.Close()
+
+		for i := 0; i < 3; i++ {
+			read, err := io.ReadAll(b.NewReader())
+			require.NoError(t, err)
+			assert.Equal(t, "hello world", string(read))
+		}
+		assert.False(t, b.Spilled())
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("spill to disk", func(t *testing.T) {
+		dir := t.TempDir()
+		data := make([]byte, 100<<10)
+		_, err := io.ReadFull(rand.Reader, data)
+		require.NoError(t, err)
+
+		b := NewReplayBuffer(bytes.NewReader(data), 1024, dir)
+
+		// Read partially first, then fully with concurrent readers
+		first := make([]byte, 100)
+		_, err = io.ReadFull(b.NewReader(), first)
+		require.NoError(t, err)
+		assert.Equal(t, data[:100], first)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				read, err := io.ReadAll(b.NewReader())
+				assert.NoError(t, err)
+				assert.Equal(t, data, read)
+			}()
+		}
+		wg.Wait()
+		assert.True(t, b.Spilled())
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+
+		// Closing the buffer removes the temporary file
+		require.NoError(t, b.Close())
+		entries, err = os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+
+		_, err = b.NewReader().Read(first)
+		require.ErrorIs(t, err, ErrReplayBufferClosed)
+	})
+
+	t.Run("source errors are returned", func(t *testing.T) {
+		b := NewReplayBuffer(io.MultiReader(bytes.NewReader([]byte("hello")), &errReader{errors.New("boom")}), 1024, "")
+		defer b.Close()
+
+		for i := 0; i < 2; i++ {
+			read, err := io.ReadAll(b.NewReader())
+			require.EqualError(t, err, "boom")
+			assert.Equal(t, "hello", string(read))
+		}
+	})
+
+	t.Run("empty reads from the source are retried", func(t *testing.T) {
+		b := NewReplayBuffer(&emptyReader{Reader: bytes.NewReader([]byte("hello")), empty: 3}, 1024, "")
+		defer b.Close()
+
+		p := make([]byte, 10)
+		n, err := b.NewReader().Read(p)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(p[:n]))
+
+		b = NewReplayBuffer(&emptyReader{empty: -1}, 1024, "")
+		defer b.Close()
+		_, err = b.NewReader().Read(p)
+		require.ErrorIs(t, err, io.ErrNoProgress)
+	})
+
+	t.Run("source is closed", func(t *testing.T) {
+		src := &closeTracker{Reader: bytes.NewReader([]byte("hello"))}
+		b := NewReplayBuffer(src, 1024, "")
+		require.NoError(t, b.Close())
+		assert.True(t, src.closed)
+	})
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// emptyReader returns no data and no error for the first empty reads, or always if empty is negative.
+type emptyReader struct {
+	io.Reader
+	empty int
+}
+
+func (r *emptyReader) Read(p []byte) (int, error) {
+	if r.empty != 0 {
+		r.empty--
+		return 0, nil
+	}
+	return r.Reader.Read(p)
+}
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/dapr/kit/crypto"
+)
+
+// ChecksumMismatchError is returned by VerifyingReader when the digest of the data doesn't match the expected one.
+// It matches crypto.ErrChecksumMismatch with errors.Is.
+type ChecksumMismatchError struct {
+	Algorithm string
+	Expected  []byte
+	Actual    []byte
+}
+
+// Error implements error.
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s: expected %s digest %s, got %s",
+		crypto.ErrChecksumMismatch, e.Algorithm, hex.EncodeToString(e.Expected), hex.EncodeToString(e.Actual))
+}
+
+// Is allows matching the error with crypto.ErrChecksumMismatch.
+func (e *ChecksumMismatchError) Is(target error) bool {
+	return target == crypto.ErrChecksumMismatch
+}
+
+// VerifyingReader is an io.Reader that computes the digest of the data while it's read, and compares it with the
+// expected digest when the underlying reader returns io.EOF.
+// If the digests don't match, Read returns a *ChecksumMismatchError instead of io.EOF, so consumers that read until
+// EOF (such as io.Copy or io.ReadAll) fail.
+// Note that data is returned to the caller before it's verified.
+type VerifyingReader struct {
+	r         io.Reader
+	h         hash.Hash
+	algorithm string
+	expected  []byte
+	err       error
+}
+
+// NewVerifyingReader returns a new VerifyingReader that reads from r and verifies that the digest computed with
+// algorithm, one of the values returned by crypto.SupportedHashAlgorithms, matches expectedDigest.
+func NewVerifyingReader(r io.Reader, algorithm string, expectedDigest []byte) (*VerifyingReader, error) {
+	h, err := crypto.NewHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &VerifyingReader{
+		r:         r,
+		h:         h,
+		algorithm: algorithm,
+		expected:  expectedDigest,
+	}, nil
+}
+
+// Read implements io.Reader.
+func (v *VerifyingReader) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+
+	n, err := v.r.Read(p)
+	if n > 0 {
+		// Writing to a hash never returns an error
+		_, _ = v.h.Write(p[:n])
+	}
+	if errors.Is(err, io.EOF) {
+		actual := v.h.Sum(nil)
+		if subtle.ConstantTimeCompare(actual, v.expected) != 1 {
+			err = &ChecksumMismatchError{
+				Algorithm: v.algorithm,
+				Expected:  v.expected,
+				Actual:    actual,
+			}
+		}
+		v.err = err
+	}
+	return n, err
+}
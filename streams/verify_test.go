@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/crypto"
+)
+
+func TestVerifyingReader(t *testing.T) {
+	const data = "the quick brown fox jumps over the lazy dog"
+	digest := sha256.Sum256([]byte(data))
+
+	t.Run("digest matches", func(t *testing.T) {
+		r, err := NewVerifyingReader(iotest.OneByteReader(strings.NewReader(data)), crypto.HashAlgorithm_SHA256, digest[:])
+		require.NoError(t, err)
+		read, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data, string(read))
+
+		// EOF is sticky
+		n, err := r.Read(make([]byte, 1))
+		assert.Equal(t, 0, n)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("digest mismatch", func(t *testing.T) {
+		r, err := NewVerifyingReader(strings.NewReader(data+"!"), crypto.HashAlgorithm_SHA256, digest[:])
+		require.NoError(t, err)
+		_, err = io.ReadAll(r)
+		require.ErrorIs(t, err, crypto.ErrChecksumMismatch)
+
+		var mismatchErr *ChecksumMismatchError
+		require.ErrorAs(t, err, &mismatchErr)
+		assert.Equal(t, crypto.HashAlgorithm_SHA256, mismatchErr.Algorithm)
+		assert.Equal(t, digest[:], mismatchErr.Expected)
+		actual := sha256.Sum256([]byte(data + "!"))
+		assert.Equal(t, actual[:], mismatchErr.Actual)
+
+		_, err = r.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, crypto.ErrChecksumMismatch)
+	})
+
+	t.Run("errors from the source are returned", func(t *testing.T) {
+		srcErr := errors.New("simulated")
+		r, err := NewVerifyingReader(iotest.ErrReader(srcErr), crypto.HashAlgorithm_SHA256, digest[:])
+		require.NoError(t, err)
+		_, err = io.ReadAll(r)
+		assert.ErrorIs(t, err, srcErr)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := NewVerifyingReader(strings.NewReader(data), "MD5", digest[:])
+		assert.ErrorIs(t, err, crypto.ErrUnsupportedAlgorithm)
+	})
+}
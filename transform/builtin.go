@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+
+	encv1 "github.com/dapr/kit/schemes/enc/v1"
+)
+
+// Gzip returns a Transformer that compresses data with gzip, using the given compression level.
+func Gzip(level int) Transformer {
+	return gzipTransformer{level: level}
+}
+
+type gzipTransformer struct {
+	level int
+}
+
+func (t gzipTransformer) Encode(in io.Reader) (io.ReadCloser, error) {
+	// Validate the level before starting the goroutine
+	if _, err := gzip.NewWriterLevel(io.Discard, t.level); err != nil {
+		return nil, err
+	}
+	return pipeWriter(func(w io.Writer) error {
+		zw, _ := gzip.NewWriterLevel(w, t.level)
+		_, err := io.Copy(zw, in)
+		if err != nil {
+			return err
+		}
+		return zw.Close()
+	}), nil
+}
+
+func (t gzipTransformer) Decode(in io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(in)
+}
+
+func (t gzipTransformer) ContentType(string) string {
+	return "application/gzip"
+}
+
+// Base64 returns a Transformer that encodes data with the given base64 encoding.
+func Base64(enc *base64.Encoding) Transformer {
+	return base64Transformer{enc: enc}
+}
+
+type base64Transformer struct {
+	enc *base64.Encoding
+}
+
+func (t base64Transformer) Encode(in io.Reader) (io.ReadCloser, error) {
+	return pipeWriter(func(w io.Writer) error {
+		bw := base64.NewEncoder(t.enc, w)
+		_, err := io.Copy(bw, in)
+		if err != nil {
+			return err
+		}
+		return bw.Close()
+	}), nil
+}
+
+func (t base64Transformer) Decode(in io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(base64.NewDecoder(t.enc, in)), nil
+}
+
+func (t base64Transformer) ContentType(string) string {
+	return "text/plain"
+}
+
+// Encrypt returns a Transformer that encrypts data using the "dapr.io/enc/v1" scheme.
+func Encrypt(encOpts encv1.EncryptOptions, decOpts encv1.DecryptOptions) Transformer {
+	return encryptTransformer{encOpts: encOpts, decOpts: decOpts}
+}
+
+type encryptTransformer struct {
+	encOpts encv1.EncryptOptions
+	decOpts encv1.DecryptOptions
+}
+
+func (t encryptTransformer) Encode(in io.Reader) (io.ReadCloser, error) {
+	r, err := encv1.Encrypt(in, t.encOpts)
+	if err != nil {
+		return nil, err
+	}
+	return readCloser(r), nil
+}
+
+func (t encryptTransformer) Decode(in io.Reader) (io.ReadCloser, error) {
+	r, err := encv1.Decrypt(in, t.decOpts)
+	if err != nil {
+		return nil, err
+	}
+	return readCloser(r), nil
+}
+
+func (t encryptTransformer) ContentType(string) string {
+	return "application/octet-stream"
+}
+
+// ConvertFn converts a payload from a format to another.
+type ConvertFn func(data []byte) ([]byte, error)
+
+// Convert returns a Transformer that converts payloads to contentType with encode, and back with decode.
+// Because conversions usually need the entire payload, data is buffered in memory.
+func Convert(contentType string, encode ConvertFn, decode ConvertFn) Transformer {
+	return convertTransformer{contentType: contentType, encode: encode, decode: decode}
+}
+
+type convertTransformer struct {
+	contentType string
+	encode      ConvertFn
+	decode      ConvertFn
+}
+
+func (t convertTransformer) Encode(in io.Reader) (io.ReadCloser, error) {
+	return convert(in, t.encode)
+}
+
+func (t convertTransformer) Decode(in io.Reader) (io.ReadCloser, error) {
+	return convert(in, t.decode)
+}
+
+func (t convertTransformer) ContentType(string) string {
+	return t.contentType
+}
+
+func convert(in io.Reader, fn ConvertFn) (io.ReadCloser, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	data, err = fn(data)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/dapr/kit/crypto"
+	encv1 "github.com/dapr/kit/schemes/enc/v1"
+)
+
+// ErrUnknownTransformer is returned when a pipeline configuration references a transformer that isn't registered.
+var ErrUnknownTransformer = errors.New("unknown transformer")
+
+// Factory creates a Transformer from its metadata.
+type Factory func(metadata map[string]string) (Transformer, error)
+
+// Config is the configuration of a step of a pipeline.
+type Config struct {
+	// Name of the transformer in the registry.
+	Name string `mapstructure:"name" json:"name"`
+	// Metadata passed to the transformer's factory.
+	Metadata map[string]string `mapstructure:"metadata" json:"metadata,omitempty"`
+}
+
+var (
+	factories = map[string]Factory{
+		"gzip":    newGzipFromMetadata,
+		"base64":  newBase64FromMetadata,
+		"encrypt": newEncryptFromMetadata,
+	}
+	pipelines    = map[string]*Pipeline{}
+	registryLock sync.RWMutex
+)
+
+// Register adds a transformer factory to the registry, replacing any existing one with the same name.
+// The built-in transformers are "gzip", "base64", and "encrypt".
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	factories[name] = factory
+}
+
+// New returns a new Pipeline from the configuration of its steps.
+func New(cfgs []Config) (*Pipeline, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	transformers := make([]Transformer, len(cfgs))
+	for i, cfg := range cfgs {
+		factory, ok := factories[cfg.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTransformer, cfg.Name)
+		}
+		t, err := factory(cfg.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create transformer '%s': %w", cfg.Name, err)
+		}
+		transformers[i] = t
+	}
+	return NewPipeline(transformers...), nil
+}
+
+// RegisterPipeline registers a pipeline with a name, so it can be retrieved with GetPipeline.
+func RegisterPipeline(name string, p *Pipeline) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	pipelines[name] = p
+}
+
+// GetPipeline returns the pipeline registered with the given name.
+func GetPipeline(name string) (*Pipeline, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	p, ok := pipelines[name]
+	return p, ok
+}
+
+// newGzipFromMetadata creates a gzip transformer. Metadata: "level" (optional, between 1 and 9).
+func newGzipFromMetadata(md map[string]string) (Transformer, error) {
+	level := gzip.DefaultCompression
+	if v := md["level"]; v != "" {
+		var err error
+		level, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level: %w", err)
+		}
+		if level < gzip.BestSpeed || level > gzip.BestCompression {
+			return nil, fmt.Errorf("invalid level: %d", level)
+		}
+	}
+	return Gzip(level), nil
+}
+
+// newBase64FromMetadata creates a base64 transformer. Metadata: "encoding" (optional, one of "std", "url", "rawstd",
+// "rawurl"; default is "std").
+func newBase64FromMetadata(md map[string]string) (Transformer, error) {
+	switch md["encoding"] {
+	case "", "std":
+		return Base64(base64.StdEncoding), nil
+	case "url":
+		return Base64(base64.URLEncoding), nil
+	case "rawstd":
+		return Base64(base64.RawStdEncoding), nil
+	case "rawurl":
+		return Base64(base64.RawURLEncoding), nil
+	default:
+		return nil, fmt.Errorf("invalid encoding: %s", md["encoding"])
+	}
+}
+
+// newEncryptFromMetadata creates an encrypt transformer that wraps keys with A256KW. Metadata: "key" (required,
+// base64-encoded 256-bit key) and "keyName" (optional).
+func newEncryptFromMetadata(md map[string]string) (Transformer, error) {
+	keyBytes, err := base64.StdEncoding.DecodeString(md["key"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	if len(keyBytes) != 32 {
+		return nil, errors.New("invalid key: must be 256-bit long")
+	}
+	key, err := jwk.FromRaw(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+
+	keyName := md["keyName"]
+	if keyName == "" {
+		keyName = "transform"
+	}
+
+	return Encrypt(
+		encv1.EncryptOptions{
+			KeyName:   keyName,
+			Algorithm: encv1.KeyAlgorithmAES256KW,
+			WrapKeyFn: func(plaintextKey []byte, algorithm string, _ string, nonce []byte) ([]byte, []byte, error) {
+				return crypto.EncryptSymmetric(plaintextKey, algorithm, key, nonce, nil)
+			},
+		},
+		encv1.DecryptOptions{
+			UnwrapKeyFn: func(wrappedKey []byte, algorithm string, _ string, nonce []byte, tag []byte) ([]byte, error) {
+				return crypto.DecryptSymmetric(wrappedKey, algorithm, key, nonce, tag, nil)
+			},
+		},
+	), nil
+}
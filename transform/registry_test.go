@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/config"
+)
+
+type upperTransformer struct{}
+
+func (upperTransformer) Encode(in io.Reader) (io.ReadCloser, error) {
+	data, err := io.ReadAll(in)
+	return io.NopCloser(bytes.NewReader(bytes.ToUpper(data))), err
+}
+
+func (upperTransformer) Decode(in io.Reader) (io.ReadCloser, error) {
+	data, err := io.ReadAll(in)
+	return io.NopCloser(bytes.NewReader(bytes.ToLower(data))), err
+}
+
+func TestRegistry(t *testing.T) {
+	Register("upper", func(map[string]string) (Transformer, error) {
+		return upperTransformer{}, nil
+	})
+	t.Cleanup(func() {
+		registryLock.Lock()
+		delete(factories, "upper")
+		delete(pipelines, "test-pipeline")
+		registryLock.Unlock()
+	})
+
+	t.Run("pipeline from configuration", func(t *testing.T) {
+		var cfgs []Config
+		require.NoError(t, config.Decode([]map[string]any{
+			{"name": "upper"},
+			{"name": "base64", "metadata": map[string]string{"encoding": "rawurl"}},
+		}, &cfgs))
+
+		p, err := New(cfgs)
+		require.NoError(t, err)
+		encoded, err := EncodeBytes(p, []byte("hi"))
+		require.NoError(t, err)
+		assert.Equal(t, "SEk", string(encoded))
+
+		RegisterPipeline("test-pipeline", p)
+		got, ok := GetPipeline("test-pipeline")
+		require.True(t, ok)
+		assert.Same(t, p, got)
+
+		_, ok = GetPipeline("missing")
+		assert.False(t, ok)
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		_, err := New([]Config{{Name: "nope"}})
+		require.ErrorIs(t, err, ErrUnknownTransformer)
+
+		_, err = New([]Config{{Name: "gzip", Metadata: map[string]string{"level": "12"}}})
+		require.ErrorContains(t, err, "invalid level")
+
+		_, err = New([]Config{{Name: "base64", Metadata: map[string]string{"encoding": "hex"}}})
+		require.ErrorContains(t, err, "invalid encoding")
+
+		_, err = New([]Config{{Name: "encrypt", Metadata: map[string]string{"key": "c2hvcnQ="}}})
+		require.ErrorContains(t, err, "256-bit")
+	})
+}
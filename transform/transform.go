@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package transform contains a pipeline of transformations applied to request and response payloads, such as
+// compression, encryption, and base64-encoding.
+// Transformers are chainable and work on streams. Pipelines can be built in code or declared via configuration,
+// using the names of the transformers in the registry, and they can be registered with a name to be shared.
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Transformer transforms a stream of data.
+// Transformations can run in background goroutines that write to the returned stream, so callers must always close
+// the streams returned by Encode and Decode, even if they're not read until the end; closing them doesn't close in.
+type Transformer interface {
+	// Encode returns a stream with the data read from in, transformed.
+	Encode(in io.Reader) (io.ReadCloser, error)
+	// Decode returns a stream with the data read from in, with the transformation reversed.
+	Decode(in io.Reader) (io.ReadCloser, error)
+}
+
+// ContentTyper is implemented by transformers that change the content type of the payload.
+type ContentTyper interface {
+	// ContentType returns the content type of the payload after the transformation, given the one before.
+	ContentType(in string) string
+}
+
+// Pipeline is a chain of transformers.
+// When encoding, transformers are applied in order; when decoding, they are applied in reverse order.
+type Pipeline struct {
+	transformers []Transformer
+}
+
+// NewPipeline returns a new Pipeline with the given transformers.
+func NewPipeline(transformers ...Transformer) *Pipeline {
+	return &Pipeline{
+		transformers: transformers,
+	}
+}
+
+// Len returns the number of transformers in the pipeline.
+func (p *Pipeline) Len() int {
+	return len(p.transformers)
+}
+
+// Encode implements Transformer.
+// Closing the returned stream closes the streams of all the transformers in the pipeline.
+func (p *Pipeline) Encode(in io.Reader) (io.ReadCloser, error) {
+	res := &pipelineReader{Reader: in}
+	for i, t := range p.transformers {
+		r, err := t.Encode(res.Reader)
+		if err != nil {
+			_ = res.Close()
+			return nil, fmt.Errorf("failed to encode with transformer %d: %w", i, err)
+		}
+		res.Reader = r
+		res.closers = append(res.closers, r)
+	}
+	return res, nil
+}
+
+// Decode implements Transformer.
+// Closing the returned stream closes the streams of all the transformers in the pipeline.
+func (p *Pipeline) Decode(in io.Reader) (io.ReadCloser, error) {
+	res := &pipelineReader{Reader: in}
+	for i := len(p.transformers) - 1; i >= 0; i-- {
+		r, err := p.transformers[i].Decode(res.Reader)
+		if err != nil {
+			_ = res.Close()
+			return nil, fmt.Errorf("failed to decode with transformer %d: %w", i, err)
+		}
+		res.Reader = r
+		res.closers = append(res.closers, r)
+	}
+	return res, nil
+}
+
+// pipelineReader reads from the last stream of a pipeline, and closes the streams of all transformers when closed.
+type pipelineReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close implements io.Closer.
+func (r *pipelineReader) Close() error {
+	var errs []error
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		err := r.closers[i].Close()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	r.closers = nil
+	return errors.Join(errs...)
+}
+
+// ContentType implements ContentTyper, returning the content type after all transformers are applied.
+func (p *Pipeline) ContentType(in string) string {
+	for _, t := range p.transformers {
+		if ct, ok := t.(ContentTyper); ok {
+			in = ct.ContentType(in)
+		}
+	}
+	return in
+}
+
+// EncodeBytes encodes data with the transformer and returns the result.
+func EncodeBytes(t Transformer, data []byte) ([]byte, error) {
+	r, err := t.Encode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// DecodeBytes decodes data with the transformer and returns the result.
+func DecodeBytes(t Transformer, data []byte) ([]byte, error) {
+	r, err := t.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// pipeWriter returns a reader with the data written by fn to w.
+// fn is invoked in a background goroutine; its error, if any, is returned by the reader. Closing the reader makes
+// writes to w fail, so the goroutine returns.
+func pipeWriter(fn func(w io.Writer) error) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(fn(pw))
+	}()
+	return pr
+}
+
+// readCloser returns r as an io.ReadCloser, adding a Close method that does nothing if r doesn't have one.
+func readCloser(r io.Reader) io.ReadCloser {
+	if rc, ok := r.(io.ReadCloser); ok {
+		return rc
+	}
+	return io.NopCloser(r)
+}
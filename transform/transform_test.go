@@ -0,0 +1,164 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline(t *testing.T) {
+	data := bytes.Repeat([]byte("hello world "), 10000)
+
+	p := NewPipeline(Gzip(gzip.BestCompression), Base64(base64.StdEncoding))
+	assert.Equal(t, 2, p.Len())
+	assert.Equal(t, "text/plain", p.ContentType("application/json"))
+
+	encoded, err := EncodeBytes(p, data)
+	require.NoError(t, err)
+	assert.Less(t, len(encoded), len(data))
+
+	// The output is base64 of gzip
+	raw, err := base64.StdEncoding.DecodeString(string(encoded))
+	require.NoError(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+	unzipped, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, data, unzipped)
+
+	decoded, err := DecodeBytes(p, encoded)
+	require.NoError(t, err)
+	assert.Equal(t, data, decoded)
+}
+
+func TestStreamingErrors(t *testing.T) {
+	srcErr := errors.New("simulated")
+	p := NewPipeline(Gzip(gzip.DefaultCompression), Base64(base64.URLEncoding))
+
+	r, err := p.Encode(iotest.ErrReader(srcErr))
+	require.NoError(t, err)
+	defer r.Close()
+	_, err = io.ReadAll(r)
+	require.ErrorIs(t, err, srcErr)
+
+	_, err = DecodeBytes(p, []byte("not base64!"))
+	require.Error(t, err)
+
+	_, err = Gzip(42).Encode(bytes.NewReader(nil))
+	require.Error(t, err)
+}
+
+// zeroReader is an endless stream of zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestCloseStopsGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	p := NewPipeline(Gzip(gzip.BestSpeed), Base64(base64.StdEncoding))
+	r, err := p.Encode(zeroReader{})
+	require.NoError(t, err)
+	_, err = io.ReadFull(r, make([]byte, 100))
+	require.NoError(t, err)
+	assert.Greater(t, runtime.NumGoroutine(), before)
+
+	// Closing the stream before reading it until the end stops the background goroutines
+	// (Eventually is not used, since it runs the condition in another goroutine)
+	require.NoError(t, r.Close())
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+	_, err = r.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+func TestEncrypt(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := io.ReadFull(rand.Reader, key)
+	require.NoError(t, err)
+
+	p, err := New([]Config{
+		{Name: "gzip", Metadata: map[string]string{"level": "9"}},
+		{Name: "encrypt", Metadata: map[string]string{"key": base64.StdEncoding.EncodeToString(key)}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "application/octet-stream", p.ContentType("application/json"))
+
+	data := []byte("secret message")
+	encoded, err := EncodeBytes(p, data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "secret")
+
+	decoded, err := DecodeBytes(p, encoded)
+	require.NoError(t, err)
+	assert.Equal(t, data, decoded)
+
+	// A different key can't decrypt the data
+	otherKey := make([]byte, 32)
+	other, err := New([]Config{
+		{Name: "gzip"},
+		{Name: "encrypt", Metadata: map[string]string{"key": base64.StdEncoding.EncodeToString(otherKey)}},
+	})
+	require.NoError(t, err)
+	_, err = DecodeBytes(other, encoded)
+	require.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	// Converts JSON to a compact form, and back to an indented form
+	c := Convert("application/json",
+		func(data []byte) ([]byte, error) {
+			var buf bytes.Buffer
+			err := json.Compact(&buf, data)
+			return buf.Bytes(), err
+		},
+		func(data []byte) ([]byte, error) {
+			var buf bytes.Buffer
+			err := json.Indent(&buf, data, "", " ")
+			return buf.Bytes(), err
+		},
+	)
+
+	encoded, err := EncodeBytes(c, []byte("{ \"a\" : 1 }"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(encoded))
+
+	decoded, err := DecodeBytes(c, encoded)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n \"a\": 1\n}", string(decoded))
+
+	_, err = EncodeBytes(c, []byte("{"))
+	require.Error(t, err)
+}
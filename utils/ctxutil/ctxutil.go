@@ -0,0 +1,50 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ctxutil contains helpers to store values in a context.Context with typed keys, and the request scope that is
+// propagated across services through HTTP headers and gRPC metadata.
+package ctxutil
+
+import (
+	"context"
+)
+
+// key is the context key for values of type T.
+// Since each instantiation is a distinct type, values of different types never collide.
+type key[T any] struct{}
+
+// With returns a new Context, derived from ctx, which carries value.
+// Values are keyed by their type, so callers should use types they define, rather than built-in types such as
+// string, which could be set by other packages too.
+func With[T any](ctx context.Context, value T) context.Context {
+	return context.WithValue(ctx, key[T]{}, value)
+}
+
+// From returns the value of type T carried by ctx, and false if there's none.
+func From[T any](ctx context.Context) (T, bool) {
+	if ctx == nil {
+		var zero T
+		return zero, false
+	}
+	v, ok := ctx.Value(key[T]{}).(T)
+	return v, ok
+}
+
+// FromOrDefault returns the value of type T carried by ctx, or def if there's none.
+func FromOrDefault[T any](ctx context.Context, def T) T {
+	v, ok := From[T](ctx)
+	if !ok {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ctxutil
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+type (
+	userID   string
+	tenantID string
+)
+
+func TestWithFrom(t *testing.T) {
+	ctx := context.Background()
+	_, ok := From[userID](ctx)
+	assert.False(t, ok)
+	assert.Equal(t, userID("anonymous"), FromOrDefault(ctx, userID("anonymous")))
+
+	ctx = With(ctx, userID("alice"))
+	ctx = With(ctx, tenantID("acme"))
+
+	// Values of different types don't collide, even with the same underlying type
+	u, ok := From[userID](ctx)
+	require.True(t, ok)
+	assert.Equal(t, userID("alice"), u)
+	tn, ok := From[tenantID](ctx)
+	require.True(t, ok)
+	assert.Equal(t, tenantID("acme"), tn)
+
+	// Values of the same type are overridden
+	ctx = With(ctx, userID("bob"))
+	assert.Equal(t, userID("bob"), FromOrDefault(ctx, userID("")))
+
+	//nolint:staticcheck
+	_, ok = From[userID](nil)
+	assert.False(t, ok)
+}
+
+func TestScopeHTTP(t *testing.T) {
+	scope := RequestScope{AppID: "myapp", Namespace: "default", CorrelationID: "abc123"}
+
+	h := http.Header{}
+	InjectHTTP(WithScope(context.Background(), scope), h)
+	assert.Equal(t, "myapp", h.Get(HeaderAppID))
+	assert.Equal(t, "default", h.Get(HeaderNamespace))
+	assert.Equal(t, "abc123", h.Get(HeaderCorrelationID))
+	// The routing header used by Dapr's service invocation is not touched
+	assert.Empty(t, h.Get("dapr-app-id"))
+
+	got, ok := ScopeFrom(ExtractHTTP(context.Background(), h))
+	require.True(t, ok)
+	assert.Equal(t, scope, got)
+
+	// Only the fields that are set are propagated
+	h = http.Header{}
+	InjectHTTP(WithScope(context.Background(), RequestScope{AppID: "myapp"}), h)
+	assert.Len(t, h, 1)
+
+	// Nothing is set without a scope
+	h = http.Header{}
+	InjectHTTP(context.Background(), h)
+	assert.Empty(t, h)
+	ctx := context.Background()
+	assert.Equal(t, ctx, ExtractHTTP(ctx, h))
+	h.Set("dapr-app-id", "target")
+	assert.Equal(t, ctx, ExtractHTTP(ctx, h))
+}
+
+func TestScopeGRPC(t *testing.T) {
+	scope := RequestScope{AppID: "myapp", Namespace: "default", CorrelationID: "abc123"}
+
+	ctx := InjectGRPC(WithScope(context.Background(), scope))
+	md, ok := metadata.FromOutgoingContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, []string{"myapp"}, md.Get(HeaderAppID))
+
+	// Simulate the server side
+	serverCtx := metadata.NewIncomingContext(context.Background(), md)
+	got, ok := ScopeFrom(ExtractGRPC(serverCtx))
+	require.True(t, ok)
+	assert.Equal(t, scope, got)
+
+	// Nothing is set without a scope
+	ctx = context.Background()
+	assert.Equal(t, ctx, InjectGRPC(ctx))
+	assert.Equal(t, ctx, ExtractGRPC(ctx))
+	_, ok = ScopeFrom(ExtractGRPC(metadata.NewIncomingContext(ctx, metadata.MD{})))
+	assert.False(t, ok)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ctxutil
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Names of the HTTP headers (or gRPC metadata keys) that carry the request scope.
+// They use a "dapr-scope-" prefix so they don't collide with headers that Dapr uses for routing, such as
+// "dapr-app-id", which identifies the target of a service invocation.
+const (
+	HeaderAppID         = "dapr-scope-app-id"
+	HeaderNamespace     = "dapr-scope-namespace"
+	HeaderCorrelationID = "dapr-scope-correlation-id"
+)
+
+// RequestScope contains the information that identifies the scope of a request, which is propagated across services.
+type RequestScope struct {
+	// AppID is the ID of the app that originated the operation. It's propagated unchanged to all the downstream
+	// requests, so it never identifies the target of a request.
+	AppID string
+	// Namespace of the app that originated the operation.
+	Namespace string
+	// CorrelationID correlates all the requests that are part of the same operation.
+	CorrelationID string
+}
+
+// IsZero returns true if no field of the scope is set.
+func (s RequestScope) IsZero() bool {
+	return s == RequestScope{}
+}
+
+// pairs returns the header names and values of the fields that are set.
+func (s RequestScope) pairs() []string {
+	res := make([]string, 0, 6)
+	if s.AppID != "" {
+		res = append(res, HeaderAppID, s.AppID)
+	}
+	if s.Namespace != "" {
+		res = append(res, HeaderNamespace, s.Namespace)
+	}
+	if s.CorrelationID != "" {
+		res = append(res, HeaderCorrelationID, s.CorrelationID)
+	}
+	return res
+}
+
+// WithScope returns a new Context, derived from ctx, which carries the request scope.
+func WithScope(ctx context.Context, s RequestScope) context.Context {
+	return With(ctx, s)
+}
+
+// ScopeFrom returns the request scope carried by ctx, and false if there's none.
+func ScopeFrom(ctx context.Context) (RequestScope, bool) {
+	return From[RequestScope](ctx)
+}
+
+// InjectHTTP sets the headers for the request scope carried by ctx on h.
+func InjectHTTP(ctx context.Context, h http.Header) {
+	s, _ := ScopeFrom(ctx)
+	p := s.pairs()
+	for i := 0; i < len(p); i += 2 {
+		h.Set(p[i], p[i+1])
+	}
+}
+
+// ExtractHTTP returns a new Context, derived from ctx, which carries the request scope in the headers h.
+// If the headers don't contain any field of the scope, ctx is returned as-is.
+func ExtractHTTP(ctx context.Context, h http.Header) context.Context {
+	s := RequestScope{
+		AppID:         h.Get(HeaderAppID),
+		Namespace:     h.Get(HeaderNamespace),
+		CorrelationID: h.Get(HeaderCorrelationID),
+	}
+	if s.IsZero() {
+		return ctx
+	}
+	return WithScope(ctx, s)
+}
+
+// InjectGRPC returns a new Context, derived from ctx, whose outgoing gRPC metadata contains the request scope carried
+// by ctx.
+func InjectGRPC(ctx context.Context) context.Context {
+	s, _ := ScopeFrom(ctx)
+	p := s.pairs()
+	if len(p) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, p...)
+}
+
+// ExtractGRPC returns a new Context, derived from ctx, which carries the request scope in the incoming gRPC metadata
+// of ctx.
+// If the metadata doesn't contain any field of the scope, ctx is returned as-is.
+func ExtractGRPC(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	s := RequestScope{
+		AppID:         firstValue(md, HeaderAppID),
+		Namespace:     firstValue(md, HeaderNamespace),
+		CorrelationID: firstValue(md, HeaderCorrelationID),
+	}
+	if s.IsZero() {
+		return ctx
+	}
+	return WithScope(ctx, s)
+}
+
+func firstValue(md metadata.MD, key string) string {
+	v := md.Get(key)
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseWriter(t *testing.T) {
+	t.Run("implicit status", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		w := NewResponseWriter(rec)
+		assert.False(t, w.WroteHeader())
+		assert.Equal(t, http.StatusOK, w.StatusCode())
+
+		_, err := w.Write([]byte("hello"))
+		require.NoError(t, err)
+		_, err = w.Write([]byte(" world"))
+		require.NoError(t, err)
+		w.Flush()
+
+		assert.True(t, w.WroteHeader())
+		assert.Equal(t, http.StatusOK, w.StatusCode())
+		assert.Equal(t, int64(11), w.BytesWritten())
+		assert.True(t, rec.Flushed)
+		assert.Equal(t, "hello world", rec.Body.String())
+	})
+
+	t.Run("explicit status", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		w := NewResponseWriter(rec)
+		w.WriteHeader(http.StatusTeapot)
+		w.WriteHeader(http.StatusInternalServerError)
+
+		assert.True(t, w.WroteHeader())
+		assert.Equal(t, http.StatusTeapot, w.StatusCode())
+		assert.Equal(t, http.StatusTeapot, rec.Code)
+		assert.Same(t, rec, w.Unwrap())
+	})
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Option holds a value that may be absent.
+// The zero value is an empty Option.
+type Option[T any] struct {
+	value T
+	ok    bool
+}
+
+// Some returns an Option with value v.
+func Some[T any](v T) Option[T] {
+	return Option[T]{value: v, ok: true}
+}
+
+// None returns an empty Option.
+func None[T any]() Option[T] {
+	return Option[T]{}
+}
+
+// OptionFromPtr returns an Option with the value pointed by p, or an empty Option if p is nil.
+func OptionFromPtr[T any](p *T) Option[T] {
+	if p == nil {
+		return None[T]()
+	}
+	return Some(*p)
+}
+
+// Get returns the value and true, or the zero value and false if the Option is empty.
+func (o Option[T]) Get() (T, bool) {
+	return o.value, o.ok
+}
+
+// IsSome returns true if the Option has a value.
+func (o Option[T]) IsSome() bool {
+	return o.ok
+}
+
+// IsNone returns true if the Option is empty.
+func (o Option[T]) IsNone() bool {
+	return !o.ok
+}
+
+// OrElse returns the value, or v if the Option is empty.
+func (o Option[T]) OrElse(v T) T {
+	if !o.ok {
+		return v
+	}
+	return o.value
+}
+
+// Must returns the value, and panics if the Option is empty.
+func (o Option[T]) Must() T {
+	if !o.ok {
+		panic("option is empty")
+	}
+	return o.value
+}
+
+// Ptr returns a pointer to a copy of the value, or nil if the Option is empty.
+func (o Option[T]) Ptr() *T {
+	if !o.ok {
+		return nil
+	}
+	v := o.value
+	return &v
+}
+
+// MapOption returns an Option with the value of o transformed by fn, or an empty Option if o is empty.
+func MapOption[T any, U any](o Option[T], fn func(T) U) Option[U] {
+	if !o.ok {
+		return None[U]()
+	}
+	return Some(fn(o.value))
+}
+
+// MarshalJSON implements json.Marshaler.
+// Empty Options are encoded as null.
+func (o Option[T]) MarshalJSON() ([]byte, error) {
+	if !o.ok {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// A null value results in an empty Option.
+func (o *Option[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*o = None[T]()
+		return nil
+	}
+
+	var v T
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*o = Some(v)
+	return nil
+}
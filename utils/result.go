@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package utils contains small generic types shared by the other packages.
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Result holds either a value or an error, for example to send the outcome of an asynchronous operation through
+// a channel.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Ok returns a successful Result with value v.
+func Ok[T any](v T) Result[T] {
+	return Result[T]{Value: v}
+}
+
+// Err returns a failed Result with error err.
+func Err[T any](err error) Result[T] {
+	return Result[T]{Err: err}
+}
+
+// ResultOf returns a Result from the values returned by a function, such as `utils.ResultOf(os.ReadFile(name))`.
+func ResultOf[T any](v T, err error) Result[T] {
+	return Result[T]{Value: v, Err: err}
+}
+
+// Get returns the value and the error.
+func (r Result[T]) Get() (T, error) {
+	return r.Value, r.Err
+}
+
+// IsOk returns true if the result doesn't have an error.
+func (r Result[T]) IsOk() bool {
+	return r.Err == nil
+}
+
+// OrElse returns the value if the result is successful, or v otherwise.
+func (r Result[T]) OrElse(v T) T {
+	if r.Err != nil {
+		return v
+	}
+	return r.Value
+}
+
+// Must returns the value, and panics if the result has an error.
+func (r Result[T]) Must() T {
+	if r.Err != nil {
+		panic(fmt.Sprintf("result has an error: %v", r.Err))
+	}
+	return r.Value
+}
+
+// MapResult returns a Result with the value of r transformed by fn, or with the error of r if it failed.
+func MapResult[T any, U any](r Result[T], fn func(T) U) Result[U] {
+	if r.Err != nil {
+		return Err[U](r.Err)
+	}
+	return Ok(fn(r.Value))
+}
+
+type resultJSON[T any] struct {
+	Value *T     `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+// Successful results are encoded as `{"value":...}`, and failed ones as `{"error":"message"}`.
+func (r Result[T]) MarshalJSON() ([]byte, error) {
+	if r.Err != nil {
+		return json.Marshal(resultJSON[T]{Error: r.Err.Error()})
+	}
+	return json.Marshal(resultJSON[T]{Value: &r.Value})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Because only the message of errors is encoded, the error of decoded results only has the original message.
+func (r *Result[T]) UnmarshalJSON(data []byte) error {
+	var v resultJSON[T]
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+
+	*r = Result[T]{}
+	if v.Error != "" {
+		r.Err = errors.New(v.Error)
+	} else if v.Value != nil {
+		r.Value = *v.Value
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResult(t *testing.T) {
+	errTest := errors.New("test error")
+
+	t.Run("ok", func(t *testing.T) {
+		r := Ok(42)
+		assert.True(t, r.IsOk())
+		v, err := r.Get()
+		require.NoError(t, err)
+		assert.Equal(t, 42, v)
+		assert.Equal(t, 42, r.OrElse(1))
+		assert.Equal(t, 42, r.Must())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		r := Err[int](errTest)
+		assert.False(t, r.IsOk())
+		_, err := r.Get()
+		assert.ErrorIs(t, err, errTest)
+		assert.Equal(t, 1, r.OrElse(1))
+		assert.Panics(t, func() { r.Must() })
+	})
+
+	t.Run("result of", func(t *testing.T) {
+		r := ResultOf(strconv.Atoi("12"))
+		assert.Equal(t, 12, r.Must())
+
+		r = ResultOf(strconv.Atoi("nope"))
+		assert.Error(t, r.Err)
+	})
+
+	t.Run("map", func(t *testing.T) {
+		r := MapResult(Ok(2), strconv.Itoa)
+		assert.Equal(t, "2", r.Must())
+
+		r = MapResult(Err[int](errTest), strconv.Itoa)
+		assert.ErrorIs(t, r.Err, errTest)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		b, err := json.Marshal(Ok("hello"))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"value":"hello"}`, string(b))
+
+		var r Result[string]
+		require.NoError(t, json.Unmarshal(b, &r))
+		assert.Equal(t, Ok("hello"), r)
+
+		b, err = json.Marshal(Err[string](errTest))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"test error"}`, string(b))
+
+		require.NoError(t, json.Unmarshal(b, &r))
+		require.Error(t, r.Err)
+		assert.Equal(t, "test error", r.Err.Error())
+		assert.Empty(t, r.Value)
+	})
+}
+
+func TestOption(t *testing.T) {
+	t.Run("some", func(t *testing.T) {
+		o := Some("a")
+		assert.True(t, o.IsSome())
+		assert.False(t, o.IsNone())
+		v, ok := o.Get()
+		assert.True(t, ok)
+		assert.Equal(t, "a", v)
+		assert.Equal(t, "a", o.OrElse("b"))
+		assert.Equal(t, "a", o.Must())
+		assert.Equal(t, "a", *o.Ptr())
+	})
+
+	t.Run("none", func(t *testing.T) {
+		o := None[string]()
+		assert.True(t, o.IsNone())
+		_, ok := o.Get()
+		assert.False(t, ok)
+		assert.Equal(t, "b", o.OrElse("b"))
+		assert.Panics(t, func() { o.Must() })
+		assert.Nil(t, o.Ptr())
+
+		var zero Option[string]
+		assert.Equal(t, o, zero)
+	})
+
+	t.Run("from pointer", func(t *testing.T) {
+		v := 3
+		assert.Equal(t, Some(3), OptionFromPtr(&v))
+		assert.Equal(t, None[int](), OptionFromPtr[int](nil))
+	})
+
+	t.Run("map", func(t *testing.T) {
+		assert.Equal(t, Some("3"), MapOption(Some(3), strconv.Itoa))
+		assert.Equal(t, None[string](), MapOption(None[int](), strconv.Itoa))
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		type obj struct {
+			A Option[int] `json:"a"`
+			B Option[int] `json:"b"`
+		}
+
+		b, err := json.Marshal(obj{A: Some(1)})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"a":1,"b":null}`, string(b))
+
+		var res obj
+		require.NoError(t, json.Unmarshal([]byte(`{"a":0,"b":null}`), &res))
+		assert.Equal(t, Some(0), res.A)
+		assert.Equal(t, None[int](), res.B)
+
+		assert.Error(t, json.Unmarshal([]byte(`{"a":"x"}`), &res))
+	})
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watchdog
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/dapr/kit/logger"
+)
+
+// ExitFlushTimeout is the maximum time ExitAction waits for the logs to be flushed before terminating the process.
+const ExitFlushTimeout = 5 * time.Second
+
+// exitFn and shutdownLogsFn are invoked by ExitAction. They can be replaced in tests.
+var (
+	exitFn         = os.Exit
+	shutdownLogsFn = logger.Shutdown
+)
+
+// LogAction returns an Action that logs stalls as errors, including the stack of the stuck goroutine.
+func LogAction(log logger.Logger) Action {
+	return func(s Stall) {
+		log.WithFields(map[string]any{
+			"watchdog": s.Name,
+			"deadline": s.Deadline.String(),
+			"elapsed":  s.Elapsed.String(),
+		}).Errorf("Watchdog '%s' missed its deadline of %v; last pet %v ago. Stack of the goroutine:\n%s", s.Name, s.Deadline, s.Elapsed, s.Stack)
+	}
+}
+
+// CountAction returns an Action that invokes inc with the name of the handle, for example to increment a metric.
+func CountAction(inc func(name string)) Action {
+	return func(s Stall) {
+		inc(s.Name)
+	}
+}
+
+// ExitAction returns an Action that terminates the process with the given exit code, so it's restarted by its
+// supervisor (for example, Kubernetes). It should be the last action.
+// Before exiting, it flushes the logs (including the ones written by LogAction), waiting up to ExitFlushTimeout, as the
+// process may be stuck in a way that prevents the sinks from completing.
+func ExitAction(code int) Action {
+	return func(Stall) {
+		ctx, cancel := context.WithTimeout(context.Background(), ExitFlushTimeout)
+		_, _ = shutdownLogsFn(ctx)
+		cancel()
+		exitFn(code)
+	}
+}
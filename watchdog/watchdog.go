@@ -0,0 +1,252 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package watchdog detects loops that are stuck, catching silent hangs in production.
+// Long-running loops register with a Watchdog and must invoke Pet on their handle within a deadline; when a deadline
+// is missed, the watchdog invokes the configured actions, for example to log the stack of the stuck goroutine,
+// increment a metric, or exit the process so it's restarted by its supervisor.
+package watchdog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kclock "k8s.io/utils/clock"
+)
+
+// DefaultCheckInterval is the default interval at which the watchdog checks the deadlines.
+const DefaultCheckInterval = time.Second
+
+// ErrWatchdogRunning is returned when starting a watchdog that is already running.
+var ErrWatchdogRunning = errors.New("watchdog is already running")
+
+// Stall describes a missed deadline.
+type Stall struct {
+	// Name of the handle.
+	Name string
+	// Deadline of the handle.
+	Deadline time.Duration
+	// LastPet is the time the handle was last pet, or registered.
+	LastPet time.Time
+	// Elapsed is the time since LastPet.
+	Elapsed time.Duration
+	// Stack is the stack of the goroutine that registered the handle, if it's still running.
+	Stack string
+}
+
+// Action is invoked when a deadline is missed.
+type Action func(s Stall)
+
+// Options contains the options for a Watchdog.
+type Options struct {
+	// CheckInterval is the interval at which deadlines are checked. Default is DefaultCheckInterval.
+	CheckInterval time.Duration
+	// Actions are invoked, in order, every time a deadline is missed. Actions are invoked once per stall: they are not
+	// invoked again for the same handle until it's pet.
+	Actions []Action
+}
+
+// Watchdog checks that registered loops pet their handles within their deadlines.
+type Watchdog struct {
+	opts  Options
+	clock kclock.WithTicker
+
+	lock    sync.Mutex
+	handles map[*Handle]struct{}
+	running atomic.Bool
+}
+
+// Handle is the registration of a loop with a Watchdog.
+type Handle struct {
+	w           *Watchdog
+	name        string
+	deadline    time.Duration
+	goroutineID uint64
+
+	lastPet atomic.Int64
+	stalled atomic.Bool
+	missed  atomic.Uint64
+}
+
+// New returns a new Watchdog.
+func New(opts Options) *Watchdog {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = DefaultCheckInterval
+	}
+	return &Watchdog{
+		opts:    opts,
+		clock:   kclock.RealClock{},
+		handles: map[*Handle]struct{}{},
+	}
+}
+
+// WithClock sets the clock used by the watchdog. Used for testing.
+func (w *Watchdog) WithClock(clock kclock.WithTicker) *Watchdog {
+	w.clock = clock
+	return w
+}
+
+// Register registers a loop, which must invoke Pet on the returned handle at least once every deadline.
+// It should be invoked from the goroutine that runs the loop, whose stack is included in stalls.
+// Callers must invoke Unregister when the loop is done.
+func (w *Watchdog) Register(name string, deadline time.Duration) *Handle {
+	h := &Handle{
+		w:           w,
+		name:        name,
+		deadline:    deadline,
+		goroutineID: currentGoroutineID(),
+	}
+	h.lastPet.Store(w.clock.Now().UnixNano())
+
+	w.lock.Lock()
+	w.handles[h] = struct{}{}
+	w.lock.Unlock()
+	return h
+}
+
+// Pet signals that the loop is making progress, resetting its deadline.
+func (h *Handle) Pet() {
+	h.lastPet.Store(h.w.clock.Now().UnixNano())
+	h.stalled.Store(false)
+}
+
+// Missed returns the number of deadlines the handle missed. It can be used, for example, to export a metric.
+func (h *Handle) Missed() uint64 {
+	return h.missed.Load()
+}
+
+// Unregister removes the handle from the watchdog.
+func (h *Handle) Unregister() {
+	h.w.lock.Lock()
+	delete(h.w.handles, h)
+	h.w.lock.Unlock()
+}
+
+// Run checks the deadlines until ctx is canceled.
+func (w *Watchdog) Run(ctx context.Context) error {
+	if !w.running.CompareAndSwap(false, true) {
+		return ErrWatchdogRunning
+	}
+	defer w.running.Store(false)
+
+	t := w.clock.NewTicker(w.opts.CheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C():
+			w.check()
+		}
+	}
+}
+
+// check invokes the actions for the handles that missed their deadlines.
+func (w *Watchdog) check() {
+	now := w.clock.Now()
+
+	w.lock.Lock()
+	var stalled []*Handle
+	for h := range w.handles {
+		if h.stalled.Load() {
+			continue
+		}
+		if now.Sub(time.Unix(0, h.lastPet.Load())) > h.deadline {
+			stalled = append(stalled, h)
+		}
+	}
+	w.lock.Unlock()
+
+	if len(stalled) == 0 {
+		return
+	}
+
+	stacks := goroutineStacks()
+	for _, h := range stalled {
+		w.stall(h, now, stacks)
+	}
+}
+
+// stall marks the handle as stalled and invokes the actions, unless it was already marked as stalled or it was pet
+// after now, when its deadline was found to be missed.
+func (w *Watchdog) stall(h *Handle, now time.Time, stacks map[uint64]string) {
+	if !h.stalled.CompareAndSwap(false, true) {
+		return
+	}
+	// The loop may have been pet after the scan: in that case, it's not stalled
+	lastPet := time.Unix(0, h.lastPet.Load())
+	if now.Sub(lastPet) <= h.deadline {
+		h.stalled.CompareAndSwap(true, false)
+		return
+	}
+	h.missed.Add(1)
+	s := Stall{
+		Name:     h.name,
+		Deadline: h.deadline,
+		LastPet:  lastPet,
+		Elapsed:  now.Sub(lastPet),
+		Stack:    stacks[h.goroutineID],
+	}
+	for _, action := range w.opts.Actions {
+		action(s)
+	}
+}
+
+func currentGoroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	id, _ := parseGoroutineID(buf)
+	return id
+}
+
+// goroutineStacks returns the stacks of all goroutines, by ID.
+func goroutineStacks() map[uint64]string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	res := map[uint64]string{}
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if id, ok := parseGoroutineID(stack); ok {
+			res[id] = string(stack)
+		}
+	}
+	return res
+}
+
+// parseGoroutineID parses the goroutine ID from the header of a stack trace, "goroutine 1 [running]:".
+func parseGoroutineID(stack []byte) (uint64, bool) {
+	stack, ok := bytes.CutPrefix(stack, []byte("goroutine "))
+	if !ok {
+		return 0, false
+	}
+	i := bytes.IndexByte(stack, ' ')
+	if i < 0 {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(string(stack[:i]), 10, 64)
+	return id, err == nil
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watchdog
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestParseGoroutineID(t *testing.T) {
+	id, ok := parseGoroutineID([]byte("goroutine 42 [running]:\nmain.main()"))
+	require.True(t, ok)
+	assert.Equal(t, uint64(42), id)
+
+	_, ok = parseGoroutineID([]byte("not a stack"))
+	assert.False(t, ok)
+
+	assert.NotZero(t, currentGoroutineID())
+}
+
+func stuckLoop(h *Handle, blockCh chan struct{}) {
+	<-blockCh
+	h.Pet()
+}
+
+func TestWatchdog(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+
+	var (
+		lock   sync.Mutex
+		stalls []Stall
+		counts = map[string]int{}
+	)
+	w := New(Options{
+		CheckInterval: time.Second,
+		Actions: []Action{
+			func(s Stall) {
+				lock.Lock()
+				stalls = append(stalls, s)
+				lock.Unlock()
+			},
+			CountAction(func(name string) {
+				lock.Lock()
+				counts[name]++
+				lock.Unlock()
+			}),
+		},
+	}).WithClock(clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErrCh := make(chan error, 1)
+	go func() {
+		runErrCh <- w.Run(ctx)
+	}()
+
+	// Register a handle from a goroutine that gets stuck, and one that is pet regularly
+	blockCh := make(chan struct{})
+	registeredCh := make(chan *Handle)
+	go func() {
+		h := w.Register("stuck", 5*time.Second)
+		registeredCh <- h
+		stuckLoop(h, blockCh)
+	}()
+	stuck := <-registeredCh
+	healthy := w.Register("healthy", 5*time.Second)
+	defer healthy.Unregister()
+
+	step := func(d time.Duration) {
+		assert.Eventually(t, clock.HasWaiters, time.Second, time.Millisecond)
+		clock.Step(d)
+		// Wait for the check to complete
+		assert.Eventually(t, clock.HasWaiters, time.Second, time.Millisecond)
+	}
+
+	for i := 0; i < 5; i++ {
+		step(time.Second)
+		healthy.Pet()
+	}
+	lock.Lock()
+	assert.Empty(t, stalls)
+	lock.Unlock()
+
+	step(time.Second)
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(stalls) == 1
+	}, time.Second, time.Millisecond)
+
+	lock.Lock()
+	s := stalls[0]
+	assert.Equal(t, "stuck", s.Name)
+	assert.Equal(t, 5*time.Second, s.Deadline)
+	assert.Equal(t, 6*time.Second, s.Elapsed)
+	assert.Contains(t, s.Stack, "stuckLoop")
+	assert.Equal(t, map[string]int{"stuck": 1}, counts)
+	lock.Unlock()
+	assert.Equal(t, uint64(1), stuck.Missed())
+	assert.Equal(t, uint64(0), healthy.Missed())
+
+	// Actions are not invoked again for the same stall
+	step(time.Second)
+	healthy.Pet()
+	lock.Lock()
+	assert.Len(t, stalls, 1)
+	lock.Unlock()
+
+	// After the handle is pet, it can stall again
+	close(blockCh)
+	assert.Eventually(t, func() bool {
+		return !stuck.stalled.Load()
+	}, time.Second, time.Millisecond)
+	for i := 0; i < 6; i++ {
+		step(time.Second)
+		healthy.Pet()
+	}
+	assert.Eventually(t, func() bool {
+		return stuck.Missed() == 2
+	}, time.Second, time.Millisecond)
+
+	// Unregistered handles are not checked
+	stuck.Unregister()
+	for i := 0; i < 6; i++ {
+		step(time.Second)
+		healthy.Pet()
+	}
+	assert.Equal(t, uint64(2), stuck.Missed())
+
+	require.ErrorIs(t, w.Run(ctx), ErrWatchdogRunning)
+	cancel()
+	select {
+	case err := <-runErrCh:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("watchdog did not stop")
+	}
+}
+
+func TestPetBeforeStall(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	var stalls int
+	w := New(Options{
+		Actions: []Action{func(Stall) { stalls++ }},
+	}).WithClock(clock)
+	h := w.Register("loop", 5*time.Second)
+
+	// The deadline is found to be missed, but the loop is pet before the handle is marked as stalled
+	clock.Step(6 * time.Second)
+	now := clock.Now()
+	h.Pet()
+	w.stall(h, now, nil)
+	assert.Zero(t, stalls)
+	assert.Zero(t, h.Missed())
+	assert.False(t, h.stalled.Load())
+
+	clock.Step(6 * time.Second)
+	w.stall(h, clock.Now(), nil)
+	assert.Equal(t, 1, stalls)
+	assert.Equal(t, uint64(1), h.Missed())
+}
+
+func TestExitAction(t *testing.T) {
+	var (
+		code    int
+		flushed bool
+	)
+	origExitFn, origShutdownLogsFn := exitFn, shutdownLogsFn
+	exitFn = func(c int) {
+		assert.True(t, flushed, "logs must be flushed before exiting")
+		code = c
+	}
+	shutdownLogsFn = func(ctx context.Context) (uint64, error) {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok, "flushing logs must be bounded")
+		flushed = true
+		return 0, nil
+	}
+	defer func() {
+		exitFn, shutdownLogsFn = origExitFn, origShutdownLogsFn
+	}()
+
+	ExitAction(3)(Stall{})
+	assert.Equal(t, 3, code)
+}